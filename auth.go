@@ -9,6 +9,7 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
@@ -258,8 +259,13 @@ type AuthStatus struct {
 }
 
 // AuthCheck validates the given credentials. It returns nil if successful.
+// The credentials are checked against conf.ServerAddress, or against the
+// default registry when it is empty.
 //
-// For Docker API versions >= 1.23, the AuthStatus struct will be populated, otherwise it will be empty.`
+// For Docker API versions >= 1.23, the AuthStatus struct will be populated, otherwise it will be empty.
+// Registries that support token authentication return an IdentityToken,
+// which may be stored in AuthConfiguration.IdentityToken and used in place
+// of the password in subsequent requests.
 //
 // See https://goo.gl/6nsZkH for more details.
 func (c *Client) AuthCheck(conf *AuthConfiguration) (AuthStatus, error) {
@@ -285,6 +291,56 @@ func (c *Client) AuthCheck(conf *AuthConfiguration) (AuthStatus, error) {
 	return authStatus, nil
 }
 
+// AuthCheckRegistry validates the given credentials against the registry at
+// serverAddress, overriding conf.ServerAddress. It behaves like AuthCheck,
+// and is convenient when checking the same credentials against several
+// registries.
+func (c *Client) AuthCheckRegistry(serverAddress string, conf AuthConfiguration) (AuthStatus, error) {
+	conf.ServerAddress = serverAddress
+	return c.AuthCheck(&conf)
+}
+
+// RegistryAuthError is the error returned by LoginRegistry when the registry
+// rejects the given credentials, as opposed to failures to reach the daemon or
+// the registry.
+type RegistryAuthError struct {
+	ServerAddress string
+	Err           error
+}
+
+func (err *RegistryAuthError) Error() string {
+	if err.Err != nil {
+		return err.Err.Error()
+	}
+	return "invalid credentials for registry " + err.ServerAddress
+}
+
+// Unwrap returns the error returned by the API.
+func (err *RegistryAuthError) Unwrap() error {
+	return err.Err
+}
+
+// LoginRegistry performs the login handshake with the registry at
+// auth.ServerAddress (or the default registry, when it is empty), like
+// "docker login". The returned AuthStatus contains the IdentityToken issued
+// by registries that support token authentication, which can be cached and
+// sent in AuthConfiguration.IdentityToken (along with the ServerAddress) in
+// subsequent operations, instead of the password.
+//
+// When the registry rejects the credentials, the error is a
+// *RegistryAuthError.
+func (c *Client) LoginRegistry(auth AuthConfiguration) (*AuthStatus, error) {
+	status, err := c.AuthCheck(&auth)
+	if err != nil {
+		var e *Error
+		if errors.As(err, &e) && (e.Status == http.StatusUnauthorized || e.Status == http.StatusForbidden) {
+			return nil, &RegistryAuthError{ServerAddress: auth.ServerAddress, Err: err}
+		}
+		return nil, err
+	}
+	return &status, nil
+}
+
 // helperCredentials represents credentials commit from an helper
 type helperCredentials struct {
 	Username string `json:"Username,omitempty"`
@@ -299,7 +355,10 @@ func NewAuthConfigurationsFromCredsHelpers(registry string) (*AuthConfiguration,
 	if len(pathsToTry) < 1 {
 		return nil, errors.New("no docker configuration found")
 	}
+	return newAuthConfigurationsFromCredsHelpers(pathsToTry, registry)
+}
 
+func newAuthConfigurationsFromCredsHelpers(pathsToTry []string, registry string) (*AuthConfiguration, error) {
 	provider, err := getHelperProviderFromDockerCfg(pathsToTry, registry)
 	if err != nil {
 		return nil, err
@@ -316,6 +375,40 @@ func NewAuthConfigurationsFromCredsHelpers(registry string) (*AuthConfiguration,
 	return creds, nil
 }
 
+// NewAuthConfigurationForRegistry returns the AuthConfiguration for the given
+// registry host, as resolved from the system config files (see
+// NewAuthConfigurationsFromDockerCfg for the list of files). If a credential
+// helper or credentials store is configured, it's invoked to resolve the
+// credentials. When there's no helper, or the helper fails, the static entry
+// in "auths" is used, like the docker CLI does.
+func NewAuthConfigurationForRegistry(registry string) (*AuthConfiguration, error) {
+	pathsToTry := cfgPaths(os.Getenv("DOCKER_CONFIG"), os.Getenv("HOME"))
+	if len(pathsToTry) < 1 {
+		return nil, errors.New("no docker configuration found")
+	}
+	return newAuthConfigurationForRegistry(pathsToTry, registry)
+}
+
+func newAuthConfigurationForRegistry(pathsToTry []string, registry string) (*AuthConfiguration, error) {
+	creds, helperErr := newAuthConfigurationsFromCredsHelpers(pathsToTry, registry)
+	if helperErr == nil {
+		creds.ServerAddress = registry
+		// helpers return the "<token>" username for identity tokens
+		if creds.Username == "<token>" {
+			creds.IdentityToken = creds.Password
+			creds.Username = ""
+			creds.Password = ""
+		}
+		return creds, nil
+	}
+	if auths, err := newAuthConfigurationsFromDockerCfg(pathsToTry); err == nil {
+		if creds, ok := auths.Configs[registry]; ok {
+			return &creds, nil
+		}
+	}
+	return nil, fmt.Errorf("no credentials found for registry %q: %w", registry, helperErr)
+}
+
 func getHelperProviderFromDockerCfg(pathsToTry []string, registry string) (string, error) {
 	for _, path := range pathsToTry {
 		content, err := ioutil.ReadFile(path)
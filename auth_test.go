@@ -6,13 +6,16 @@ package docker
 
 import (
 	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
 	"os"
+	"os/exec"
 	"path"
 	"reflect"
+	"runtime"
 	"strings"
 	"testing"
 )
@@ -282,6 +285,99 @@ func TestAuthCheck(t *testing.T) {
 	}
 }
 
+func TestAuthCheckIdentityToken(t *testing.T) {
+	t.Parallel()
+	fakeRT := &FakeRoundTripper{message: `{"Status":"Login Succeeded","IdentityToken":"9cbaf023786cd7"}`, status: http.StatusOK}
+	client := newTestClient(fakeRT)
+	status, err := client.AuthCheck(&AuthConfiguration{Username: "user", Password: "secret", ServerAddress: "registry.example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := AuthStatus{Status: "Login Succeeded", IdentityToken: "9cbaf023786cd7"}
+	if status != expected {
+		t.Errorf("AuthCheck: wrong status. Want %#v. Got %#v.", expected, status)
+	}
+	var conf AuthConfiguration
+	if err := json.NewDecoder(fakeRT.requests[0].Body).Decode(&conf); err != nil {
+		t.Fatal(err)
+	}
+	if conf.ServerAddress != "registry.example.com" {
+		t.Errorf("AuthCheck: wrong server address. Want %q. Got %q.", "registry.example.com", conf.ServerAddress)
+	}
+}
+
+func TestAuthCheckRegistry(t *testing.T) {
+	t.Parallel()
+	fakeRT := &FakeRoundTripper{message: `{"Status":"Login Succeeded"}`, status: http.StatusOK}
+	client := newTestClient(fakeRT)
+	creds := AuthConfiguration{Username: "user", Password: "secret", ServerAddress: "docker.io"}
+	for _, registry := range []string{"quay.io", "registry.example.com:5000"} {
+		if _, err := client.AuthCheckRegistry(registry, creds); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i, registry := range []string{"quay.io", "registry.example.com:5000"} {
+		var conf AuthConfiguration
+		if err := json.NewDecoder(fakeRT.requests[i].Body).Decode(&conf); err != nil {
+			t.Fatal(err)
+		}
+		expected := AuthConfiguration{Username: "user", Password: "secret", ServerAddress: registry}
+		if conf != expected {
+			t.Errorf("AuthCheckRegistry: wrong request body. Want %#v. Got %#v.", expected, conf)
+		}
+	}
+	if creds.ServerAddress != "docker.io" {
+		t.Errorf("AuthCheckRegistry: modified the given credentials: %#v", creds)
+	}
+}
+
+func TestLoginRegistry(t *testing.T) {
+	t.Parallel()
+	fakeRT := &FakeRoundTripper{message: `{"Status":"Login Succeeded","IdentityToken":"9cbaf023786cd7"}`, status: http.StatusOK}
+	client := newTestClient(fakeRT)
+	status, err := client.LoginRegistry(AuthConfiguration{Username: "user", Password: "secret", ServerAddress: "registry.example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := &AuthStatus{Status: "Login Succeeded", IdentityToken: "9cbaf023786cd7"}
+	if !reflect.DeepEqual(status, expected) {
+		t.Errorf("LoginRegistry: wrong status. Want %#v. Got %#v.", expected, status)
+	}
+	req := fakeRT.requests[0]
+	if req.Method != http.MethodPost || req.URL.Path != "/auth" {
+		t.Errorf("LoginRegistry: wrong request. Want POST /auth. Got %s %s.", req.Method, req.URL.Path)
+	}
+}
+
+func TestLoginRegistryUnauthorized(t *testing.T) {
+	t.Parallel()
+	for _, code := range []int{http.StatusUnauthorized, http.StatusForbidden} {
+		client := newTestClient(&FakeRoundTripper{message: "unauthorized: incorrect username or password", status: code})
+		_, err := client.LoginRegistry(AuthConfiguration{Username: "user", Password: "wrong", ServerAddress: "registry.example.com"})
+		var authErr *RegistryAuthError
+		if !errors.As(err, &authErr) {
+			t.Fatalf("LoginRegistry (%d): wrong error. Want RegistryAuthError. Got %#v.", code, err)
+		}
+		if authErr.ServerAddress != "registry.example.com" {
+			t.Errorf("LoginRegistry (%d): wrong server address. Want %q. Got %q.", code, "registry.example.com", authErr.ServerAddress)
+		}
+		var e *Error
+		if !errors.As(err, &e) || e.Status != code {
+			t.Errorf("LoginRegistry (%d): the API error should be wrapped. Got %#v.", code, err)
+		}
+	}
+}
+
+func TestLoginRegistryFailure(t *testing.T) {
+	t.Parallel()
+	client := newTestClient(&FakeRoundTripper{message: "registry unavailable", status: http.StatusInternalServerError})
+	_, err := client.LoginRegistry(AuthConfiguration{ServerAddress: "registry.example.com"})
+	var authErr *RegistryAuthError
+	if err == nil || errors.As(err, &authErr) {
+		t.Errorf("LoginRegistry: wrong error. Want a non-authentication error. Got %#v.", err)
+	}
+}
+
 func TestAuthConfigurationsMerge(t *testing.T) {
 	t.Parallel()
 	tests := []struct {
@@ -432,3 +528,98 @@ func TestParseCredsDockerConfig(t *testing.T) {
 		}
 	}
 }
+
+func TestAuthConfigurationForRegistryFallback(t *testing.T) {
+	t.Parallel()
+	tmpDir, err := ioutil.TempDir("", "go-dockerclient-auth-registry-test")
+	if err != nil {
+		t.Fatalf("Unable to create temporary directory for TestAuthConfigurationForRegistryFallback: %s", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	authString := base64.StdEncoding.EncodeToString([]byte("user:pass"))
+	content := fmt.Sprintf(`{"auths":{"docker.io": {"auth": "%s"}}}`, authString)
+	configFile := path.Join(tmpDir, "config.json")
+	if err = ioutil.WriteFile(configFile, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	auth, err := newAuthConfigurationForRegistry([]string{configFile}, "docker.io")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := &AuthConfiguration{Username: "user", Password: "pass", ServerAddress: "docker.io"}
+	if !reflect.DeepEqual(auth, expected) {
+		t.Errorf("wrong auth configuration\nwant %#v\ngot  %#v", expected, auth)
+	}
+	if _, err = newAuthConfigurationForRegistry([]string{configFile}, "quay.io"); err == nil {
+		t.Error("expected non-nil error for registry without credentials")
+	}
+}
+
+func TestAuthConfigurationForRegistryCredsHelper(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("credential helper script requires a POSIX shell")
+	}
+	tmpDir, err := ioutil.TempDir("", "go-dockerclient-creds-helper-test")
+	if err != nil {
+		t.Fatalf("Unable to create temporary directory for TestAuthConfigurationForRegistryCredsHelper: %s", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	script := "#!/bin/sh\nread registry\necho \"{\\\"ServerURL\\\":\\\"$registry\\\",\\\"Username\\\":\\\"AWS\\\",\\\"Secret\\\":\\\"s3cr3t\\\"}\"\n"
+	if err = ioutil.WriteFile(path.Join(tmpDir, "docker-credential-fake"), []byte(script), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	configFile := path.Join(tmpDir, "config.json")
+	content := `{"credHelpers":{"123.dkr.ecr.us-east-1.amazonaws.com":"fake"},"auths":{"123.dkr.ecr.us-east-1.amazonaws.com":{}}}`
+	if err = ioutil.WriteFile(configFile, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	oldPath := os.Getenv("PATH")
+	defer os.Setenv("PATH", oldPath)
+	os.Setenv("PATH", tmpDir+string(os.PathListSeparator)+oldPath)
+
+	auth, err := newAuthConfigurationForRegistry([]string{configFile}, "123.dkr.ecr.us-east-1.amazonaws.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := &AuthConfiguration{Username: "AWS", Password: "s3cr3t", ServerAddress: "123.dkr.ecr.us-east-1.amazonaws.com"}
+	if !reflect.DeepEqual(auth, expected) {
+		t.Errorf("wrong auth configuration\nwant %#v\ngot  %#v", expected, auth)
+	}
+}
+
+func TestAuthConfigurationForRegistryCredsHelperFallback(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("credential helper script requires a POSIX shell")
+	}
+	tmpDir, err := ioutil.TempDir("", "go-dockerclient-creds-helper-test")
+	if err != nil {
+		t.Fatalf("Unable to create temporary directory for TestAuthConfigurationForRegistryCredsHelperFallback: %s", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	script := "#!/bin/sh\necho 'credentials not found in native keychain' >&2\nexit 1\n"
+	if err = ioutil.WriteFile(path.Join(tmpDir, "docker-credential-broken"), []byte(script), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	authString := base64.StdEncoding.EncodeToString([]byte("user:pass"))
+	content := fmt.Sprintf(`{"credHelpers":{"docker.io":"broken","quay.io":"broken"},"auths":{"docker.io":{"auth":"%s"}}}`, authString)
+	configFile := path.Join(tmpDir, "config.json")
+	if err = ioutil.WriteFile(configFile, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	oldPath := os.Getenv("PATH")
+	defer os.Setenv("PATH", oldPath)
+	os.Setenv("PATH", tmpDir+string(os.PathListSeparator)+oldPath)
+
+	auth, err := newAuthConfigurationForRegistry([]string{configFile}, "docker.io")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := &AuthConfiguration{Username: "user", Password: "pass", ServerAddress: "docker.io"}
+	if !reflect.DeepEqual(auth, expected) {
+		t.Errorf("wrong auth configuration\nwant %#v\ngot  %#v", expected, auth)
+	}
+	var exitErr *exec.ExitError
+	if _, err = newAuthConfigurationForRegistry([]string{configFile}, "quay.io"); !errors.As(err, &exitErr) {
+		t.Errorf("expected the helper error for registry without static credentials, got %#v", err)
+	}
+}
@@ -6,14 +6,18 @@ package docker
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"io"
 	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/docker/docker/pkg/archive"
 )
@@ -40,6 +44,23 @@ func TestBuildImageMultipleContextsError(t *testing.T) {
 	}
 }
 
+func TestBuildImageRemoteWithLocalContext(t *testing.T) {
+	t.Parallel()
+	fakeRT := &FakeRoundTripper{message: "", status: http.StatusOK}
+	client := newTestClient(fakeRT)
+	for _, opts := range []BuildImageOptions{
+		{Name: "app", Remote: "https://github.com/fsouza/go-dockerclient.git", ContextDir: "testing/data", OutputStream: ioutil.Discard},
+		{Name: "app", Remote: "https://example.com/context.tar.gz", InputStream: strings.NewReader(""), OutputStream: ioutil.Discard},
+	} {
+		if err := client.BuildImage(opts); !errors.Is(err, ErrRemoteWithLocalContext) {
+			t.Errorf("BuildImage: wrong error. Want %v. Got %v.", ErrRemoteWithLocalContext, err)
+		}
+	}
+	if len(fakeRT.requests) != 0 {
+		t.Errorf("BuildImage: unexpected requests: %d", len(fakeRT.requests))
+	}
+}
+
 func TestBuildImageContextDirDockerignoreParsing(t *testing.T) {
 	t.Parallel()
 	fakeRT := &FakeRoundTripper{message: "", status: http.StatusOK}
@@ -112,6 +133,98 @@ func TestBuildImageContextDirDockerignoreParsing(t *testing.T) {
 	}
 }
 
+func TestBuildImageContextDirDockerignorePatterns(t *testing.T) {
+	t.Parallel()
+	fakeRT := &FakeRoundTripper{message: "", status: http.StatusOK}
+	client := newTestClient(fakeRT)
+	contextDir, err := ioutil.TempDir("", "go-dockerclient-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(contextDir)
+	dockerignore := "# build outputs\r\n" +
+		"*.log\r\n" +
+		"!important.log\r\n" +
+		"/build/\r\n" +
+		"\r\n" +
+		"docs\r\n" +
+		"! docs/README.md\r\n" +
+		"node_modules/  \r\n" +
+		"Dockerfile\r\n"
+	files := map[string]string{
+		".dockerignore":            dockerignore,
+		"# build outputs":          "",
+		"Dockerfile":               "FROM busybox\n",
+		"main.go":                  "package main\n",
+		"debug.log":                "",
+		"important.log":            "",
+		"build/app":                "",
+		"docs/README.md":           "",
+		"docs/guide.md":            "",
+		"node_modules/pkg/main.js": "",
+		"src/build/keep.go":        "",
+	}
+	for name, content := range files {
+		filePath := filepath.Join(contextDir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(filePath), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filePath, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	opts := BuildImageOptions{
+		Name:         "testImage",
+		OutputStream: ioutil.Discard,
+		ContextDir:   contextDir,
+	}
+	if err := client.BuildImage(opts); err != nil {
+		t.Fatal(err)
+	}
+	tmpdir, err := unpackBodyTarball(fakeRT.requests[0].Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir)
+	var foundFiles []string
+	err = filepath.Walk(tmpdir, func(filePath string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(tmpdir, filePath)
+		foundFiles = append(foundFiles, filepath.ToSlash(rel))
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedFiles := []string{
+		"# build outputs",
+		".dockerignore",
+		"Dockerfile",
+		"docs/README.md",
+		"important.log",
+		"main.go",
+		"src/build/keep.go",
+	}
+	if !reflect.DeepEqual(expectedFiles, foundFiles) {
+		t.Errorf("BuildImage: incorrect files sent in tarball to docker server\nexpected %+v, found %+v", expectedFiles, foundFiles)
+	}
+}
+
+func TestReadDockerignore(t *testing.T) {
+	t.Parallel()
+	input := "\xEF\xBB\xBF# comment\n  *.tmp  \n\n/abs/path/\n!/abs/path/keep\n! spaced\na/../b\n#!not-an-exception\n"
+	excludes, err := readDockerignore(bytes.NewBufferString(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"*.tmp", "abs/path", "!abs/path/keep", "!spaced", "b"}
+	if !reflect.DeepEqual(excludes, expected) {
+		t.Errorf("readDockerignore: wrong patterns. Want %#v. Got %#v.", expected, excludes)
+	}
+}
+
 func TestBuildImageSendXRegistryConfig(t *testing.T) {
 	t.Parallel()
 	fakeRT := &FakeRoundTripper{message: "", status: http.StatusOK}
@@ -163,3 +276,50 @@ func unpackBodyTarball(req io.Reader) (tmpdir string, err error) {
 	})
 	return
 }
+
+func TestBuildImageCancel(t *testing.T) {
+	t.Parallel()
+	aborted := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/version" {
+			w.Write([]byte(`{"ApiVersion":"1.41"}`))
+			return
+		}
+		w.Write([]byte("Step 1/2 : FROM busybox\n"))
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+		close(aborted)
+	}))
+	defer srv.Close()
+	client, err := NewClient(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var buf bytes.Buffer
+	errC := make(chan error, 1)
+	go func() {
+		errC <- client.BuildImage(BuildImageOptions{
+			Name:         "test",
+			Remote:       "github.com/fsouza/go-dockerclient",
+			OutputStream: &buf,
+			Context:      ctx,
+		})
+	}()
+	time.Sleep(100 * time.Millisecond)
+	cancel()
+	select {
+	case err = <-errC:
+	case <-time.After(5 * time.Second):
+		t.Fatal("BuildImage: timed out waiting for the build to be aborted")
+	}
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("BuildImage: wrong error. Want %v. Got %v.", context.Canceled, err)
+	}
+	select {
+	case <-aborted:
+	case <-time.After(5 * time.Second):
+		t.Error("BuildImage: the build request wasn't closed")
+	}
+}
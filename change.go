@@ -7,18 +7,18 @@ package docker
 import "fmt"
 
 // ChangeType is a type for constants indicating the type of change
-// in a container
+// in a container. The values match the ones used by the Docker daemon.
 type ChangeType int
 
 const (
 	// ChangeModify is the ChangeType for container modifications
-	ChangeModify ChangeType = iota
+	ChangeModify ChangeType = 0
 
 	// ChangeAdd is the ChangeType for additions to a container
-	ChangeAdd
+	ChangeAdd ChangeType = 1
 
 	// ChangeDelete is the ChangeType for deletions from a container
-	ChangeDelete
+	ChangeDelete ChangeType = 2
 )
 
 // Change represents a change in a container.
@@ -29,7 +29,9 @@ type Change struct {
 	Kind ChangeType
 }
 
-func (change *Change) String() string {
+// String returns the change in the same format used by "docker diff", e.g.
+// "A /etc/passwd".
+func (change Change) String() string {
 	var kind string
 	switch change.Kind {
 	case ChangeModify:
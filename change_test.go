@@ -4,7 +4,10 @@
 
 package docker
 
-import "testing"
+import (
+	"fmt"
+	"testing"
+)
 
 func TestChangeString(t *testing.T) {
 	t.Parallel()
@@ -27,3 +30,19 @@ func TestChangeString(t *testing.T) {
 		})
 	}
 }
+
+func TestChangeFormat(t *testing.T) {
+	t.Parallel()
+	changes := []Change{{"/etc", ChangeModify}, {"/etc/hosts", ChangeAdd}}
+	expected := "[C /etc A /etc/hosts]"
+	if got := fmt.Sprint(changes); got != expected {
+		t.Errorf("fmt.Sprint(changes): want %q. Got %q.", expected, got)
+	}
+}
+
+func TestChangeTypeValues(t *testing.T) {
+	t.Parallel()
+	if ChangeModify != 0 || ChangeAdd != 1 || ChangeDelete != 2 {
+		t.Errorf("ChangeType values don't match the daemon: modify=%d add=%d delete=%d", ChangeModify, ChangeAdd, ChangeDelete)
+	}
+}
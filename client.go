@@ -20,6 +20,7 @@ import (
 	"io/ioutil"
 	"net"
 	"net/http"
+	"net/http/httptrace"
 	"net/http/httputil"
 	"net/url"
 	"os"
@@ -28,6 +29,7 @@ import (
 	"runtime"
 	"strconv"
 	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -53,13 +55,26 @@ var (
 	// ErrInactivityTimeout is returned when a streamable call has been inactive for some time.
 	ErrInactivityTimeout = errors.New("inactivity time exceeded timeout")
 
+	// ErrResponseHeaderTimeout is returned by streaming calls when the
+	// daemon doesn't send the response headers in time. It's a net.Error
+	// whose Timeout method returns true.
+	ErrResponseHeaderTimeout error = timeoutError("timeout awaiting response headers")
+
+	// ErrDaemonUnhealthy is returned by PingHealthcheck when the daemon is
+	// reachable but responds to the ping with a server error.
+	ErrDaemonUnhealthy = errors.New("docker daemon is unhealthy")
+
 	apiVersion112, _ = NewAPIVersion("1.12")
 	apiVersion118, _ = NewAPIVersion("1.18")
 	apiVersion119, _ = NewAPIVersion("1.19")
 	apiVersion121, _ = NewAPIVersion("1.21")
 	apiVersion124, _ = NewAPIVersion("1.24")
 	apiVersion125, _ = NewAPIVersion("1.25")
+	apiVersion130, _ = NewAPIVersion("1.30")
 	apiVersion135, _ = NewAPIVersion("1.35")
+	apiVersion139, _ = NewAPIVersion("1.39")
+	apiVersion141, _ = NewAPIVersion("1.41")
+	apiVersion142, _ = NewAPIVersion("1.42")
 )
 
 // APIVersion is an internal representation of a version of the Remote API.
@@ -149,6 +164,9 @@ type Client struct {
 	requestedAPIVersion APIVersion
 	serverAPIVersion    APIVersion
 	expectedAPIVersion  APIVersion
+	serverExperimental  *bool
+	retryPolicy         *RetryPolicy
+	inactivityTimeout   time.Duration
 }
 
 // Dialer is an interface that allows network connections to be dialed
@@ -354,12 +372,38 @@ func NewVersionedTLSClientFromBytes(endpoint string, certPEMBlock, keyPEMBlock,
 
 // SetTimeout takes a timeout and applies it to the HTTPClient. It should not
 // be called concurrently with any other Client methods.
+//
+// The timeout bounds the whole request, including reading the response body,
+// for regular API calls. Long-running streaming operations are not subject to
+// it, as they may legitimately take much longer than a quick inspect: for
+// them the timeout only limits the wait for the response headers once the
+// request is sent, failing with ErrResponseHeaderTimeout, on any kind of
+// endpoint, including unix sockets and named pipes. Once the headers arrive
+// the stream may stay quiet for as long as the daemon needs, see
+// SetInactivityTimeout. The streaming operations are BuildImage, PullImage,
+// PushImage, ImportImage, ExportImage, ExportImages, LoadImage,
+// ExportContainer, DownloadFromContainer, UploadToContainer, Logs,
+// ContainerAttachLogs, GetServiceLogs, GetTaskLogs and Stats.
+//
+// A deadline for any single call can be set using the Context field of its
+// options.
 func (c *Client) SetTimeout(t time.Duration) {
 	if c.HTTPClient != nil {
 		c.HTTPClient.Timeout = t
 	}
 }
 
+// SetInactivityTimeout sets the inactivity timeout of the streaming
+// operations listed in SetTimeout, which fail with ErrInactivityTimeout when
+// no data is received for that long. Zero, the default, disables it, as
+// streams like followed logs, stats or silent builds may legitimately stay
+// quiet for a long time. An explicit InactivityTimeout in the options of a
+// call takes precedence over it. It should not be called concurrently with
+// any other Client methods.
+func (c *Client) SetInactivityTimeout(t time.Duration) {
+	c.inactivityTimeout = t
+}
+
 func (c *Client) checkAPIVersion() error {
 	serverAPIVersionString, err := c.getServerAPIVersionString()
 	if err != nil {
@@ -408,6 +452,53 @@ func (c *Client) PingWithContext(ctx context.Context) error {
 	return nil
 }
 
+// PingResult contains the information sent by the daemon in the headers of
+// the response to a ping.
+//
+// Swarm is the state of the node in the Swarm, as reported by the daemon (for
+// example "inactive", "active/worker" or "active/manager"). It's empty when
+// the daemon doesn't report it.
+type PingResult struct {
+	APIVersion     string
+	OSType         string
+	Experimental   bool
+	BuilderVersion string
+	Swarm          string
+}
+
+// PingHealthcheck pings the docker server and returns the capabilities the
+// daemon includes in the headers of the response, which avoids a call to
+// /version: the ping is sent without checking the API version of the server
+// first. The context object can be used to bound the ping request.
+//
+// When the daemon is reachable but responds with a server error (for example
+// a 500, when it's unhealthy), the returned error wraps ErrDaemonUnhealthy.
+// Failures to reach the daemon are returned as they are, as in Ping.
+//
+// See https://goo.gl/wYfgY1 for more details.
+func (c *Client) PingHealthcheck(ctx context.Context) (*PingResult, error) {
+	resp, err := c.do(http.MethodGet, "/_ping", doOptions{context: ctx, skipVersionCheck: true})
+	if err != nil {
+		var e *Error
+		if errors.As(err, &e) && e.Status >= http.StatusInternalServerError {
+			return nil, fmt.Errorf("%w: API error (%d): %s", ErrDaemonUnhealthy, e.Status, e.Message)
+		}
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, newError(resp)
+	}
+	experimental, _ := strconv.ParseBool(resp.Header.Get("Docker-Experimental"))
+	return &PingResult{
+		APIVersion:     resp.Header.Get("Api-Version"),
+		OSType:         resp.Header.Get("Ostype"),
+		Experimental:   experimental,
+		BuilderVersion: resp.Header.Get("Builder-Version"),
+		Swarm:          resp.Header.Get("Swarm"),
+	}, nil
+}
+
 func (c *Client) getServerAPIVersionString() (version string, err error) {
 	resp, err := c.do(http.MethodGet, "/version", doOptions{})
 	if err != nil {
@@ -432,18 +523,21 @@ type doOptions struct {
 	forceJSON bool
 	headers   map[string]string
 	context   context.Context
+	// skipVersionCheck sends the request without negotiating the API
+	// version with the daemon first
+	skipVersionCheck bool
 }
 
 func (c *Client) do(method, path string, doOptions doOptions) (*http.Response, error) {
-	var params io.Reader
+	var body []byte
 	if doOptions.data != nil || doOptions.forceJSON {
 		buf, err := json.Marshal(doOptions.data)
 		if err != nil {
 			return nil, err
 		}
-		params = bytes.NewBuffer(buf)
+		body = buf
 	}
-	if path != "/version" && !c.SkipServerVersionCheck && c.expectedAPIVersion == nil {
+	if path != "/version" && !doOptions.skipVersionCheck && !c.SkipServerVersionCheck && c.expectedAPIVersion == nil {
 		err := c.checkAPIVersion()
 		if err != nil {
 			return nil, err
@@ -458,6 +552,32 @@ func (c *Client) do(method, path string, doOptions doOptions) (*http.Response, e
 		u = c.getURL(path)
 	}
 
+	ctx := doOptions.context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	maxAttempts := c.maxAttempts(method)
+	for attempt := 1; ; attempt++ {
+		resp, err := c.doRequest(ctx, method, u, body, doOptions)
+		if attempt >= maxAttempts {
+			return resp, err
+		}
+		var e *Error
+		if err == nil || (errors.As(err, &e) && !isRetryableStatus(e.Status)) || ctx.Err() != nil {
+			return resp, err
+		}
+		if !c.waitRetry(ctx, attempt) {
+			return resp, err
+		}
+	}
+}
+
+func (c *Client) doRequest(ctx context.Context, method, u string, body []byte, doOptions doOptions) (*http.Response, error) {
+	var params io.Reader
+	if body != nil {
+		params = bytes.NewReader(body)
+	}
 	req, err := http.NewRequest(method, u, params)
 	if err != nil {
 		return nil, err
@@ -473,11 +593,6 @@ func (c *Client) do(method, path string, doOptions doOptions) (*http.Response, e
 		req.Header.Set(k, v)
 	}
 
-	ctx := doOptions.context
-	if ctx == nil {
-		ctx = context.Background()
-	}
-
 	resp, err := c.HTTPClient.Do(req.WithContext(ctx))
 	if err != nil {
 		if strings.Contains(err.Error(), "connection refused") {
@@ -506,7 +621,18 @@ type streamOptions struct {
 	// Timeout with no data is received, it's reset every time new data
 	// arrives
 	inactivityTimeout time.Duration
-	context           context.Context
+	// eventStream opts out of the inactivity timeout of the client, as the
+	// event stream may be silent for any amount of time
+	eventStream bool
+	context     context.Context
+	// responseHeaders, when set, receives the headers of the response
+	responseHeaders *http.Header
+	// started, when set, is closed once the daemon responds with a
+	// successful status, before the body is streamed
+	started chan struct{}
+	// auxCallback, when set, is called with each JSON message carrying
+	// auxiliary data (such as the digest at the end of a push)
+	auxCallback func(jsonmessage.JSONMessage)
 }
 
 func chooseError(ctx context.Context, err error) error {
@@ -570,6 +696,16 @@ func (c *Client) streamURL(method, url string, streamOptions streamOptions) erro
 	if streamOptions.stderr == nil {
 		streamOptions.stderr = ioutil.Discard
 	}
+	// streaming calls are not bounded by the overall client timeout: it
+	// only limits the wait for the response headers.
+	headerTimeout := streamOptions.timeout
+	if headerTimeout == 0 && c.HTTPClient != nil {
+		headerTimeout = c.HTTPClient.Timeout
+	}
+	if streamOptions.inactivityTimeout == 0 && !streamOptions.eventStream {
+		streamOptions.inactivityTimeout = c.inactivityTimeout
+	}
+	var canceled, headerTimedOut uint32
 
 	if protocol == unixProtocol || protocol == namedPipeProtocol {
 		var dial net.Conn
@@ -588,30 +724,51 @@ func (c *Client) streamURL(method, url string, streamOptions streamOptions) erro
 		}
 
 		// ReadResponse may hang if server does not replay
-		if streamOptions.timeout > 0 {
-			dial.SetDeadline(time.Now().Add(streamOptions.timeout))
+		if headerTimeout > 0 {
+			dial.SetDeadline(time.Now().Add(headerTimeout))
 		}
 
 		if streamOptions.reqSent != nil {
 			close(streamOptions.reqSent)
 		}
-		if resp, err = http.ReadResponse(breader, req); err != nil {
-			// Cancel timeout for future I/O operations
-			if streamOptions.timeout > 0 {
-				dial.SetDeadline(time.Time{})
-			}
+		resp, err = http.ReadResponse(breader, req)
+		// Cancel timeout for future I/O operations
+		if headerTimeout > 0 {
+			dial.SetDeadline(time.Time{})
+		}
+		if err != nil {
 			if strings.Contains(err.Error(), "connection refused") {
 				return ErrConnectionRefused
 			}
+			var netErr net.Error
+			if headerTimeout > 0 && errors.As(err, &netErr) && netErr.Timeout() {
+				return ErrResponseHeaderTimeout
+			}
 
 			return chooseError(subCtx, err)
 		}
 		defer resp.Body.Close()
 	} else {
-		if resp, err = c.HTTPClient.Do(req.WithContext(subCtx)); err != nil {
+		httpClient := c.HTTPClient
+		if httpClient.Timeout > 0 {
+			noTimeout := *httpClient
+			noTimeout.Timeout = 0
+			httpClient = &noTimeout
+		}
+		reqCtx := subCtx
+		stopHeaderTimeout := func() {}
+		if headerTimeout > 0 {
+			reqCtx, stopHeaderTimeout = handleResponseHeaderTimeout(subCtx, headerTimeout, cancelRequest, &headerTimedOut)
+		}
+		resp, err = httpClient.Do(req.WithContext(reqCtx))
+		stopHeaderTimeout()
+		if err != nil {
 			if strings.Contains(err.Error(), "connection refused") {
 				return ErrConnectionRefused
 			}
+			if atomic.LoadUint32(&headerTimedOut) != 0 {
+				return ErrResponseHeaderTimeout
+			}
 			return chooseError(subCtx, err)
 		}
 		defer resp.Body.Close()
@@ -619,10 +776,15 @@ func (c *Client) streamURL(method, url string, streamOptions streamOptions) erro
 			close(streamOptions.reqSent)
 		}
 	}
+	if streamOptions.responseHeaders != nil {
+		*streamOptions.responseHeaders = resp.Header
+	}
 	if resp.StatusCode < 200 || resp.StatusCode >= 400 {
 		return newError(resp)
 	}
-	var canceled uint32
+	if streamOptions.started != nil {
+		close(streamOptions.started)
+	}
 	if streamOptions.inactivityTimeout > 0 {
 		var ch chan<- struct{}
 		resp.Body, ch = handleInactivityTimeout(resp.Body, streamOptions.inactivityTimeout, cancelRequest, &canceled)
@@ -638,6 +800,54 @@ func (c *Client) streamURL(method, url string, streamOptions streamOptions) erro
 	return nil
 }
 
+// streamReader runs the given streaming call in a goroutine, writing its
+// output to the returned reader. run must close started once the daemon
+// accepted the request, which is when streamReader returns, so errors sent
+// by the daemon in response to the request are returned here, while errors
+// in the middle of the stream are returned by Read. Closing the reader
+// cancels the context given to run and waits for it to return.
+func streamReader(ctx context.Context, run func(ctx context.Context, w io.Writer, started chan struct{}) error) (io.ReadCloser, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	pr, pw := io.Pipe()
+	started := make(chan struct{})
+	done := make(chan error, 1)
+	go func() {
+		err := run(ctx, pw, started)
+		pw.CloseWithError(err)
+		done <- err
+	}()
+	select {
+	case <-started:
+	case err := <-done:
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		done <- err
+	}
+	return &pipeStreamReader{PipeReader: pr, cancel: cancel, done: done}, nil
+}
+
+// pipeStreamReader is the reader returned by streamReader.
+type pipeStreamReader struct {
+	*io.PipeReader
+	cancel context.CancelFunc
+	done   chan error
+	once   sync.Once
+}
+
+func (r *pipeStreamReader) Close() error {
+	r.once.Do(func() {
+		r.cancel()
+		r.PipeReader.Close()
+		<-r.done
+	})
+	return nil
+}
+
 func handleStreamResponse(resp *http.Response, streamOptions *streamOptions) error {
 	var err error
 	if !streamOptions.useJSONDecoder && resp.Header.Get("Content-Type") != "application/json" {
@@ -651,17 +861,44 @@ func handleStreamResponse(resp *http.Response, streamOptions *streamOptions) err
 	// if we want to get raw json stream, just copy it back to output
 	// without decoding it
 	if streamOptions.rawJSONStream {
+		if streamOptions.auxCallback != nil {
+			return decodeAuxMessages(io.TeeReader(resp.Body, streamOptions.stdout), streamOptions.auxCallback)
+		}
 		_, err = io.Copy(streamOptions.stdout, resp.Body)
 		return err
 	}
 	if st, ok := streamOptions.stdout.(stream); ok {
-		err = jsonmessage.DisplayJSONMessagesToStream(resp.Body, st, nil)
+		err = jsonmessage.DisplayJSONMessagesToStream(resp.Body, st, streamOptions.auxCallback)
 	} else {
-		err = jsonmessage.DisplayJSONMessagesStream(resp.Body, streamOptions.stdout, 0, false, nil)
+		err = jsonmessage.DisplayJSONMessagesStream(resp.Body, streamOptions.stdout, 0, false, streamOptions.auxCallback)
 	}
 	return err
 }
 
+// decodeAuxMessages reads the JSON messages in r, calling callback for the
+// ones carrying auxiliary data. Anything after a message that can't be
+// decoded is read without being inspected.
+func decodeAuxMessages(r io.Reader, callback func(jsonmessage.JSONMessage)) error {
+	decoder := json.NewDecoder(r)
+	for {
+		var msg jsonmessage.JSONMessage
+		if err := decoder.Decode(&msg); err != nil {
+			var syntaxErr *json.SyntaxError
+			if errors.As(err, &syntaxErr) {
+				_, err = io.Copy(ioutil.Discard, r)
+				return err
+			}
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		if msg.Aux != nil {
+			callback(msg)
+		}
+	}
+}
+
 type stream interface {
 	io.Writer
 	FD() uintptr
@@ -705,6 +942,45 @@ func handleInactivityTimeout(reader io.ReadCloser, timeout time.Duration, cancel
 	return proxyReader, done
 }
 
+// handleResponseHeaderTimeout returns a context that traces the request sent
+// with it, calling cancelRequest and setting timedOut if the response headers
+// don't arrive within timeout once the request is written. The returned
+// function must be called as soon as the response headers are received.
+func handleResponseHeaderTimeout(ctx context.Context, timeout time.Duration, cancelRequest func(), timedOut *uint32) (context.Context, func()) {
+	var (
+		mu    sync.Mutex
+		timer *time.Timer
+		done  bool
+	)
+	trace := httptrace.ClientTrace{
+		WroteRequest: func(httptrace.WroteRequestInfo) {
+			mu.Lock()
+			defer mu.Unlock()
+			if !done {
+				timer = time.AfterFunc(timeout, func() {
+					atomic.AddUint32(timedOut, 1)
+					cancelRequest()
+				})
+			}
+		},
+	}
+	return httptrace.WithClientTrace(ctx, &trace), func() {
+		mu.Lock()
+		defer mu.Unlock()
+		done = true
+		if timer != nil {
+			timer.Stop()
+		}
+	}
+}
+
+// timeoutError is a net.Error for the timeouts enforced by the client.
+type timeoutError string
+
+func (e timeoutError) Error() string   { return string(e) }
+func (e timeoutError) Timeout() bool   { return true }
+func (e timeoutError) Temporary() bool { return true }
+
 type hijackOptions struct {
 	success        chan struct{}
 	setRawTerminal bool
@@ -751,31 +1027,14 @@ func (c *Client) hijack(method, path string, hijackOptions hijackOptions) (Close
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Connection", "Upgrade")
 	req.Header.Set("Upgrade", "tcp")
-	protocol := c.endpointURL.Scheme
-	address := c.endpointURL.Path
-	if protocol != unixProtocol && protocol != namedPipeProtocol {
-		protocol = "tcp"
-		address = c.endpointURL.Host
-	}
-	var dial net.Conn
-	if c.TLSConfig != nil && protocol != unixProtocol && protocol != namedPipeProtocol {
-		netDialer, ok := c.Dialer.(*net.Dialer)
-		if !ok {
-			return nil, ErrTLSNotSupported
-		}
-		dial, err = tlsDialWithDialer(netDialer, protocol, address, c.TLSConfig)
-		if err != nil {
-			return nil, err
-		}
-	} else {
-		dial, err = c.Dialer.Dial(protocol, address)
-		if err != nil {
-			return nil, err
-		}
+	dial, err := c.dialRaw()
+	if err != nil {
+		return nil, err
 	}
 
 	errs := make(chan error, 1)
 	quit := make(chan struct{})
+	var closeOnce sync.Once
 	go func() {
 		//lint:ignore SA1019 the alternative doesn't quite work, so keep using the deprecated thing.
 		clientconn := httputil.NewClientConn(dial, nil)
@@ -830,15 +1089,17 @@ func (c *Client) hijack(method, path string, hijackOptions hijackOptions) (Close
 				_, err = io.Copy(rwc, hijackOptions.in)
 			}
 			errChanIn <- err
-			rwc.(interface {
-				CloseWrite() error
-			}).CloseWrite()
+			closeWrite(rwc)
 		}()
 
 		var errIn error
 		select {
 		case errIn = <-errChanIn:
 		case <-quit:
+			// Detaching: stop sending input and let the deferred Close
+			// unblock the output copy, which reports to a buffered
+			// channel and exits on its own.
+			closeWrite(rwc)
 		}
 
 		var errOut error
@@ -858,11 +1119,40 @@ func (c *Client) hijack(method, path string, hijackOptions hijackOptions) (Close
 		closerFunc
 		waiterFunc
 	}{
-		closerFunc(func() error { close(quit); return nil }),
+		closerFunc(func() error { closeOnce.Do(func() { close(quit) }); return nil }),
 		waiterFunc(func() error { return <-errs }),
 	}, nil
 }
 
+// dialRaw opens a new connection to the Docker daemon, for requests that take
+// over the connection after the HTTP handshake.
+func (c *Client) dialRaw() (net.Conn, error) {
+	protocol := c.endpointURL.Scheme
+	address := c.endpointURL.Path
+	if protocol != unixProtocol && protocol != namedPipeProtocol {
+		protocol = "tcp"
+		address = c.endpointURL.Host
+	}
+	if c.TLSConfig != nil && protocol != unixProtocol && protocol != namedPipeProtocol {
+		netDialer, ok := c.Dialer.(*net.Dialer)
+		if !ok {
+			return nil, ErrTLSNotSupported
+		}
+		return tlsDialWithDialer(netDialer, protocol, address, c.TLSConfig)
+	}
+	return c.Dialer.Dial(protocol, address)
+}
+
+// closeWrite half-closes the connection for writes, when the underlying
+// connection supports it.
+func closeWrite(conn io.ReadWriteCloser) {
+	if cw, ok := conn.(interface {
+		CloseWrite() error
+	}); ok {
+		cw.CloseWrite()
+	}
+}
+
 func (c *Client) getURL(path string) string {
 	urlStr := strings.TrimRight(c.endpointURL.String(), "/")
 	if c.endpointURL.Scheme == unixProtocol || c.endpointURL.Scheme == namedPipeProtocol {
@@ -11,6 +11,7 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
@@ -495,6 +496,98 @@ func TestPingFailingWrongStatus(t *testing.T) {
 	}
 }
 
+func TestPingHealthcheck(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/_ping" {
+			t.Errorf("PingHealthcheck: wrong path. Want %q. Got %q.", "/_ping", r.URL.Path)
+		}
+		w.Header().Set("Api-Version", "1.41")
+		w.Header().Set("Ostype", "linux")
+		w.Header().Set("Docker-Experimental", "true")
+		w.Header().Set("Builder-Version", "2")
+		w.Header().Set("Swarm", "active/manager")
+		w.Write([]byte("OK"))
+	}))
+	defer srv.Close()
+	client, err := NewClient(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the ping must not wait for the version check
+	client.SkipServerVersionCheck = false
+	result, err := client.PingHealthcheck(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := PingResult{
+		APIVersion:     "1.41",
+		OSType:         "linux",
+		Experimental:   true,
+		BuilderVersion: "2",
+		Swarm:          "active/manager",
+	}
+	if !reflect.DeepEqual(*result, expected) {
+		t.Errorf("PingHealthcheck: wrong result. Want %#v. Got %#v.", expected, *result)
+	}
+}
+
+func TestPingHealthcheckUnhealthy(t *testing.T) {
+	t.Parallel()
+	fakeRT := &FakeRoundTripper{message: "daemon is shutting down", status: http.StatusInternalServerError}
+	client := newTestClient(fakeRT)
+	_, err := client.PingHealthcheck(context.Background())
+	if !errors.Is(err, ErrDaemonUnhealthy) {
+		t.Fatalf("PingHealthcheck: wrong error. Want %v. Got %v.", ErrDaemonUnhealthy, err)
+	}
+	if !strings.Contains(err.Error(), "daemon is shutting down") {
+		t.Errorf("PingHealthcheck: the error doesn't include the message from the daemon: %v", err)
+	}
+}
+
+func TestPingHealthcheckConnectionFailure(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(http.NotFoundHandler())
+	srv.Close()
+	client, err := NewClient(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.SkipServerVersionCheck = true
+	_, err = client.PingHealthcheck(context.Background())
+	if err == nil {
+		t.Fatal("PingHealthcheck: unexpected <nil> error")
+	}
+	if errors.Is(err, ErrDaemonUnhealthy) {
+		t.Errorf("PingHealthcheck: connection failure reported as %v", err)
+	}
+}
+
+func TestPingHealthcheckContextTimeout(t *testing.T) {
+	t.Parallel()
+	done := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-done:
+		case <-r.Context().Done():
+		}
+	}))
+	defer srv.Close()
+	defer close(done)
+	client, err := NewClient(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the ping must not wait for the version check
+	client.SkipServerVersionCheck = false
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	_, err = client.PingHealthcheck(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("PingHealthcheck: wrong error. Want %v. Got %v.", context.DeadlineExceeded, err)
+	}
+}
+
 func TestPingErrorWithNativeClient(t *testing.T) {
 	t.Parallel()
 	srv, cleanup, err := newNativeServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
@@ -581,6 +674,99 @@ func TestClientStreamInactivityTimeout(t *testing.T) {
 	}
 }
 
+func TestClientStreamIgnoresClientTimeout(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		for i := 0; i < 5; i++ {
+			fmt.Fprintf(w, "%d\n", i)
+			if f, ok := w.(http.Flusher); ok {
+				f.Flush()
+			}
+			time.Sleep(200 * time.Millisecond)
+		}
+	}))
+	defer srv.Close()
+	client, err := NewClient(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.SetTimeout(100 * time.Millisecond)
+	var w bytes.Buffer
+	err = client.stream(http.MethodPost, "/image/create", streamOptions{
+		setRawTerminal: true,
+		stdout:         &w,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "0\n1\n2\n3\n4\n"
+	if result := w.String(); result != expected {
+		t.Fatalf("expected stream result %q, got: %q", expected, result)
+	}
+	if client.HTTPClient.Timeout != 100*time.Millisecond {
+		t.Errorf("stream: modified the client timeout: %s", client.HTTPClient.Timeout)
+	}
+}
+
+func TestClientStreamClientInactivityTimeout(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		for i := 0; i < 5; i++ {
+			fmt.Fprintf(w, "%d\n", i)
+			if f, ok := w.(http.Flusher); ok {
+				f.Flush()
+			}
+			time.Sleep(500 * time.Millisecond)
+		}
+	}))
+	defer srv.Close()
+	client, err := NewClient(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.SetInactivityTimeout(100 * time.Millisecond)
+	var w bytes.Buffer
+	err = client.stream(http.MethodPost, "/image/create", streamOptions{
+		setRawTerminal: true,
+		stdout:         &w,
+	})
+	if !errors.Is(err, ErrInactivityTimeout) {
+		t.Fatalf("expected %s, got: %s", ErrInactivityTimeout, err)
+	}
+	expected := "0\n"
+	if result := w.String(); result != expected {
+		t.Fatalf("expected stream result %q, got: %q", expected, result)
+	}
+}
+
+func TestClientStreamClientTimeoutWaitingHeaders(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		time.Sleep(time.Second)
+		fmt.Fprint(w, "0\n")
+	}))
+	defer srv.Close()
+	client, err := NewClient(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.SetTimeout(100 * time.Millisecond)
+	start := time.Now()
+	err = client.stream(http.MethodPost, "/image/create", streamOptions{
+		setRawTerminal: true,
+		stdout:         ioutil.Discard,
+	})
+	if !errors.Is(err, ErrResponseHeaderTimeout) {
+		t.Fatalf("expected %s, got: %s", ErrResponseHeaderTimeout, err)
+	}
+	if e, ok := err.(net.Error); !ok || !e.Timeout() {
+		t.Errorf("expected a timeout net.Error, got %#v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("stream: waited %s for the response headers", elapsed)
+	}
+}
+
 func TestClientStreamContextDeadline(t *testing.T) {
 	t.Parallel()
 	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
@@ -817,6 +1003,75 @@ func TestClientDoContextCancel(t *testing.T) {
 	}
 }
 
+func TestClientStreamClientInactivityTimeoutNativeClient(t *testing.T) {
+	t.Parallel()
+	srv, cleanup, err := newNativeServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		for i := 0; i < 5; i++ {
+			fmt.Fprintf(w, "%d\n", i)
+			if f, ok := w.(http.Flusher); ok {
+				f.Flush()
+			}
+			time.Sleep(500 * time.Millisecond)
+		}
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup()
+	srv.Start()
+	defer srv.Close()
+	client, err := NewClient(nativeProtocol + "://" + srv.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.SetInactivityTimeout(100 * time.Millisecond)
+	var w bytes.Buffer
+	err = client.stream(http.MethodPost, "/image/create", streamOptions{
+		setRawTerminal: true,
+		stdout:         &w,
+	})
+	if !errors.Is(err, ErrInactivityTimeout) {
+		t.Fatalf("expected %s, got: %s", ErrInactivityTimeout, err)
+	}
+	expected := "0\n"
+	if result := w.String(); result != expected {
+		t.Fatalf("expected stream result %q, got: %q", expected, result)
+	}
+}
+
+func TestClientStreamClientTimeoutWaitingHeadersNativeClient(t *testing.T) {
+	t.Parallel()
+	srv, cleanup, err := newNativeServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		time.Sleep(time.Second)
+		fmt.Fprint(w, "0\n")
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup()
+	srv.Start()
+	defer srv.Close()
+	client, err := NewClient(nativeProtocol + "://" + srv.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.SetTimeout(100 * time.Millisecond)
+	start := time.Now()
+	err = client.stream(http.MethodPost, "/image/create", streamOptions{
+		setRawTerminal: true,
+		stdout:         ioutil.Discard,
+	})
+	if !errors.Is(err, ErrResponseHeaderTimeout) {
+		t.Fatalf("expected %s, got: %s", ErrResponseHeaderTimeout, err)
+	}
+	if e, ok := err.(net.Error); !ok || !e.Timeout() {
+		t.Errorf("expected a timeout net.Error, got %#v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("stream: waited %s for the response headers", elapsed)
+	}
+}
+
 func TestClientStreamTimeoutNativeClient(t *testing.T) {
 	t.Parallel()
 	srv, cleanup, err := newNativeServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
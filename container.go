@@ -5,7 +5,11 @@
 package docker
 
 import (
+	"errors"
 	"fmt"
+	"net"
+	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -51,6 +55,24 @@ type APIContainers struct {
 	Mounts     []APIMount        `json:"Mounts,omitempty" yaml:"Mounts,omitempty" toml:"Mounts,omitempty"`
 }
 
+// Health returns the health status of the container, as reported in the
+// Status field by the daemon in the list of containers. It returns one of
+// HealthStarting, Healthy or Unhealthy, or an empty string if the container
+// does not have a health check or is not running.
+func (c *APIContainers) Health() string {
+	start := strings.LastIndex(c.Status, "(")
+	end := strings.LastIndex(c.Status, ")")
+	if start < 0 || end < start {
+		return ""
+	}
+	status := strings.TrimPrefix(c.Status[start+1:end], "health: ")
+	switch status {
+	case HealthStarting, Healthy, Unhealthy:
+		return status
+	}
+	return ""
+}
+
 // NetworkList encapsulates a map of networks, as returned by the Docker API in
 // ListContainers.
 type NetworkList struct {
@@ -83,6 +105,15 @@ type HealthCheck struct {
 	Output   string    `json:"Output,omitempty" yaml:"Output,omitempty" toml:"Output,omitempty"`
 }
 
+// Possible values for the health status of a container, as reported in
+// Health.Status and accepted by the "health" filter in ListContainers.
+const (
+	HealthStarting = "starting"
+	Healthy        = "healthy"
+	Unhealthy      = "unhealthy"
+	NoHealthcheck  = "none"
+)
+
 // Health represents the health of a container.
 type Health struct {
 	Status        string        `json:"Status,omitempty" yaml:"Status,omitempty" toml:"Status,omitempty"`
@@ -244,6 +275,21 @@ func parsePort(rawPort string) (int, error) {
 	return int(port), nil
 }
 
+// formatContainerErrors describes the errors, keyed by container ID, of an
+// operation on a set of containers, sorted by ID.
+func formatContainerErrors(verb string, errs map[string]error) string {
+	ids := make([]string, 0, len(errs))
+	for id := range errs {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	msgs := make([]string, len(ids))
+	for i, id := range ids {
+		msgs[i] = id + ": " + errs[id].Error()
+	}
+	return fmt.Sprintf("failed to %s %d container(s): %s", verb, len(ids), strings.Join(msgs, "; "))
+}
+
 // Config is the list of configuration options used when creating a container.
 // Config does not contain the options that are specific to starting a container on a
 // given host.  Those are contained in HostConfig
@@ -331,14 +377,17 @@ type VolumeDriverConfig struct {
 // Mount represents a mount point in the container.
 //
 // It has been added in the version 1.20 of the Docker API, available since
-// Docker 1.8.
+// Docker 1.8. Type ("bind", "volume", "tmpfs" or "npipe") and Propagation
+// are reported by Docker API 1.25 and above.
 type Mount struct {
+	Type        string
 	Name        string
 	Source      string
 	Destination string
 	Driver      string
 	Mode        string
 	RW          bool
+	Propagation string
 }
 
 // LogConfig defines the log driver type and the configuration for it.
@@ -457,7 +506,13 @@ type Device struct {
 	CgroupPermissions string `json:"CgroupPermissions,omitempty" yaml:"CgroupPermissions,omitempty" toml:"CgroupPermissions,omitempty"`
 }
 
-// DeviceRequest represents a request for device that's sent to device drivers.
+// DeviceRequest represents a request for device that's sent to device drivers,
+// such as the request for GPUs sent to the nvidia driver (API 1.40 and above).
+//
+// Count is the number of devices to allocate (-1 means all of them), and it
+// can't be combined with DeviceIDs, which selects specific devices.
+// Capabilities is a list of alternatives, each one a list of capabilities
+// that the driver must support, for example [][]string{{"gpu"}}.
 type DeviceRequest struct {
 	Driver       string            `json:"Driver,omitempty" yaml:"Driver,omitempty" toml:"Driver,omitempty"`
 	Count        int               `json:"Count,omitempty" yaml:"Count,omitempty" toml:"Count,omitempty"`
@@ -550,13 +605,88 @@ type HostConfig struct {
 	MaskedPaths          []string               `json:"MaskedPaths,omitempty" yaml:"MaskedPaths,omitempty" toml:"MaskedPaths,omitempty"`
 	ReadonlyPaths        []string               `json:"ReadonlyPaths,omitempty" yaml:"ReadonlyPaths,omitempty" toml:"ReadonlyPaths,omitempty"`
 	Runtime              string                 `json:"Runtime,omitempty" yaml:"Runtime,omitempty" toml:"Runtime,omitempty"`
-	Init                 bool                   `json:",omitempty" yaml:",omitempty"`
+	Init                 *bool                  `json:"Init,omitempty" yaml:"Init,omitempty" toml:"Init,omitempty"` // run an init process (tini) as PID 1; nil means use the daemon default
 	Privileged           bool                   `json:"Privileged,omitempty" yaml:"Privileged,omitempty" toml:"Privileged,omitempty"`
 	PublishAllPorts      bool                   `json:"PublishAllPorts,omitempty" yaml:"PublishAllPorts,omitempty" toml:"PublishAllPorts,omitempty"`
 	ReadonlyRootfs       bool                   `json:"ReadonlyRootfs,omitempty" yaml:"ReadonlyRootfs,omitempty" toml:"ReadonlyRootfs,omitempty"`
 	AutoRemove           bool                   `json:"AutoRemove,omitempty" yaml:"AutoRemove,omitempty" toml:"AutoRemove,omitempty"`
 }
 
+// HostGateway is the special value that can be used as the address of an
+// entry in HostConfig.ExtraHosts. The daemon resolves it to the IP address of
+// the host (API 1.41 and above).
+const HostGateway = "host-gateway"
+
+// ErrInvalidExtraHost is the error returned when an entry of
+// HostConfig.ExtraHosts is not in the "name:address" format, where address is
+// either an IP address or HostGateway.
+var ErrInvalidExtraHost = errors.New("invalid extra host")
+
+// AddExtraHost appends an entry mapping name to value to ExtraHosts. The value
+// must be an IP address (IPv6 addresses may be enclosed in brackets) or
+// HostGateway.
+func (hc *HostConfig) AddExtraHost(name, value string) error {
+	entry := name + ":" + strings.TrimSuffix(strings.TrimPrefix(value, "["), "]")
+	if err := validateExtraHost(entry); err != nil {
+		return err
+	}
+	hc.ExtraHosts = append(hc.ExtraHosts, entry)
+	return nil
+}
+
+func validateExtraHost(entry string) error {
+	parts := strings.SplitN(entry, ":", 2)
+	if len(parts) != 2 || parts[0] == "" || strings.ContainsAny(parts[0], " \t") {
+		return fmt.Errorf("%w %q: must be in the format name:address", ErrInvalidExtraHost, entry)
+	}
+	address := parts[1]
+	if address == HostGateway {
+		return nil
+	}
+	if net.ParseIP(strings.TrimSuffix(strings.TrimPrefix(address, "["), "]")) == nil {
+		return fmt.Errorf("%w %q: %q is not an IP address or %q", ErrInvalidExtraHost, entry, address, HostGateway)
+	}
+	return nil
+}
+
+func validateExtraHosts(entries []string) error {
+	for _, entry := range entries {
+		if err := validateExtraHost(entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ErrInvalidDeviceRequest is the error returned when an entry of
+// HostConfig.DeviceRequests sets both Count and DeviceIDs, or has a negative
+// Count other than -1.
+var ErrInvalidDeviceRequest = errors.New("invalid device request")
+
+// ErrInvalidDeviceCgroupRule is the error returned when an entry of
+// HostConfig.DeviceCgroupRules is not in the "type major:minor access" format
+// (for example "c 189:* rwm").
+var ErrInvalidDeviceCgroupRule = errors.New("invalid device cgroup rule")
+
+var deviceCgroupRuleRegexp = regexp.MustCompile(`^[acb] ([0-9]+|\*):([0-9]+|\*) [rwm]{1,3}$`)
+
+func validateDevices(hc *HostConfig) error {
+	for _, request := range hc.DeviceRequests {
+		if request.Count != 0 && len(request.DeviceIDs) > 0 {
+			return fmt.Errorf("%w: cannot set both Count and DeviceIDs", ErrInvalidDeviceRequest)
+		}
+		if request.Count < -1 {
+			return fmt.Errorf("%w: invalid Count %d", ErrInvalidDeviceRequest, request.Count)
+		}
+	}
+	for _, rule := range hc.DeviceCgroupRules {
+		if !deviceCgroupRuleRegexp.MatchString(rule) {
+			return fmt.Errorf("%w %q", ErrInvalidDeviceCgroupRule, rule)
+		}
+	}
+	return nil
+}
+
 // NetworkingConfig represents the container's networking configuration for each of its interfaces
 // Carries the networking configs specified in the `docker run` and `docker network connect` commands
 type NetworkingConfig struct {
@@ -11,11 +11,18 @@ import (
 // UploadToContainerOptions is the set of options that can be used when
 // uploading an archive into a container.
 //
+// NoOverwriteDirNonDir makes the daemon fail the upload instead of replacing
+// an existing directory with a file, or an existing file with a directory.
+// CopyUIDGID makes the uploaded files owned by the user and group of the
+// container, instead of the owner recorded in the archive, and requires
+// Docker API 1.30 or greater.
+//
 // See https://goo.gl/g25o7u for more details.
 type UploadToContainerOptions struct {
 	InputStream          io.Reader `json:"-" qs:"-"`
 	Path                 string    `qs:"path"`
 	NoOverwriteDirNonDir bool      `qs:"noOverwriteDirNonDir"`
+	CopyUIDGID           bool      `qs:"copyUIDGID" ver:"1.30"`
 	Context              context.Context
 }
 
@@ -24,9 +31,11 @@ type UploadToContainerOptions struct {
 //
 // See https://goo.gl/g25o7u for more details.
 func (c *Client) UploadToContainer(id string, opts UploadToContainerOptions) error {
-	url := fmt.Sprintf("/containers/%s/archive?", id) + queryString(opts)
-
-	return c.stream(http.MethodPut, url, streamOptions{
+	url, err := c.getPath(fmt.Sprintf("/containers/%s/archive", id), opts)
+	if err != nil {
+		return err
+	}
+	return c.streamURL(http.MethodPut, url, streamOptions{
 		in:      opts.InputStream,
 		context: opts.Context,
 	})
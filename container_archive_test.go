@@ -3,6 +3,7 @@ package docker
 import (
 	"bytes"
 	"net/http"
+	"reflect"
 	"testing"
 )
 
@@ -32,6 +33,47 @@ func TestUploadToContainer(t *testing.T) {
 	}
 }
 
+func TestUploadToContainerOwnershipOptions(t *testing.T) {
+	t.Parallel()
+	fakeRT := &FakeRoundTripper{status: http.StatusOK}
+	client := newTestClient(fakeRT)
+	opts := UploadToContainerOptions{
+		Path:                 "/app",
+		InputStream:          bytes.NewBufferString("archive"),
+		CopyUIDGID:           true,
+		NoOverwriteDirNonDir: true,
+	}
+	if err := client.UploadToContainer("a123456", opts); err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string][]string{"path": {"/app"}, "copyUIDGID": {"1"}, "noOverwriteDirNonDir": {"1"}}
+	if got := map[string][]string(fakeRT.requests[0].URL.Query()); !reflect.DeepEqual(got, expected) {
+		t.Errorf("UploadToContainer: wrong query string. Want %#v. Got %#v.", expected, got)
+	}
+}
+
+func TestUploadToContainerCopyUIDGIDVersion(t *testing.T) {
+	t.Parallel()
+	fakeRT := &FakeRoundTripper{status: http.StatusOK}
+	client := newTestClient(fakeRT)
+	opts := UploadToContainerOptions{Path: "/app", InputStream: bytes.NewBufferString("archive"), CopyUIDGID: true}
+	if err := client.UploadToContainer("a123456", opts); err != nil {
+		t.Fatal(err)
+	}
+	if path := fakeRT.requests[0].URL.Path; path != "/v1.30/containers/a123456/archive" {
+		t.Errorf("UploadToContainer: wrong path. Want %q. Got %q.", "/v1.30/containers/a123456/archive", path)
+	}
+	fakeRT = &FakeRoundTripper{status: http.StatusOK}
+	client = newTestClient(fakeRT)
+	client.requestedAPIVersion, _ = NewAPIVersion("1.29")
+	if err := client.UploadToContainer("a123456", opts); err == nil {
+		t.Error("UploadToContainer: unexpected <nil> error with CopyUIDGID on API 1.29")
+	}
+	if len(fakeRT.requests) != 0 {
+		t.Errorf("UploadToContainer: expected no requests, got %d", len(fakeRT.requests))
+	}
+}
+
 func TestDownloadFromContainer(t *testing.T) {
 	t.Parallel()
 	filecontent := "File content"
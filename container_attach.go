@@ -1,10 +1,17 @@
 package docker
 
 import (
+	"errors"
+	"fmt"
 	"io"
 	"net/http"
+	"strings"
 )
 
+// ErrInvalidDetachKeys is the error returned by AttachToContainer and
+// CreateExec when the detach key sequence isn't valid.
+var ErrInvalidDetachKeys = errors.New("invalid detach keys, use a comma-separated list of single characters or ctrl-<value>, where <value> is one of a-z, @, ^, [, \\, ] or _")
+
 // AttachToContainerOptions is the set of options that can be used when
 // attaching to a container.
 //
@@ -22,8 +29,11 @@ type AttachToContainerOptions struct {
 	// to unexpected behavior.
 	Success chan struct{}
 
-	// Override the key sequence for detaching a container.
-	DetachKeys string
+	// Override the key sequence for detaching a container, which defaults
+	// to "ctrl-p,ctrl-q". It's a comma-separated list of keys, each one a
+	// single character or ctrl-<value>, where <value> is one of a-z, @, ^,
+	// [, \, ] or _, for instance "ctrl-x,x".
+	DetachKeys string `qs:"detachKeys"`
 
 	// Use raw terminal? Usually true when the container contains a TTY.
 	RawTerminal bool `qs:"-"`
@@ -58,11 +68,19 @@ func (c *Client) AttachToContainer(opts AttachToContainerOptions) error {
 // AttachToContainerNonBlocking attaches to a container, using the given options.
 // This function does not block.
 //
+// Calling Close on the returned CloseWaiter detaches from the container: the
+// connection is half-closed for writes and the output streams stop receiving
+// data. Detaching does not stop the container. Close may be called more than
+// once, and Wait returns once the attach session has been torn down.
+//
 // See https://goo.gl/NKpkFk for more details.
 func (c *Client) AttachToContainerNonBlocking(opts AttachToContainerOptions) (CloseWaiter, error) {
 	if opts.Container == "" {
 		return nil, &NoSuchContainer{ID: opts.Container}
 	}
+	if err := validateDetachKeys(opts.DetachKeys); err != nil {
+		return nil, err
+	}
 	path := "/containers/" + opts.Container + "/attach?" + queryString(opts)
 	return c.hijack(http.MethodPost, path, hijackOptions{
 		success:        opts.Success,
@@ -72,3 +90,25 @@ func (c *Client) AttachToContainerNonBlocking(opts AttachToContainerOptions) (Cl
 		stderr:         opts.ErrorStream,
 	})
 }
+
+// validateDetachKeys checks the detach key sequence like the daemon does.
+// An empty sequence is valid, and means the default one.
+func validateDetachKeys(keys string) error {
+	if keys == "" {
+		return nil
+	}
+	for _, key := range strings.Split(keys, ",") {
+		key = strings.TrimSpace(key)
+		if len(key) == 1 {
+			continue
+		}
+		key = strings.ToLower(key)
+		if len(key) == len("ctrl-")+1 && strings.HasPrefix(key, "ctrl-") {
+			if c := key[len(key)-1]; (c >= 'a' && c <= 'z') || strings.IndexByte("@^[\\]_", c) >= 0 {
+				continue
+			}
+		}
+		return fmt.Errorf("%w: %q", ErrInvalidDetachKeys, keys)
+	}
+	return nil
+}
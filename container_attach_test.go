@@ -2,6 +2,9 @@ package docker
 
 import (
 	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
@@ -288,9 +291,116 @@ func TestAttachToContainerRawTerminalFalse(t *testing.T) {
 	}
 }
 
+func TestAttachToContainerDetachKeys(t *testing.T) {
+	t.Parallel()
+	var req http.Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte{1, 0, 0, 0, 0, 0, 0, 5})
+		w.Write([]byte("hello"))
+		req = *r
+	}))
+	defer server.Close()
+	client, _ := NewClient(server.URL)
+	client.SkipServerVersionCheck = true
+	var stdout bytes.Buffer
+	opts := AttachToContainerOptions{
+		Container:    "a123456",
+		OutputStream: &stdout,
+		Stdout:       true,
+		Stream:       true,
+		DetachKeys:   "ctrl-x,x",
+	}
+	if err := client.AttachToContainer(opts); err != nil {
+		t.Fatal(err)
+	}
+	if got := req.URL.Query().Get("detachKeys"); got != "ctrl-x,x" {
+		t.Errorf("AttachToContainer: wrong detachKeys. Want %q. Got %q.", "ctrl-x,x", got)
+	}
+}
+
+func TestAttachToContainerInvalidDetachKeys(t *testing.T) {
+	t.Parallel()
+	fakeRT := &FakeRoundTripper{message: "", status: http.StatusOK}
+	client := newTestClient(fakeRT)
+	for _, keys := range []string{"ctrl-1", "ctrl-", "ab", "ctrl-p,,x"} {
+		err := client.AttachToContainer(AttachToContainerOptions{Container: "a123456", DetachKeys: keys})
+		if !errors.Is(err, ErrInvalidDetachKeys) {
+			t.Errorf("AttachToContainer(%q): wrong error. Want %#v. Got %#v.", keys, ErrInvalidDetachKeys, err)
+		}
+	}
+	if len(fakeRT.requests) != 0 {
+		t.Errorf("AttachToContainer: sent %d requests with invalid detach keys", len(fakeRT.requests))
+	}
+}
+
 func TestAttachToContainerWithoutContainer(t *testing.T) {
 	t.Parallel()
 	var client Client
 	err := client.AttachToContainer(AttachToContainerOptions{})
 	expectNoSuchContainer(t, "", err)
 }
+
+func TestAttachToContainerNonBlockingClose(t *testing.T) {
+	t.Parallel()
+	serverDone := make(chan error, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		hj, ok := w.(http.Hijacker)
+		if !ok {
+			t.Fatal("cannot hijack server connection")
+		}
+		conn, _, err := hj.Hijack()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		conn.Write([]byte{1, 0, 0, 0, 0, 0, 0, 5})
+		conn.Write([]byte("hello"))
+		// Block until the client detaches, which half-closes the
+		// connection for writes.
+		_, err = ioutil.ReadAll(conn)
+		serverDone <- err
+	}))
+	defer server.Close()
+	client, _ := NewClient(server.URL)
+	client.SkipServerVersionCheck = true
+	success := make(chan struct{})
+	stdoutReader, stdout := io.Pipe()
+	opts := AttachToContainerOptions{
+		Container:    "a123456",
+		OutputStream: stdout,
+		Stdout:       true,
+		Stream:       true,
+		Success:      success,
+	}
+	cw, err := client.AttachToContainerNonBlocking(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-success
+	success <- struct{}{}
+	buf := make([]byte, 5)
+	if _, err := io.ReadFull(stdoutReader, buf); err != nil {
+		t.Fatal(err)
+	}
+	if string(buf) != "hello" {
+		t.Errorf("AttachToContainerNonBlocking: wrong content written to stdout. Want %q. Got %q.", "hello", buf)
+	}
+	if err := cw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := cw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := cw.Wait(); err != nil {
+		t.Errorf("AttachToContainerNonBlocking: unexpected error after detaching: %v", err)
+	}
+	select {
+	case err := <-serverDone:
+		if err != nil {
+			t.Errorf("AttachToContainerNonBlocking: server failed to read until EOF: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("AttachToContainerNonBlocking: connection was not closed after detaching")
+	}
+}
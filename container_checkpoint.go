@@ -0,0 +1,218 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// ErrExperimentalDisabled is the error returned by the checkpoint functions
+// when the daemon isn't running with experimental features enabled.
+var ErrExperimentalDisabled = errors.New("checkpoints are only supported when the daemon runs with experimental features enabled")
+
+// NoSuchCheckpoint is the error returned when the given checkpoint of a
+// container doesn't exist.
+type NoSuchCheckpoint struct {
+	ContainerID  string
+	CheckpointID string
+	Err          error
+}
+
+func (err *NoSuchCheckpoint) Error() string {
+	if err.Err != nil {
+		return err.Err.Error()
+	}
+	return "No such checkpoint: " + err.CheckpointID
+}
+
+// Checkpoint represents a checkpoint of a container, as returned by
+// ListCheckpoints.
+type Checkpoint struct {
+	Name string `json:"Name,omitempty" yaml:"Name,omitempty" toml:"Name,omitempty"`
+}
+
+// CreateCheckpointOptions specify parameters to the CreateCheckpoint function.
+//
+// See https://docs.docker.com/engine/reference/commandline/checkpoint_create/
+// for more details.
+type CreateCheckpointOptions struct {
+	// The ID of the container.
+	ID string `qs:"-" json:"-"`
+
+	CheckpointID  string          `json:"CheckpointID,omitempty" yaml:"CheckpointID,omitempty" toml:"CheckpointID,omitempty"`
+	CheckpointDir string          `json:"CheckpointDir,omitempty" yaml:"CheckpointDir,omitempty" toml:"CheckpointDir,omitempty"`
+	Exit          bool            `json:"Exit,omitempty" yaml:"Exit,omitempty" toml:"Exit,omitempty"`
+	Context       context.Context `json:"-"`
+}
+
+// ListCheckpointsOptions specify parameters to the ListCheckpoints function.
+//
+// See https://docs.docker.com/engine/reference/commandline/checkpoint_ls/
+// for more details.
+type ListCheckpointsOptions struct {
+	// The ID of the container.
+	ID string `qs:"-"`
+
+	CheckpointDir string `qs:"dir"`
+	Context       context.Context
+}
+
+// DeleteCheckpointOptions specify parameters to the DeleteCheckpoint function.
+//
+// See https://docs.docker.com/engine/reference/commandline/checkpoint_rm/
+// for more details.
+type DeleteCheckpointOptions struct {
+	// The ID of the container.
+	ID string `qs:"-"`
+
+	CheckpointID  string `qs:"-"`
+	CheckpointDir string `qs:"dir"`
+	Context       context.Context
+}
+
+// StartContainerFromCheckpointOptions specify parameters to the
+// StartContainerFromCheckpoint function.
+type StartContainerFromCheckpointOptions struct {
+	CheckpointID  string `qs:"checkpoint"`
+	CheckpointDir string `qs:"checkpoint-dir"`
+	Context       context.Context
+}
+
+// CreateCheckpoint creates a checkpoint of the given container.
+//
+// Checkpoints are an experimental feature, and require Docker API 1.25 or
+// greater, with the daemon running with experimental features enabled
+// (ErrExperimentalDisabled is returned otherwise).
+//
+// See https://docs.docker.com/engine/reference/commandline/checkpoint_create/
+// for more details.
+func (c *Client) CreateCheckpoint(opts CreateCheckpointOptions) error {
+	if err := c.checkCheckpointSupport(opts.Context); err != nil {
+		return err
+	}
+	path := "/containers/" + opts.ID + "/checkpoints"
+	resp, err := c.do(http.MethodPost, path, doOptions{data: opts, context: opts.Context})
+	if err != nil {
+		var e *Error
+		if errors.As(err, &e) && e.Status == http.StatusNotFound {
+			return &NoSuchContainer{ID: opts.ID}
+		}
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// ListCheckpoints returns the list of checkpoints of the given container.
+//
+// Checkpoints are an experimental feature, and require Docker API 1.25 or
+// greater, with the daemon running with experimental features enabled
+// (ErrExperimentalDisabled is returned otherwise).
+//
+// See https://docs.docker.com/engine/reference/commandline/checkpoint_ls/
+// for more details.
+func (c *Client) ListCheckpoints(opts ListCheckpointsOptions) ([]Checkpoint, error) {
+	if err := c.checkCheckpointSupport(opts.Context); err != nil {
+		return nil, err
+	}
+	path := "/containers/" + opts.ID + "/checkpoints?" + queryString(opts)
+	resp, err := c.do(http.MethodGet, path, doOptions{context: opts.Context})
+	if err != nil {
+		var e *Error
+		if errors.As(err, &e) && e.Status == http.StatusNotFound {
+			return nil, &NoSuchContainer{ID: opts.ID}
+		}
+		return nil, err
+	}
+	defer resp.Body.Close()
+	var checkpoints []Checkpoint
+	if err := json.NewDecoder(resp.Body).Decode(&checkpoints); err != nil {
+		return nil, err
+	}
+	return checkpoints, nil
+}
+
+// DeleteCheckpoint removes a checkpoint of the given container. It returns
+// NoSuchContainer when the container doesn't exist, and NoSuchCheckpoint when
+// the checkpoint doesn't.
+//
+// Checkpoints are an experimental feature, and require Docker API 1.25 or
+// greater, with the daemon running with experimental features enabled
+// (ErrExperimentalDisabled is returned otherwise).
+//
+// See https://docs.docker.com/engine/reference/commandline/checkpoint_rm/
+// for more details.
+func (c *Client) DeleteCheckpoint(opts DeleteCheckpointOptions) error {
+	if err := c.checkCheckpointSupport(opts.Context); err != nil {
+		return err
+	}
+	path := "/containers/" + opts.ID + "/checkpoints/" + opts.CheckpointID + "?" + queryString(opts)
+	resp, err := c.do(http.MethodDelete, path, doOptions{context: opts.Context})
+	if err != nil {
+		var e *Error
+		if errors.As(err, &e) && e.Status == http.StatusNotFound {
+			if strings.Contains(strings.ToLower(e.Message), "no such container") {
+				return &NoSuchContainer{ID: opts.ID}
+			}
+			return &NoSuchCheckpoint{ContainerID: opts.ID, CheckpointID: opts.CheckpointID}
+		}
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// StartContainerFromCheckpoint starts a container, restoring its state from
+// the given checkpoint.
+//
+// Checkpoints are an experimental feature, and require Docker API 1.25 or
+// greater, with the daemon running with experimental features enabled
+// (ErrExperimentalDisabled is returned otherwise).
+func (c *Client) StartContainerFromCheckpoint(id string, opts StartContainerFromCheckpointOptions) error {
+	if err := c.checkCheckpointSupport(opts.Context); err != nil {
+		return err
+	}
+	return c.startContainer(id, nil, queryString(opts), doOptions{context: opts.Context})
+}
+
+func (c *Client) checkCheckpointSupport(ctx context.Context) error {
+	if c.serverAPIVersion == nil {
+		c.checkAPIVersion()
+	}
+	if c.serverAPIVersion != nil && c.serverAPIVersion.LessThan(apiVersion125) {
+		return errors.New("checkpoints are only supported in API#1.25 and above")
+	}
+	if c.serverExperimental == nil {
+		experimental, err := c.getServerExperimental(ctx)
+		if err != nil {
+			return err
+		}
+		c.serverExperimental = &experimental
+	}
+	if !*c.serverExperimental {
+		return ErrExperimentalDisabled
+	}
+	return nil
+}
+
+// getServerExperimental reports whether the daemon has experimental features
+// enabled, from the Docker-Experimental header sent in response to pings, or
+// from Info for daemons that don't send it.
+func (c *Client) getServerExperimental(ctx context.Context) (bool, error) {
+	resp, err := c.do(http.MethodGet, "/_ping", doOptions{context: ctx})
+	if err != nil {
+		return false, err
+	}
+	resp.Body.Close()
+	if header := resp.Header.Get("Docker-Experimental"); header != "" {
+		return strconv.ParseBool(header)
+	}
+	info, err := c.info(ctx)
+	if err != nil {
+		return false, err
+	}
+	return info.ExperimentalBuild, nil
+}
@@ -0,0 +1,232 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+)
+
+var experimentalEnabled = true
+
+func TestCreateCheckpoint(t *testing.T) {
+	t.Parallel()
+	fakeRT := &FakeRoundTripper{message: "", status: http.StatusCreated}
+	client := newTestClient(fakeRT)
+	client.serverAPIVersion = apiVersion125
+	client.serverExperimental = &experimentalEnabled
+	id := "4fa6e0f0c6786287e131c3852c58a2e01cc697a68231826813597e4994f1d6e2"
+	opts := CreateCheckpointOptions{ID: id, CheckpointID: "cp1", CheckpointDir: "/tmp/cp", Exit: true}
+	err := client.CreateCheckpoint(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := fakeRT.requests[0]
+	if req.Method != http.MethodPost {
+		t.Errorf("CreateCheckpoint: wrong HTTP method. Want %q. Got %q.", http.MethodPost, req.Method)
+	}
+	expectedURL, _ := url.Parse(client.getURL("/containers/" + id + "/checkpoints"))
+	if gotPath := req.URL.Path; gotPath != expectedURL.Path {
+		t.Errorf("CreateCheckpoint: Wrong path in request. Want %q. Got %q.", expectedURL.Path, gotPath)
+	}
+	var got map[string]interface{}
+	if err := json.NewDecoder(req.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]interface{}{"CheckpointID": "cp1", "CheckpointDir": "/tmp/cp", "Exit": true}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("CreateCheckpoint: wrong body. Want %#v. Got %#v.", expected, got)
+	}
+}
+
+func TestCreateCheckpointNotFound(t *testing.T) {
+	t.Parallel()
+	client := newTestClient(&FakeRoundTripper{message: "no such container", status: http.StatusNotFound})
+	client.serverAPIVersion = apiVersion125
+	client.serverExperimental = &experimentalEnabled
+	err := client.CreateCheckpoint(CreateCheckpointOptions{ID: "a2334", CheckpointID: "cp1"})
+	expectNoSuchContainer(t, "a2334", err)
+}
+
+func TestCreateCheckpointOldAPI(t *testing.T) {
+	t.Parallel()
+	fakeRT := &FakeRoundTripper{message: "", status: http.StatusCreated}
+	client := newTestClient(fakeRT)
+	err := client.CreateCheckpoint(CreateCheckpointOptions{ID: "a2334", CheckpointID: "cp1"})
+	if err == nil || err.Error() != "checkpoints are only supported in API#1.25 and above" {
+		t.Errorf("CreateCheckpoint: unexpected error: %v", err)
+	}
+	if len(fakeRT.requests) > 0 {
+		t.Errorf("CreateCheckpoint: expected no requests, got %d", len(fakeRT.requests))
+	}
+}
+
+func TestListCheckpoints(t *testing.T) {
+	t.Parallel()
+	fakeRT := &FakeRoundTripper{message: `[{"Name":"cp1"},{"Name":"cp2"}]`, status: http.StatusOK}
+	client := newTestClient(fakeRT)
+	client.serverAPIVersion = apiVersion125
+	client.serverExperimental = &experimentalEnabled
+	id := "4fa6e0f0c678"
+	checkpoints, err := client.ListCheckpoints(ListCheckpointsOptions{ID: id, CheckpointDir: "/tmp/cp"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Checkpoint{{Name: "cp1"}, {Name: "cp2"}}
+	if !reflect.DeepEqual(checkpoints, expected) {
+		t.Errorf("ListCheckpoints: Expected %#v. Got %#v.", expected, checkpoints)
+	}
+	req := fakeRT.requests[0]
+	if req.Method != http.MethodGet {
+		t.Errorf("ListCheckpoints: wrong HTTP method. Want %q. Got %q.", http.MethodGet, req.Method)
+	}
+	expectedURL, _ := url.Parse(client.getURL("/containers/" + id + "/checkpoints"))
+	if gotPath := req.URL.Path; gotPath != expectedURL.Path {
+		t.Errorf("ListCheckpoints: Wrong path in request. Want %q. Got %q.", expectedURL.Path, gotPath)
+	}
+	expectedQuery := map[string][]string{"dir": {"/tmp/cp"}}
+	if query := map[string][]string(req.URL.Query()); !reflect.DeepEqual(query, expectedQuery) {
+		t.Errorf("ListCheckpoints: wrong query string. Want %#v. Got %#v.", expectedQuery, query)
+	}
+}
+
+func TestDeleteCheckpoint(t *testing.T) {
+	t.Parallel()
+	fakeRT := &FakeRoundTripper{message: "", status: http.StatusNoContent}
+	client := newTestClient(fakeRT)
+	client.serverAPIVersion = apiVersion125
+	client.serverExperimental = &experimentalEnabled
+	id := "4fa6e0f0c678"
+	err := client.DeleteCheckpoint(DeleteCheckpointOptions{ID: id, CheckpointID: "cp1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := fakeRT.requests[0]
+	if req.Method != http.MethodDelete {
+		t.Errorf("DeleteCheckpoint: wrong HTTP method. Want %q. Got %q.", http.MethodDelete, req.Method)
+	}
+	expectedURL, _ := url.Parse(client.getURL("/containers/" + id + "/checkpoints/cp1"))
+	if gotPath := req.URL.Path; gotPath != expectedURL.Path {
+		t.Errorf("DeleteCheckpoint: Wrong path in request. Want %q. Got %q.", expectedURL.Path, gotPath)
+	}
+}
+
+func TestStartContainerFromCheckpoint(t *testing.T) {
+	t.Parallel()
+	fakeRT := &FakeRoundTripper{message: "", status: http.StatusNoContent}
+	client := newTestClient(fakeRT)
+	client.serverAPIVersion = apiVersion125
+	client.serverExperimental = &experimentalEnabled
+	id := "4fa6e0f0c678"
+	err := client.StartContainerFromCheckpoint(id, StartContainerFromCheckpointOptions{CheckpointID: "cp1", CheckpointDir: "/tmp/cp"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := fakeRT.requests[0]
+	expectedURL, _ := url.Parse(client.getURL("/containers/" + id + "/start"))
+	if gotPath := req.URL.Path; gotPath != expectedURL.Path {
+		t.Errorf("StartContainerFromCheckpoint: Wrong path in request. Want %q. Got %q.", expectedURL.Path, gotPath)
+	}
+	expectedQuery := map[string][]string{"checkpoint": {"cp1"}, "checkpoint-dir": {"/tmp/cp"}}
+	if query := map[string][]string(req.URL.Query()); !reflect.DeepEqual(query, expectedQuery) {
+		t.Errorf("StartContainerFromCheckpoint: wrong query string. Want %#v. Got %#v.", expectedQuery, query)
+	}
+}
+
+func TestDeleteCheckpointNotFound(t *testing.T) {
+	t.Parallel()
+	client := newTestClient(&FakeRoundTripper{message: "No such container: a2334", status: http.StatusNotFound})
+	client.serverAPIVersion = apiVersion125
+	client.serverExperimental = &experimentalEnabled
+	err := client.DeleteCheckpoint(DeleteCheckpointOptions{ID: "a2334", CheckpointID: "cp1"})
+	expectNoSuchContainer(t, "a2334", err)
+
+	client = newTestClient(&FakeRoundTripper{message: "checkpoint cp1 does not exist for container a2334", status: http.StatusNotFound})
+	client.serverAPIVersion = apiVersion125
+	client.serverExperimental = &experimentalEnabled
+	err = client.DeleteCheckpoint(DeleteCheckpointOptions{ID: "a2334", CheckpointID: "cp1"})
+	var serr *NoSuchCheckpoint
+	if !errors.As(err, &serr) || serr.ContainerID != "a2334" || serr.CheckpointID != "cp1" {
+		t.Errorf("DeleteCheckpoint: wrong error. Want NoSuchCheckpoint. Got %#v.", err)
+	}
+}
+
+func TestCheckpointExperimentalDisabled(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		header       string
+		info         string
+		experimental bool
+	}{
+		{header: "false", experimental: false},
+		{header: "true", experimental: true},
+		{info: `{"ExperimentalBuild":false}`, experimental: false},
+		{info: `{"ExperimentalBuild":true}`, experimental: true},
+	}
+	for _, tt := range tests {
+		var paths []string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			paths = append(paths, r.URL.Path)
+			switch r.URL.Path {
+			case "/_ping":
+				if tt.header != "" {
+					w.Header().Set("Docker-Experimental", tt.header)
+				}
+				w.Write([]byte("OK"))
+			case "/info":
+				w.Write([]byte(tt.info))
+			default:
+				w.Write([]byte("[]"))
+			}
+		}))
+		client, _ := NewClient(server.URL)
+		client.serverAPIVersion = apiVersion125
+		_, err := client.ListCheckpoints(ListCheckpointsOptions{ID: "a2334"})
+		if tt.experimental && err != nil {
+			t.Errorf("ListCheckpoints(%q, %q): unexpected error: %v", tt.header, tt.info, err)
+		}
+		if !tt.experimental && !errors.Is(err, ErrExperimentalDisabled) {
+			t.Errorf("ListCheckpoints(%q, %q): wrong error. Want %#v. Got %#v.", tt.header, tt.info, ErrExperimentalDisabled, err)
+		}
+		// the result is cached in the client
+		client.ListCheckpoints(ListCheckpointsOptions{ID: "a2334"})
+		for _, path := range paths[1:] {
+			if path == "/_ping" {
+				t.Errorf("ListCheckpoints(%q, %q): pinged the daemon more than once: %v", tt.header, tt.info, paths)
+			}
+		}
+		server.Close()
+	}
+}
+
+func TestCheckpointExperimentalInfoContext(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/info" {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(5 * time.Second):
+			}
+			return
+		}
+		w.Write([]byte("OK"))
+	}))
+	defer server.Close()
+	client, _ := NewClient(server.URL)
+	client.serverAPIVersion = apiVersion125
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := client.ListCheckpoints(ListCheckpointsOptions{ID: "a2334", Context: ctx})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("ListCheckpoints: wrong error. Want %#v. Got %#v.", context.DeadlineExceeded, err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("ListCheckpoints: waited %s for /info past the context deadline", elapsed)
+	}
+}
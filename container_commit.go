@@ -4,11 +4,40 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
+	"strings"
 )
 
+// ErrInvalidCommitChange is the error returned by CommitContainer and
+// AddChange when a change isn't a Dockerfile instruction supported by the
+// daemon in commits.
+var ErrInvalidCommitChange = errors.New("invalid commit change")
+
+// commitInstructions are the Dockerfile instructions the daemon accepts in
+// the changes of a commit.
+var commitInstructions = map[string]bool{
+	"CMD":         true,
+	"ENTRYPOINT":  true,
+	"ENV":         true,
+	"EXPOSE":      true,
+	"HEALTHCHECK": true,
+	"LABEL":       true,
+	"ONBUILD":     true,
+	"STOPSIGNAL":  true,
+	"USER":        true,
+	"VOLUME":      true,
+	"WORKDIR":     true,
+}
+
 // CommitContainerOptions aggregates parameters to the CommitContainer method.
 //
+// Changes are Dockerfile instructions applied to the configuration of the
+// new image, like `docker commit --change`, for example "CMD [\"app\"]" or
+// "ENV DEBUG=1". Only CMD, ENTRYPOINT, ENV, EXPOSE, HEALTHCHECK, LABEL,
+// ONBUILD, STOPSIGNAL, USER, VOLUME and WORKDIR are supported. AddChange
+// formats them from their arguments.
+//
 // See https://goo.gl/CzIguf for more details.
 type CommitContainerOptions struct {
 	Container  string
@@ -21,10 +50,86 @@ type CommitContainerOptions struct {
 	Context    context.Context
 }
 
+// AddChange appends the given Dockerfile instruction to the changes of the
+// commit, formatting its arguments: CMD, ENTRYPOINT and VOLUME use the JSON
+// form, so arguments may contain spaces, ENV and LABEL take "key=value"
+// arguments, whose values are quoted, and the other instructions take their
+// arguments as is, separated by spaces:
+//
+//	opts.AddChange("CMD", "/app", "--listen", ":8080")
+//	opts.AddChange("ENV", "GREETING=hello world")
+//	opts.AddChange("EXPOSE", "8080/tcp")
+//
+// It returns ErrInvalidCommitChange for unsupported instructions, and
+// instructions without arguments.
+func (opts *CommitContainerOptions) AddChange(instruction string, args ...string) error {
+	instruction = strings.ToUpper(instruction)
+	if !commitInstructions[instruction] {
+		return fmt.Errorf("%w: unsupported instruction %q", ErrInvalidCommitChange, instruction)
+	}
+	if len(args) == 0 {
+		return fmt.Errorf("%w: %s requires arguments", ErrInvalidCommitChange, instruction)
+	}
+	var value string
+	switch instruction {
+	case "CMD", "ENTRYPOINT", "VOLUME":
+		data, err := json.Marshal(args)
+		if err != nil {
+			return err
+		}
+		value = string(data)
+	case "ENV", "LABEL":
+		pairs := make([]string, len(args))
+		for i, arg := range args {
+			parts := strings.SplitN(arg, "=", 2)
+			if len(parts) != 2 || parts[0] == "" {
+				return fmt.Errorf("%w: %s requires key=value arguments, got %q", ErrInvalidCommitChange, instruction, arg)
+			}
+			pairs[i] = parts[0] + "=" + quoteChangeValue(parts[1])
+		}
+		value = strings.Join(pairs, " ")
+	default:
+		value = strings.Join(args, " ")
+	}
+	opts.Changes = append(opts.Changes, instruction+" "+value)
+	return nil
+}
+
+// quoteChangeValue quotes the value of an ENV or LABEL change in the double
+// quoted form understood by the Dockerfile parser.
+func quoteChangeValue(value string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value) + `"`
+}
+
+// validateCommitChanges checks that every change starts with an instruction
+// supported by the daemon, followed by its arguments.
+func validateCommitChanges(changes []string) error {
+	for _, change := range changes {
+		fields := strings.Fields(change)
+		if len(fields) == 0 {
+			return fmt.Errorf("%w: empty change", ErrInvalidCommitChange)
+		}
+		instruction := strings.ToUpper(fields[0])
+		if !commitInstructions[instruction] {
+			return fmt.Errorf("%w: unsupported instruction %q in %q", ErrInvalidCommitChange, fields[0], change)
+		}
+		if len(fields) == 1 {
+			return fmt.Errorf("%w: %s requires arguments", ErrInvalidCommitChange, instruction)
+		}
+	}
+	return nil
+}
+
 // CommitContainer creates a new image from a container's changes.
 //
+// The changes in opts are checked before sending the request, returning an
+// error wrapping ErrInvalidCommitChange for unsupported instructions.
+//
 // See https://goo.gl/CzIguf for more details.
 func (c *Client) CommitContainer(opts CommitContainerOptions) (*Image, error) {
+	if err := validateCommitChanges(opts.Changes); err != nil {
+		return nil, err
+	}
 	path := "/commit?" + queryString(opts)
 	resp, err := c.do(http.MethodPost, path, doOptions{
 		data:    opts.Run,
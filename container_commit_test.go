@@ -3,6 +3,7 @@ package docker
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"io/ioutil"
 	"net/http"
 	"reflect"
@@ -78,6 +79,54 @@ func TestCommitContainerParams(t *testing.T) {
 	}
 }
 
+func TestCommitContainerAddChange(t *testing.T) {
+	t.Parallel()
+	var opts CommitContainerOptions
+	for _, change := range [][]string{
+		{"cmd", "/app", "--name", "hello world"},
+		{"ENTRYPOINT", "/entrypoint.sh"},
+		{"ENV", "A=1", `B=say "hi"`, `C=back\slash`},
+		{"LABEL", "team=storage"},
+		{"EXPOSE", "8080/tcp", "53/udp"},
+		{"USER", "nobody"},
+	} {
+		if err := opts.AddChange(change[0], change[1:]...); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expected := []string{
+		`CMD ["/app","--name","hello world"]`,
+		`ENTRYPOINT ["/entrypoint.sh"]`,
+		`ENV A="1" B="say \"hi\"" C="back\\slash"`,
+		`LABEL team="storage"`,
+		`EXPOSE 8080/tcp 53/udp`,
+		`USER nobody`,
+	}
+	if !reflect.DeepEqual(opts.Changes, expected) {
+		t.Errorf("AddChange: wrong changes.\nWant %q.\nGot  %q.", expected, opts.Changes)
+	}
+	for _, change := range [][]string{{"RUN", "make"}, {"CMD"}, {"ENV", "NOVALUE"}, {"LABEL", "=value"}} {
+		if err := opts.AddChange(change[0], change[1:]...); !errors.Is(err, ErrInvalidCommitChange) {
+			t.Errorf("AddChange(%q): wrong error. Want %v. Got %v.", change, ErrInvalidCommitChange, err)
+		}
+	}
+}
+
+func TestCommitContainerInvalidChanges(t *testing.T) {
+	t.Parallel()
+	fakeRT := &FakeRoundTripper{message: "{}", status: http.StatusOK}
+	client := newTestClient(fakeRT)
+	for _, change := range []string{"RUN make", "", "CMD", "COPY . /app"} {
+		_, err := client.CommitContainer(CommitContainerOptions{Container: "abc", Changes: []string{"USER nobody", change}})
+		if !errors.Is(err, ErrInvalidCommitChange) {
+			t.Errorf("CommitContainer(%q): wrong error. Want %v. Got %v.", change, ErrInvalidCommitChange, err)
+		}
+	}
+	if len(fakeRT.requests) != 0 {
+		t.Errorf("CommitContainer: unexpected requests with invalid changes: %d", len(fakeRT.requests))
+	}
+}
+
 func TestCommitContainerFailure(t *testing.T) {
 	t.Parallel()
 	client := newTestClient(&FakeRoundTripper{message: "no such container", status: http.StatusInternalServerError})
@@ -12,11 +12,36 @@ import (
 // container already exists.
 var ErrContainerAlreadyExists = errors.New("container already exists")
 
+// sentinelError pairs one of the sentinel errors of the package (such as
+// ErrContainerAlreadyExists) with the underlying API error, so callers can
+// match the sentinel with errors.Is and still reach the status code through
+// errors.As.
+type sentinelError struct {
+	sentinel error
+	err      *Error
+}
+
+func (e *sentinelError) Error() string {
+	return e.sentinel.Error()
+}
+
+func (e *sentinelError) Is(target error) bool {
+	return target == e.sentinel
+}
+
+func (e *sentinelError) Unwrap() error {
+	return e.err
+}
+
 // CreateContainerOptions specify parameters to the CreateContainer function.
 //
 // See https://goo.gl/tyzwVM for more details.
 type CreateContainerOptions struct {
-	Name             string
+	Name string
+	// Platform selects the variant of a multi-arch image to create the
+	// container from, in the form os[/arch[/variant]] (e.g. "linux/arm64").
+	// It requires Docker API 1.41 or greater; older daemons ignore it.
+	Platform         string
 	Config           *Config           `qs:"-"`
 	HostConfig       *HostConfig       `qs:"-"`
 	NetworkingConfig *NetworkingConfig `qs:"-"`
@@ -29,8 +54,27 @@ type CreateContainerOptions struct {
 // The returned container instance contains only the container ID. To get more
 // details about the container after creating it, use InspectContainer.
 //
+// When the image does not exist, the returned error matches ErrNoSuchImage,
+// and when a container with the same name already exists it matches
+// ErrContainerAlreadyExists. In both cases the underlying *Error is still
+// available through errors.As. Other 404 errors, such as a missing network
+// or volume plugin, are returned as a plain *Error.
+//
+// Entries of HostConfig.ExtraHosts are validated before sending the request,
+// and malformed entries result in an error matching ErrInvalidExtraHost. The
+// same applies to HostConfig.DeviceRequests and HostConfig.DeviceCgroupRules,
+// with ErrInvalidDeviceRequest and ErrInvalidDeviceCgroupRule.
+//
 // See https://goo.gl/tyzwVM for more details.
 func (c *Client) CreateContainer(opts CreateContainerOptions) (*Container, error) {
+	if opts.HostConfig != nil {
+		if err := validateExtraHosts(opts.HostConfig.ExtraHosts); err != nil {
+			return nil, err
+		}
+		if err := validateDevices(opts.HostConfig); err != nil {
+			return nil, err
+		}
+	}
 	path := "/containers/create?" + queryString(opts)
 	resp, err := c.do(
 		http.MethodPost,
@@ -51,16 +95,16 @@ func (c *Client) CreateContainer(opts CreateContainerOptions) (*Container, error
 
 	var e *Error
 	if errors.As(err, &e) {
-		if e.Status == http.StatusNotFound && strings.Contains(e.Message, "No such image") {
-			return nil, ErrNoSuchImage
+		if e.Status == http.StatusNotFound && strings.Contains(strings.ToLower(e.Message), "no such image") {
+			return nil, &sentinelError{sentinel: ErrNoSuchImage, err: e}
 		}
 		if e.Status == http.StatusConflict {
-			return nil, ErrContainerAlreadyExists
+			return nil, &sentinelError{sentinel: ErrContainerAlreadyExists, err: e}
 		}
 		// Workaround for 17.09 bug returning 400 instead of 409.
 		// See https://github.com/moby/moby/issues/35021
 		if e.Status == http.StatusBadRequest && strings.Contains(e.Message, "Conflict.") {
-			return nil, ErrContainerAlreadyExists
+			return nil, &sentinelError{sentinel: ErrContainerAlreadyExists, err: e}
 		}
 	}
 
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"net/http"
 	"net/url"
+	"reflect"
 	"testing"
 )
 
@@ -46,6 +47,21 @@ func TestCreateContainer(t *testing.T) {
 	}
 }
 
+func TestCreateContainerPlatform(t *testing.T) {
+	t.Parallel()
+	fakeRT := &FakeRoundTripper{message: `{"Id":"4fa6e0f0c678"}`, status: http.StatusOK}
+	client := newTestClient(fakeRT)
+	opts := CreateContainerOptions{Name: "multiarch", Platform: "linux/arm64", Config: &Config{Image: "base"}}
+	if _, err := client.CreateContainer(opts); err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string][]string{"name": {"multiarch"}, "platform": {"linux/arm64"}}
+	got := map[string][]string(fakeRT.requests[0].URL.Query())
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("CreateContainer: wrong query string. Want %#v. Got %#v.", expected, got)
+	}
+}
+
 func TestCreateContainerImageNotFound(t *testing.T) {
 	t.Parallel()
 	client := newTestClient(&FakeRoundTripper{message: "No such image: whatever", status: http.StatusNotFound})
@@ -72,6 +88,51 @@ func TestCreateContainerDuplicateName(t *testing.T) {
 	}
 }
 
+func TestCreateContainerImageNotFoundKeepsAPIError(t *testing.T) {
+	t.Parallel()
+	client := newTestClient(&FakeRoundTripper{message: "No such image: whatever:latest", status: http.StatusNotFound})
+	_, err := client.CreateContainer(CreateContainerOptions{Config: &Config{Image: "whatever"}})
+	if !errors.Is(err, ErrNoSuchImage) {
+		t.Errorf("CreateContainer: Wrong error type. Want %#v. Got %#v.", ErrNoSuchImage, err)
+	}
+	var e *Error
+	if !errors.As(err, &e) {
+		t.Fatalf("CreateContainer: expected error to wrap *Error, got %#v.", err)
+	}
+	if e.Status != http.StatusNotFound {
+		t.Errorf("CreateContainer: wrong status. Want %d. Got %d.", http.StatusNotFound, e.Status)
+	}
+}
+
+func TestCreateContainerOtherNotFound(t *testing.T) {
+	t.Parallel()
+	client := newTestClient(&FakeRoundTripper{message: "network backend not found", status: http.StatusNotFound})
+	_, err := client.CreateContainer(CreateContainerOptions{Config: &Config{Image: "whatever"}})
+	if errors.Is(err, ErrNoSuchImage) {
+		t.Errorf("CreateContainer: error should not match ErrNoSuchImage: %#v.", err)
+	}
+	var e *Error
+	if !errors.As(err, &e) || e.Status != http.StatusNotFound {
+		t.Errorf("CreateContainer: expected *Error with status %d, got %#v.", http.StatusNotFound, err)
+	}
+}
+
+func TestCreateContainerDuplicateNameKeepsAPIError(t *testing.T) {
+	t.Parallel()
+	client := newTestClient(&FakeRoundTripper{message: "name already in use", status: http.StatusConflict})
+	_, err := client.CreateContainer(CreateContainerOptions{Name: "c1", Config: &Config{}})
+	if !errors.Is(err, ErrContainerAlreadyExists) {
+		t.Errorf("CreateContainer: Wrong error type. Want %#v. Got %#v.", ErrContainerAlreadyExists, err)
+	}
+	if errors.Is(err, ErrNoSuchImage) {
+		t.Errorf("CreateContainer: error should not match ErrNoSuchImage: %#v.", err)
+	}
+	var e *Error
+	if !errors.As(err, &e) || e.Status != http.StatusConflict {
+		t.Errorf("CreateContainer: expected wrapped *Error with status %d, got %#v.", http.StatusConflict, err)
+	}
+}
+
 // Workaround for 17.09 bug returning 400 instead of 409.
 // See https://github.com/moby/moby/issues/35021
 func TestCreateContainerDuplicateNameWorkaroundDocker17_09(t *testing.T) {
@@ -109,6 +170,162 @@ func TestCreateContainerWithHostConfig(t *testing.T) {
 	}
 }
 
+func TestCreateContainerInit(t *testing.T) {
+	t.Parallel()
+	enabled, disabled := true, false
+	tests := []struct {
+		name     string
+		init     *bool
+		expected interface{}
+	}{
+		{name: "daemon default", init: nil, expected: nil},
+		{name: "enabled", init: &enabled, expected: true},
+		{name: "disabled", init: &disabled, expected: false},
+	}
+	for _, tt := range tests {
+		fakeRT := &FakeRoundTripper{message: "{}", status: http.StatusOK}
+		client := newTestClient(fakeRT)
+		opts := CreateContainerOptions{Config: &Config{}, HostConfig: &HostConfig{Init: tt.init}}
+		if _, err := client.CreateContainer(opts); err != nil {
+			t.Fatal(err)
+		}
+		var gotBody struct {
+			HostConfig map[string]interface{}
+		}
+		if err := json.NewDecoder(fakeRT.requests[0].Body).Decode(&gotBody); err != nil {
+			t.Fatal(err)
+		}
+		value, ok := gotBody.HostConfig["Init"]
+		if tt.expected == nil && ok {
+			t.Errorf("CreateContainer (%s): Init should not be serialized. Got %v.", tt.name, value)
+		}
+		if tt.expected != nil && value != tt.expected {
+			t.Errorf("CreateContainer (%s): wrong Init. Want %v. Got %v.", tt.name, tt.expected, value)
+		}
+	}
+}
+
+func TestCreateContainerExtraHosts(t *testing.T) {
+	t.Parallel()
+	fakeRT := &FakeRoundTripper{message: "{}", status: http.StatusOK}
+	client := newTestClient(fakeRT)
+	hostConfig := HostConfig{ExtraHosts: []string{"db:10.0.0.2", "ip6:::1"}}
+	if err := hostConfig.AddExtraHost("host.docker.internal", HostGateway); err != nil {
+		t.Fatal(err)
+	}
+	if err := hostConfig.AddExtraHost("ip6-bracketed", "[fe80::1]"); err != nil {
+		t.Fatal(err)
+	}
+	opts := CreateContainerOptions{Config: &Config{}, HostConfig: &hostConfig}
+	if _, err := client.CreateContainer(opts); err != nil {
+		t.Fatal(err)
+	}
+	var gotBody struct {
+		HostConfig HostConfig
+	}
+	if err := json.NewDecoder(fakeRT.requests[0].Body).Decode(&gotBody); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"db:10.0.0.2", "ip6:::1", "host.docker.internal:host-gateway", "ip6-bracketed:fe80::1"}
+	if !reflect.DeepEqual(gotBody.HostConfig.ExtraHosts, expected) {
+		t.Errorf("CreateContainer: wrong ExtraHosts. Want %#v. Got %#v.", expected, gotBody.HostConfig.ExtraHosts)
+	}
+}
+
+func TestCreateContainerInvalidExtraHosts(t *testing.T) {
+	t.Parallel()
+	tests := []string{"db", ":10.0.0.2", "db:", "db:not-an-ip", "my db:10.0.0.2", "db=10.0.0.2"}
+	for _, entry := range tests {
+		fakeRT := &FakeRoundTripper{message: "{}", status: http.StatusOK}
+		client := newTestClient(fakeRT)
+		opts := CreateContainerOptions{Config: &Config{}, HostConfig: &HostConfig{ExtraHosts: []string{entry}}}
+		_, err := client.CreateContainer(opts)
+		if !errors.Is(err, ErrInvalidExtraHost) {
+			t.Errorf("CreateContainer(%q): wrong error. Want %v. Got %v.", entry, ErrInvalidExtraHost, err)
+		}
+		if len(fakeRT.requests) != 0 {
+			t.Errorf("CreateContainer(%q): expected no requests, got %d", entry, len(fakeRT.requests))
+		}
+	}
+}
+
+func TestCreateContainerDeviceRequests(t *testing.T) {
+	t.Parallel()
+	fakeRT := &FakeRoundTripper{message: "{}", status: http.StatusOK}
+	client := newTestClient(fakeRT)
+	hostConfig := HostConfig{
+		DeviceRequests: []DeviceRequest{
+			{Driver: "nvidia", Count: -1, Capabilities: [][]string{{"gpu"}}},
+			{Driver: "nvidia", DeviceIDs: []string{"0", "GPU-3a23c669"}, Capabilities: [][]string{{"gpu", "compute"}}, Options: map[string]string{"mode": "exclusive"}},
+		},
+		DeviceCgroupRules: []string{"c 189:* rmw", "b 8:0 r"},
+	}
+	opts := CreateContainerOptions{Config: &Config{Image: "nvidia/cuda"}, HostConfig: &hostConfig}
+	if _, err := client.CreateContainer(opts); err != nil {
+		t.Fatal(err)
+	}
+	var gotBody map[string]json.RawMessage
+	if err := json.NewDecoder(fakeRT.requests[0].Body).Decode(&gotBody); err != nil {
+		t.Fatal(err)
+	}
+	var gotHostConfig struct {
+		DeviceRequests    []map[string]interface{}
+		DeviceCgroupRules []string
+	}
+	if err := json.Unmarshal(gotBody["HostConfig"], &gotHostConfig); err != nil {
+		t.Fatal(err)
+	}
+	expectedRequests := []map[string]interface{}{
+		{"Driver": "nvidia", "Count": float64(-1), "Capabilities": []interface{}{[]interface{}{"gpu"}}},
+		{"Driver": "nvidia", "DeviceIDs": []interface{}{"0", "GPU-3a23c669"}, "Capabilities": []interface{}{[]interface{}{"gpu", "compute"}}, "Options": map[string]interface{}{"mode": "exclusive"}},
+	}
+	if !reflect.DeepEqual(gotHostConfig.DeviceRequests, expectedRequests) {
+		t.Errorf("CreateContainer: wrong DeviceRequests. Want %#v. Got %#v.", expectedRequests, gotHostConfig.DeviceRequests)
+	}
+	if !reflect.DeepEqual(gotHostConfig.DeviceCgroupRules, hostConfig.DeviceCgroupRules) {
+		t.Errorf("CreateContainer: wrong DeviceCgroupRules. Want %#v. Got %#v.", hostConfig.DeviceCgroupRules, gotHostConfig.DeviceCgroupRules)
+	}
+}
+
+func TestCreateContainerInvalidDevices(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		hostConfig HostConfig
+		expected   error
+	}{
+		{HostConfig{DeviceRequests: []DeviceRequest{{Count: 1, DeviceIDs: []string{"0"}}}}, ErrInvalidDeviceRequest},
+		{HostConfig{DeviceRequests: []DeviceRequest{{Count: -2}}}, ErrInvalidDeviceRequest},
+		{HostConfig{DeviceCgroupRules: []string{"c 189:*"}}, ErrInvalidDeviceCgroupRule},
+		{HostConfig{DeviceCgroupRules: []string{"x 1:2 rwm"}}, ErrInvalidDeviceCgroupRule},
+	}
+	for _, tt := range tests {
+		fakeRT := &FakeRoundTripper{message: "{}", status: http.StatusOK}
+		client := newTestClient(fakeRT)
+		hostConfig := tt.hostConfig
+		_, err := client.CreateContainer(CreateContainerOptions{Config: &Config{}, HostConfig: &hostConfig})
+		if !errors.Is(err, tt.expected) {
+			t.Errorf("CreateContainer(%#v): wrong error. Want %v. Got %v.", tt.hostConfig, tt.expected, err)
+		}
+		if len(fakeRT.requests) != 0 {
+			t.Errorf("CreateContainer(%#v): expected no requests, got %d", tt.hostConfig, len(fakeRT.requests))
+		}
+	}
+}
+
+func TestAddExtraHostInvalid(t *testing.T) {
+	t.Parallel()
+	var hostConfig HostConfig
+	if err := hostConfig.AddExtraHost("db", "localhost"); !errors.Is(err, ErrInvalidExtraHost) {
+		t.Errorf("AddExtraHost: wrong error. Want %v. Got %v.", ErrInvalidExtraHost, err)
+	}
+	if err := hostConfig.AddExtraHost("", "10.0.0.2"); !errors.Is(err, ErrInvalidExtraHost) {
+		t.Errorf("AddExtraHost: wrong error. Want %v. Got %v.", ErrInvalidExtraHost, err)
+	}
+	if len(hostConfig.ExtraHosts) != 0 {
+		t.Errorf("AddExtraHost: invalid entries were added: %#v", hostConfig.ExtraHosts)
+	}
+}
+
 func TestPassingNameOptToCreateContainerReturnsItInContainer(t *testing.T) {
 	t.Parallel()
 	jsonContainer := `{
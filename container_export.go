@@ -4,18 +4,28 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"net/http"
+	"os"
+	"path/filepath"
 	"time"
 )
 
 // ExportContainerOptions is the set of parameters to the ExportContainer
 // method.
 //
+// Progress, when set, is called with the total number of bytes written to
+// OutputStream so far, after every write. It's called from the goroutine
+// that calls ExportContainer, never concurrently, so it doesn't need to
+// synchronize with other calls to itself, but it should return quickly as the
+// export doesn't proceed while it runs.
+//
 // See https://goo.gl/yGJCIh for more details.
 type ExportContainerOptions struct {
 	ID                string
 	OutputStream      io.Writer
-	InactivityTimeout time.Duration `qs:"-"`
+	Progress          func(written int64) `qs:"-"`
+	InactivityTimeout time.Duration       `qs:"-"`
 	Context           context.Context
 }
 
@@ -27,11 +37,68 @@ func (c *Client) ExportContainer(opts ExportContainerOptions) error {
 	if opts.ID == "" {
 		return &NoSuchContainer{ID: opts.ID}
 	}
+	out := opts.OutputStream
+	if opts.Progress != nil {
+		if out == nil {
+			out = ioutil.Discard
+		}
+		out = &progressWriter{w: out, progress: opts.Progress}
+	}
 	url := fmt.Sprintf("/containers/%s/export", opts.ID)
 	return c.stream(http.MethodGet, url, streamOptions{
 		setRawTerminal:    true,
-		stdout:            opts.OutputStream,
+		stdout:            out,
 		inactivityTimeout: opts.InactivityTimeout,
 		context:           opts.Context,
 	})
 }
+
+// progressWriter counts the bytes written to w, reporting the total to
+// progress after every write.
+type progressWriter struct {
+	w        io.Writer
+	written  int64
+	progress func(int64)
+}
+
+func (p *progressWriter) Write(data []byte) (int, error) {
+	n, err := p.w.Write(data)
+	if n > 0 {
+		p.written += int64(n)
+		p.progress(p.written)
+	}
+	return n, err
+}
+
+// ExportContainerToFile exports the contents of the container id as a tar
+// archive to the file at path.
+//
+// The archive is written to a temporary file in the same directory, which is
+// synced and then renamed to path, so path never holds a partial export: on
+// any error the temporary file is removed and an existing file at path is left
+// untouched. The file is created with mode 0600.
+func (c *Client) ExportContainerToFile(id, path string) error {
+	if id == "" {
+		return &NoSuchContainer{ID: id}
+	}
+	f, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	tmpPath := f.Name()
+	err = c.ExportContainer(ExportContainerOptions{ID: id, OutputStream: f})
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmpPath, path)
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return nil
+}
@@ -2,7 +2,14 @@ package docker
 
 import (
 	"bytes"
+	"errors"
+	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -34,3 +41,142 @@ func TestExportContainerNoId(t *testing.T) {
 		t.Errorf("ExportContainer: wrong ID. Want %q. Got %q", "", e.ID)
 	}
 }
+
+func TestExportContainerProgress(t *testing.T) {
+	t.Parallel()
+	chunks := []string{"exported ", "container ", "tar content"}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, chunk := range chunks {
+			w.Write([]byte(chunk))
+			w.(http.Flusher).Flush()
+		}
+	}))
+	defer server.Close()
+	client, err := NewClient(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.SkipServerVersionCheck = true
+	var out bytes.Buffer
+	var reported []int64
+	err = client.ExportContainer(ExportContainerOptions{
+		ID:           "4fa6e0f0c678",
+		OutputStream: &out,
+		Progress: func(written int64) {
+			reported = append(reported, written)
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	content := strings.Join(chunks, "")
+	if out.String() != content {
+		t.Errorf("ExportContainer: wrong stdout. Want %q. Got %q.", content, out.String())
+	}
+	if len(reported) == 0 {
+		t.Fatal("ExportContainer: progress was never reported")
+	}
+	for i := 1; i < len(reported); i++ {
+		if reported[i] <= reported[i-1] {
+			t.Errorf("ExportContainer: progress isn't increasing: %v", reported)
+		}
+	}
+	if last := reported[len(reported)-1]; last != int64(len(content)) {
+		t.Errorf("ExportContainer: wrong final progress. Want %d. Got %d.", len(content), last)
+	}
+}
+
+func TestExportContainerProgressNoOutputStream(t *testing.T) {
+	t.Parallel()
+	content := "exported container tar content"
+	client := newTestClient(&FakeRoundTripper{message: content, status: http.StatusOK})
+	var written int64
+	err := client.ExportContainer(ExportContainerOptions{
+		ID:       "4fa6e0f0c678",
+		Progress: func(n int64) { written = n },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if written != int64(len(content)) {
+		t.Errorf("ExportContainer: wrong progress. Want %d. Got %d.", len(content), written)
+	}
+}
+
+func TestExportContainerToFile(t *testing.T) {
+	t.Parallel()
+	content := "exported container tar content"
+	fakeRT := &FakeRoundTripper{message: content, status: http.StatusOK}
+	client := newTestClient(fakeRT)
+	dir, err := ioutil.TempDir("", "export")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "container.tar")
+	if err := client.ExportContainerToFile("4fa6e0f0c678", path); err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != content {
+		t.Errorf("ExportContainerToFile: wrong content. Want %q. Got %q.", content, data)
+	}
+	if path := fakeRT.requests[0].URL.Path; path != "/containers/4fa6e0f0c678/export" {
+		t.Errorf("ExportContainerToFile: wrong path. Want %q. Got %q.", "/containers/4fa6e0f0c678/export", path)
+	}
+	expectFiles(t, dir, "container.tar")
+}
+
+func TestExportContainerToFileFailure(t *testing.T) {
+	t.Parallel()
+	client := newTestClient(&FakeRoundTripper{message: "no such container", status: http.StatusNotFound})
+	dir, err := ioutil.TempDir("", "export")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "container.tar")
+	if err := ioutil.WriteFile(path, []byte("previous export"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	err = client.ExportContainerToFile("4fa6e0f0c678", path)
+	var e *Error
+	if !errors.As(err, &e) || e.Status != http.StatusNotFound {
+		t.Errorf("ExportContainerToFile: wrong error. Want status 404. Got %#v.", err)
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "previous export" {
+		t.Errorf("ExportContainerToFile: the existing file was modified: %q", data)
+	}
+	expectFiles(t, dir, "container.tar")
+}
+
+func TestExportContainerToFileNoID(t *testing.T) {
+	t.Parallel()
+	var client Client
+	err := client.ExportContainerToFile("", filepath.Join(os.TempDir(), "container.tar"))
+	expectNoSuchContainer(t, "", err)
+}
+
+// expectFiles checks that dir contains only the given files, so no temporary
+// files are left behind.
+func expectFiles(t *testing.T, dir string, names ...string) {
+	t.Helper()
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, entry := range entries {
+		got = append(got, entry.Name())
+	}
+	if !reflect.DeepEqual(got, names) {
+		t.Errorf("wrong files in %s. Want %v. Got %v.", dir, names, got)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"io/ioutil"
 	"net/http"
 )
 
@@ -23,9 +24,21 @@ func (c *Client) InspectContainerWithContext(id string, ctx context.Context) (*C
 }
 
 // InspectContainerWithOptions returns information about a container by its ID.
+// SizeRw and SizeRootFs are only populated when opts.Size is true, as
+// computing them can be expensive for the daemon.
 //
 // See https://goo.gl/FaI5JT for more details.
 func (c *Client) InspectContainerWithOptions(opts InspectContainerOptions) (*Container, error) {
+	container, _, err := c.InspectContainerWithRaw(opts)
+	return container, err
+}
+
+// InspectContainerWithRaw is like InspectContainerWithOptions, but also
+// returns the JSON document sent by the daemon, which may include fields that
+// Container doesn't model.
+//
+// See https://goo.gl/FaI5JT for more details.
+func (c *Client) InspectContainerWithRaw(opts InspectContainerOptions) (*Container, []byte, error) {
 	path := "/containers/" + opts.ID + "/json?" + queryString(opts)
 	resp, err := c.do(http.MethodGet, path, doOptions{
 		context: opts.Context,
@@ -33,19 +46,25 @@ func (c *Client) InspectContainerWithOptions(opts InspectContainerOptions) (*Con
 	if err != nil {
 		var e *Error
 		if errors.As(err, &e) && e.Status == http.StatusNotFound {
-			return nil, &NoSuchContainer{ID: opts.ID}
+			return nil, nil, &NoSuchContainer{ID: opts.ID}
 		}
-		return nil, err
+		return nil, nil, err
 	}
 	defer resp.Body.Close()
+	raw, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, err
+	}
 	var container Container
-	if err := json.NewDecoder(resp.Body).Decode(&container); err != nil {
-		return nil, err
+	if err := json.Unmarshal(raw, &container); err != nil {
+		return nil, nil, err
 	}
-	return &container, nil
+	return &container, raw, nil
 }
 
 // InspectContainerOptions specifies parameters for InspectContainerWithOptions.
+// Size requests the size of the container's writable layer (SizeRw) and of
+// its whole filesystem (SizeRootFs).
 //
 // See https://goo.gl/FaI5JT for more details.
 type InspectContainerOptions struct {
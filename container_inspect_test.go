@@ -387,6 +387,41 @@ func TestInspectContainerWithOptions(t *testing.T) {
 	if gotPath := fakeRT.requests[0].URL.Path; gotPath != expectedURL.Path {
 		t.Errorf("InspectContainer(%q): Wrong path in request. Want %q. Got %q.", id, expectedURL.Path, gotPath)
 	}
+	if size := fakeRT.requests[0].URL.Query().Get("size"); size != "1" {
+		t.Errorf("InspectContainer(%q): Wrong size parameter. Want %q. Got %q.", id, "1", size)
+	}
+}
+
+func TestInspectContainerWithRaw(t *testing.T) {
+	t.Parallel()
+	jsonContainer := `{"Id":"4fa6e0f0c678","Name":"/web","UnmodeledField":true}`
+	fakeRT := &FakeRoundTripper{message: jsonContainer, status: http.StatusOK}
+	client := newTestClient(fakeRT)
+	container, raw, err := client.InspectContainerWithRaw(InspectContainerOptions{ID: "web"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if container.ID != "4fa6e0f0c678" || container.Name != "/web" {
+		t.Errorf("InspectContainerWithRaw: wrong container: %#v", container)
+	}
+	if string(raw) != jsonContainer {
+		t.Errorf("InspectContainerWithRaw: wrong raw JSON. Want %s. Got %s.", jsonContainer, raw)
+	}
+	client = newTestClient(&FakeRoundTripper{message: "no such container", status: http.StatusNotFound})
+	_, _, err = client.InspectContainerWithRaw(InspectContainerOptions{ID: "web"})
+	expectNoSuchContainer(t, "web", err)
+}
+
+func TestInspectContainerWithoutSize(t *testing.T) {
+	t.Parallel()
+	fakeRT := &FakeRoundTripper{message: `{"Id":"4fa6e0f0c678"}`, status: http.StatusOK}
+	client := newTestClient(fakeRT)
+	if _, err := client.InspectContainerWithOptions(InspectContainerOptions{ID: "4fa6e0f0c678"}); err != nil {
+		t.Fatal(err)
+	}
+	if query := fakeRT.requests[0].URL.Query(); query.Get("size") != "" {
+		t.Errorf("InspectContainer: unexpected size parameter: %q", query.Get("size"))
+	}
 }
 
 func TestInspectContainerNetwork(t *testing.T) {
@@ -715,6 +750,80 @@ func TestInspectContainerNegativeSwap(t *testing.T) {
 	}
 }
 
+func TestInspectContainerRestartCountAndOOMKilled(t *testing.T) {
+	t.Parallel()
+	jsonContainer := `{
+             "Id": "4fa6e0f0c6786287e131c3852c58a2e01cc697a68231826813597e4994f1d6e2",
+             "State": {
+                     "Status": "restarting",
+                     "Running": true,
+                     "Restarting": true,
+                     "OOMKilled": true,
+                     "ExitCode": 137
+             },
+             "RestartCount": 7
+}`
+	client := newTestClient(&FakeRoundTripper{message: jsonContainer, status: http.StatusOK})
+	container, err := client.InspectContainer("4fa6e0f0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if container.RestartCount != 7 {
+		t.Errorf("InspectContainer: wrong RestartCount. Want 7. Got %d.", container.RestartCount)
+	}
+	if !container.State.OOMKilled {
+		t.Error("InspectContainer: expected State.OOMKilled to be true")
+	}
+}
+
+func TestInspectContainerMounts(t *testing.T) {
+	t.Parallel()
+	jsonContainer := `{
+             "Id": "4fa6e0f0c6786287e131c3852c58a2e01cc697a68231826813597e4994f1d6e2",
+             "Mounts": [
+                     {
+                             "Type": "bind",
+                             "Source": "/etc/ssl",
+                             "Destination": "/etc/ssl",
+                             "Mode": "ro,rslave",
+                             "RW": false,
+                             "Propagation": "rslave"
+                     },
+                     {
+                             "Type": "volume",
+                             "Name": "data",
+                             "Source": "/var/lib/docker/volumes/data/_data",
+                             "Destination": "/data",
+                             "Driver": "local",
+                             "Mode": "z",
+                             "RW": true,
+                             "Propagation": ""
+                     },
+                     {
+                             "Type": "tmpfs",
+                             "Source": "",
+                             "Destination": "/run",
+                             "Mode": "",
+                             "RW": true,
+                             "Propagation": ""
+                     }
+             ]
+}`
+	client := newTestClient(&FakeRoundTripper{message: jsonContainer, status: http.StatusOK})
+	container, err := client.InspectContainer("4fa6e0f0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Mount{
+		{Type: "bind", Source: "/etc/ssl", Destination: "/etc/ssl", Mode: "ro,rslave", Propagation: "rslave"},
+		{Type: "volume", Name: "data", Source: "/var/lib/docker/volumes/data/_data", Destination: "/data", Driver: "local", Mode: "z", RW: true},
+		{Type: "tmpfs", Destination: "/run", RW: true},
+	}
+	if !reflect.DeepEqual(container.Mounts, expected) {
+		t.Errorf("InspectContainer: wrong mounts.\nWant %#v.\nGot  %#v.", expected, container.Mounts)
+	}
+}
+
 func TestInspectContainerFailure(t *testing.T) {
 	t.Parallel()
 	client := newTestClient(&FakeRoundTripper{message: "server error", status: 500})
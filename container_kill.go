@@ -16,15 +16,29 @@ type KillContainerOptions struct {
 
 	// The signal to send to the container. When omitted, Docker server
 	// will assume SIGKILL.
-	Signal  Signal
+	Signal Signal
+
+	// SignalName is the name of the signal to send to the container, like
+	// "SIGHUP" or "TERM", as accepted by ParseSignal. It takes precedence
+	// over Signal when set.
+	SignalName string `qs:"-"`
+
 	Context context.Context
 }
 
 // KillContainer sends a signal to a container, returning an error in case of
-// failure.
+// failure. An unknown SignalName results in ErrInvalidSignal, without
+// sending any request to the daemon.
 //
 // See https://goo.gl/JnTxXZ for more details.
 func (c *Client) KillContainer(opts KillContainerOptions) error {
+	if opts.SignalName != "" {
+		sig, err := ParseSignal(opts.SignalName)
+		if err != nil {
+			return err
+		}
+		opts.Signal = sig
+	}
 	path := "/containers/" + opts.ID + "/kill" + "?" + queryString(opts)
 	resp, err := c.do(http.MethodPost, path, doOptions{context: opts.Context})
 	if err != nil {
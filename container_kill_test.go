@@ -1,6 +1,7 @@
 package docker
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
@@ -45,6 +46,34 @@ func TestKillContainerSignal(t *testing.T) {
 	}
 }
 
+func TestKillContainerSignalName(t *testing.T) {
+	t.Parallel()
+	fakeRT := &FakeRoundTripper{message: "", status: http.StatusNoContent}
+	client := newTestClient(fakeRT)
+	id := "4fa6e0f0c6786287e131c3852c58a2e01cc697a68231826813597e4994f1d6e2"
+	err := client.KillContainer(KillContainerOptions{ID: id, Signal: SIGTERM, SignalName: "SIGHUP"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := fakeRT.requests[0]
+	if signal := req.URL.Query().Get("signal"); signal != "1" {
+		t.Errorf("KillContainer(%q): Wrong query string in request. Want %q. Got %q.", id, "1", signal)
+	}
+}
+
+func TestKillContainerInvalidSignalName(t *testing.T) {
+	t.Parallel()
+	fakeRT := &FakeRoundTripper{message: "", status: http.StatusNoContent}
+	client := newTestClient(fakeRT)
+	err := client.KillContainer(KillContainerOptions{ID: "abc", SignalName: "SIGFOO"})
+	if !errors.Is(err, ErrInvalidSignal) {
+		t.Errorf("KillContainer: wrong error. Want %v. Got %v.", ErrInvalidSignal, err)
+	}
+	if len(fakeRT.requests) != 0 {
+		t.Errorf("KillContainer: unexpected request with an invalid signal: %v", fakeRT.requests[0].URL)
+	}
+}
+
 func TestKillContainerNotFound(t *testing.T) {
 	t.Parallel()
 	client := newTestClient(&FakeRoundTripper{message: "no such container", status: http.StatusNotFound})
@@ -3,15 +3,25 @@ package docker
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 )
 
 // ListContainersOptions specify parameters to the ListContainers function.
 //
+// The "health" filter, which accepts one of HealthStarting, Healthy,
+// Unhealthy or NoHealthcheck, requires Docker API 1.24 or greater.
+//
 // See https://goo.gl/kaOHGw for more details.
 type ListContainersOptions struct {
-	All     bool
-	Size    bool
+	All bool
+
+	// Size populates SizeRw and SizeRootFs in the returned containers. It's
+	// expensive for the daemon, which has to walk the filesystem of every
+	// container, so listing can take much longer with many containers, or
+	// containers with many files.
+	Size bool
+
 	Limit   int
 	Since   string
 	Before  string
@@ -23,6 +33,14 @@ type ListContainersOptions struct {
 //
 // See https://goo.gl/kaOHGw for more details.
 func (c *Client) ListContainers(opts ListContainersOptions) ([]APIContainers, error) {
+	if _, ok := opts.Filters["health"]; ok {
+		if c.serverAPIVersion == nil {
+			c.checkAPIVersion()
+		}
+		if c.serverAPIVersion != nil && c.serverAPIVersion.LessThan(apiVersion124) {
+			return nil, errors.New("the health filter is only supported in API#1.24 and above")
+		}
+	}
 	path := "/containers/json?" + queryString(opts)
 	resp, err := c.do(http.MethodGet, path, doOptions{context: opts.Context})
 	if err != nil {
@@ -130,3 +130,50 @@ func TestListContainersFailure(t *testing.T) {
 		})
 	}
 }
+
+func TestListContainersHealthFilter(t *testing.T) {
+	t.Parallel()
+	fakeRT := &FakeRoundTripper{message: "[]", status: http.StatusOK}
+	client := newTestClient(fakeRT)
+	client.serverAPIVersion = apiVersion124
+	opts := ListContainersOptions{Filters: map[string][]string{"health": {Unhealthy}}}
+	if _, err := client.ListContainers(opts); err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string][]string{"filters": {"{\"health\":[\"unhealthy\"]}"}}
+	got := map[string][]string(fakeRT.requests[0].URL.Query())
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %#v, got %#v.", expected, got)
+	}
+}
+
+func TestListContainersHealthFilterOldAPI(t *testing.T) {
+	t.Parallel()
+	fakeRT := &FakeRoundTripper{message: "[]", status: http.StatusOK}
+	client := newTestClient(fakeRT)
+	opts := ListContainersOptions{Filters: map[string][]string{"health": {Healthy}}}
+	_, err := client.ListContainers(opts)
+	if err == nil || err.Error() != "the health filter is only supported in API#1.24 and above" {
+		t.Errorf("ListContainers: unexpected error: %v", err)
+	}
+	if len(fakeRT.requests) > 0 {
+		t.Errorf("ListContainers: expected no requests, got %d", len(fakeRT.requests))
+	}
+}
+
+func TestListContainersSize(t *testing.T) {
+	t.Parallel()
+	body := `[{"Id": "8dfafdbc3a40", "SizeRw": 12288, "SizeRootFs": 4194304}]`
+	fakeRT := &FakeRoundTripper{message: body, status: http.StatusOK}
+	client := newTestClient(fakeRT)
+	containers, err := client.ListContainers(ListContainersOptions{All: true, Size: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := fakeRT.requests[0].URL.Query().Get("size"); got != "1" {
+		t.Errorf("ListContainers: wrong size parameter. Want %q. Got %q.", "1", got)
+	}
+	if len(containers) != 1 || containers[0].SizeRw != 12288 || containers[0].SizeRootFs != 4194304 {
+		t.Errorf("ListContainers: wrong sizes: %#v", containers)
+	}
+}
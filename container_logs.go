@@ -2,14 +2,21 @@ package docker
 
 import (
 	"context"
+	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"time"
 )
 
 // LogsOptions represents the set of options used when getting logs from a
 // container.
 //
+// Since and Until are Unix timestamps in seconds. SinceTime and UntilTime
+// allow specifying the same bounds with sub-second precision, and take
+// precedence over Since and Until when set. Until requires Docker API 1.35 or
+// greater.
+//
 // See https://goo.gl/krK0ZH for more details.
 type LogsOptions struct {
 	Context           context.Context
@@ -20,6 +27,9 @@ type LogsOptions struct {
 	Tail              string
 
 	Since      int64
+	Until      int64
+	SinceTime  time.Time `qs:"-"`
+	UntilTime  time.Time `qs:"-"`
 	Follow     bool
 	Stdout     bool
 	Stderr     bool
@@ -41,18 +51,97 @@ type LogsOptions struct {
 //
 // See https://goo.gl/krK0ZH for more details.
 func (c *Client) Logs(opts LogsOptions) error {
+	return c.logs(opts, nil)
+}
+
+func (c *Client) logs(opts LogsOptions, started chan struct{}) error {
 	if opts.Container == "" {
 		return &NoSuchContainer{ID: opts.Container}
 	}
 	if opts.Tail == "" {
 		opts.Tail = "all"
 	}
-	path := "/containers/" + opts.Container + "/logs?" + queryString(opts)
+	path := "/containers/" + opts.Container + "/logs?" + logsQueryString(opts)
 	return c.stream(http.MethodGet, path, streamOptions{
 		setRawTerminal:    opts.RawTerminal,
 		stdout:            opts.OutputStream,
 		stderr:            opts.ErrorStream,
 		inactivityTimeout: opts.InactivityTimeout,
 		context:           opts.Context,
+		started:           started,
+	})
+}
+
+// LogsReader gets the logs from the specified container as a stream, so
+// callers can read (or bufio.Scan) them instead of providing writers.
+// OutputStream and ErrorStream are ignored: stdout and stderr are both
+// written to the returned reader, with the stdcopy headers already removed,
+// unless opts.RawTerminal is true, in which case the stream is returned as
+// sent by the daemon.
+//
+// LogsReader returns once the daemon accepts the request, so errors such as a
+// missing container are reported here, while errors that happen in the
+// middle of the stream are returned by Read. Closing the reader aborts the
+// request, which is how callers stop following the logs.
+//
+// See https://goo.gl/krK0ZH for more details.
+func (c *Client) LogsReader(opts LogsOptions) (io.ReadCloser, error) {
+	if opts.Container == "" {
+		return nil, &NoSuchContainer{ID: opts.Container}
+	}
+	return streamReader(opts.Context, func(ctx context.Context, w io.Writer, started chan struct{}) error {
+		opts.Context = ctx
+		opts.OutputStream = w
+		opts.ErrorStream = w
+		return c.logs(opts, started)
 	})
 }
+
+// ContainerAttachLogs replays the logs of the given container and then keeps
+// following new output, like "docker logs -f". It returns when the container
+// stops, the connection is closed or opts.Context is done.
+//
+// Unlike calling Logs and then AttachToContainer, the backlog and the live
+// output are served by the daemon from a single request to the logs endpoint,
+// so no lines are lost or repeated between the two. opts.Follow is always
+// enabled, opts.Tail defaults to "all" (the whole backlog) and, when neither
+// opts.Stdout nor opts.Stderr is set, both streams are included.
+//
+// When the container doesn't have a TTY, the daemon multiplexes stdout and
+// stderr in a single stream, prefixing each chunk with an 8-byte header that
+// identifies the stream and the length of the chunk (the stdcopy format).
+// With opts.RawTerminal set to false, go-dockerclient decodes the headers and
+// writes each chunk to opts.OutputStream or opts.ErrorStream. When the
+// container has a TTY there is no framing, and opts.RawTerminal must be set to
+// true so that the output is copied to opts.OutputStream unmodified; setting
+// it for a container without a TTY delivers the headers along with the data.
+func (c *Client) ContainerAttachLogs(opts LogsOptions) error {
+	opts.Follow = true
+	if !opts.Stdout && !opts.Stderr {
+		opts.Stdout = true
+		opts.Stderr = true
+	}
+	return c.Logs(opts)
+}
+
+func logsQueryString(opts LogsOptions) string {
+	qs := queryString(opts)
+	if opts.SinceTime.IsZero() && opts.UntilTime.IsZero() {
+		return qs
+	}
+	items, _ := url.ParseQuery(qs)
+	if !opts.SinceTime.IsZero() {
+		items.Set("since", formatLogsTimestamp(opts.SinceTime))
+	}
+	if !opts.UntilTime.IsZero() {
+		items.Set("until", formatLogsTimestamp(opts.UntilTime))
+	}
+	return items.Encode()
+}
+
+// formatLogsTimestamp formats t the way the daemon expects in the since and
+// until parameters: seconds since the epoch, optionally followed by a dot and
+// the nanoseconds.
+func formatLogsTimestamp(t time.Time) string {
+	return fmt.Sprintf("%d.%09d", t.Unix(), t.Nanosecond())
+}
@@ -1,12 +1,16 @@
 package docker
 
 import (
+	"bufio"
 	"bytes"
+	"errors"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"reflect"
 	"testing"
+	"time"
 )
 
 func TestLogs(t *testing.T) {
@@ -189,3 +193,242 @@ func TestLogsNoContainer(t *testing.T) {
 	err := client.Logs(LogsOptions{})
 	expectNoSuchContainer(t, "", err)
 }
+
+func TestLogsReader(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte{1, 0, 0, 0, 0, 0, 0, 7})
+		w.Write([]byte("line 1\n"))
+		w.Write([]byte{2, 0, 0, 0, 0, 0, 0, 5})
+		w.Write([]byte("oops\n"))
+		w.Write([]byte{1, 0, 0, 0, 0, 0, 0, 7})
+		w.Write([]byte("line 2\n"))
+	}))
+	defer server.Close()
+	client, _ := NewClient(server.URL)
+	client.SkipServerVersionCheck = true
+	reader, err := client.LogsReader(LogsOptions{Container: "a123456", Stdout: true, Stderr: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+	var lines []string
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"line 1", "oops", "line 2"}; !reflect.DeepEqual(lines, expected) {
+		t.Errorf("LogsReader: wrong output. Want %#v. Got %#v.", expected, lines)
+	}
+}
+
+func TestLogsReaderRawTerminal(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte{1, 0, 0, 0, 0, 0, 0, 3})
+		w.Write([]byte("tty"))
+	}))
+	defer server.Close()
+	client, _ := NewClient(server.URL)
+	client.SkipServerVersionCheck = true
+	reader, err := client.LogsReader(LogsOptions{Container: "a123456", Stdout: true, RawTerminal: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+	data, err := ioutil.ReadAll(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "\x01\x00\x00\x00\x00\x00\x00\x03tty"; string(data) != expected {
+		t.Errorf("LogsReader: wrong output. Want %q. Got %q.", expected, data)
+	}
+}
+
+func TestLogsReaderCloseAbortsFollow(t *testing.T) {
+	t.Parallel()
+	aborted := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte{1, 0, 0, 0, 0, 0, 0, 7})
+		w.Write([]byte("line 1\n"))
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+		close(aborted)
+	}))
+	defer server.Close()
+	client, _ := NewClient(server.URL)
+	client.SkipServerVersionCheck = true
+	reader, err := client.LogsReader(LogsOptions{Container: "a123456", Stdout: true, Follow: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	scanner := bufio.NewScanner(reader)
+	if !scanner.Scan() || scanner.Text() != "line 1" {
+		t.Fatalf("LogsReader: wrong first line. Want %q. Got %q (%v).", "line 1", scanner.Text(), scanner.Err())
+	}
+	if err := reader.Close(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-aborted:
+	case <-time.After(5 * time.Second):
+		t.Fatal("LogsReader: closing the reader didn't abort the request")
+	}
+}
+
+func TestLogsReaderNotFound(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "no such container", http.StatusNotFound)
+	}))
+	defer server.Close()
+	client, _ := NewClient(server.URL)
+	client.SkipServerVersionCheck = true
+	reader, err := client.LogsReader(LogsOptions{Container: "a123456", Stdout: true})
+	if reader != nil {
+		t.Errorf("LogsReader: unexpected reader on error: %#v", reader)
+	}
+	var e *Error
+	if !errors.As(err, &e) || e.Status != http.StatusNotFound {
+		t.Errorf("LogsReader: wrong error. Want 404 *Error. Got %#v.", err)
+	}
+}
+
+func TestLogsReaderNoContainer(t *testing.T) {
+	t.Parallel()
+	var client Client
+	_, err := client.LogsReader(LogsOptions{})
+	expectNoSuchContainer(t, "", err)
+}
+
+func TestContainerAttachLogs(t *testing.T) {
+	t.Parallel()
+	var req http.Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req = *r
+		w.Write([]byte{1, 0, 0, 0, 0, 0, 0, 7})
+		w.Write([]byte("line 1\n"))
+		w.(http.Flusher).Flush()
+		w.Write([]byte{2, 0, 0, 0, 0, 0, 0, 5})
+		w.Write([]byte("oops\n"))
+		w.Write([]byte{1, 0, 0, 0, 0, 0, 0, 7})
+		w.Write([]byte("line 2\n"))
+	}))
+	defer server.Close()
+	client, _ := NewClient(server.URL)
+	client.SkipServerVersionCheck = true
+	var stdout, stderr bytes.Buffer
+	err := client.ContainerAttachLogs(LogsOptions{
+		Container:    "a123456",
+		OutputStream: &stdout,
+		ErrorStream:  &stderr,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "line 1\nline 2\n"; stdout.String() != expected {
+		t.Errorf("ContainerAttachLogs: wrong stdout. Want %q. Got %q.", expected, stdout.String())
+	}
+	if expected := "oops\n"; stderr.String() != expected {
+		t.Errorf("ContainerAttachLogs: wrong stderr. Want %q. Got %q.", expected, stderr.String())
+	}
+	u, _ := url.Parse(client.getURL("/containers/a123456/logs"))
+	if req.URL.Path != u.Path {
+		t.Errorf("ContainerAttachLogs: wrong HTTP path. Want %q. Got %q.", u.Path, req.URL.Path)
+	}
+	expectedQs := map[string][]string{
+		"follow": {"1"},
+		"stdout": {"1"},
+		"stderr": {"1"},
+		"tail":   {"all"},
+	}
+	if got := map[string][]string(req.URL.Query()); !reflect.DeepEqual(got, expectedQs) {
+		t.Errorf("ContainerAttachLogs: wrong query string. Want %#v. Got %#v.", expectedQs, got)
+	}
+}
+
+func TestContainerAttachLogsKeepsStreamSelection(t *testing.T) {
+	t.Parallel()
+	var req http.Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req = *r
+	}))
+	defer server.Close()
+	client, _ := NewClient(server.URL)
+	client.SkipServerVersionCheck = true
+	err := client.ContainerAttachLogs(LogsOptions{
+		Container:    "a123456",
+		OutputStream: &bytes.Buffer{},
+		Stderr:       true,
+		Tail:         "100",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedQs := map[string][]string{
+		"follow": {"1"},
+		"stderr": {"1"},
+		"tail":   {"100"},
+	}
+	if got := map[string][]string(req.URL.Query()); !reflect.DeepEqual(got, expectedQs) {
+		t.Errorf("ContainerAttachLogs: wrong query string. Want %#v. Got %#v.", expectedQs, got)
+	}
+}
+
+func TestContainerAttachLogsNoContainer(t *testing.T) {
+	t.Parallel()
+	var client Client
+	err := client.ContainerAttachLogs(LogsOptions{})
+	expectNoSuchContainer(t, "", err)
+}
+
+func TestLogsTimeWindow(t *testing.T) {
+	t.Parallel()
+	var req http.Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req = *r
+	}))
+	defer server.Close()
+	client, _ := NewClient(server.URL)
+	client.SkipServerVersionCheck = true
+	var tests = []struct {
+		name     string
+		opts     LogsOptions
+		expected map[string][]string
+	}{
+		{
+			name:     "numeric",
+			opts:     LogsOptions{Since: 1500000000, Until: 1500000600},
+			expected: map[string][]string{"tail": {"all"}, "since": {"1500000000"}, "until": {"1500000600"}},
+		},
+		{
+			name: "time overrides numeric",
+			opts: LogsOptions{
+				Since:     1,
+				Until:     2,
+				SinceTime: time.Unix(1500000000, 250000000),
+				UntilTime: time.Unix(1500000600, 5),
+			},
+			expected: map[string][]string{"tail": {"all"}, "since": {"1500000000.250000000"}, "until": {"1500000600.000000005"}},
+		},
+		{
+			name:     "only until time",
+			opts:     LogsOptions{Since: 1500000000, UntilTime: time.Unix(1500000600, 0)},
+			expected: map[string][]string{"tail": {"all"}, "since": {"1500000000"}, "until": {"1500000600.000000000"}},
+		},
+	}
+	for _, tt := range tests {
+		tt.opts.Container = "a123456"
+		tt.opts.OutputStream = &bytes.Buffer{}
+		if err := client.Logs(tt.opts); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		got := map[string][]string(req.URL.Query())
+		if !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("Logs(%s): wrong query string. Want %#v. Got %#v.", tt.name, tt.expected, got)
+		}
+	}
+}
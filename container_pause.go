@@ -1,6 +1,7 @@
 package docker
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"net/http"
@@ -10,8 +11,20 @@ import (
 //
 // See https://goo.gl/D1Yaii for more details.
 func (c *Client) PauseContainer(id string) error {
+	return c.pauseContainer(id, doOptions{})
+}
+
+// PauseContainerWithContext pauses the given container. The context can be
+// used to cancel the pause container request.
+//
+// See https://goo.gl/D1Yaii for more details.
+func (c *Client) PauseContainerWithContext(id string, ctx context.Context) error {
+	return c.pauseContainer(id, doOptions{context: ctx})
+}
+
+func (c *Client) pauseContainer(id string, opts doOptions) error {
 	path := fmt.Sprintf("/containers/%s/pause", id)
-	resp, err := c.do(http.MethodPost, path, doOptions{})
+	resp, err := c.do(http.MethodPost, path, opts)
 	if err != nil {
 		var e *Error
 		if errors.As(err, &e) && e.Status == http.StatusNotFound {
@@ -22,3 +35,58 @@ func (c *Client) PauseContainer(id string) error {
 	resp.Body.Close()
 	return nil
 }
+
+// PauseContainersError is the error returned by PauseContainers when one of
+// the containers couldn't be paused. Err is the error returned when pausing
+// the container identified by ID. RollbackErrors holds, keyed by container
+// ID, the errors for the containers that couldn't be unpaused while rolling
+// back, which are left paused.
+type PauseContainersError struct {
+	ID             string
+	Err            error
+	RollbackErrors map[string]error
+}
+
+func (err *PauseContainersError) Error() string {
+	msg := fmt.Sprintf("failed to pause container %s: %s", err.ID, err.Err)
+	if len(err.RollbackErrors) == 0 {
+		return msg
+	}
+	return fmt.Sprintf("%s (%s)", msg, formatContainerErrors("unpause", err.RollbackErrors))
+}
+
+func (err *PauseContainersError) Unwrap() error {
+	return err.Err
+}
+
+// PauseContainers pauses the given containers in order, so a set of related
+// containers can be frozen at a consistent point in time, for instance while
+// taking a backup. UnpauseContainers undoes it.
+//
+// If a container can't be paused, the containers paused so far are unpaused
+// in reverse order and a *PauseContainersError is returned. Either way, the
+// returned slice holds the IDs of the containers left paused: all of them on
+// success, and the ones that couldn't be unpaused on failure.
+func (c *Client) PauseContainers(ids []string) ([]string, error) {
+	paused := make([]string, 0, len(ids))
+	for _, id := range ids {
+		err := c.PauseContainer(id)
+		if err == nil {
+			paused = append(paused, id)
+			continue
+		}
+		pauseErr := PauseContainersError{ID: id, Err: err}
+		var stuck []string
+		for i := len(paused) - 1; i >= 0; i-- {
+			if err := c.UnpauseContainer(paused[i]); err != nil {
+				if pauseErr.RollbackErrors == nil {
+					pauseErr.RollbackErrors = make(map[string]error)
+				}
+				pauseErr.RollbackErrors[paused[i]] = err
+				stuck = append([]string{paused[i]}, stuck...)
+			}
+		}
+		return stuck, &pauseErr
+	}
+	return paused, nil
+}
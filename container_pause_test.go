@@ -1,9 +1,16 @@
 package docker
 
 import (
+	"context"
+	"errors"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
+	"reflect"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 func TestPauseContainer(t *testing.T) {
@@ -31,3 +38,121 @@ func TestPauseContainerNotFound(t *testing.T) {
 	err := client.PauseContainer("a2334")
 	expectNoSuchContainer(t, "a2334", err)
 }
+
+func TestPauseContainerWhenContextTimesOut(t *testing.T) {
+	t.Parallel()
+	rt := sleepyRoudTripper{sleepDuration: 300 * time.Millisecond}
+
+	client := newTestClient(&rt)
+
+	ctx, cancel := context.WithTimeout(context.TODO(), 50*time.Millisecond)
+	defer cancel()
+
+	err := client.PauseContainerWithContext("id", ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected 'DeadlineExceededError', got: %v", err)
+	}
+}
+
+// pauseServer records the pause and unpause requests it gets, as "pause c1"
+// or "unpause c1", failing the ones listed in statuses.
+type pauseServer struct {
+	mu       sync.Mutex
+	calls    []string
+	statuses map[string]int
+}
+
+func (s *pauseServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/containers/"), "/")
+	call := parts[1] + " " + parts[0]
+	s.mu.Lock()
+	s.calls = append(s.calls, call)
+	s.mu.Unlock()
+	if status, ok := s.statuses[call]; ok {
+		http.Error(w, "cannot "+call, status)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func newPauseTestClient(t *testing.T, server *httptest.Server) *Client {
+	client, err := NewClient(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.SkipServerVersionCheck = true
+	return client
+}
+
+func TestPauseContainers(t *testing.T) {
+	t.Parallel()
+	handler := &pauseServer{}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+	client := newPauseTestClient(t, server)
+	paused, err := client.PauseContainers([]string{"db", "app", "proxy"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"db", "app", "proxy"}
+	if !reflect.DeepEqual(paused, expected) {
+		t.Errorf("PauseContainers: wrong paused containers. Want %#v. Got %#v.", expected, paused)
+	}
+	expectedCalls := []string{"pause db", "pause app", "pause proxy"}
+	if !reflect.DeepEqual(handler.calls, expectedCalls) {
+		t.Errorf("PauseContainers: wrong requests. Want %#v. Got %#v.", expectedCalls, handler.calls)
+	}
+}
+
+func TestPauseContainersRollback(t *testing.T) {
+	t.Parallel()
+	handler := &pauseServer{statuses: map[string]int{"pause proxy": http.StatusConflict}}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+	client := newPauseTestClient(t, server)
+	paused, err := client.PauseContainers([]string{"db", "app", "proxy", "worker"})
+	var pauseErr *PauseContainersError
+	if !errors.As(err, &pauseErr) {
+		t.Fatalf("PauseContainers: wrong error. Want *PauseContainersError. Got %#v.", err)
+	}
+	if pauseErr.ID != "proxy" || len(pauseErr.RollbackErrors) != 0 {
+		t.Errorf("PauseContainers: wrong error: %v", pauseErr)
+	}
+	var e *Error
+	if !errors.As(err, &e) || e.Status != http.StatusConflict {
+		t.Errorf("PauseContainers: wrong wrapped error: %v", err)
+	}
+	if len(paused) != 0 {
+		t.Errorf("PauseContainers: unexpected paused containers: %#v", paused)
+	}
+	expectedCalls := []string{"pause db", "pause app", "pause proxy", "unpause app", "unpause db"}
+	if !reflect.DeepEqual(handler.calls, expectedCalls) {
+		t.Errorf("PauseContainers: wrong requests. Want %#v. Got %#v.", expectedCalls, handler.calls)
+	}
+}
+
+func TestPauseContainersRollbackFailure(t *testing.T) {
+	t.Parallel()
+	handler := &pauseServer{statuses: map[string]int{
+		"pause proxy": http.StatusInternalServerError,
+		"unpause app": http.StatusInternalServerError,
+	}}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+	client := newPauseTestClient(t, server)
+	paused, err := client.PauseContainers([]string{"db", "app", "proxy"})
+	var pauseErr *PauseContainersError
+	if !errors.As(err, &pauseErr) {
+		t.Fatalf("PauseContainers: wrong error. Want *PauseContainersError. Got %#v.", err)
+	}
+	if _, ok := pauseErr.RollbackErrors["app"]; !ok || len(pauseErr.RollbackErrors) != 1 {
+		t.Errorf("PauseContainers: wrong rollback errors: %#v", pauseErr.RollbackErrors)
+	}
+	if expected := []string{"app"}; !reflect.DeepEqual(paused, expected) {
+		t.Errorf("PauseContainers: wrong paused containers. Want %#v. Got %#v.", expected, paused)
+	}
+	expectedCalls := []string{"pause db", "pause app", "pause proxy", "unpause app", "unpause db"}
+	if !reflect.DeepEqual(handler.calls, expectedCalls) {
+		t.Errorf("PauseContainers: wrong requests. Want %#v. Got %#v.", expectedCalls, handler.calls)
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"errors"
 	"net/http"
+	"sort"
+	"sync"
 )
 
 // RemoveContainerOptions encapsulates options to remove a container.
@@ -39,3 +41,107 @@ func (c *Client) RemoveContainer(opts RemoveContainerOptions) error {
 	resp.Body.Close()
 	return nil
 }
+
+// removeContainersConcurrency is the maximum number of containers removed at
+// the same time by RemoveContainers.
+const removeContainersConcurrency = 8
+
+// RemoveContainersOptions specify parameters to the RemoveContainers function.
+//
+// Filters select the containers to remove, with the same keys accepted by
+// ListContainers, e.g. {"label": {"test-run=42"}} or {"status": {"exited"}}.
+// Both running and stopped containers are matched. Force and RemoveVolumes
+// apply to each removal, as in RemoveContainerOptions.
+type RemoveContainersOptions struct {
+	Filters       map[string][]string
+	Force         bool
+	RemoveVolumes bool
+	Context       context.Context
+}
+
+// RemoveContainersError is the error returned by RemoveContainers when some
+// of the matching containers couldn't be removed. Errors is keyed by
+// container ID.
+type RemoveContainersError struct {
+	Errors map[string]error
+}
+
+func (err *RemoveContainersError) Error() string {
+	return formatContainerErrors("remove", err.Errors)
+}
+
+// RemoveContainers removes all containers matching the given filters,
+// sending at most 8 removal requests to the daemon at the same time. It's
+// meant for cleaning up, for instance, the containers created by a test run.
+//
+// It returns the sorted IDs of the removed containers and, when some of them
+// couldn't be removed, a *RemoveContainersError with the error of each one.
+// Containers that are gone before they're removed aren't reported either way.
+// When the context is done, containers that weren't removed yet are reported
+// with the error of the context.
+func (c *Client) RemoveContainers(opts RemoveContainersOptions) ([]string, error) {
+	ctx := opts.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	containers, err := c.ListContainers(ListContainersOptions{All: true, Filters: opts.Filters, Context: ctx})
+	if err != nil {
+		return nil, err
+	}
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+	removed := make([]string, 0, len(containers))
+	failed := make(map[string]error)
+	ids := make(chan string)
+	workers := removeContainersConcurrency
+	if len(containers) < workers {
+		workers = len(containers)
+	}
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for id := range ids {
+				err := c.RemoveContainer(RemoveContainerOptions{
+					ID:            id,
+					RemoveVolumes: opts.RemoveVolumes,
+					Force:         opts.Force,
+					Context:       ctx,
+				})
+				var noSuchContainer *NoSuchContainer
+				if errors.As(err, &noSuchContainer) {
+					continue
+				}
+				mu.Lock()
+				if err != nil {
+					failed[id] = err
+				} else {
+					removed = append(removed, id)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+feed:
+	for i, container := range containers {
+		select {
+		case ids <- container.ID:
+		case <-ctx.Done():
+			mu.Lock()
+			for _, skipped := range containers[i:] {
+				failed[skipped.ID] = ctx.Err()
+			}
+			mu.Unlock()
+			break feed
+		}
+	}
+	close(ids)
+	wg.Wait()
+	sort.Strings(removed)
+	if len(failed) > 0 {
+		return removed, &RemoveContainersError{Errors: failed}
+	}
+	return removed, nil
+}
@@ -1,9 +1,16 @@
 package docker
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"reflect"
+	"strings"
+	"sync"
 	"testing"
 )
 
@@ -51,3 +58,79 @@ func TestRemoveContainerNotFound(t *testing.T) {
 	err := client.RemoveContainer(RemoveContainerOptions{ID: "a2334"})
 	expectNoSuchContainer(t, "a2334", err)
 }
+
+func removeContainersServer(count int, statuses map[string]int, handled *sync.Map) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/containers/json") {
+			containers := make([]APIContainers, count)
+			for i := range containers {
+				containers[i].ID = fmt.Sprintf("c%d", i)
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(containers)
+			return
+		}
+		id := strings.TrimPrefix(r.URL.Path, "/containers/")
+		handled.Store(id, r.URL.Query())
+		if status, ok := statuses[id]; ok {
+			http.Error(w, "cannot remove container", status)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+}
+
+func TestRemoveContainers(t *testing.T) {
+	t.Parallel()
+	var handled sync.Map
+	server := removeContainersServer(20, map[string]int{"c4": http.StatusConflict, "c11": http.StatusInternalServerError, "c7": http.StatusNotFound}, &handled)
+	defer server.Close()
+	client, err := NewClient(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.SkipServerVersionCheck = true
+	removed, err := client.RemoveContainers(RemoveContainersOptions{
+		Filters:       map[string][]string{"label": {"test-run=42"}},
+		Force:         true,
+		RemoveVolumes: true,
+	})
+	var removeErr *RemoveContainersError
+	if !errors.As(err, &removeErr) {
+		t.Fatalf("RemoveContainers: wrong error. Want *RemoveContainersError. Got %#v.", err)
+	}
+	if len(removeErr.Errors) != 2 || removeErr.Errors["c4"] == nil || removeErr.Errors["c11"] == nil {
+		t.Errorf("RemoveContainers: wrong errors: %v", removeErr.Errors)
+	}
+	if len(removed) != 17 {
+		t.Errorf("RemoveContainers: wrong number of removed containers. Want 17. Got %d: %v.", len(removed), removed)
+	}
+	for _, id := range removed {
+		if id == "c4" || id == "c7" || id == "c11" {
+			t.Errorf("RemoveContainers: %s reported as removed", id)
+		}
+		query, _ := handled.Load(id)
+		expected := url.Values{"force": {"1"}, "v": {"1"}}
+		if !reflect.DeepEqual(query, expected) {
+			t.Errorf("RemoveContainers: wrong query for %s. Want %v. Got %v.", id, expected, query)
+		}
+	}
+}
+
+func TestRemoveContainersContextCanceled(t *testing.T) {
+	t.Parallel()
+	var handled sync.Map
+	server := removeContainersServer(5, nil, &handled)
+	defer server.Close()
+	client, err := NewClient(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.SkipServerVersionCheck = true
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = client.RemoveContainers(RemoveContainersOptions{Context: ctx})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("RemoveContainers: wrong error. Want %v. Got %v.", context.Canceled, err)
+	}
+}
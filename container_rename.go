@@ -2,10 +2,18 @@ package docker
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
+	"regexp"
 )
 
+// ErrInvalidContainerName is the error returned by RenameContainer when the
+// new name contains characters not allowed by the daemon.
+var ErrInvalidContainerName = errors.New("invalid container name, only [a-zA-Z0-9][a-zA-Z0-9_.-] are allowed")
+
+var containerNameRegexp = regexp.MustCompile(`^/?[a-zA-Z0-9][a-zA-Z0-9_.-]+$`)
+
 // RenameContainerOptions specify parameters to the RenameContainer function.
 //
 // See https://goo.gl/46inai for more details.
@@ -20,11 +28,30 @@ type RenameContainerOptions struct {
 
 // RenameContainer updates and existing containers name
 //
+// The new name is validated before sending the request, and a name that the
+// daemon wouldn't accept results in an error matching
+// ErrInvalidContainerName. When another container already uses the name, the
+// returned error matches ErrContainerAlreadyExists, with the underlying *Error
+// still available through errors.As, and when the container doesn't exist
+// the error is a *NoSuchContainer.
+//
 // See https://goo.gl/46inai for more details.
 func (c *Client) RenameContainer(opts RenameContainerOptions) error {
+	if !containerNameRegexp.MatchString(opts.Name) {
+		return fmt.Errorf("%w: %q", ErrInvalidContainerName, opts.Name)
+	}
 	resp, err := c.do(http.MethodPost, fmt.Sprintf("/containers/"+opts.ID+"/rename?%s", queryString(opts)), doOptions{
 		context: opts.Context,
 	})
+	var e *Error
+	if errors.As(err, &e) {
+		if e.Status == http.StatusNotFound {
+			return &NoSuchContainer{ID: opts.ID}
+		}
+		if e.Status == http.StatusConflict {
+			return &sentinelError{sentinel: ErrContainerAlreadyExists, err: e}
+		}
+	}
 	if err != nil {
 		return err
 	}
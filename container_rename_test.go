@@ -1,6 +1,7 @@
 package docker
 
 import (
+	"errors"
 	"net/http"
 	"net/url"
 	"testing"
@@ -29,3 +30,38 @@ func TestRenameContainer(t *testing.T) {
 		t.Errorf("RenameContainer: Wrong params in request. Want %q. Got %q.", expectedValues, actualValues)
 	}
 }
+
+func TestRenameContainerInvalidName(t *testing.T) {
+	t.Parallel()
+	fakeRT := &FakeRoundTripper{message: "", status: http.StatusOK}
+	client := newTestClient(fakeRT)
+	for _, name := range []string{"", "a", "-web", "web/1", "web 1"} {
+		err := client.RenameContainer(RenameContainerOptions{ID: "something_old", Name: name})
+		if !errors.Is(err, ErrInvalidContainerName) {
+			t.Errorf("RenameContainer(%q): wrong error. Want %v. Got %v.", name, ErrInvalidContainerName, err)
+		}
+	}
+	if len(fakeRT.requests) != 0 {
+		t.Errorf("RenameContainer: unexpected requests: %d", len(fakeRT.requests))
+	}
+}
+
+func TestRenameContainerConflict(t *testing.T) {
+	t.Parallel()
+	client := newTestClient(&FakeRoundTripper{message: "name already in use", status: http.StatusConflict})
+	err := client.RenameContainer(RenameContainerOptions{ID: "something_old", Name: "something_new"})
+	if !errors.Is(err, ErrContainerAlreadyExists) {
+		t.Errorf("RenameContainer: wrong error. Want %v. Got %v.", ErrContainerAlreadyExists, err)
+	}
+	var e *Error
+	if !errors.As(err, &e) || e.Status != http.StatusConflict {
+		t.Errorf("RenameContainer: wrong API error: %#v", err)
+	}
+}
+
+func TestRenameContainerNotFound(t *testing.T) {
+	t.Parallel()
+	client := newTestClient(&FakeRoundTripper{message: "no such container", status: http.StatusNotFound})
+	err := client.RenameContainer(RenameContainerOptions{ID: "something_old", Name: "something_new"})
+	expectNoSuchContainer(t, "something_old", err)
+}
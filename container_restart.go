@@ -1,6 +1,7 @@
 package docker
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"net/http"
@@ -50,8 +51,21 @@ func NeverRestart() RestartPolicy {
 //
 // See https://goo.gl/MrAKQ5 for more details.
 func (c *Client) RestartContainer(id string, timeout uint) error {
+	return c.restartContainer(id, timeout, doOptions{})
+}
+
+// RestartContainerWithContext stops a container, killing it after the given
+// timeout (in seconds), during the stop process. The context can be used to
+// cancel the restart container request.
+//
+// See https://goo.gl/MrAKQ5 for more details.
+func (c *Client) RestartContainerWithContext(id string, timeout uint, ctx context.Context) error {
+	return c.restartContainer(id, timeout, doOptions{context: ctx})
+}
+
+func (c *Client) restartContainer(id string, timeout uint, opts doOptions) error {
 	path := fmt.Sprintf("/containers/%s/restart?t=%d", id, timeout)
-	resp, err := c.do(http.MethodPost, path, doOptions{})
+	resp, err := c.do(http.MethodPost, path, opts)
 	if err != nil {
 		var e *Error
 		if errors.As(err, &e) && e.Status == http.StatusNotFound {
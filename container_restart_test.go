@@ -1,9 +1,12 @@
 package docker
 
 import (
+	"context"
+	"errors"
 	"net/http"
 	"net/url"
 	"testing"
+	"time"
 )
 
 func TestRestartContainer(t *testing.T) {
@@ -32,6 +35,21 @@ func TestRestartContainerNotFound(t *testing.T) {
 	expectNoSuchContainer(t, "a2334", err)
 }
 
+func TestRestartContainerWhenContextTimesOut(t *testing.T) {
+	t.Parallel()
+	rt := sleepyRoudTripper{sleepDuration: 300 * time.Millisecond}
+
+	client := newTestClient(&rt)
+
+	ctx, cancel := context.WithTimeout(context.TODO(), 50*time.Millisecond)
+	defer cancel()
+
+	err := client.RestartContainerWithContext("id", 10, ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected 'DeadlineExceededError', got: %v", err)
+	}
+}
+
 func TestAlwaysRestart(t *testing.T) {
 	t.Parallel()
 	policy := AlwaysRestart()
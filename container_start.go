@@ -15,7 +15,7 @@ import (
 //
 // See https://goo.gl/fbOSZy for more details.
 func (c *Client) StartContainer(id string, hostConfig *HostConfig) error {
-	return c.startContainer(id, hostConfig, doOptions{})
+	return c.startContainer(id, hostConfig, "", doOptions{})
 }
 
 // StartContainerWithContext starts a container, returning an error in case of
@@ -29,11 +29,14 @@ func (c *Client) StartContainer(id string, hostConfig *HostConfig) error {
 //
 // See https://goo.gl/fbOSZy for more details.
 func (c *Client) StartContainerWithContext(id string, hostConfig *HostConfig, ctx context.Context) error {
-	return c.startContainer(id, hostConfig, doOptions{context: ctx})
+	return c.startContainer(id, hostConfig, "", doOptions{context: ctx})
 }
 
-func (c *Client) startContainer(id string, hostConfig *HostConfig, opts doOptions) error {
+func (c *Client) startContainer(id string, hostConfig *HostConfig, query string, opts doOptions) error {
 	path := "/containers/" + id + "/start"
+	if query != "" {
+		path += "?" + query
+	}
 	if c.serverAPIVersion == nil {
 		c.checkAPIVersion()
 	}
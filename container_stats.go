@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"sync"
 	"time"
 )
 
@@ -147,7 +148,24 @@ type StatsOptions struct {
 // signaling on the Done channel.
 //
 // See https://goo.gl/Dk3Xio for more details.
-func (c *Client) Stats(opts StatsOptions) (retErr error) {
+func (c *Client) Stats(opts StatsOptions) error {
+	ctx := opts.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return c.ContainerStatsWithContext(ctx, opts)
+}
+
+// ContainerStatsWithContext is like Stats, but the streaming request is torn
+// down as soon as the given context is cancelled or its deadline expires. The
+// Context field in opts is ignored in favor of ctx.
+//
+// When the stream ends because of the context, the returned error wraps
+// ctx.Err(), so callers may use errors.Is with context.Canceled or
+// context.DeadlineExceeded to tell it apart from a normal end of the stream.
+//
+// See https://goo.gl/Dk3Xio for more details.
+func (c *Client) ContainerStatsWithContext(ctx context.Context, opts StatsOptions) (retErr error) {
 	errC := make(chan error, 1)
 	readCloser, writeCloser := io.Pipe()
 
@@ -161,6 +179,10 @@ func (c *Client) Stats(opts StatsOptions) (retErr error) {
 		if err := readCloser.Close(); err != nil && retErr == nil {
 			retErr = err
 		}
+
+		if ctxErr := ctx.Err(); ctxErr != nil && retErr != nil {
+			retErr = fmt.Errorf("stats for container %s: %w", opts.ID, ctxErr)
+		}
 	}()
 
 	reqSent := make(chan struct{})
@@ -172,7 +194,7 @@ func (c *Client) Stats(opts StatsOptions) (retErr error) {
 			stdout:            writeCloser,
 			timeout:           opts.Timeout,
 			inactivityTimeout: opts.InactivityTimeout,
-			context:           opts.Context,
+			context:           ctx,
 			reqSent:           reqSent,
 		})
 		if err != nil {
@@ -196,6 +218,8 @@ func (c *Client) Stats(opts StatsOptions) (retErr error) {
 		select {
 		case <-opts.Done:
 			readCloser.Close()
+		case <-ctx.Done():
+			readCloser.CloseWithError(ctx.Err())
 		case <-quit:
 			return
 		}
@@ -203,13 +227,285 @@ func (c *Client) Stats(opts StatsOptions) (retErr error) {
 
 	decoder := json.NewDecoder(readCloser)
 	stats := new(Stats)
-	<-reqSent
+	select {
+	case <-reqSent:
+	case err := <-errC:
+		// the request failed before it could be sent
+		return err
+	}
 	for err := decoder.Decode(stats); !errors.Is(err, io.EOF); err = decoder.Decode(stats) {
 		if err != nil {
 			return err
 		}
-		opts.Stats <- stats
+		select {
+		case opts.Stats <- stats:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
 		stats = new(Stats)
 	}
 	return nil
 }
+
+// ContainerStatsOneShot returns a single snapshot of the statistics of the
+// given container, without streaming.
+//
+// Daemons running Docker API 1.41 or greater are asked for one-shot stats,
+// which skip the extra sampling the daemon does to compute CPU usage, so
+// PreCPUStats is empty. On older daemons, only the first sample returned is
+// decoded.
+//
+// See https://goo.gl/Dk3Xio for more details.
+func (c *Client) ContainerStatsOneShot(id string) (*Stats, error) {
+	if c.serverAPIVersion == nil {
+		c.checkAPIVersion()
+	}
+	return c.containerStatsOneShot(context.Background(), id)
+}
+
+func (c *Client) containerStatsOneShot(ctx context.Context, id string) (*Stats, error) {
+	path := "/containers/" + id + "/stats?stream=false"
+	if c.serverAPIVersion != nil && c.serverAPIVersion.GreaterThanOrEqualTo(apiVersion141) {
+		path += "&one-shot=true"
+	}
+	resp, err := c.do(http.MethodGet, path, doOptions{context: ctx})
+	if err != nil {
+		var e *Error
+		if errors.As(err, &e) && e.Status == http.StatusNotFound {
+			return nil, &NoSuchContainer{ID: id}
+		}
+		return nil, err
+	}
+	defer resp.Body.Close()
+	var stats Stats
+	if err := json.NewDecoder(resp.Body).Decode(&stats); err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
+
+// allContainerStatsConcurrency is the maximum number of stats requests sent
+// at the same time by AllContainerStats.
+const allContainerStatsConcurrency = 8
+
+// AllContainerStats returns a snapshot of the statistics of every running
+// container, keyed by container ID, as ContainerStatsOneShot does for a
+// single container. At most 8 requests are sent to the daemon at the same
+// time, so it's suitable for hosts running hundreds of containers.
+//
+// Each request is bounded by interval (typically the refresh interval of the
+// caller, zero means no bound), and containers whose stats can't be
+// retrieved in time, or at all (for example because they exited in the
+// middle of the collection), are left out of the result. When ctx is done
+// before the collection finishes, AllContainerStats returns the stats
+// collected so far along with the error of the context.
+//
+// See https://goo.gl/Dk3Xio for more details.
+func (c *Client) AllContainerStats(ctx context.Context, interval time.Duration) (map[string]*Stats, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	containers, err := c.ListContainers(ListContainersOptions{Context: ctx})
+	if err != nil {
+		return nil, err
+	}
+	if c.serverAPIVersion == nil {
+		c.checkAPIVersion()
+	}
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+	result := make(map[string]*Stats, len(containers))
+	ids := make(chan string)
+	workers := allContainerStatsConcurrency
+	if len(containers) < workers {
+		workers = len(containers)
+	}
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for id := range ids {
+				reqCtx, cancel := ctx, context.CancelFunc(func() {})
+				if interval > 0 {
+					reqCtx, cancel = context.WithTimeout(ctx, interval)
+				}
+				stats, err := c.containerStatsOneShot(reqCtx, id)
+				cancel()
+				if err != nil {
+					continue
+				}
+				mu.Lock()
+				result[id] = stats
+				mu.Unlock()
+			}
+		}()
+	}
+feed:
+	for _, container := range containers {
+		select {
+		case ids <- container.ID:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(ids)
+	wg.Wait()
+	return result, ctx.Err()
+}
+
+// CPUPercent returns the CPU usage of the container between PreCPUStats and
+// CPUStats, computed with the same formulas as docker stats, where 100 means
+// one full CPU. It may go up to 100 times the number of CPUs.
+//
+// Stats reported by Windows daemons (the ones with NumProcs set) are computed
+// from the 100ns intervals available to the container between PreRead and
+// Read. On Linux, the usage is relative to the system usage, scaled by
+// OnlineCPUs, or by the length of PercpuUsage when reported by daemons older
+// than API 1.27.
+//
+// The first entry sent by the daemon has no previous read to compare with,
+// so CPUPercent returns 0 for it.
+func (s *Stats) CPUPercent() float64 {
+	if s.NumProcs > 0 {
+		if s.CPUStats.CPUUsage.TotalUsage <= s.PreCPUStats.CPUUsage.TotalUsage || s.PreRead.IsZero() || !s.Read.After(s.PreRead) {
+			return 0
+		}
+		intervals := float64(s.Read.Sub(s.PreRead).Nanoseconds()) / 100 * float64(s.NumProcs)
+		return float64(s.CPUStats.CPUUsage.TotalUsage-s.PreCPUStats.CPUUsage.TotalUsage) / intervals * 100
+	}
+	if s.PreCPUStats.SystemCPUUsage == 0 {
+		return 0
+	}
+	return cpuPercent(s.PreCPUStats, s.CPUStats)
+}
+
+// MemoryPercent returns the memory usage of the container as a percentage of
+// its limit, computed like docker stats does: the page cache that can be
+// reclaimed (total_inactive_file on cgroup v1 and inactive_file on cgroup v2)
+// isn't counted as used.
+//
+// Windows daemons don't report a limit, so MemoryPercent returns 0 for their
+// stats, like it does when the limit is unknown.
+func (s *Stats) MemoryPercent() float64 {
+	if s.MemoryStats.Limit == 0 {
+		return 0
+	}
+	usage := s.MemoryStats.Usage
+	inactive := s.MemoryStats.Stats.TotalInactiveFile
+	if inactive == 0 {
+		inactive = s.MemoryStats.Stats.InactiveFile
+	}
+	if inactive < usage {
+		usage -= inactive
+	}
+	return float64(usage) / float64(s.MemoryStats.Limit) * 100
+}
+
+// StatsSample is a stats entry returned by SampleStats, along with the CPU
+// usage of the container computed from the previous sample. The CPUPercent
+// method of the embedded Stats still reports the usage relative to the
+// PreCPUStats sent by the daemon.
+type StatsSample struct {
+	Stats
+
+	// IntervalCPUPercent is the CPU usage of the container since the
+	// previous sample, where 100 means one full CPU. It's computed as in
+	// docker stats, so it may go up to 100 times the number of online CPUs.
+	IntervalCPUPercent float64
+}
+
+// SampleStats streams the statistics of the given container and returns up
+// to samples entries, taken at least interval apart according to the time
+// reported by the daemon (the daemon sends a new entry about once every
+// second). The CPU usage of the first sample is computed from the previous
+// read done by the daemon, and the CPU usage of the others from the sample
+// before them.
+//
+// SampleStats returns early, with the samples collected so far, when the
+// container stops running or is removed.
+//
+// See https://goo.gl/Dk3Xio for more details.
+func (c *Client) SampleStats(id string, interval time.Duration, samples int) ([]StatsSample, error) {
+	return c.SampleStatsWithOptions(SampleStatsOptions{ID: id, Interval: interval, Samples: samples})
+}
+
+// SampleStatsOptions specify parameters to the SampleStatsWithOptions
+// function.
+type SampleStatsOptions struct {
+	ID string
+
+	// Interval is the minimum time between two samples.
+	Interval time.Duration
+
+	// Samples is the maximum number of samples returned.
+	Samples int
+
+	// Context can be used to stop sampling, in which case its error is
+	// returned along with the samples collected so far.
+	Context context.Context
+}
+
+// SampleStatsWithOptions works like SampleStats, with the parameters given
+// in opts.
+//
+// See https://goo.gl/Dk3Xio for more details.
+func (c *Client) SampleStatsWithOptions(opts SampleStatsOptions) ([]StatsSample, error) {
+	if opts.Samples < 1 {
+		return nil, nil
+	}
+	parent := opts.Context
+	if parent == nil {
+		parent = context.Background()
+	}
+	ctx, cancel := context.WithCancel(parent)
+	defer cancel()
+	statsC := make(chan *Stats)
+	errC := make(chan error, 1)
+	go func() {
+		errC <- c.Stats(StatsOptions{ID: opts.ID, Stats: statsC, Stream: true, Context: ctx})
+	}()
+	result := make([]StatsSample, 0, opts.Samples)
+	for stats := range statsC {
+		if stats.Read.IsZero() {
+			// the daemon sends empty stats for containers that aren't
+			// running.
+			break
+		}
+		if len(result) == 0 {
+			result = append(result, StatsSample{Stats: *stats, IntervalCPUPercent: cpuPercent(stats.PreCPUStats, stats.CPUStats)})
+		} else if last := &result[len(result)-1]; stats.Read.Sub(last.Read) >= opts.Interval {
+			result = append(result, StatsSample{Stats: *stats, IntervalCPUPercent: cpuPercent(last.CPUStats, stats.CPUStats)})
+		}
+		if len(result) == opts.Samples {
+			break
+		}
+	}
+	cancel()
+	for range statsC {
+	}
+	err := <-errC
+	if parent.Err() != nil {
+		return result, parent.Err()
+	}
+	if err != nil && !errors.Is(err, context.Canceled) {
+		return result, err
+	}
+	return result, nil
+}
+
+// cpuPercent computes the CPU usage between the two given entries, using the
+// same formula as docker stats.
+func cpuPercent(prev, cur CPUStats) float64 {
+	if cur.CPUUsage.TotalUsage <= prev.CPUUsage.TotalUsage || cur.SystemCPUUsage <= prev.SystemCPUUsage {
+		return 0
+	}
+	cpuDelta := float64(cur.CPUUsage.TotalUsage - prev.CPUUsage.TotalUsage)
+	systemDelta := float64(cur.SystemCPUUsage - prev.SystemCPUUsage)
+	onlineCPUs := float64(cur.OnlineCPUs)
+	if onlineCPUs == 0 {
+		onlineCPUs = float64(len(cur.CPUUsage.PercpuUsage))
+	}
+	return cpuDelta / systemDelta * onlineCPUs * 100
+}
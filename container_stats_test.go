@@ -1,12 +1,19 @@
 package docker
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"reflect"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 func TestStats(t *testing.T) {
@@ -330,3 +337,426 @@ func TestStatsContainerNotFound(t *testing.T) {
 	err := client.Stats(StatsOptions{ID: "abef348", Stats: statsC, Stream: true, Done: done})
 	expectNoSuchContainer(t, "abef348", err)
 }
+
+func TestContainerStatsWithContextCancel(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"read":"2015-01-08T22:57:31.547920715Z"}`))
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+	client, _ := NewClient(server.URL)
+	client.SkipServerVersionCheck = true
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	errC := make(chan error, 1)
+	statsC := make(chan *Stats)
+	go func() {
+		errC <- client.ContainerStatsWithContext(ctx, StatsOptions{ID: "4fa6e0f0", Stats: statsC, Stream: true})
+	}()
+	if _, ok := <-statsC; !ok {
+		t.Fatal("ContainerStatsWithContext: expected one result before cancellation")
+	}
+	cancel()
+	for range statsC {
+	}
+	err := <-errC
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("ContainerStatsWithContext: expected context.Canceled, got %#v", err)
+	}
+}
+
+func TestContainerStatsWithContextDeadline(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+	client, _ := NewClient(server.URL)
+	client.SkipServerVersionCheck = true
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	statsC := make(chan *Stats)
+	go func() {
+		for range statsC {
+		}
+	}()
+	err := client.ContainerStatsWithContext(ctx, StatsOptions{ID: "4fa6e0f0", Stats: statsC, Stream: true})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("ContainerStatsWithContext: expected context.DeadlineExceeded, got %#v", err)
+	}
+}
+
+func TestContainerStatsOneShot(t *testing.T) {
+	t.Parallel()
+	// only the first sample must be read, even if the daemon keeps
+	// sending more.
+	fakeRT := &FakeRoundTripper{message: `{"memory_stats":{"usage":12345}}
+{"memory_stats":{"usage":54321}}`, status: http.StatusOK}
+	client := newTestClient(fakeRT)
+	stats, err := client.ContainerStatsOneShot("4fa6e0f0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.MemoryStats.Usage != 12345 {
+		t.Errorf("ContainerStatsOneShot: wrong memory usage. Want %d. Got %d.", 12345, stats.MemoryStats.Usage)
+	}
+	req := fakeRT.requests[0]
+	expectedQuery := map[string][]string{"stream": {"false"}}
+	if query := map[string][]string(req.URL.Query()); !reflect.DeepEqual(query, expectedQuery) {
+		t.Errorf("ContainerStatsOneShot: wrong query string. Want %#v. Got %#v.", expectedQuery, query)
+	}
+}
+
+func TestContainerStatsOneShotNewAPI(t *testing.T) {
+	t.Parallel()
+	fakeRT := &FakeRoundTripper{message: `{"memory_stats":{"usage":1}}`, status: http.StatusOK}
+	client := newTestClient(fakeRT)
+	client.serverAPIVersion = apiVersion141
+	stats, err := client.ContainerStatsOneShot("4fa6e0f0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.MemoryStats.Usage != 1 {
+		t.Errorf("ContainerStatsOneShot: wrong memory usage. Want %d. Got %d.", 1, stats.MemoryStats.Usage)
+	}
+	expectedQuery := map[string][]string{"stream": {"false"}, "one-shot": {"true"}}
+	if query := map[string][]string(fakeRT.requests[0].URL.Query()); !reflect.DeepEqual(query, expectedQuery) {
+		t.Errorf("ContainerStatsOneShot: wrong query string. Want %#v. Got %#v.", expectedQuery, query)
+	}
+}
+
+func TestContainerStatsOneShotNotFound(t *testing.T) {
+	t.Parallel()
+	client := newTestClient(&FakeRoundTripper{message: "no such container", status: http.StatusNotFound})
+	_, err := client.ContainerStatsOneShot("abef348")
+	expectNoSuchContainer(t, "abef348", err)
+}
+
+func statsSampleServer(entries int, exit bool) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		encoder := json.NewEncoder(w)
+		base := time.Date(2015, 1, 8, 22, 57, 31, 0, time.UTC)
+		var prev CPUStats
+		for i := 0; i < entries; i++ {
+			var stats Stats
+			stats.Read = base.Add(time.Duration(i) * time.Second)
+			stats.PreCPUStats = prev
+			stats.CPUStats = prev
+			stats.CPUStats.CPUUsage.TotalUsage += 250000000
+			if i%2 == 1 {
+				// uses one more CPU in odd seconds
+				stats.CPUStats.CPUUsage.TotalUsage += 500000000
+			}
+			stats.CPUStats.SystemCPUUsage += 1000000000
+			stats.CPUStats.OnlineCPUs = 2
+			prev = stats.CPUStats
+			encoder.Encode(stats)
+		}
+		if exit {
+			w.Write([]byte(`{"read":"0001-01-01T00:00:00Z"}`))
+			return
+		}
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+}
+
+func TestSampleStats(t *testing.T) {
+	t.Parallel()
+	server := statsSampleServer(6, false)
+	defer server.Close()
+	client, _ := NewClient(server.URL)
+	client.SkipServerVersionCheck = true
+	samples, err := client.SampleStats("4fa6e0f0", 2*time.Second, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(samples) != 3 {
+		t.Fatalf("SampleStats: wrong number of samples. Want 3. Got %d.", len(samples))
+	}
+	base := time.Date(2015, 1, 8, 22, 57, 31, 0, time.UTC)
+	for i, sample := range samples {
+		if expected := base.Add(time.Duration(2*i) * time.Second); !sample.Read.Equal(expected) {
+			t.Errorf("SampleStats: wrong read time for sample %d. Want %s. Got %s.", i, expected, sample.Read)
+		}
+		expectedCPU := 100.0
+		if i == 0 {
+			expectedCPU = 50
+		}
+		if sample.IntervalCPUPercent != expectedCPU {
+			t.Errorf("SampleStats: wrong CPU usage for sample %d. Want %f. Got %f.", i, expectedCPU, sample.IntervalCPUPercent)
+		}
+	}
+}
+
+// samples keep the CPUPercent method of the embedded Stats
+var _ interface{ CPUPercent() float64 } = &StatsSample{}
+
+func TestSampleStatsEveryEntry(t *testing.T) {
+	t.Parallel()
+	server := statsSampleServer(3, false)
+	defer server.Close()
+	client, _ := NewClient(server.URL)
+	client.SkipServerVersionCheck = true
+	samples, err := client.SampleStats("4fa6e0f0", 0, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []float64{50, 150, 50}
+	if len(samples) != len(expected) {
+		t.Fatalf("SampleStats: wrong number of samples. Want %d. Got %d.", len(expected), len(samples))
+	}
+	for i, sample := range samples {
+		if sample.IntervalCPUPercent != expected[i] {
+			t.Errorf("SampleStats: wrong CPU usage for sample %d. Want %f. Got %f.", i, expected[i], sample.IntervalCPUPercent)
+		}
+	}
+}
+
+func TestSampleStatsContainerExited(t *testing.T) {
+	t.Parallel()
+	server := statsSampleServer(2, true)
+	defer server.Close()
+	client, _ := NewClient(server.URL)
+	client.SkipServerVersionCheck = true
+	samples, err := client.SampleStats("4fa6e0f0", time.Second, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(samples) != 2 {
+		t.Errorf("SampleStats: wrong number of samples. Want 2. Got %d.", len(samples))
+	}
+}
+
+func TestSampleStatsContext(t *testing.T) {
+	t.Parallel()
+	server := statsSampleServer(2, false)
+	defer server.Close()
+	client, _ := NewClient(server.URL)
+	client.SkipServerVersionCheck = true
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	samples, err := client.SampleStatsWithOptions(SampleStatsOptions{
+		ID:       "4fa6e0f0",
+		Interval: time.Second,
+		Samples:  5,
+		Context:  ctx,
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("SampleStats: wrong error. Want %#v. Got %#v.", context.DeadlineExceeded, err)
+	}
+	if len(samples) != 2 {
+		t.Errorf("SampleStats: wrong number of samples. Want 2. Got %d.", len(samples))
+	}
+}
+
+func TestSampleStatsContainerNotFound(t *testing.T) {
+	t.Parallel()
+	client := newTestClient(&FakeRoundTripper{message: "no such container", status: http.StatusNotFound})
+	_, err := client.SampleStats("abef348", time.Second, 5)
+	expectNoSuchContainer(t, "abef348", err)
+}
+
+// allStatsServer serves count running containers named c0, c1, ... and their
+// one-shot stats, recording the highest number of stats requests handled at
+// the same time. Stats requests for the containers in fail are answered with
+// a 404 and the ones in hang never get a response.
+func allStatsServer(count int, fail, hang map[string]bool, maxInFlight *int32) *httptest.Server {
+	var inFlight int32
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/version"):
+			w.Write([]byte(`{"ApiVersion":"1.41"}`))
+		case strings.HasSuffix(r.URL.Path, "/containers/json"):
+			containers := make([]APIContainers, count)
+			for i := range containers {
+				containers[i].ID = fmt.Sprintf("c%d", i)
+			}
+			json.NewEncoder(w).Encode(containers)
+		default:
+			n := atomic.AddInt32(&inFlight, 1)
+			defer atomic.AddInt32(&inFlight, -1)
+			for {
+				max := atomic.LoadInt32(maxInFlight)
+				if n <= max || atomic.CompareAndSwapInt32(maxInFlight, max, n) {
+					break
+				}
+			}
+			id := strings.Split(strings.TrimPrefix(r.URL.Path, "/containers/"), "/")[0]
+			if hang[id] {
+				<-r.Context().Done()
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+			if fail[id] {
+				http.Error(w, "no such container", http.StatusNotFound)
+				return
+			}
+			json.NewEncoder(w).Encode(Stats{NumProcs: uint32(len(id))})
+		}
+	}))
+}
+
+func TestAllContainerStats(t *testing.T) {
+	t.Parallel()
+	var maxInFlight int32
+	server := allStatsServer(30, map[string]bool{"c3": true, "c17": true}, nil, &maxInFlight)
+	defer server.Close()
+	client, _ := NewClient(server.URL)
+	client.SkipServerVersionCheck = true
+	result, err := client.AllContainerStats(context.Background(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result) != 28 {
+		t.Errorf("AllContainerStats: wrong number of containers. Want 28. Got %d.", len(result))
+	}
+	for _, id := range []string{"c3", "c17"} {
+		if _, ok := result[id]; ok {
+			t.Errorf("AllContainerStats: unexpected stats for container %s", id)
+		}
+	}
+	if stats := result["c25"]; stats == nil || stats.NumProcs != 3 {
+		t.Errorf("AllContainerStats: wrong stats for c25: %#v", stats)
+	}
+	if maxInFlight > allContainerStatsConcurrency {
+		t.Errorf("AllContainerStats: too many concurrent requests. Want at most %d. Got %d.", allContainerStatsConcurrency, maxInFlight)
+	}
+}
+
+func TestAllContainerStatsInterval(t *testing.T) {
+	t.Parallel()
+	var maxInFlight int32
+	server := allStatsServer(3, nil, map[string]bool{"c1": true}, &maxInFlight)
+	defer server.Close()
+	client, _ := NewClient(server.URL)
+	client.SkipServerVersionCheck = true
+	result, err := client.AllContainerStats(context.Background(), 200*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := result["c1"]; ok || len(result) != 2 {
+		t.Errorf("AllContainerStats: wrong result with a hanging container: %#v", result)
+	}
+}
+
+func TestAllContainerStatsContextCanceled(t *testing.T) {
+	t.Parallel()
+	var maxInFlight int32
+	hang := make(map[string]bool)
+	for i := 0; i < 20; i++ {
+		hang[fmt.Sprintf("c%d", i)] = true
+	}
+	server := allStatsServer(20, nil, hang, &maxInFlight)
+	defer server.Close()
+	client, _ := NewClient(server.URL)
+	client.SkipServerVersionCheck = true
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	result, err := client.AllContainerStats(ctx, 0)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("AllContainerStats: wrong error. Want %v. Got %v.", context.DeadlineExceeded, err)
+	}
+	if len(result) != 0 {
+		t.Errorf("AllContainerStats: unexpected stats: %#v", result)
+	}
+}
+
+func TestStatsPercent(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name           string
+		body           string
+		expectedCPU    float64
+		expectedMemory float64
+	}{
+		{
+			name: "linux cgroup v1",
+			body: `{
+				"read": "2021-01-01T00:00:01Z",
+				"preread": "2021-01-01T00:00:00Z",
+				"cpu_stats": {
+					"cpu_usage": {"total_usage": 400000000, "percpu_usage": [100000000, 100000000, 100000000, 100000000]},
+					"system_cpu_usage": 12000000000
+				},
+				"precpu_stats": {
+					"cpu_usage": {"total_usage": 200000000, "percpu_usage": [50000000, 50000000, 50000000, 50000000]},
+					"system_cpu_usage": 10000000000
+				},
+				"memory_stats": {
+					"usage": 209715200,
+					"limit": 1048576000,
+					"stats": {"cache": 62914560, "total_inactive_file": 52428800}
+				}
+			}`,
+			expectedCPU:    40,
+			expectedMemory: 15,
+		},
+		{
+			name: "linux cgroup v2",
+			body: `{
+				"read": "2021-01-01T00:00:01Z",
+				"preread": "2021-01-01T00:00:00Z",
+				"cpu_stats": {
+					"cpu_usage": {"total_usage": 150000000},
+					"system_cpu_usage": 21000000000,
+					"online_cpus": 2
+				},
+				"precpu_stats": {
+					"cpu_usage": {"total_usage": 100000000},
+					"system_cpu_usage": 20000000000,
+					"online_cpus": 2
+				},
+				"memory_stats": {
+					"usage": 100000000,
+					"limit": 400000000,
+					"stats": {"inactive_file": 20000000}
+				}
+			}`,
+			expectedCPU:    10,
+			expectedMemory: 20,
+		},
+		{
+			name: "windows",
+			body: `{
+				"read": "2021-01-01T00:00:01Z",
+				"preread": "2021-01-01T00:00:00Z",
+				"num_procs": 2,
+				"cpu_stats": {"cpu_usage": {"total_usage": 15000000}},
+				"precpu_stats": {"cpu_usage": {"total_usage": 10000000}},
+				"memory_stats": {"commitbytes": 104857600, "privateworkingset": 52428800}
+			}`,
+			expectedCPU:    25,
+			expectedMemory: 0,
+		},
+		{
+			name: "first read",
+			body: `{
+				"read": "2021-01-01T00:00:01Z",
+				"cpu_stats": {"cpu_usage": {"total_usage": 150000000}, "system_cpu_usage": 21000000000, "online_cpus": 2},
+				"memory_stats": {"usage": 100000000, "limit": 400000000, "stats": {"inactive_file": 200000000}}
+			}`,
+			expectedCPU:    0,
+			expectedMemory: 25,
+		},
+	}
+	for _, tt := range tests {
+		var stats Stats
+		if err := json.Unmarshal([]byte(tt.body), &stats); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if got := stats.CPUPercent(); math.Abs(got-tt.expectedCPU) > 1e-9 {
+			t.Errorf("%s: wrong CPUPercent. Want %f. Got %f.", tt.name, tt.expectedCPU, got)
+		}
+		if got := stats.MemoryPercent(); math.Abs(got-tt.expectedMemory) > 1e-9 {
+			t.Errorf("%s: wrong MemoryPercent. Want %f. Got %f.", tt.name, tt.expectedMemory, got)
+		}
+	}
+}
@@ -75,6 +75,28 @@ func (rt *sleepyRoudTripper) RoundTrip(r *http.Request) (*http.Response, error)
 	return nil, errors.New("Can't complete round trip")
 }
 
+func TestAPIContainersHealth(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		status   string
+		expected string
+	}{
+		{"Up 5 minutes (healthy)", Healthy},
+		{"Up 2 seconds (health: starting)", HealthStarting},
+		{"Up 10 minutes (unhealthy)", Unhealthy},
+		{"Up 10 minutes (Paused)", ""},
+		{"Exited (0) 3 minutes ago", ""},
+		{"Up 1 hour", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		container := APIContainers{Status: tt.status}
+		if got := container.Health(); got != tt.expected {
+			t.Errorf("Health(%q): want %q. Got %q.", tt.status, tt.expected, got)
+		}
+	}
+}
+
 func TestNoSuchContainerError(t *testing.T) {
 	t.Parallel()
 	err := &NoSuchContainer{ID: "i345"}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"strings"
 )
 
 // TopResult represents the list of processes running in a container, as
@@ -16,6 +17,51 @@ type TopResult struct {
 	Processes [][]string
 }
 
+// ErrNoSuchTopColumn is the error returned by TopResult.Column when the
+// processes don't have the requested column.
+var ErrNoSuchTopColumn = errors.New("no such column in the list of processes")
+
+// Column returns the values of the column with the given title (compared
+// case-insensitively, e.g. "PID" or "CMD") for each process, in the order of
+// Processes. It returns an error wrapping ErrNoSuchTopColumn if there's no
+// such column, which may happen as the columns depend on the platform and on
+// the ps arguments.
+func (r TopResult) Column(title string) ([]string, error) {
+	index := -1
+	for i, t := range r.Titles {
+		if strings.EqualFold(t, title) {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		return nil, fmt.Errorf("%w: %s", ErrNoSuchTopColumn, title)
+	}
+	values := make([]string, len(r.Processes))
+	for i, process := range r.Processes {
+		if index < len(process) {
+			values[i] = process[index]
+		}
+	}
+	return values, nil
+}
+
+// Rows returns the processes as maps from the column titles to their values,
+// so callers don't need to rely on the order of the columns.
+func (r TopResult) Rows() []map[string]string {
+	rows := make([]map[string]string, len(r.Processes))
+	for i, process := range r.Processes {
+		row := make(map[string]string, len(r.Titles))
+		for j, title := range r.Titles {
+			if j < len(process) {
+				row[title] = process[j]
+			}
+		}
+		rows[i] = row
+	}
+	return rows
+}
+
 // TopContainer returns processes running inside a container
 //
 // See https://goo.gl/FLwpPl for more details.
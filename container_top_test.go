@@ -2,6 +2,7 @@ package docker
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"reflect"
 	"strings"
@@ -88,3 +89,52 @@ func TestTopContainerWithPsArgs(t *testing.T) {
 		t.Errorf("TopContainer: Expected URI to have %q. Got %q.", expectedURI, fakeRT.requests[0].URL.String())
 	}
 }
+
+func TestTopResultColumn(t *testing.T) {
+	t.Parallel()
+	result := TopResult{
+		Titles: []string{"UID", "PID", "PPID", "CMD"},
+		Processes: [][]string{
+			{"root", "1", "0", "/sbin/init"},
+			{"ubuntu", "3087", "1", "sleep 3600"},
+		},
+	}
+	pids, err := result.Column("PID")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"1", "3087"}; !reflect.DeepEqual(pids, expected) {
+		t.Errorf("Column: wrong values. Want %#v. Got %#v.", expected, pids)
+	}
+	cmds, err := result.Column("cmd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"/sbin/init", "sleep 3600"}; !reflect.DeepEqual(cmds, expected) {
+		t.Errorf("Column: wrong values. Want %#v. Got %#v.", expected, cmds)
+	}
+	if _, err := result.Column("Private Working Set"); !errors.Is(err, ErrNoSuchTopColumn) {
+		t.Errorf("Column: wrong error. Want %v. Got %v.", ErrNoSuchTopColumn, err)
+	}
+}
+
+func TestTopResultRows(t *testing.T) {
+	t.Parallel()
+	result := TopResult{
+		Titles: []string{"Name", "PID", "CPU", "Private Working Set"},
+		Processes: [][]string{
+			{"smss.exe", "228", "00:00:00.062", "217.1kB"},
+			{"csrss.exe", "344"},
+		},
+	}
+	expected := []map[string]string{
+		{"Name": "smss.exe", "PID": "228", "CPU": "00:00:00.062", "Private Working Set": "217.1kB"},
+		{"Name": "csrss.exe", "PID": "344"},
+	}
+	if rows := result.Rows(); !reflect.DeepEqual(rows, expected) {
+		t.Errorf("Rows: wrong rows.\nWant %#v.\nGot  %#v.", expected, rows)
+	}
+	if rows := (TopResult{}).Rows(); len(rows) != 0 {
+		t.Errorf("Rows: expected no rows for an empty result. Got %#v.", rows)
+	}
+}
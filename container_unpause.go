@@ -1,6 +1,7 @@
 package docker
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"net/http"
@@ -10,8 +11,20 @@ import (
 //
 // See https://goo.gl/sZ2faO for more details.
 func (c *Client) UnpauseContainer(id string) error {
+	return c.unpauseContainer(id, doOptions{})
+}
+
+// UnpauseContainerWithContext unpauses the given container. The context can be
+// used to cancel the unpause container request.
+//
+// See https://goo.gl/sZ2faO for more details.
+func (c *Client) UnpauseContainerWithContext(id string, ctx context.Context) error {
+	return c.unpauseContainer(id, doOptions{context: ctx})
+}
+
+func (c *Client) unpauseContainer(id string, opts doOptions) error {
 	path := fmt.Sprintf("/containers/%s/unpause", id)
-	resp, err := c.do(http.MethodPost, path, doOptions{})
+	resp, err := c.do(http.MethodPost, path, opts)
 	if err != nil {
 		var e *Error
 		if errors.As(err, &e) && e.Status == http.StatusNotFound {
@@ -22,3 +35,37 @@ func (c *Client) UnpauseContainer(id string) error {
 	resp.Body.Close()
 	return nil
 }
+
+// UnpauseContainersError is the error returned by UnpauseContainers when some
+// of the containers couldn't be unpaused. Errors is keyed by container ID.
+type UnpauseContainersError struct {
+	Errors map[string]error
+}
+
+func (err *UnpauseContainersError) Error() string {
+	return formatContainerErrors("unpause", err.Errors)
+}
+
+// UnpauseContainers unpauses the given containers in reverse order, undoing
+// a previous call to PauseContainers with the same IDs.
+//
+// It keeps going when a container can't be unpaused, so no container is left
+// paused because of another one. It returns the IDs of the unpaused
+// containers, in the order they were unpaused, and, when some of them
+// couldn't be unpaused, an *UnpauseContainersError with the error of each
+// one.
+func (c *Client) UnpauseContainers(ids []string) ([]string, error) {
+	unpaused := make([]string, 0, len(ids))
+	failed := make(map[string]error)
+	for i := len(ids) - 1; i >= 0; i-- {
+		if err := c.UnpauseContainer(ids[i]); err != nil {
+			failed[ids[i]] = err
+			continue
+		}
+		unpaused = append(unpaused, ids[i])
+	}
+	if len(failed) > 0 {
+		return unpaused, &UnpauseContainersError{Errors: failed}
+	}
+	return unpaused, nil
+}
@@ -1,9 +1,14 @@
 package docker
 
 import (
+	"context"
+	"errors"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
+	"reflect"
 	"testing"
+	"time"
 )
 
 func TestUnpauseContainer(t *testing.T) {
@@ -31,3 +36,39 @@ func TestUnpauseContainerNotFound(t *testing.T) {
 	err := client.UnpauseContainer("a2334")
 	expectNoSuchContainer(t, "a2334", err)
 }
+
+func TestUnpauseContainerWhenContextTimesOut(t *testing.T) {
+	t.Parallel()
+	rt := sleepyRoudTripper{sleepDuration: 300 * time.Millisecond}
+
+	client := newTestClient(&rt)
+
+	ctx, cancel := context.WithTimeout(context.TODO(), 50*time.Millisecond)
+	defer cancel()
+
+	err := client.UnpauseContainerWithContext("id", ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected 'DeadlineExceededError', got: %v", err)
+	}
+}
+
+func TestUnpauseContainers(t *testing.T) {
+	t.Parallel()
+	handler := &pauseServer{statuses: map[string]int{"unpause app": http.StatusNotFound}}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+	client := newPauseTestClient(t, server)
+	unpaused, err := client.UnpauseContainers([]string{"db", "app", "proxy"})
+	var unpauseErr *UnpauseContainersError
+	if !errors.As(err, &unpauseErr) {
+		t.Fatalf("UnpauseContainers: wrong error. Want *UnpauseContainersError. Got %#v.", err)
+	}
+	expectNoSuchContainer(t, "app", unpauseErr.Errors["app"])
+	if expected := []string{"proxy", "db"}; !reflect.DeepEqual(unpaused, expected) {
+		t.Errorf("UnpauseContainers: wrong unpaused containers. Want %#v. Got %#v.", expected, unpaused)
+	}
+	expectedCalls := []string{"unpause proxy", "unpause app", "unpause db"}
+	if !reflect.DeepEqual(handler.calls, expectedCalls) {
+		t.Errorf("UnpauseContainers: wrong requests. Want %#v. Got %#v.", expectedCalls, handler.calls)
+	}
+}
@@ -2,34 +2,60 @@ package docker
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 )
 
+// ErrInvalidBlkioWeight is the error returned by UpdateContainer when
+// BlkioWeight is set to a value outside of the range accepted by the daemon.
+var ErrInvalidBlkioWeight = errors.New("blkio weight must be between 10 and 1000")
+
+// ErrInvalidMaximumRetryCount is the error returned by UpdateContainer when
+// the restart policy has a maximum retry count but isn't "on-failure".
+var ErrInvalidMaximumRetryCount = errors.New("maximum retry count can only be used with the on-failure restart policy")
+
 // UpdateContainerOptions specify parameters to the UpdateContainer function.
 //
+// BlkioWeight, when set, must be between 10 and 1000. A zero value leaves the
+// current weight unchanged.
+//
+// RestartPolicy, when its Name is set, replaces the restart policy of the
+// container, so it doesn't have to be recreated to change it (for example,
+// from NeverRestart to RestartUnlessStopped). The daemon refuses to change the
+// restart policy of containers created with AutoRemove.
+//
 // See https://goo.gl/Y6fXUy for more details.
 type UpdateContainerOptions struct {
-	BlkioWeight        int           `json:"BlkioWeight"`
-	CPUShares          int           `json:"CpuShares"`
-	CPUPeriod          int           `json:"CpuPeriod"`
-	CPURealtimePeriod  int64         `json:"CpuRealtimePeriod"`
-	CPURealtimeRuntime int64         `json:"CpuRealtimeRuntime"`
-	CPUQuota           int           `json:"CpuQuota"`
-	CpusetCpus         string        `json:"CpusetCpus"`
-	CpusetMems         string        `json:"CpusetMems"`
-	Memory             int           `json:"Memory"`
-	MemorySwap         int           `json:"MemorySwap"`
-	MemoryReservation  int           `json:"MemoryReservation"`
-	KernelMemory       int           `json:"KernelMemory"`
-	RestartPolicy      RestartPolicy `json:"RestartPolicy,omitempty"`
-	Context            context.Context
+	BlkioWeight         uint16        `json:"BlkioWeight"`
+	BlkioWeightDevice   []BlockWeight `json:"BlkioWeightDevice,omitempty"`
+	BlkioDeviceReadBps  []BlockLimit  `json:"BlkioDeviceReadBps,omitempty"`
+	BlkioDeviceWriteBps []BlockLimit  `json:"BlkioDeviceWriteBps,omitempty"`
+	CPUShares           int           `json:"CpuShares"`
+	CPUPeriod           int           `json:"CpuPeriod"`
+	CPURealtimePeriod   int64         `json:"CpuRealtimePeriod"`
+	CPURealtimeRuntime  int64         `json:"CpuRealtimeRuntime"`
+	CPUQuota            int           `json:"CpuQuota"`
+	CpusetCpus          string        `json:"CpusetCpus"`
+	CpusetMems          string        `json:"CpusetMems"`
+	Memory              int           `json:"Memory"`
+	MemorySwap          int           `json:"MemorySwap"`
+	MemoryReservation   int           `json:"MemoryReservation"`
+	KernelMemory        int           `json:"KernelMemory"`
+	RestartPolicy       RestartPolicy `json:"RestartPolicy,omitempty"`
+	Context             context.Context
 }
 
 // UpdateContainer updates the container at ID with the options
 //
 // See https://goo.gl/Y6fXUy for more details.
 func (c *Client) UpdateContainer(id string, opts UpdateContainerOptions) error {
+	if opts.BlkioWeight != 0 && (opts.BlkioWeight < 10 || opts.BlkioWeight > 1000) {
+		return ErrInvalidBlkioWeight
+	}
+	if opts.RestartPolicy.MaximumRetryCount != 0 && opts.RestartPolicy.Name != "on-failure" {
+		return ErrInvalidMaximumRetryCount
+	}
 	resp, err := c.do(http.MethodPost, fmt.Sprintf("/containers/"+id+"/update"), doOptions{
 		data:      opts,
 		forceJSON: true,
@@ -2,6 +2,7 @@ package docker
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/url"
 	"reflect"
@@ -38,3 +39,73 @@ func TestUpdateContainer(t *testing.T) {
 		t.Errorf("UpdateContainer: wrong body, got: %#v, want %#v", out, update)
 	}
 }
+
+func TestUpdateContainerBlkio(t *testing.T) {
+	t.Parallel()
+	fakeRT := &FakeRoundTripper{message: "", status: http.StatusOK}
+	client := newTestClient(fakeRT)
+	update := UpdateContainerOptions{
+		BlkioWeight:         500,
+		BlkioWeightDevice:   []BlockWeight{{Path: "/dev/sda", Weight: "100"}},
+		BlkioDeviceReadBps:  []BlockLimit{{Path: "/dev/sda", Rate: 1024}},
+		BlkioDeviceWriteBps: []BlockLimit{{Path: "/dev/sdb", Rate: 2048}},
+	}
+	err := client.UpdateContainer("4fa6e0f0c678", update)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out UpdateContainerOptions
+	if err := json.NewDecoder(fakeRT.requests[0].Body).Decode(&out); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(out, update) {
+		t.Errorf("UpdateContainer: wrong body, got: %#v, want %#v", out, update)
+	}
+}
+
+func TestUpdateContainerInvalidBlkioWeight(t *testing.T) {
+	t.Parallel()
+	for _, weight := range []uint16{9, 1001} {
+		fakeRT := &FakeRoundTripper{message: "", status: http.StatusOK}
+		client := newTestClient(fakeRT)
+		err := client.UpdateContainer("4fa6e0f0c678", UpdateContainerOptions{BlkioWeight: weight})
+		if !errors.Is(err, ErrInvalidBlkioWeight) {
+			t.Errorf("UpdateContainer(BlkioWeight=%d): wrong error. Want %#v. Got %#v.", weight, ErrInvalidBlkioWeight, err)
+		}
+		if len(fakeRT.requests) > 0 {
+			t.Errorf("UpdateContainer(BlkioWeight=%d): expected no requests, got %d", weight, len(fakeRT.requests))
+		}
+	}
+}
+
+func TestUpdateContainerRestartPolicy(t *testing.T) {
+	t.Parallel()
+	fakeRT := &FakeRoundTripper{message: "", status: http.StatusOK}
+	client := newTestClient(fakeRT)
+	err := client.UpdateContainer("4fa6e0f0c678", UpdateContainerOptions{RestartPolicy: RestartOnFailure(3)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out map[string]interface{}
+	if err := json.NewDecoder(fakeRT.requests[0].Body).Decode(&out); err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]interface{}{"Name": "on-failure", "MaximumRetryCount": float64(3)}
+	if !reflect.DeepEqual(out["RestartPolicy"], expected) {
+		t.Errorf("UpdateContainer: wrong restart policy in the body. Want %#v. Got %#v.", expected, out["RestartPolicy"])
+	}
+}
+
+func TestUpdateContainerInvalidMaximumRetryCount(t *testing.T) {
+	t.Parallel()
+	fakeRT := &FakeRoundTripper{message: "", status: http.StatusOK}
+	client := newTestClient(fakeRT)
+	policy := RestartPolicy{Name: "always", MaximumRetryCount: 2}
+	err := client.UpdateContainer("4fa6e0f0c678", UpdateContainerOptions{RestartPolicy: policy})
+	if !errors.Is(err, ErrInvalidMaximumRetryCount) {
+		t.Errorf("UpdateContainer: wrong error. Want %#v. Got %#v.", ErrInvalidMaximumRetryCount, err)
+	}
+	if len(fakeRT.requests) > 0 {
+		t.Errorf("UpdateContainer: expected no requests, got %d", len(fakeRT.requests))
+	}
+}
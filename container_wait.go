@@ -24,19 +24,130 @@ func (c *Client) WaitContainerWithContext(id string, ctx context.Context) (int,
 	return c.waitContainer(id, doOptions{context: ctx})
 }
 
+// Conditions accepted by WaitContainerWithOptions.
+const (
+	// WaitConditionNotRunning waits until the container is not running. It
+	// returns immediately if the container is already stopped.
+	WaitConditionNotRunning = "not-running"
+
+	// WaitConditionNextExit waits for the next time the container exits,
+	// even if it is currently stopped.
+	WaitConditionNextExit = "next-exit"
+
+	// WaitConditionRemoved waits until the container is removed, which is
+	// the reliable way to wait for containers created with
+	// HostConfig.AutoRemove.
+	WaitConditionRemoved = "removed"
+)
+
+// WaitContainerOptions specify parameters to the WaitContainerWithOptions
+// function.
+//
+// See https://goo.gl/4AGweZ for more details.
+type WaitContainerOptions struct {
+	// Condition is one of WaitConditionNotRunning (the daemon default when
+	// empty), WaitConditionNextExit or WaitConditionRemoved. Requires Docker
+	// API 1.30 or greater.
+	Condition string
+	Context   context.Context
+}
+
+// WaitContainerWithOptions blocks until the given container reaches the
+// condition in opts, returning the exit code of the container.
+//
+// The daemon registers the wait before answering, so a container removed
+// while it's waited for with WaitConditionRemoved, including by the daemon
+// because of HostConfig.AutoRemove, returns its exit code. A container that
+// is already gone when the call is made is a NoSuchContainer error, so
+// waiting for an AutoRemove container after starting it races with its
+// removal: use RunAndWait to start it and wait for its exit code instead.
+//
+// See https://goo.gl/4AGweZ for more details.
+func (c *Client) WaitContainerWithOptions(id string, opts WaitContainerOptions) (int, error) {
+	if opts.Condition != "" {
+		if c.serverAPIVersion == nil {
+			c.checkAPIVersion()
+		}
+		if c.serverAPIVersion != nil && c.serverAPIVersion.LessThan(apiVersion130) {
+			return 0, errors.New("wait conditions are only supported in API#1.30 and above")
+		}
+	}
+	path := "/containers/" + id + "/wait?" + queryString(opts)
+	return c.waitContainerPath(id, path, doOptions{context: opts.Context})
+}
+
 func (c *Client) waitContainer(id string, opts doOptions) (int, error) {
-	resp, err := c.do(http.MethodPost, "/containers/"+id+"/wait", opts)
+	return c.waitContainerPath(id, "/containers/"+id+"/wait", opts)
+}
+
+func (c *Client) waitContainerPath(id, path string, opts doOptions) (int, error) {
+	resp, err := c.sendWait(id, path, opts)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	return decodeWaitResponse(resp)
+}
+
+// sendWait sends a wait request, returning the response once the daemon
+// sends its headers, before the container reaches the condition.
+func (c *Client) sendWait(id, path string, opts doOptions) (*http.Response, error) {
+	resp, err := c.do(http.MethodPost, path, opts)
 	if err != nil {
 		var e *Error
 		if errors.As(err, &e) && e.Status == http.StatusNotFound {
-			return 0, &NoSuchContainer{ID: id}
+			return nil, &NoSuchContainer{ID: id}
 		}
-		return 0, err
+		return nil, err
 	}
-	defer resp.Body.Close()
+	return resp, nil
+}
+
+func decodeWaitResponse(resp *http.Response) (int, error) {
 	var r struct{ StatusCode int }
 	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
 		return 0, err
 	}
 	return r.StatusCode, nil
 }
+
+// RunAndWait starts the given container and blocks until it exits, returning
+// its exit code. It's meant for one-shot tasks, and is safe to use with
+// containers created with HostConfig.AutoRemove: the wait request is sent
+// with WaitConditionNextExit before the container is started, so the exit
+// code is captured by the daemon even when the container is removed right
+// after it exits, instead of racing with the removal like inspecting the
+// container after it stops does.
+//
+// The context can be used to cancel both the start and the wait requests.
+// RunAndWait requires Docker API 1.30 or greater, as older daemons only
+// respond to the wait request once the container exits.
+func (c *Client) RunAndWait(ctx context.Context, id string) (int, error) {
+	if c.serverAPIVersion == nil {
+		c.checkAPIVersion()
+	}
+	if c.serverAPIVersion != nil && c.serverAPIVersion.LessThan(apiVersion130) {
+		return 0, errors.New("RunAndWait is only supported in API#1.30 and above")
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	path := "/containers/" + id + "/wait?" + queryString(WaitContainerOptions{Condition: WaitConditionNextExit})
+	// the daemon only sends the headers of the response once the wait is
+	// registered, so the container can't exit unnoticed after this point.
+	resp, err := c.sendWait(id, path, doOptions{context: ctx})
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if err := c.StartContainerWithContext(id, nil, ctx); err != nil {
+		return 0, err
+	}
+	status, err := decodeWaitResponse(resp)
+	if err != nil {
+		return 0, chooseError(ctx, err)
+	}
+	return status, nil
+}
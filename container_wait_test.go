@@ -4,7 +4,9 @@ import (
 	"context"
 	"errors"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
+	"strings"
 	"testing"
 	"time"
 )
@@ -90,3 +92,118 @@ func TestWaitContainerWhenContextTimesOut(t *testing.T) {
 		t.Errorf("Expected 'DeadlineExceededError', got: %v", err)
 	}
 }
+
+func TestWaitContainerWithOptions(t *testing.T) {
+	t.Parallel()
+	fakeRT := &FakeRoundTripper{message: `{"StatusCode": 3}`, status: http.StatusOK}
+	client := newTestClient(fakeRT)
+	client.serverAPIVersion = apiVersion130
+	status, err := client.WaitContainerWithOptions("a2334", WaitContainerOptions{Condition: WaitConditionNextExit})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status != 3 {
+		t.Errorf("WaitContainerWithOptions: wrong return. Want 3. Got %d.", status)
+	}
+	req := fakeRT.requests[0]
+	expectedURL, _ := url.Parse(client.getURL("/containers/a2334/wait"))
+	if req.Method != http.MethodPost || req.URL.Path != expectedURL.Path {
+		t.Errorf("WaitContainerWithOptions: wrong request. Want POST %s. Got %s %s.", expectedURL.Path, req.Method, req.URL.Path)
+	}
+	if got := req.URL.Query().Get("condition"); got != WaitConditionNextExit {
+		t.Errorf("WaitContainerWithOptions: wrong condition. Want %q. Got %q.", WaitConditionNextExit, got)
+	}
+}
+
+func TestWaitContainerWithOptionsRemovedNotFound(t *testing.T) {
+	t.Parallel()
+	client := newTestClient(&FakeRoundTripper{message: "no such container", status: http.StatusNotFound})
+	client.serverAPIVersion = apiVersion130
+	_, err := client.WaitContainerWithOptions("a2334", WaitContainerOptions{Condition: WaitConditionRemoved})
+	expectNoSuchContainer(t, "a2334", err)
+}
+
+func TestWaitContainerWithOptionsOldAPI(t *testing.T) {
+	t.Parallel()
+	fakeRT := &FakeRoundTripper{message: `{"StatusCode": 3}`, status: http.StatusOK}
+	client := newTestClient(fakeRT)
+	client.serverAPIVersion = apiVersion125
+	_, err := client.WaitContainerWithOptions("a2334", WaitContainerOptions{Condition: WaitConditionNextExit})
+	if err == nil || err.Error() != "wait conditions are only supported in API#1.30 and above" {
+		t.Errorf("WaitContainerWithOptions: unexpected error: %v", err)
+	}
+	if len(fakeRT.requests) > 0 {
+		t.Errorf("WaitContainerWithOptions: expected no requests, got %d", len(fakeRT.requests))
+	}
+	status, err := client.WaitContainerWithOptions("a2334", WaitContainerOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status != 3 {
+		t.Errorf("WaitContainerWithOptions: wrong return. Want 3. Got %d.", status)
+	}
+}
+
+func TestRunAndWait(t *testing.T) {
+	t.Parallel()
+	started := make(chan struct{})
+	var waitQuery url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/wait"):
+			waitQuery = r.URL.Query()
+			w.WriteHeader(http.StatusOK)
+			w.(http.Flusher).Flush()
+			select {
+			case <-started:
+			case <-time.After(5 * time.Second):
+				return
+			}
+			w.Write([]byte(`{"StatusCode": 42}`))
+		case strings.HasSuffix(r.URL.Path, "/start"):
+			if waitQuery == nil {
+				t.Error("RunAndWait: the container was started before waiting for it")
+			}
+			w.WriteHeader(http.StatusNoContent)
+			close(started)
+		}
+	}))
+	defer server.Close()
+	client, err := NewClient(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.SkipServerVersionCheck = true
+	client.serverAPIVersion = apiVersion130
+	status, err := client.RunAndWait(context.Background(), "abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status != 42 {
+		t.Errorf("RunAndWait: wrong exit code. Want 42. Got %d.", status)
+	}
+	if condition := waitQuery.Get("condition"); condition != WaitConditionNextExit {
+		t.Errorf("RunAndWait: wrong condition. Want %q. Got %q.", WaitConditionNextExit, condition)
+	}
+}
+
+func TestRunAndWaitNotFound(t *testing.T) {
+	t.Parallel()
+	client := newTestClient(&FakeRoundTripper{message: "no such container", status: http.StatusNotFound})
+	client.serverAPIVersion = apiVersion130
+	_, err := client.RunAndWait(context.Background(), "a2334")
+	expectNoSuchContainer(t, "a2334", err)
+}
+
+func TestRunAndWaitOldAPI(t *testing.T) {
+	t.Parallel()
+	fakeRT := &FakeRoundTripper{message: `{"StatusCode": 0}`, status: http.StatusOK}
+	client := newTestClient(fakeRT)
+	_, err := client.RunAndWait(context.Background(), "a2334")
+	if err == nil || err.Error() != "RunAndWait is only supported in API#1.30 and above" {
+		t.Errorf("RunAndWait: unexpected error: %v", err)
+	}
+	if len(fakeRT.requests) != 0 {
+		t.Errorf("RunAndWait: expected no requests, got %d", len(fakeRT.requests))
+	}
+}
@@ -5,8 +5,10 @@
 package docker
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"math"
 	"net"
@@ -98,6 +100,10 @@ type eventMonitoringState struct {
 const (
 	maxMonitorConnRetries = 5
 	retryInitialWaitTime  = 10.
+
+	// maxEventsReconnectWait caps the delay between the attempts of
+	// ListenEventsResilient to reconnect to the daemon.
+	maxEventsReconnectWait = 5 * time.Second
 )
 
 var (
@@ -127,9 +133,15 @@ func (c *Client) AddEventListener(listener chan<- *APIEvents) error {
 	return c.AddEventListenerWithOptions(EventsOptions{}, listener)
 }
 
-// AddEventListener adds a new listener to container events in the Docker API.
+// AddEventListenerWithOptions adds a new listener to container events in the
+// Docker API, with the filters, since and until in options applied by the
+// daemon, so only the relevant events are streamed to the client.
 // See https://docs.docker.com/engine/api/v1.41/#operation/SystemEvents for more details.
 //
+// All the listeners of a client share the same connection to the daemon, so
+// options are only used by the listener that opens it: when other listeners
+// are already registered, options are ignored.
+//
 // The listener parameter is a channel through which events will be sent.
 func (c *Client) AddEventListenerWithOptions(options EventsOptions, listener chan<- *APIEvents) error {
 	var err error
@@ -412,6 +424,138 @@ func (c *Client) eventHijack(opts EventsOptions, startTime int64, eventChan chan
 	return nil
 }
 
+// ListenEventsResilient streams the events matching opts to the returned
+// channel, like AddEventListenerWithOptions, but survives daemon restarts:
+// when the connection drops, it reconnects with an exponential backoff,
+// resuming from the time of the last event received, so events that happened
+// while disconnected are still delivered, as long as the daemon retained
+// them. Since is inclusive, so the events at the resume time that were
+// already delivered are dropped on reconnection.
+//
+// The first connection is made before returning, so errors like invalid
+// filters or an unreachable daemon are reported right away. Afterwards, the
+// listener keeps reconnecting until ctx is done, and then closes the channel.
+// When opts.Until is set, the channel is also closed once the daemon ends the
+// stream normally.
+//
+// Unlike listeners added with AddEventListener, events aren't dropped when
+// the channel is full: the stream waits for the caller to receive them.
+func (c *Client) ListenEventsResilient(ctx context.Context, opts EventsOptions) (<-chan *APIEvents, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	r, err := c.eventsReader(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	events := make(chan *APIEvents)
+	go func() {
+		defer close(events)
+		var cursor eventsCursor
+		for {
+			ended := cursor.forward(ctx, r, events)
+			r.Close()
+			if ctx.Err() != nil || (ended && opts.Until != "") {
+				return
+			}
+			for attempt := 0; ; attempt++ {
+				wait := time.Duration(retryInitialWaitTime*math.Pow(2, float64(attempt))) * time.Millisecond
+				if wait > maxEventsReconnectWait {
+					wait = maxEventsReconnectWait
+				}
+				timer := time.NewTimer(wait)
+				select {
+				case <-ctx.Done():
+					timer.Stop()
+					return
+				case <-timer.C:
+				}
+				if since := cursor.since(); since != "" {
+					opts.Since = since
+				}
+				if r, err = c.eventsReader(ctx, opts); err == nil {
+					break
+				}
+			}
+		}
+	}()
+	return events, nil
+}
+
+func (c *Client) eventsReader(ctx context.Context, opts EventsOptions) (io.ReadCloser, error) {
+	return streamReader(ctx, func(ctx context.Context, w io.Writer, started chan struct{}) error {
+		return c.stream(http.MethodGet, "/events?"+queryString(opts), streamOptions{
+			rawJSONStream: true,
+			eventStream:   true,
+			stdout:        w,
+			context:       ctx,
+			started:       started,
+		})
+	})
+}
+
+// eventsCursor tracks the time of the last event delivered by
+// ListenEventsResilient, along with the events delivered at that time, which
+// are sent again after resuming.
+type eventsCursor struct {
+	last     int64
+	boundary map[string]struct{}
+}
+
+// forward sends the events read from r to events, skipping the ones already
+// delivered, until r fails or ctx is done. It returns true if r ended
+// normally.
+func (cursor *eventsCursor) forward(ctx context.Context, r io.Reader, events chan<- *APIEvents) bool {
+	decoder := json.NewDecoder(r)
+	for {
+		var event APIEvents
+		if err := decoder.Decode(&event); err != nil {
+			return errors.Is(err, io.EOF)
+		}
+		if event.Time == 0 || !cursor.advance(&event) {
+			continue
+		}
+		transformEvent(&event)
+		select {
+		case events <- &event:
+		case <-ctx.Done():
+			return false
+		}
+	}
+}
+
+// advance moves the cursor to the given event, returning false if the event
+// was already delivered.
+func (cursor *eventsCursor) advance(event *APIEvents) bool {
+	ts := event.TimeNano
+	if ts == 0 {
+		ts = event.Time * int64(time.Second)
+	}
+	key := event.Type + "|" + event.Action + "|" + event.Status + "|" + event.Actor.ID + "|" + event.ID
+	switch {
+	case ts < cursor.last:
+		return false
+	case ts == cursor.last:
+		if _, ok := cursor.boundary[key]; ok {
+			return false
+		}
+	default:
+		cursor.last = ts
+		cursor.boundary = make(map[string]struct{})
+	}
+	cursor.boundary[key] = struct{}{}
+	return true
+}
+
+// since returns the Since parameter that resumes the stream at the last
+// delivered event, in the seconds.nanoseconds format accepted by the daemon.
+func (cursor *eventsCursor) since() string {
+	if cursor.last == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%d.%09d", cursor.last/int64(time.Second), cursor.last%int64(time.Second))
+}
+
 // transformEvent takes an event and determines what version it is from
 // then populates both versions of the event
 func transformEvent(event *APIEvents) {
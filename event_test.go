@@ -6,12 +6,14 @@ package docker
 
 import (
 	"bufio"
+	"context"
 	"crypto/tls"
 	"crypto/x509"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -305,3 +307,119 @@ func TestEventListenerReAdding(t *testing.T) {
 	// Give the goroutine of the first eventHijack() time to handle the EOF.
 	time.Sleep(10 * time.Millisecond)
 }
+
+func TestListenEventsResilient(t *testing.T) {
+	t.Parallel()
+	connections := []string{
+		`{"action":"create","type":"container","actor":{"id":"c1"},"time":1442421716,"timeNano":1442421716000000001}
+{"action":"start","type":"container","actor":{"id":"c1"},"time":1442421716,"timeNano":1442421716000000002}
+{"action":"die","type":"container","actor":{"id":"c2"},"time":1442421716,"timeNano":1442421716000000002}`,
+		// the daemon restarted: the boundary events are sent again
+		`{"action":"start","type":"container","actor":{"id":"c1"},"time":1442421716,"timeNano":1442421716000000002}
+{"action":"die","type":"container","actor":{"id":"c2"},"time":1442421716,"timeNano":1442421716000000002}
+{"action":"stop","type":"container","actor":{"id":"c1"},"time":1442421716,"timeNano":1442421716000000002}
+{"action":"destroy","type":"container","actor":{"id":"c2"},"time":1442421717,"timeNano":1442421717000000000}`,
+	}
+	var (
+		mu    sync.Mutex
+		since []string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		n := len(since)
+		since = append(since, r.URL.Query().Get("since"))
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		if n >= len(connections) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(connections[n]))
+		w.(http.Flusher).Flush()
+		if n == len(connections)-1 {
+			<-r.Context().Done()
+		}
+	}))
+	defer server.Close()
+	client, err := NewClient(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.SkipServerVersionCheck = true
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events, err := client.ListenEventsResilient(ctx, EventsOptions{Since: "1442421700"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for len(got) < 5 {
+		select {
+		case event := <-events:
+			got = append(got, event.Action+" "+event.Actor.ID)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("ListenEventsResilient: timed out waiting for events, got %q", got)
+		}
+	}
+	expected := []string{"create c1", "start c1", "die c2", "stop c1", "destroy c2"}
+	if !cmp.Equal(got, expected) {
+		t.Errorf("ListenEventsResilient: wrong events. Want %q. Got %q.", expected, got)
+	}
+	mu.Lock()
+	if expectedSince := []string{"1442421700", "1442421716.000000002"}; !cmp.Equal(since, expectedSince) {
+		t.Errorf("ListenEventsResilient: wrong since. Want %q. Got %q.", expectedSince, since)
+	}
+	mu.Unlock()
+	cancel()
+	select {
+	case _, ok := <-events:
+		if ok {
+			t.Error("ListenEventsResilient: unexpected event after canceling the context")
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("ListenEventsResilient: channel wasn't closed after canceling the context")
+	}
+}
+
+func TestListenEventsResilientUntil(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"action":"create","type":"container","actor":{"id":"c1"},"time":1442421716,"timeNano":1442421716000000001}`))
+	}))
+	defer server.Close()
+	client, err := NewClient(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.SkipServerVersionCheck = true
+	events, err := client.ListenEventsResilient(context.Background(), EventsOptions{Until: "1442421720"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var count int
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case _, ok := <-events:
+			if !ok {
+				if count != 1 {
+					t.Errorf("ListenEventsResilient: wrong number of events. Want 1. Got %d.", count)
+				}
+				return
+			}
+			count++
+		case <-timeout:
+			t.Fatal("ListenEventsResilient: channel wasn't closed after the stream ended")
+		}
+	}
+}
+
+func TestListenEventsResilientError(t *testing.T) {
+	t.Parallel()
+	client := newTestClient(&FakeRoundTripper{message: "invalid filter", status: http.StatusBadRequest})
+	_, err := client.ListenEventsResilient(context.Background(), EventsOptions{Filters: map[string][]string{"foo": {"bar"}}})
+	if err == nil {
+		t.Fatal("ListenEventsResilient: unexpected <nil> error")
+	}
+}
@@ -7,11 +7,14 @@ package docker_test
 import (
 	"archive/tar"
 	"bytes"
+	"errors"
 	"fmt"
 	"log"
+	"strings"
 	"time"
 
 	docker "github.com/fsouza/go-dockerclient"
+	dtesting "github.com/fsouza/go-dockerclient/testing"
 )
 
 func ExampleClient_AttachToContainer() {
@@ -99,6 +102,86 @@ func ExampleClient_AddEventListener() {
 	}
 }
 
+func ExampleClient_ConnectNetwork() {
+	client, err := docker.NewClient("http://localhost:4243")
+	if err != nil {
+		log.Fatal(err)
+	}
+	// Connecting the running container a84849 to the network "backend"
+	// (created with the subnet 172.28.0.0/16), pinning its address and
+	// making it reachable as "db" by the other containers in the network.
+	err = client.ConnectNetwork("backend", docker.NetworkConnectionOptions{
+		Container: "a84849",
+		EndpointConfig: &docker.EndpointConfig{
+			IPAMConfig: &docker.EndpointIPAMConfig{
+				IPv4Address: "172.28.5.10",
+			},
+			Aliases: []string{"db"},
+		},
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func ExampleClient_ConnectNetwork_staticAddress() {
+	// Running against the fake server from the testing package, which
+	// validates static addresses the same way the daemon does.
+	server, err := dtesting.NewServer("127.0.0.1:0", nil, nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer server.Stop()
+	client, err := docker.NewClient(server.URL())
+	if err != nil {
+		log.Fatal(err)
+	}
+	err = client.PullImage(docker.PullImageOptions{Repository: "postgres"}, docker.AuthConfiguration{})
+	if err != nil {
+		log.Fatal(err)
+	}
+	container, err := client.CreateContainer(docker.CreateContainerOptions{
+		Name:   "db",
+		Config: &docker.Config{Image: "postgres"},
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	network, err := client.CreateNetwork(docker.CreateNetworkOptions{
+		Name: "backend",
+		IPAM: &docker.IPAMOptions{
+			Config: []docker.IPAMConfig{{Subnet: "172.28.0.0/16"}},
+		},
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	connect := func(address string) error {
+		return client.ConnectNetwork(network.ID, docker.NetworkConnectionOptions{
+			Container: container.ID,
+			EndpointConfig: &docker.EndpointConfig{
+				IPAMConfig: &docker.EndpointIPAMConfig{IPv4Address: address},
+			},
+		})
+	}
+	// Addresses outside of the subnets of the network are rejected.
+	var apiErr *docker.Error
+	if err := connect("10.0.0.10"); errors.As(err, &apiErr) {
+		fmt.Println(apiErr.Status, strings.TrimSpace(apiErr.Message))
+	}
+	if err := connect("172.28.5.10"); err != nil {
+		log.Fatal(err)
+	}
+	container, err = client.InspectContainerWithOptions(docker.InspectContainerOptions{ID: container.ID})
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(container.NetworkSettings.Networks["backend"].IPAddress)
+	// Output:
+	// 400 invalid address 10.0.0.10: it does not belong to any of the subnets of network backend
+	// 172.28.5.10
+}
+
 func ExampleEnv_Map() {
 	e := docker.Env([]string{"A=1", "B=2", "C=3"})
 	envs := e.Map()
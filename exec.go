@@ -23,6 +23,11 @@ type Exec struct {
 
 // CreateExecOptions specify parameters to the CreateExecContainer function.
 //
+// DetachKeys overrides the key sequence for detaching from the exec session,
+// in the same format as AttachToContainerOptions.DetachKeys. The daemon takes
+// it when the exec is created, so it applies to the session later started by
+// StartExec.
+//
 // See https://goo.gl/60TeBP for more details
 type CreateExecOptions struct {
 	Env          []string        `json:"Env,omitempty" yaml:"Env,omitempty" toml:"Env,omitempty"`
@@ -42,14 +47,24 @@ type CreateExecOptions struct {
 // CreateExec sets up an exec instance in a running container `id`, returning the exec
 // instance, or an error in case of failure.
 //
+// Env requires Docker API 1.25 or greater, and WorkingDir requires API 1.35
+// or greater: CreateExec returns an error instead of sending them to older
+// daemons, which would ignore them.
+//
 // See https://goo.gl/60TeBP for more details
 func (c *Client) CreateExec(opts CreateExecOptions) (*Exec, error) {
+	if (len(opts.Env) > 0 || len(opts.WorkingDir) > 0) && c.serverAPIVersion == nil {
+		c.checkAPIVersion()
+	}
 	if len(opts.Env) > 0 && c.serverAPIVersion.LessThan(apiVersion125) {
 		return nil, errors.New("exec configuration Env is only supported in API#1.25 and above")
 	}
 	if len(opts.WorkingDir) > 0 && c.serverAPIVersion.LessThan(apiVersion135) {
 		return nil, errors.New("exec configuration WorkingDir is only supported in API#1.35 and above")
 	}
+	if err := validateDetachKeys(opts.DetachKeys); err != nil {
+		return nil, err
+	}
 	path := fmt.Sprintf("/containers/%s/exec", opts.Container)
 	resp, err := c.do(http.MethodPost, path, doOptions{data: opts, context: opts.Context})
 	if err != nil {
@@ -79,6 +94,14 @@ type StartExecOptions struct {
 	Detach bool `json:"Detach,omitempty" yaml:"Detach,omitempty" toml:"Detach,omitempty"`
 	Tty    bool `json:"Tty,omitempty" yaml:"Tty,omitempty" toml:"Tty,omitempty"`
 
+	// Initial size of the pseudo-terminal, used when Tty is true. When both
+	// are set, the daemon sizes the terminal as the exec starts, instead of
+	// requiring a ResizeExecTTY call after the session is already running.
+	//
+	// Requires Docker API 1.42 or greater, older daemons ignore it.
+	Height uint `json:"-" yaml:"-" toml:"-"`
+	Width  uint `json:"-" yaml:"-" toml:"-"`
+
 	// Use raw terminal? Usually true when the container contains a TTY.
 	RawTerminal bool `qs:"-"`
 
@@ -119,9 +142,13 @@ func (c *Client) StartExecNonBlocking(id string, opts StartExecOptions) (CloseWa
 	}
 
 	path := fmt.Sprintf("/exec/%s/start", id)
+	body := startExecBody{Detach: opts.Detach, Tty: opts.Tty}
+	if opts.Height > 0 && opts.Width > 0 {
+		body.ConsoleSize = &[2]uint{opts.Height, opts.Width}
+	}
 
 	if opts.Detach {
-		resp, err := c.do(http.MethodPost, path, doOptions{data: opts, context: opts.Context})
+		resp, err := c.do(http.MethodPost, path, doOptions{data: body, context: opts.Context})
 		if err != nil {
 			var e *Error
 			if errors.As(err, &e) && e.Status == http.StatusNotFound {
@@ -139,10 +166,17 @@ func (c *Client) StartExecNonBlocking(id string, opts StartExecOptions) (CloseWa
 		in:             opts.InputStream,
 		stdout:         opts.OutputStream,
 		stderr:         opts.ErrorStream,
-		data:           opts,
+		data:           body,
 	})
 }
 
+// startExecBody is the payload sent to /exec/(id)/start.
+type startExecBody struct {
+	Detach      bool     `json:"Detach,omitempty"`
+	Tty         bool     `json:"Tty,omitempty"`
+	ConsoleSize *[2]uint `json:"ConsoleSize,omitempty"`
+}
+
 // ResizeExecTTY resizes the tty session used by the exec command id. This API
 // is valid only if Tty was specified as part of creating and starting the exec
 // command.
@@ -170,6 +204,11 @@ type ExecProcessConfig struct {
 	Tty        bool     `json:"tty,omitempty" yaml:"tty,omitempty" toml:"tty,omitempty"`
 	EntryPoint string   `json:"entrypoint,omitempty" yaml:"entrypoint,omitempty" toml:"entrypoint,omitempty"`
 	Arguments  []string `json:"arguments,omitempty" yaml:"arguments,omitempty" toml:"arguments,omitempty"`
+
+	// Env is the environment of the command. It's only reported by the
+	// fake server in the testing package, the Docker daemon doesn't include
+	// it in the response.
+	Env []string `json:"env,omitempty" yaml:"env,omitempty" toml:"env,omitempty"`
 }
 
 // ExecInspect is a type with details about a exec instance, including the
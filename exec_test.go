@@ -7,6 +7,7 @@ package docker
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"net"
 	"net/http"
 	"net/http/httptest"
@@ -120,6 +121,38 @@ func TestExecCreateWithEnv(t *testing.T) {
 	}
 }
 
+func TestExecCreateWithEnvChecksVersion(t *testing.T) {
+	t.Parallel()
+	var body CreateExecOptions
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/version" {
+			w.Write([]byte(`{"ApiVersion":"1.41"}`))
+			return
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		w.Write([]byte(`{"Id":"4fa6e0f0c678"}`))
+	}))
+	defer server.Close()
+	client, err := NewClient(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exec, err := client.CreateExec(CreateExecOptions{
+		Container: "test",
+		Env:       []string{"PAGER=", "LANG=C"},
+		Cmd:       []string{"env"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exec.ID != "4fa6e0f0c678" {
+		t.Errorf("CreateExec: wrong ID. Want %q. Got %q.", "4fa6e0f0c678", exec.ID)
+	}
+	if expected := []string{"PAGER=", "LANG=C"}; !reflect.DeepEqual(body.Env, expected) {
+		t.Errorf("CreateExec: wrong Env. Want %#v. Got %#v.", expected, body.Env)
+	}
+}
+
 func TestExecCreateWithWorkingDirErr(t *testing.T) {
 	t.Parallel()
 	jsonContainer := `{"Id": "4fa6e0f0c6786287e131c3852c58a2e01cc697a68231826813597e4994f1d6e2"}`
@@ -146,6 +179,24 @@ func TestExecCreateWithWorkingDirErr(t *testing.T) {
 	}
 }
 
+func TestExecCreateInvalidDetachKeys(t *testing.T) {
+	t.Parallel()
+	fakeRT := &FakeRoundTripper{message: `{"Id": "4fa6e0f0"}`, status: http.StatusOK}
+	client := newTestClient(fakeRT)
+	config := CreateExecOptions{
+		Container:  "test",
+		Cmd:        []string{"sh"},
+		DetachKeys: "ctrl-p,ctrl-",
+	}
+	_, err := client.CreateExec(config)
+	if !errors.Is(err, ErrInvalidDetachKeys) {
+		t.Errorf("CreateExec: wrong error. Want %#v. Got %#v.", ErrInvalidDetachKeys, err)
+	}
+	if len(fakeRT.requests) != 0 {
+		t.Errorf("CreateExec: sent %d requests with invalid detach keys", len(fakeRT.requests))
+	}
+}
+
 func TestExecCreateWithWorkingDir(t *testing.T) {
 	t.Parallel()
 	jsonContainer := `{"Id": "4fa6e0f0c6786287e131c3852c58a2e01cc697a68231826813597e4994f1d6e2"}`
@@ -213,6 +264,48 @@ func TestExecStartDetached(t *testing.T) {
 	}
 }
 
+func TestExecStartWithConsoleSize(t *testing.T) {
+	t.Parallel()
+	execID := "4fa6e0f0c6786287e131c3852c58a2e01cc697a68231826813597e4994f1d6e2"
+	fakeRT := &FakeRoundTripper{status: http.StatusOK}
+	client := newTestClient(fakeRT)
+	err := client.StartExec(execID, StartExecOptions{Detach: true, Tty: true, Height: 40, Width: 120})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var gotBody map[string]interface{}
+	if err := json.NewDecoder(fakeRT.requests[0].Body).Decode(&gotBody); err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]interface{}{
+		"Detach":      true,
+		"Tty":         true,
+		"ConsoleSize": []interface{}{float64(40), float64(120)},
+	}
+	if !reflect.DeepEqual(gotBody, expected) {
+		t.Errorf("StartExec: wrong body. Want %#v. Got %#v.", expected, gotBody)
+	}
+}
+
+func TestExecStartWithoutConsoleSize(t *testing.T) {
+	t.Parallel()
+	execID := "4fa6e0f0c6786287e131c3852c58a2e01cc697a68231826813597e4994f1d6e2"
+	fakeRT := &FakeRoundTripper{status: http.StatusOK}
+	client := newTestClient(fakeRT)
+	err := client.StartExec(execID, StartExecOptions{Detach: true, Height: 40})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var gotBody map[string]interface{}
+	if err := json.NewDecoder(fakeRT.requests[0].Body).Decode(&gotBody); err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]interface{}{"Detach": true}
+	if !reflect.DeepEqual(gotBody, expected) {
+		t.Errorf("StartExec: wrong body. Want %#v. Got %#v.", expected, gotBody)
+	}
+}
+
 func TestExecStartAndAttach(t *testing.T) {
 	reader := strings.NewReader("send value")
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
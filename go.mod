@@ -8,17 +8,16 @@ require (
 	github.com/containerd/containerd v1.4.3 // indirect
 	github.com/containerd/continuity v0.0.0-20210208174643-50096c924a4e // indirect
 	github.com/docker/docker v20.10.3-0.20210216175712-646072ed6524+incompatible
-	github.com/docker/go-connections v0.4.0 // indirect
 	github.com/docker/go-units v0.4.0
 	github.com/gogo/protobuf v1.3.2 // indirect
-	github.com/google/go-cmp v0.5.4
+	github.com/google/go-cmp v0.6.0
 	github.com/gorilla/mux v1.8.0
+	github.com/moby/buildkit v0.8.3
 	github.com/moby/sys/mount v0.2.0 // indirect
 	github.com/moby/term v0.0.0-20201216013528-df9cb8a40635 // indirect
-	github.com/morikuni/aec v1.0.0 // indirect
-	github.com/opencontainers/image-spec v1.0.1 // indirect
-	github.com/opencontainers/runc v0.1.1 // indirect
-	golang.org/x/sys v0.0.0-20210216224549-f992740a1bac // indirect
-	golang.org/x/term v0.0.0-20201113234701-d7a72108b828
+	golang.org/x/crypto v0.31.0
+	golang.org/x/net v0.33.0
+	golang.org/x/term v0.27.0
+	google.golang.org/grpc v1.56.3 // indirect
 	gotest.tools/v3 v3.0.3 // indirect
 )
@@ -5,17 +5,24 @@
 package docker
 
 import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
 	"context"
 	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"net/http"
 	"net/url"
 	"os"
+	"regexp"
 	"strings"
 	"time"
+
+	"github.com/docker/docker/pkg/jsonmessage"
 )
 
 // APIImages represent an image returned in the ListImages call.
@@ -36,24 +43,33 @@ type RootFS struct {
 	Layers []string `json:"Layers,omitempty" yaml:"Layers,omitempty" toml:"Layers,omitempty"`
 }
 
+// ImageMetadata holds local metadata about an image, tracked by the daemon
+// rather than stored in the image itself.
+type ImageMetadata struct {
+	// LastTagTime is the last time the image was tagged or pulled. It's the
+	// zero time when the image has never been tagged locally.
+	LastTagTime time.Time `json:"LastTagTime,omitempty" yaml:"LastTagTime,omitempty" toml:"LastTagTime,omitempty"`
+}
+
 // Image is the type representing a docker image and its various properties
 type Image struct {
-	ID              string    `json:"Id" yaml:"Id" toml:"Id"`
-	RepoTags        []string  `json:"RepoTags,omitempty" yaml:"RepoTags,omitempty" toml:"RepoTags,omitempty"`
-	Parent          string    `json:"Parent,omitempty" yaml:"Parent,omitempty" toml:"Parent,omitempty"`
-	Comment         string    `json:"Comment,omitempty" yaml:"Comment,omitempty" toml:"Comment,omitempty"`
-	Created         time.Time `json:"Created,omitempty" yaml:"Created,omitempty" toml:"Created,omitempty"`
-	Container       string    `json:"Container,omitempty" yaml:"Container,omitempty" toml:"Container,omitempty"`
-	ContainerConfig Config    `json:"ContainerConfig,omitempty" yaml:"ContainerConfig,omitempty" toml:"ContainerConfig,omitempty"`
-	DockerVersion   string    `json:"DockerVersion,omitempty" yaml:"DockerVersion,omitempty" toml:"DockerVersion,omitempty"`
-	Author          string    `json:"Author,omitempty" yaml:"Author,omitempty" toml:"Author,omitempty"`
-	Config          *Config   `json:"Config,omitempty" yaml:"Config,omitempty" toml:"Config,omitempty"`
-	Architecture    string    `json:"Architecture,omitempty" yaml:"Architecture,omitempty"`
-	Size            int64     `json:"Size,omitempty" yaml:"Size,omitempty" toml:"Size,omitempty"`
-	VirtualSize     int64     `json:"VirtualSize,omitempty" yaml:"VirtualSize,omitempty" toml:"VirtualSize,omitempty"`
-	RepoDigests     []string  `json:"RepoDigests,omitempty" yaml:"RepoDigests,omitempty" toml:"RepoDigests,omitempty"`
-	RootFS          *RootFS   `json:"RootFS,omitempty" yaml:"RootFS,omitempty" toml:"RootFS,omitempty"`
-	OS              string    `json:"Os,omitempty" yaml:"Os,omitempty" toml:"Os,omitempty"`
+	ID              string        `json:"Id" yaml:"Id" toml:"Id"`
+	RepoTags        []string      `json:"RepoTags,omitempty" yaml:"RepoTags,omitempty" toml:"RepoTags,omitempty"`
+	Parent          string        `json:"Parent,omitempty" yaml:"Parent,omitempty" toml:"Parent,omitempty"`
+	Comment         string        `json:"Comment,omitempty" yaml:"Comment,omitempty" toml:"Comment,omitempty"`
+	Created         time.Time     `json:"Created,omitempty" yaml:"Created,omitempty" toml:"Created,omitempty"`
+	Container       string        `json:"Container,omitempty" yaml:"Container,omitempty" toml:"Container,omitempty"`
+	ContainerConfig Config        `json:"ContainerConfig,omitempty" yaml:"ContainerConfig,omitempty" toml:"ContainerConfig,omitempty"`
+	DockerVersion   string        `json:"DockerVersion,omitempty" yaml:"DockerVersion,omitempty" toml:"DockerVersion,omitempty"`
+	Author          string        `json:"Author,omitempty" yaml:"Author,omitempty" toml:"Author,omitempty"`
+	Config          *Config       `json:"Config,omitempty" yaml:"Config,omitempty" toml:"Config,omitempty"`
+	Architecture    string        `json:"Architecture,omitempty" yaml:"Architecture,omitempty"`
+	Size            int64         `json:"Size,omitempty" yaml:"Size,omitempty" toml:"Size,omitempty"`
+	VirtualSize     int64         `json:"VirtualSize,omitempty" yaml:"VirtualSize,omitempty" toml:"VirtualSize,omitempty"`
+	RepoDigests     []string      `json:"RepoDigests,omitempty" yaml:"RepoDigests,omitempty" toml:"RepoDigests,omitempty"`
+	RootFS          *RootFS       `json:"RootFS,omitempty" yaml:"RootFS,omitempty" toml:"RootFS,omitempty"`
+	OS              string        `json:"Os,omitempty" yaml:"Os,omitempty" toml:"Os,omitempty"`
+	Metadata        ImageMetadata `json:"Metadata,omitempty" yaml:"Metadata,omitempty" toml:"Metadata,omitempty"`
 }
 
 // ImagePre012 serves the same purpose as the Image type except that it is for
@@ -88,18 +104,52 @@ var (
 	// InputStream are provided in BuildImageOptions
 	ErrMultipleContexts = errors.New("image build may not be provided BOTH context dir and input stream")
 
+	// ErrRemoteWithLocalContext is the error returned when Remote is
+	// provided in BuildImageOptions along with ContextDir or InputStream.
+	ErrRemoteWithLocalContext = errors.New("image build may not be provided BOTH a remote context and a local context")
+
 	// ErrMustSpecifyNames is the error returned when the Names field on
 	// ExportImagesOptions is nil or empty
 	ErrMustSpecifyNames = errors.New("must specify at least one name to export")
+
+	// ErrInvalidReference is the error returned by TagImage when the source
+	// image or the target repository and tag are not valid references.
+	ErrInvalidReference = errors.New("invalid reference format")
+
+	// ErrAllTagsWithTag is the error returned by PullImage when All is set
+	// along with a tag or digest.
+	ErrAllTagsWithTag = errors.New("tag can't be used when pulling all tags")
+
+	// ErrInvalidImageArchive is the error returned by LoadImageFromFile
+	// when the file isn't an image archive, as created by ExportImage or
+	// ExportImages.
+	ErrInvalidImageArchive = errors.New("not a valid image archive")
 )
 
 // ListImagesOptions specify parameters to the ListImages function.
 //
 // See https://goo.gl/BVzauZ for more details.
 type ListImagesOptions struct {
+	// Filters to apply to the listing. Commonly used filters are:
+	//
+	//     reference=<pattern>  glob on repository[:tag], e.g. "nginx:1.*"
+	//     since=<image>        images created after the given image
+	//     before=<image>       images created before the given image
+	//     dangling=true|false  untagged images
+	//     label=key[=value]    images carrying the given label
+	//
+	// With a reference filter, RepoTags only lists the matching tags.
 	Filters map[string][]string
-	All     bool
+
+	// All includes the intermediate images, that are hidden by default.
+	// It doesn't affect the filters.
+	All bool
+
+	// Digests includes the RepoDigests of each image in the listing.
 	Digests bool
+
+	// Filter is the legacy repository filter, superseded by the reference
+	// filter in Filters.
 	Filter  string
 	Context context.Context
 }
@@ -123,13 +173,23 @@ func (c *Client) ListImages(opts ListImagesOptions) ([]APIImages, error) {
 
 // ImageHistory represent a layer in an image's history returned by the
 // ImageHistory call.
+//
+// ID is "<missing>" for layers that were built on another host, and Tags is
+// nil for intermediate layers, that aren't tagged. CreatedBy holds the full,
+// untruncated, instruction that created the layer.
 type ImageHistory struct {
 	ID        string   `json:"Id" yaml:"Id" toml:"Id"`
 	Tags      []string `json:"Tags,omitempty" yaml:"Tags,omitempty" toml:"Tags,omitempty"`
-	Created   int64    `json:"Created,omitempty" yaml:"Created,omitempty" toml:"Tags,omitempty"`
+	Created   int64    `json:"Created,omitempty" yaml:"Created,omitempty" toml:"Created,omitempty"`
 	CreatedBy string   `json:"CreatedBy,omitempty" yaml:"CreatedBy,omitempty" toml:"CreatedBy,omitempty"`
 	Size      int64    `json:"Size,omitempty" yaml:"Size,omitempty" toml:"Size,omitempty"`
 	Comment   string   `json:"Comment,omitempty" yaml:"Comment,omitempty" toml:"Comment,omitempty"`
+
+	// EmptyLayer is true for metadata-only instructions, like ENV or CMD,
+	// that don't add a filesystem layer. Daemons that don't report it leave
+	// it false, in that case such entries can be recognized by their zero
+	// Size.
+	EmptyLayer bool `json:"EmptyLayer,omitempty" yaml:"EmptyLayer,omitempty" toml:"EmptyLayer,omitempty"`
 }
 
 // ImageHistory returns the history of the image by its name or ID.
@@ -200,27 +260,41 @@ func (c *Client) RemoveImageExtended(name string, opts RemoveImageOptions) error
 //
 // See https://goo.gl/ncLTG8 for more details.
 func (c *Client) InspectImage(name string) (*Image, error) {
+	image, _, err := c.InspectImageWithRaw(name)
+	return image, err
+}
+
+// InspectImageWithRaw returns an image by its name or ID, along with the JSON
+// document sent by the daemon, which may include fields that Image doesn't
+// model.
+//
+// See https://goo.gl/ncLTG8 for more details.
+func (c *Client) InspectImageWithRaw(name string) (*Image, []byte, error) {
 	resp, err := c.do(http.MethodGet, "/images/"+name+"/json", doOptions{})
 	if err != nil {
 		var e *Error
 		if errors.As(err, &e) && e.Status == http.StatusNotFound {
-			return nil, ErrNoSuchImage
+			return nil, nil, ErrNoSuchImage
 		}
-		return nil, err
+		return nil, nil, err
 	}
 	defer resp.Body.Close()
+	raw, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, err
+	}
 
 	var image Image
 
 	// if the caller elected to skip checking the server's version, assume it's the latest
 	if c.SkipServerVersionCheck || c.expectedAPIVersion.GreaterThanOrEqualTo(apiVersion112) {
-		if err := json.NewDecoder(resp.Body).Decode(&image); err != nil {
-			return nil, err
+		if err := json.Unmarshal(raw, &image); err != nil {
+			return nil, nil, err
 		}
 	} else {
 		var imagePre012 ImagePre012
-		if err := json.NewDecoder(resp.Body).Decode(&imagePre012); err != nil {
-			return nil, err
+		if err := json.Unmarshal(raw, &imagePre012); err != nil {
+			return nil, nil, err
 		}
 
 		image.ID = imagePre012.ID
@@ -236,7 +310,7 @@ func (c *Client) InspectImage(name string) (*Image, error) {
 		image.Size = imagePre012.Size
 	}
 
-	return &image, nil
+	return &image, raw, nil
 }
 
 // PushImageOptions represents options to use in the PushImage method.
@@ -266,6 +340,58 @@ type PushImageOptions struct {
 //
 // See https://goo.gl/BZemGg for more details.
 func (c *Client) PushImage(opts PushImageOptions, auth AuthConfiguration) error {
+	return c.pushImage(opts, auth, nil)
+}
+
+// PushImageWithHeaders works like PushImage, but also returns the headers of
+// the response sent by the Docker daemon. The headers are returned whenever a
+// response was received, even if the push failed.
+func (c *Client) PushImageWithHeaders(opts PushImageOptions, auth AuthConfiguration) (http.Header, error) {
+	var respHeaders http.Header
+	err := c.pushImage(opts, auth, &respHeaders)
+	return respHeaders, err
+}
+
+// PushImageWithDigest works like PushImage, but also returns the content
+// digest of the pushed manifest (e.g. "sha256:..."), as reported by the
+// daemon in the auxiliary data at the end of the push. The digest can be used
+// to deploy the image by digest (see ResolveImageDigest), which is immutable,
+// unlike tags.
+//
+// The digest is read both from the auxiliary message sent by the classic
+// image store and from the one sent when the daemon uses the containerd image
+// store; other auxiliary messages, such as BuildKit traces, are ignored. It's
+// an empty string if the daemon didn't report a digest.
+func (c *Client) PushImageWithDigest(opts PushImageOptions, auth AuthConfiguration) (string, error) {
+	var digest string
+	err := c.pushImageStream(opts, auth, nil, func(msg jsonmessage.JSONMessage) {
+		if msg.ID == buildkitTraceID {
+			return
+		}
+		var aux pushAux
+		if err := json.Unmarshal(*msg.Aux, &aux); err == nil && aux.Digest != "" {
+			digest = aux.Digest
+		}
+	})
+	return digest, err
+}
+
+// buildkitTraceID is the ID of the auxiliary messages sent by BuildKit with
+// progress information.
+const buildkitTraceID = "moby.buildkit.trace"
+
+// pushAux is the auxiliary data sent by the daemon at the end of a push.
+type pushAux struct {
+	Tag    string
+	Digest string
+	Size   int
+}
+
+func (c *Client) pushImage(opts PushImageOptions, auth AuthConfiguration, respHeaders *http.Header) error {
+	return c.pushImageStream(opts, auth, respHeaders, nil)
+}
+
+func (c *Client) pushImageStream(opts PushImageOptions, auth AuthConfiguration, respHeaders *http.Header, auxCallback func(jsonmessage.JSONMessage)) error {
 	if opts.Name == "" {
 		return ErrNoSuchImage
 	}
@@ -283,9 +409,64 @@ func (c *Client) PushImage(opts PushImageOptions, auth AuthConfiguration) error
 		stdout:            opts.OutputStream,
 		inactivityTimeout: opts.InactivityTimeout,
 		context:           opts.Context,
+		responseHeaders:   respHeaders,
+		auxCallback:       auxCallback,
 	})
 }
 
+// ErrNoImageDigest is the error returned by ResolveImageDigest when the image
+// has no digest for the repository, which happens for images that were built
+// locally and never pushed or pulled.
+var ErrNoImageDigest = errors.New("image has no digest for the repository")
+
+// ResolveImageDigest resolves a local image reference, like
+// "registry.example.com/app:v1", to the digest reference of the same
+// repository, like "registry.example.com/app@sha256:...", using the
+// RepoDigests returned by InspectImage. It returns an error wrapping
+// ErrNoImageDigest if the image doesn't have a digest for the repository.
+func (c *Client) ResolveImageDigest(name string) (string, error) {
+	image, err := c.InspectImage(name)
+	if err != nil {
+		return "", err
+	}
+	repository, _ := ParseRepositoryTag(name)
+	for _, repoDigest := range image.RepoDigests {
+		if strings.HasPrefix(repoDigest, repository+"@") {
+			return repoDigest, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %s", ErrNoImageDigest, repository)
+}
+
+// InspectImageByDigest returns the local image with the given digest, either
+// as a digest reference, like "registry.example.com/app@sha256:...", or as a
+// bare digest, like "sha256:...", that matches any repository. It returns
+// ErrNoSuchImage if no local image has the digest in its RepoDigests, which
+// allows checking that a pinned image is present before running it.
+func (c *Client) InspectImageByDigest(digest string) (*Image, error) {
+	if i := strings.Index(digest, "@"); i >= 0 {
+		if !digestRegexp.MatchString(digest[i+1:]) {
+			return nil, fmt.Errorf("%w: invalid digest %q", ErrInvalidReference, digest[i+1:])
+		}
+		return c.InspectImage(digest)
+	}
+	if !digestRegexp.MatchString(digest) {
+		return nil, fmt.Errorf("%w: invalid digest %q", ErrInvalidReference, digest)
+	}
+	images, err := c.ListImages(ListImagesOptions{All: true, Digests: true})
+	if err != nil {
+		return nil, err
+	}
+	for _, image := range images {
+		for _, repoDigest := range image.RepoDigests {
+			if strings.HasSuffix(repoDigest, "@"+digest) {
+				return c.InspectImage(image.ID)
+			}
+		}
+	}
+	return nil, ErrNoSuchImage
+}
+
 // PullImageOptions present the set of options available for pulling an image
 // from a registry.
 //
@@ -293,7 +474,24 @@ func (c *Client) PushImage(opts PushImageOptions, auth AuthConfiguration) error
 type PullImageOptions struct {
 	Repository string `qs:"fromImage"`
 	Tag        string
-	Platform   string `ver:"1.32"`
+
+	// All pulls every tag of Repository, like docker pull --all-tags. The
+	// tag is omitted from the request, so neither Tag nor Repository may
+	// include a tag or digest.
+	//
+	// The progress of all tags is written to OutputStream as a single
+	// stream, and the messages about the layers of the different tags may
+	// be interleaved. The "Pulling from" message of each tag carries the tag
+	// in its id, and the layers are identified by their own IDs, so with
+	// RawJSONStream the messages must be told apart by their id rather than
+	// by their position in the stream.
+	All bool `qs:"-"`
+
+	// Platform selects the variant of a multi-arch image to pull, in the
+	// os[/arch[/variant]] format, for example "linux/amd64". It requires
+	// Docker API 1.32 or greater. When omitted, the daemon pulls the variant
+	// matching its own platform.
+	Platform string `ver:"1.32"`
 
 	// Only required for Docker Engine 1.9 or 1.10 w/ Remote API < 1.21
 	// and Docker Engine < 1.9
@@ -311,6 +509,19 @@ type PullImageOptions struct {
 //
 // See https://goo.gl/qkoSsn for more details.
 func (c *Client) PullImage(opts PullImageOptions, auth AuthConfiguration) error {
+	return c.pullImage(opts, auth, nil)
+}
+
+// PullImageWithHeaders works like PullImage, but also returns the headers of
+// the response sent by the Docker daemon. The headers are returned whenever a
+// response was received, even if the pull failed.
+func (c *Client) PullImageWithHeaders(opts PullImageOptions, auth AuthConfiguration) (http.Header, error) {
+	var respHeaders http.Header
+	err := c.pullImage(opts, auth, &respHeaders)
+	return respHeaders, err
+}
+
+func (c *Client) pullImage(opts PullImageOptions, auth AuthConfiguration, respHeaders *http.Header) error {
 	if opts.Repository == "" {
 		return ErrNoSuchImage
 	}
@@ -319,15 +530,20 @@ func (c *Client) PullImage(opts PullImageOptions, auth AuthConfiguration) error
 	if err != nil {
 		return err
 	}
+	if opts.All {
+		if _, tag := ParseRepositoryTag(opts.Repository); tag != "" || opts.Tag != "" || strings.Contains(opts.Repository, "@") {
+			return ErrAllTagsWithTag
+		}
+	}
 	if opts.Tag == "" && strings.Contains(opts.Repository, "@") {
 		parts := strings.SplitN(opts.Repository, "@", 2)
 		opts.Repository = parts[0]
 		opts.Tag = parts[1]
 	}
-	return c.createImage(&opts, headers, nil, opts.OutputStream, opts.RawJSONStream, opts.InactivityTimeout, opts.Context)
+	return c.createImage(&opts, headers, nil, opts.OutputStream, opts.RawJSONStream, opts.InactivityTimeout, opts.Context, respHeaders)
 }
 
-func (c *Client) createImage(opts interface{}, headers map[string]string, in io.Reader, w io.Writer, rawJSONStream bool, timeout time.Duration, context context.Context) error {
+func (c *Client) createImage(opts interface{}, headers map[string]string, in io.Reader, w io.Writer, rawJSONStream bool, timeout time.Duration, context context.Context, respHeaders *http.Header) error {
 	url, err := c.getPath("/images/create", opts)
 	if err != nil {
 		return err
@@ -340,6 +556,7 @@ func (c *Client) createImage(opts interface{}, headers map[string]string, in io.
 		rawJSONStream:     rawJSONStream,
 		inactivityTimeout: timeout,
 		context:           context,
+		responseHeaders:   respHeaders,
 	})
 }
 
@@ -354,6 +571,9 @@ type LoadImageOptions struct {
 
 // LoadImage imports a tarball docker image
 //
+// The progress reported by the daemon is written to opts.OutputStream, when
+// it is set, and discarded otherwise.
+//
 // See https://goo.gl/rEsBV3 for more details.
 func (c *Client) LoadImage(opts LoadImageOptions) error {
 	return c.stream(http.MethodPost, "/images/load", streamOptions{
@@ -364,6 +584,143 @@ func (c *Client) LoadImage(opts LoadImageOptions) error {
 	})
 }
 
+// LoadImageFromFile imports the docker image tarball at the given path, like
+// LoadImage. opts.InputStream is ignored.
+//
+// Before sending anything to the daemon, it checks that the file is a tar
+// archive, optionally gzip compressed, containing a manifest.json (or, for
+// archives created by older daemons, a repositories) file at its root, and
+// returns ErrInvalidImageArchive otherwise. Archives compressed with bzip2 or
+// xz are sent without checking.
+func (c *Client) LoadImageFromFile(path string, opts LoadImageOptions) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := validateImageArchive(f); err != nil {
+		return fmt.Errorf("%w: %s: %v", ErrInvalidImageArchive, path, err)
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	opts.InputStream = f
+	return c.LoadImage(opts)
+}
+
+var (
+	bzip2Magic = []byte("BZh")
+	gzipMagic  = []byte{0x1f, 0x8b, 0x08}
+	xzMagic    = []byte{0xfd, '7', 'z', 'X', 'Z', 0x00}
+)
+
+// validateImageArchive looks for the manifest of an image archive in f,
+// leaving the offset of f at an arbitrary position.
+func validateImageArchive(f io.ReadSeeker) error {
+	magic := make([]byte, len(xzMagic))
+	n, err := io.ReadFull(f, magic)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return err
+	}
+	magic = magic[:n]
+	if bytes.HasPrefix(magic, bzip2Magic) || bytes.HasPrefix(magic, xzMagic) {
+		return nil
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	// Reading f directly lets tar seek over the layers instead of reading
+	// them.
+	var archive io.Reader = f
+	if bytes.HasPrefix(magic, gzipMagic) {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		archive = gz
+	}
+	tr := tar.NewReader(archive)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return errors.New("missing manifest.json")
+		}
+		if err != nil {
+			return err
+		}
+		switch strings.TrimPrefix(header.Name, "./") {
+		case "manifest.json", "repositories":
+			return nil
+		}
+	}
+}
+
+const (
+	loadedImagePrefix   = "Loaded image: "
+	loadedImageIDPrefix = "Loaded image ID: "
+)
+
+// ImageLoadWithProgress imports a tarball docker image, like LoadImage, and
+// returns the images that were loaded. Tagged images are identified by their
+// repository and tag (e.g. "busybox:latest"), and untagged images by their ID.
+//
+// The progress reported by the daemon is written to opts.OutputStream as it
+// arrives, when it is set.
+//
+// See https://goo.gl/rEsBV3 for more details.
+func (c *Client) ImageLoadWithProgress(opts LoadImageOptions) ([]string, error) {
+	out := opts.OutputStream
+	if out == nil {
+		out = ioutil.Discard
+	}
+	pr, pw := io.Pipe()
+	var images []string
+	done := make(chan error, 1)
+	go func() {
+		done <- readLoadProgress(pr, out, &images)
+	}()
+	err := c.stream(http.MethodPost, "/images/load", streamOptions{
+		setRawTerminal: true,
+		rawJSONStream:  true,
+		in:             opts.InputStream,
+		stdout:         pw,
+		context:        opts.Context,
+	})
+	pw.CloseWithError(err)
+	if progressErr := <-done; err == nil {
+		err = progressErr
+	}
+	return images, err
+}
+
+// readLoadProgress decodes the messages sent by the daemon when loading
+// images, writing them to out and collecting the names of the loaded images.
+func readLoadProgress(r io.Reader, out io.Writer, images *[]string) error {
+	defer io.Copy(ioutil.Discard, r)
+	decoder := json.NewDecoder(r)
+	for {
+		var msg jsonmessage.JSONMessage
+		if err := decoder.Decode(&msg); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		for _, line := range strings.Split(msg.Stream, "\n") {
+			switch {
+			case strings.HasPrefix(line, loadedImagePrefix):
+				*images = append(*images, strings.TrimPrefix(line, loadedImagePrefix))
+			case strings.HasPrefix(line, loadedImageIDPrefix):
+				*images = append(*images, strings.TrimPrefix(line, loadedImageIDPrefix))
+			}
+		}
+		if err := msg.Display(out, false); err != nil {
+			return err
+		}
+	}
+}
+
 // ExportImageOptions represent the options for ExportImage Docker API call.
 //
 // See https://goo.gl/AuySaA for more details.
@@ -396,7 +753,9 @@ type ExportImagesOptions struct {
 	Context           context.Context
 }
 
-// ExportImages exports one or more images (as a tar file) into the stream
+// ExportImages exports one or more images (as a tar file) into the stream.
+// The resulting tarball contains a manifest.json file listing every
+// requested repository, and can be loaded back with LoadImage.
 //
 // See https://goo.gl/N9XlDn for more details.
 func (c *Client) ExportImages(opts ExportImagesOptions) error {
@@ -428,6 +787,7 @@ func (c *Client) ExportImages(opts ExportImagesOptions) error {
 		setRawTerminal:    true,
 		stdout:            opts.OutputStream,
 		inactivityTimeout: opts.InactivityTimeout,
+		context:           opts.Context,
 	})
 }
 
@@ -465,43 +825,98 @@ func (c *Client) ImportImage(opts ImportImageOptions) error {
 		opts.InputStream = f
 		opts.Source = "-"
 	}
-	return c.createImage(&opts, nil, opts.InputStream, opts.OutputStream, opts.RawJSONStream, opts.InactivityTimeout, opts.Context)
+	return c.createImage(&opts, nil, opts.InputStream, opts.OutputStream, opts.RawJSONStream, opts.InactivityTimeout, opts.Context, nil)
 }
 
 // BuildImageOptions present the set of informations available for building an
 // image from a tarfile with a Dockerfile in it.
 //
+// The build context is either InputStream, a tar archive that is sent to the
+// daemon as is (it's not filtered by any .dockerignore file), or ContextDir, a
+// directory that the client archives excluding the files matched by its
+// .dockerignore file. The Dockerfile and the .dockerignore file themselves are
+// always sent, so the daemon can process them.
+//
+// Alternatively, Remote makes the daemon fetch the build context itself,
+// without anything being sent by the client. It may be a Git repository URL,
+// optionally followed by #ref:dir to build a given branch, tag or commit from
+// a subdirectory, like "https://github.com/user/repo.git#main:app", or the
+// URL of a tar archive. When the URL points to a plain text file, the file is
+// used as the Dockerfile and the build has no context. Remote can't be used
+// along with InputStream or ContextDir.
+//
+// CacheFrom lists images that the builder may use as cache sources, like the
+// --cache-from flag of docker build. The images must be available to the
+// daemon (for example, pulled by a previous step of a CI job), the ones that
+// aren't are ignored. It requires Docker API 1.25 or greater.
+//
 // For more details about the Docker building process, see
 // https://goo.gl/4nYHwV.
 type BuildImageOptions struct {
-	Context             context.Context
-	Name                string   `qs:"t"`
-	Dockerfile          string   `ver:"1.25"`
-	ExtraHosts          string   `ver:"1.28"`
-	CacheFrom           []string `qs:"-" ver:"1.25"`
-	Memory              int64
-	Memswap             int64
-	ShmSize             int64
-	CPUShares           int64
-	CPUQuota            int64 `ver:"1.21"`
-	CPUPeriod           int64 `ver:"1.21"`
-	CPUSetCPUs          string
-	Labels              map[string]string
-	InputStream         io.Reader `qs:"-"`
-	OutputStream        io.Writer `qs:"-"`
-	Remote              string
-	Auth                AuthConfiguration  `qs:"-"` // for older docker X-Registry-Auth header
-	AuthConfigs         AuthConfigurations `qs:"-"` // for newer docker X-Registry-Config header
-	ContextDir          string             `qs:"-"`
-	Ulimits             []ULimit           `qs:"-" ver:"1.18"`
-	BuildArgs           []BuildArg         `qs:"-" ver:"1.21"`
-	NetworkMode         string             `ver:"1.25"`
-	Platform            string             `ver:"1.32"`
-	InactivityTimeout   time.Duration      `qs:"-"`
-	CgroupParent        string
-	SecurityOpt         []string
-	Target              string
-	Outputs             string `ver:"1.40"`
+	Context           context.Context
+	Name              string   `qs:"t"`
+	Dockerfile        string   `ver:"1.25"`
+	ExtraHosts        string   `ver:"1.28"`
+	CacheFrom         []string `qs:"-" ver:"1.25"`
+	Memory            int64
+	Memswap           int64
+	ShmSize           int64
+	CPUShares         int64
+	CPUQuota          int64 `ver:"1.21"`
+	CPUPeriod         int64 `ver:"1.21"`
+	CPUSetCPUs        string
+	Labels            map[string]string
+	InputStream       io.Reader `qs:"-"`
+	OutputStream      io.Writer `qs:"-"`
+	Remote            string
+	Auth              AuthConfiguration  `qs:"-"` // for older docker X-Registry-Auth header
+	AuthConfigs       AuthConfigurations `qs:"-"` // for newer docker X-Registry-Config header
+	ContextDir        string             `qs:"-"`
+	Ulimits           []ULimit           `qs:"-" ver:"1.18"`
+	BuildArgs         []BuildArg         `qs:"-" ver:"1.21"`
+	NetworkMode       string             `ver:"1.25"`
+	Platform          string             `ver:"1.32"`
+	InactivityTimeout time.Duration      `qs:"-"`
+	CgroupParent      string
+	SecurityOpt       []string
+	Target            string
+	Outputs           string `ver:"1.40"`
+
+	// Version selects the builder backend. BuilderBuildKit is required by
+	// Dockerfiles using features like RUN --mount, and by Secrets and
+	// SSHAgents. It's the equivalent of setting DOCKER_BUILDKIT=1 for the
+	// docker CLI, which the client doesn't read: builds use the daemon's
+	// default builder unless Version is set.
+	Version BuilderVersion `ver:"1.38"`
+
+	// SessionID is the ID of a BuildKit session, attached to the daemon
+	// with DialSession, that provides build secrets, SSH forwarding and
+	// other client side features to the build. See DialSession for an
+	// example.
+	SessionID string `qs:"session" ver:"1.39"`
+
+	// Secrets are build secrets, by ID, that the Dockerfile mounts with
+	// RUN --mount=type=secret,id=<ID>. They require Version to be
+	// BuilderBuildKit, and can't be used along with SessionID: the client
+	// attaches its own session to provide them. The daemon fetches them
+	// through the session only while the instruction that mounts them runs,
+	// so they never end up in the image layers, the build cache or the
+	// build output.
+	Secrets map[string][]byte `qs:"-"`
+
+	// SSHAgents are the SSH agents the Dockerfile can use with
+	// RUN --mount=type=ssh[,id=<ID>], in the format of docker build --ssh:
+	// <ID>[=<socket>|<key>[,<key>]]. An entry without a path uses the agent
+	// at SSH_AUTH_SOCK, and the ID "default" is the one used by mounts
+	// without an id. Like Secrets, they require Version to be
+	// BuilderBuildKit and can't be used along with SessionID: the
+	// connections to the agents are forwarded through the session of the
+	// client, so the keys never leave the client. Paths that can't be read
+	// fail the call with ErrInvalidSSHAgent, while an agent that can't be
+	// reached during the build fails the forwarded connection, which the
+	// daemon reports as an error of the instruction that mounts it.
+	SSHAgents []string `qs:"-"`
+
 	NoCache             bool
 	SuppressOutput      bool `qs:"q"`
 	Pull                bool `ver:"1.16"`
@@ -510,6 +925,17 @@ type BuildImageOptions struct {
 	RawJSONStream       bool `qs:"-"`
 }
 
+// BuilderVersion is the version of the builder backend used by BuildImage.
+type BuilderVersion string
+
+const (
+	// BuilderV1 is the legacy builder.
+	BuilderV1 BuilderVersion = "1"
+
+	// BuilderBuildKit is the BuildKit builder.
+	BuilderBuildKit BuilderVersion = "2"
+)
+
 // BuildArg represents arguments that can be passed to the image when building
 // it from a Dockerfile.
 //
@@ -523,34 +949,164 @@ type BuildArg struct {
 // BuildImage builds an image from a tarball's url or a Dockerfile in the input
 // stream.
 //
+// The build can be aborted by cancelling opts.Context: the client closes the
+// connection, the daemon stops the build and removes its intermediate
+// containers, and BuildImage returns the error from the context.
+//
 // See https://goo.gl/4nYHwV for more details.
 func (c *Client) BuildImage(opts BuildImageOptions) error {
 	if opts.OutputStream == nil {
 		return ErrMissingOutputStream
 	}
-	headers, err := headersWithAuth(opts.Auth, c.versionedAuthConfigs(opts.AuthConfigs))
+	closeSession, err := c.startBuildSession(&opts)
+	if err != nil {
+		return err
+	}
+	defer closeSession()
+	buildURL, headers, err := c.prepareBuild(&opts)
 	if err != nil {
 		return err
 	}
+	return c.streamURL(http.MethodPost, buildURL, streamOptions{
+		setRawTerminal:    true,
+		rawJSONStream:     opts.RawJSONStream,
+		headers:           headers,
+		in:                opts.InputStream,
+		stdout:            opts.OutputStream,
+		inactivityTimeout: opts.InactivityTimeout,
+		context:           opts.Context,
+	})
+}
 
+// BuildMessage represents a single message in the JSON stream sent by the
+// daemon while building an image.
+type BuildMessage struct {
+	Stream         string               `json:"stream,omitempty" yaml:"stream,omitempty" toml:"stream,omitempty"`
+	Status         string               `json:"status,omitempty" yaml:"status,omitempty" toml:"status,omitempty"`
+	ID             string               `json:"id,omitempty" yaml:"id,omitempty" toml:"id,omitempty"`
+	Progress       string               `json:"progress,omitempty" yaml:"progress,omitempty" toml:"progress,omitempty"`
+	ProgressDetail *BuildProgressDetail `json:"progressDetail,omitempty" yaml:"progressDetail,omitempty" toml:"progressDetail,omitempty"`
+	Error          *BuildError          `json:"errorDetail,omitempty" yaml:"errorDetail,omitempty" toml:"errorDetail,omitempty"`
+	ErrorMessage   string               `json:"error,omitempty" yaml:"error,omitempty" toml:"error,omitempty"`
+	Aux            *BuildAux            `json:"aux,omitempty" yaml:"aux,omitempty" toml:"aux,omitempty"`
+}
+
+// BuildProgressDetail represents the progress of a single layer download or
+// extraction during a build.
+type BuildProgressDetail struct {
+	Current int64 `json:"current,omitempty" yaml:"current,omitempty" toml:"current,omitempty"`
+	Total   int64 `json:"total,omitempty" yaml:"total,omitempty" toml:"total,omitempty"`
+}
+
+// BuildError represents an error reported by the daemon in the middle of a
+// build.
+type BuildError struct {
+	Code    int    `json:"code,omitempty" yaml:"code,omitempty" toml:"code,omitempty"`
+	Message string `json:"message,omitempty" yaml:"message,omitempty" toml:"message,omitempty"`
+}
+
+func (e *BuildError) Error() string {
+	return e.Message
+}
+
+// BuildAux represents the auxiliary data sent by the daemon at the end of a
+// build, containing the ID of the built image.
+type BuildAux struct {
+	ID string `json:"ID,omitempty" yaml:"ID,omitempty" toml:"ID,omitempty"`
+}
+
+// BuildImageStream is like BuildImage, but instead of writing the output of
+// the build to OutputStream, it decodes each message sent by the daemon and
+// sends it to the returned channel. Errors reported by the daemon during the
+// build are sent as messages with the Error field set.
+//
+// The messages channel is closed when the build finishes, after which the
+// error channel receives nil or the error that interrupted the stream.
+// The caller must drain the messages channel, otherwise the build will
+// block. The OutputStream and RawJSONStream fields in opts are ignored.
+//
+// See https://goo.gl/4nYHwV for more details.
+func (c *Client) BuildImageStream(opts BuildImageOptions) (<-chan *BuildMessage, <-chan error) {
+	messages := make(chan *BuildMessage)
+	errC := make(chan error, 1)
+	go func() {
+		defer close(errC)
+		defer close(messages)
+		closeSession, err := c.startBuildSession(&opts)
+		if err != nil {
+			errC <- err
+			return
+		}
+		defer closeSession()
+		buildURL, headers, err := c.prepareBuild(&opts)
+		if err != nil {
+			errC <- err
+			return
+		}
+		readCloser, writeCloser := io.Pipe()
+		streamErrC := make(chan error, 1)
+		go func() {
+			err := c.streamURL(http.MethodPost, buildURL, streamOptions{
+				rawJSONStream:     true,
+				useJSONDecoder:    true,
+				headers:           headers,
+				in:                opts.InputStream,
+				stdout:            writeCloser,
+				inactivityTimeout: opts.InactivityTimeout,
+				context:           opts.Context,
+			})
+			writeCloser.CloseWithError(err)
+			streamErrC <- err
+		}()
+		decoder := json.NewDecoder(readCloser)
+		for {
+			var msg BuildMessage
+			if err = decoder.Decode(&msg); err != nil {
+				break
+			}
+			if msg.Error == nil && msg.ErrorMessage != "" {
+				msg.Error = &BuildError{Message: msg.ErrorMessage}
+			}
+			messages <- &msg
+		}
+		readCloser.Close()
+		streamErr := <-streamErrC
+		if !errors.Is(err, io.EOF) {
+			errC <- err
+		} else {
+			errC <- streamErr
+		}
+	}()
+	return messages, errC
+}
+
+func (c *Client) prepareBuild(opts *BuildImageOptions) (string, map[string]string, error) {
+	headers, err := headersWithAuth(opts.Auth, c.versionedAuthConfigs(opts.AuthConfigs))
+	if err != nil {
+		return "", nil, err
+	}
+
+	if opts.Remote != "" && (opts.InputStream != nil || opts.ContextDir != "") {
+		return "", nil, ErrRemoteWithLocalContext
+	}
 	if opts.Remote != "" && opts.Name == "" {
 		opts.Name = opts.Remote
 	}
 	if opts.InputStream != nil || opts.ContextDir != "" {
 		headers["Content-Type"] = "application/tar"
 	} else if opts.Remote == "" {
-		return ErrMissingRepo
+		return "", nil, ErrMissingRepo
 	}
 	if opts.ContextDir != "" {
 		if opts.InputStream != nil {
-			return ErrMultipleContexts
+			return "", nil, ErrMultipleContexts
 		}
 		var err error
 		if opts.InputStream, err = createTarStream(opts.ContextDir, opts.Dockerfile); err != nil {
-			return err
+			return "", nil, err
 		}
 	}
-	qs, ver := queryStringVersion(&opts)
+	qs, ver := queryStringVersion(opts)
 
 	if len(opts.CacheFrom) > 0 {
 		if b, err := json.Marshal(opts.CacheFrom); err == nil {
@@ -591,18 +1147,9 @@ func (c *Client) BuildImage(opts BuildImageOptions) error {
 
 	buildURL, err := c.pathVersionCheck("/build", qs, ver)
 	if err != nil {
-		return err
+		return "", nil, err
 	}
-
-	return c.streamURL(http.MethodPost, buildURL, streamOptions{
-		setRawTerminal:    true,
-		rawJSONStream:     opts.RawJSONStream,
-		headers:           headers,
-		in:                opts.InputStream,
-		stdout:            opts.OutputStream,
-		inactivityTimeout: opts.InactivityTimeout,
-		context:           opts.Context,
-	})
+	return buildURL, headers, nil
 }
 
 func (c *Client) versionedAuthConfigs(authConfigs AuthConfigurations) registryAuth {
@@ -617,6 +1164,10 @@ func (c *Client) versionedAuthConfigs(authConfigs AuthConfigurations) registryAu
 
 // TagImageOptions present the set of options to tag an image.
 //
+// Repo may include the tag ("repo:tag") when Tag is empty, but not a digest,
+// as tags can't be created with digest references. Recent versions of the
+// daemon always overwrite an existing tag, so Force only matters for old ones.
+//
 // See https://goo.gl/prHrvo for more details.
 type TagImageOptions struct {
 	Repo    string
@@ -625,27 +1176,60 @@ type TagImageOptions struct {
 	Context context.Context
 }
 
-// TagImage adds a tag to the image identified by the given name.
+var (
+	tagRegexp    = regexp.MustCompile(`^[\w][\w.-]{0,127}$`)
+	digestRegexp = regexp.MustCompile(`^[a-z0-9]+(?:[.+_-][a-z0-9]+)*:[a-zA-Z0-9=_-]{32,}$`)
+)
+
+// TagImage adds a tag to the image identified by the given name, which may be
+// an ID, a repository and tag or a digest reference (repo@sha256:...), so an
+// image pulled by digest can be tagged.
 //
 // See https://goo.gl/prHrvo for more details.
 func (c *Client) TagImage(name string, opts TagImageOptions) error {
 	if name == "" {
 		return ErrNoSuchImage
 	}
+	if err := validateTagImage(name, &opts); err != nil {
+		return err
+	}
 	resp, err := c.do(http.MethodPost, "/images/"+name+"/tag?"+queryString(&opts), doOptions{
 		context: opts.Context,
 	})
 	if err != nil {
+		var e *Error
+		if errors.As(err, &e) && e.Status == http.StatusNotFound {
+			return ErrNoSuchImage
+		}
 		return err
 	}
+	resp.Body.Close()
+	return nil
+}
 
-	defer resp.Body.Close()
-
-	if resp.StatusCode == http.StatusNotFound {
-		return ErrNoSuchImage
+// validateTagImage checks the references used by TagImage, moving the tag out
+// of opts.Repo when opts.Tag is empty.
+func validateTagImage(name string, opts *TagImageOptions) error {
+	if i := strings.Index(name, "@"); i >= 0 && !digestRegexp.MatchString(name[i+1:]) {
+		return fmt.Errorf("%w: invalid digest in %q", ErrInvalidReference, name)
 	}
-
-	return err
+	if strings.Contains(opts.Repo, "@") {
+		return fmt.Errorf("%w: refusing to create a tag with a digest reference: %q", ErrInvalidReference, opts.Repo)
+	}
+	repo, tag := ParseRepositoryTag(opts.Repo)
+	if tag != "" {
+		if opts.Tag != "" {
+			return fmt.Errorf("%w: the repository %q already includes a tag", ErrInvalidReference, opts.Repo)
+		}
+		opts.Repo, opts.Tag = repo, tag
+	}
+	if opts.Repo == "" {
+		return ErrMissingRepo
+	}
+	if opts.Tag != "" && !tagRegexp.MatchString(opts.Tag) {
+		return fmt.Errorf("%w: invalid tag %q", ErrInvalidReference, opts.Tag)
+	}
+	return nil
 }
 
 func isURL(u string) bool {
@@ -684,33 +1268,54 @@ type APIImageSearch struct {
 	StarCount   int    `json:"star_count,omitempty" yaml:"star_count,omitempty" toml:"star_count,omitempty"`
 }
 
+// SearchImagesOptions specify parameters to the SearchImagesWithOptions
+// function.
+//
+// See https://goo.gl/KLO9IZ for more details.
+type SearchImagesOptions struct {
+	Term string
+
+	// Limit is the maximum number of results to return. When zero, the
+	// daemon default of 25 is used.
+	Limit int
+
+	// Filters to apply to the search. Supported filters are is-official,
+	// is-automated and stars, for example:
+	//
+	//     map[string][]string{"is-official": {"true"}, "stars": {"3"}}
+	Filters map[string][]string
+
+	Auth    AuthConfiguration `qs:"-"`
+	Context context.Context
+}
+
 // SearchImages search the docker hub with a specific given term.
 //
 // See https://goo.gl/KLO9IZ for more details.
 func (c *Client) SearchImages(term string) ([]APIImageSearch, error) {
-	resp, err := c.do(http.MethodGet, "/images/search?term="+term, doOptions{})
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-	var searchResult []APIImageSearch
-	if err := json.NewDecoder(resp.Body).Decode(&searchResult); err != nil {
-		return nil, err
-	}
-	return searchResult, nil
+	return c.SearchImagesWithOptions(SearchImagesOptions{Term: term})
 }
 
 // SearchImagesEx search the docker hub with a specific given term and authentication.
 //
 // See https://goo.gl/KLO9IZ for more details.
 func (c *Client) SearchImagesEx(term string, auth AuthConfiguration) ([]APIImageSearch, error) {
-	headers, err := headersWithAuth(auth)
+	return c.SearchImagesWithOptions(SearchImagesOptions{Term: term, Auth: auth})
+}
+
+// SearchImagesWithOptions search the docker hub using the given options,
+// allowing callers to limit the number of results and filter them.
+//
+// See https://goo.gl/KLO9IZ for more details.
+func (c *Client) SearchImagesWithOptions(opts SearchImagesOptions) ([]APIImageSearch, error) {
+	headers, err := headersWithAuth(opts.Auth)
 	if err != nil {
 		return nil, err
 	}
 
-	resp, err := c.do(http.MethodGet, "/images/search?term="+term, doOptions{
+	resp, err := c.do(http.MethodGet, "/images/search?"+queryString(opts), doOptions{
 		headers: headers,
+		context: opts.Context,
 	})
 	if err != nil {
 		return nil, err
@@ -728,12 +1333,54 @@ func (c *Client) SearchImagesEx(term string, auth AuthConfiguration) ([]APIImage
 
 // PruneImagesOptions specify parameters to the PruneImages function.
 //
+// By default, the daemon only removes dangling images: images without tags
+// that aren't used by any container. All makes it remove every image that
+// isn't used by a container, including tagged ones, like docker image prune
+// --all does.
+//
+// Until and Labels restrict the images that are removed. They're combined
+// with Filters, which holds the filters in the format of the API.
+//
 // See https://goo.gl/qfZlbZ for more details.
 type PruneImagesOptions struct {
 	Filters map[string][]string
+
+	// All removes all unused images instead of only the dangling ones, by
+	// setting the dangling filter to false.
+	All bool `qs:"-"`
+
+	// Until only removes images created before the given time.
+	Until time.Time `qs:"-"`
+
+	// Labels only removes images with all the given labels, in the key or
+	// key=value format. Selectors starting with ! (like "!keep" or
+	// "!env=prod") only remove images without the label.
+	Labels []string `qs:"-"`
+
 	Context context.Context
 }
 
+func (opts PruneImagesOptions) filters() map[string][]string {
+	filters := make(map[string][]string, len(opts.Filters))
+	for key, values := range opts.Filters {
+		filters[key] = append([]string(nil), values...)
+	}
+	if opts.All {
+		filters["dangling"] = []string{"false"}
+	}
+	if !opts.Until.IsZero() {
+		filters["until"] = []string{formatLogsTimestamp(opts.Until)}
+	}
+	for _, label := range opts.Labels {
+		if strings.HasPrefix(label, "!") {
+			filters["label!"] = append(filters["label!"], label[1:])
+		} else {
+			filters["label"] = append(filters["label"], label)
+		}
+	}
+	return filters
+}
+
 // PruneImagesResults specify results from the PruneImages function.
 //
 // See https://goo.gl/qfZlbZ for more details.
@@ -746,6 +1393,7 @@ type PruneImagesResults struct {
 //
 // See https://goo.gl/qfZlbZ for more details.
 func (c *Client) PruneImages(opts PruneImagesOptions) (*PruneImagesResults, error) {
+	opts.Filters = opts.filters()
 	path := "/images/prune?" + queryString(opts)
 	resp, err := c.do(http.MethodPost, path, doOptions{context: opts.Context})
 	if err != nil {
@@ -758,3 +1406,43 @@ func (c *Client) PruneImages(opts PruneImagesOptions) (*PruneImagesResults, erro
 	}
 	return &results, nil
 }
+
+// PruneBuildCacheOptions specify parameters to the PruneBuildCache function.
+//
+// KeepStorage, All and the "until" filter require Docker API 1.39 or greater.
+//
+// See https://docs.docker.com/engine/api/v1.41/#operation/BuildPrune for more
+// details.
+type PruneBuildCacheOptions struct {
+	Filters     map[string][]string
+	KeepStorage int64 `qs:"keep-storage"`
+	All         bool
+	Context     context.Context
+}
+
+// PruneBuildCacheResults specify results from the PruneBuildCache function.
+//
+// See https://docs.docker.com/engine/api/v1.41/#operation/BuildPrune for more
+// details.
+type PruneBuildCacheResults struct {
+	CachesDeleted  []string
+	SpaceReclaimed int64
+}
+
+// PruneBuildCache deletes the builder cache.
+//
+// See https://docs.docker.com/engine/api/v1.41/#operation/BuildPrune for more
+// details.
+func (c *Client) PruneBuildCache(opts PruneBuildCacheOptions) (*PruneBuildCacheResults, error) {
+	path := "/build/prune?" + queryString(opts)
+	resp, err := c.do(http.MethodPost, path, doOptions{context: opts.Context})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	var results PruneBuildCacheResults
+	if err := json.NewDecoder(resp.Body).Decode(&results); err != nil {
+		return nil, err
+	}
+	return &results, nil
+}
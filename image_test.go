@@ -5,15 +5,20 @@
 package docker
 
 import (
+	"archive/tar"
 	"bytes"
+	"compress/gzip"
 	"encoding/base64"
 	"encoding/json"
 	"errors"
+	"io"
 	"io/ioutil"
 	"net"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"os"
+	"path/filepath"
 	"reflect"
 	"strings"
 	"testing"
@@ -104,6 +109,27 @@ func TestListImages(t *testing.T) {
 	}
 }
 
+func TestListImagesFilters(t *testing.T) {
+	t.Parallel()
+	fakeRT := &FakeRoundTripper{message: "[]", status: http.StatusOK}
+	client := newTestClient(fakeRT)
+	_, err := client.ListImages(ListImagesOptions{
+		Filters: map[string][]string{"reference": {"nginx:1.*"}, "since": {"b750fe79269d"}},
+		Digests: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := fakeRT.requests[0]
+	expected := map[string][]string{
+		"filters": {`{"reference":["nginx:1.*"],"since":["b750fe79269d"]}`},
+		"digests": {"1"},
+	}
+	if query := map[string][]string(req.URL.Query()); !reflect.DeepEqual(query, expected) {
+		t.Errorf("ListImages: wrong query string. Want %#v. Got %#v.", expected, query)
+	}
+}
+
 func TestListImagesParameters(t *testing.T) {
 	t.Parallel()
 	fakeRT := &FakeRoundTripper{message: "null", status: http.StatusOK}
@@ -190,6 +216,50 @@ func TestImageHistory(t *testing.T) {
 	}
 }
 
+func TestImageHistoryDetails(t *testing.T) {
+	t.Parallel()
+	createdBy := "/bin/sh -c apt-get update && apt-get install -y --no-install-recommends ca-certificates curl gnupg netbase wget && rm -rf /var/lib/apt/lists/*"
+	body := `[
+	{
+		"Id": "<missing>",
+		"Created": 1409856216,
+		"CreatedBy": "/bin/sh -c #(nop) ENV PATH=/usr/local/bin:/usr/bin",
+		"Size": 0,
+		"Comment": "buildkit.dockerfile.v0",
+		"EmptyLayer": true
+	},
+	{
+		"Id": "<missing>",
+		"Created": 1409856213,
+		"CreatedBy": "` + createdBy + `",
+		"Size": 85178663
+	}
+]`
+	client := newTestClient(&FakeRoundTripper{message: body, status: http.StatusOK})
+	history, err := client.ImageHistory("debian:latest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []ImageHistory{
+		{
+			ID:         "<missing>",
+			Created:    1409856216,
+			CreatedBy:  "/bin/sh -c #(nop) ENV PATH=/usr/local/bin:/usr/bin",
+			Comment:    "buildkit.dockerfile.v0",
+			EmptyLayer: true,
+		},
+		{
+			ID:        "<missing>",
+			Created:   1409856213,
+			CreatedBy: createdBy,
+			Size:      85178663,
+		},
+	}
+	if !reflect.DeepEqual(history, expected) {
+		t.Errorf("ImageHistory: Wrong return value. Want %#v. Got %#v.", expected, history)
+	}
+}
+
 func TestRemoveImage(t *testing.T) {
 	t.Parallel()
 	name := "test"
@@ -258,6 +328,9 @@ func TestInspectImage(t *testing.T) {
          "sha256:05a0deb2e405eb3095ab646dc1695a26bffe8bd4071e3af90efcf16e9d3f6d93",
          "sha256:4c5db681b9aa9ab1cf666ec969a810c8ff4410e70e06394670dc4f3bf595532f"
        ]
+    },
+    "Metadata": {
+      "LastTagTime": "2020-02-12T10:02:53.057886476Z"
     }
 }`
 
@@ -265,6 +338,10 @@ func TestInspectImage(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
+	lastTagTime, err := time.Parse(time.RFC3339Nano, "2020-02-12T10:02:53.057886476Z")
+	if err != nil {
+		t.Fatal(err)
+	}
 
 	expected := Image{
 		ID:        "b750fe79269d2ec9a3c593ef05b4332b1d1a02a62b4accb2c21d589ff2f5f2dc",
@@ -282,6 +359,7 @@ func TestInspectImage(t *testing.T) {
 				"sha256:4c5db681b9aa9ab1cf666ec969a810c8ff4410e70e06394670dc4f3bf595532f",
 			},
 		},
+		Metadata: ImageMetadata{LastTagTime: lastTagTime},
 	}
 	fakeRT := &FakeRoundTripper{message: body, status: http.StatusOK}
 	client := newTestClient(fakeRT)
@@ -302,6 +380,27 @@ func TestInspectImage(t *testing.T) {
 	}
 }
 
+func TestInspectImageWithRaw(t *testing.T) {
+	t.Parallel()
+	body := `{"Id":"25daec02219d2d852f7526137213a9b199926b4b24e732eab5b8bc6c49bd470e","Os":"linux","UnmodeledField":[1,2,3]}`
+	fakeRT := &FakeRoundTripper{message: body, status: http.StatusOK}
+	client := newTestClient(fakeRT)
+	image, raw, err := client.InspectImageWithRaw("busybox")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if image.ID != "25daec02219d2d852f7526137213a9b199926b4b24e732eab5b8bc6c49bd470e" || image.OS != "linux" {
+		t.Errorf("InspectImageWithRaw: wrong image: %#v", image)
+	}
+	if string(raw) != body {
+		t.Errorf("InspectImageWithRaw: wrong raw JSON. Want %s. Got %s.", body, raw)
+	}
+	expectedURL, _ := url.Parse(client.getURL("/images/busybox/json"))
+	if gotPath := fakeRT.requests[0].URL.Path; gotPath != expectedURL.Path {
+		t.Errorf("InspectImageWithRaw: Wrong path in request. Want %q. Got %q.", expectedURL.Path, gotPath)
+	}
+}
+
 func TestInspectImageNotFound(t *testing.T) {
 	t.Parallel()
 	client := newTestClient(&FakeRoundTripper{message: "no such image", status: http.StatusNotFound})
@@ -315,6 +414,27 @@ func TestInspectImageNotFound(t *testing.T) {
 	}
 }
 
+func TestPushImageWithHeaders(t *testing.T) {
+	t.Parallel()
+	fakeRT := &FakeRoundTripper{
+		message: "Pushing 1/100",
+		status:  http.StatusOK,
+		header:  map[string]string{"Docker-Content-Digest": "sha256:abc123"},
+	}
+	client := newTestClient(fakeRT)
+	var buf bytes.Buffer
+	headers, err := client.PushImageWithHeaders(PushImageOptions{Name: "test", OutputStream: &buf}, AuthConfiguration{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := headers.Get("Docker-Content-Digest"); got != "sha256:abc123" {
+		t.Errorf("PushImageWithHeaders: wrong digest header. Want %q. Got %q.", "sha256:abc123", got)
+	}
+	if buf.String() != "Pushing 1/100" {
+		t.Errorf("PushImageWithHeaders: Wrong output. Want %q. Got %q.", "Pushing 1/100", buf.String())
+	}
+}
+
 func TestPushImage(t *testing.T) {
 	t.Parallel()
 	fakeRT := &FakeRoundTripper{message: "Pushing 1/100", status: http.StatusOK}
@@ -376,6 +496,106 @@ func TestPushImageWithRawJSON(t *testing.T) {
 	}
 }
 
+func TestPushImageWithDigest(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name string
+		body string
+	}{
+		{
+			name: "classic",
+			body: `{"status":"The push refers to repository [registry.example.com/app]"}
+{"status":"v1: digest: sha256:8f8ab5bd9d3e size: 528"}
+{"progressDetail":{},"aux":{"Tag":"v1","Digest":"sha256:8f8ab5bd9d3e","Size":528}}
+`,
+		},
+		{
+			name: "buildkit",
+			body: `{"id":"moby.buildkit.trace","aux":"Cm0KR3NoYTI1Njo="}
+{"status":"The push refers to repository [registry.example.com/app]"}
+{"id":"moby.buildkit.trace","aux":"Cm0KR3NoYTI1Njo="}
+{"status":"v1: digest: sha256:8f8ab5bd9d3e size: 528"}
+{"progressDetail":{},"aux":{"Tag":"v1","Digest":"sha256:8f8ab5bd9d3e","Size":528}}
+`,
+		},
+	}
+	for _, tt := range tests {
+		for _, raw := range []bool{false, true} {
+			fakeRT := &FakeRoundTripper{message: tt.body, status: http.StatusOK, header: map[string]string{"Content-Type": "application/json"}}
+			client := newTestClient(fakeRT)
+			var buf bytes.Buffer
+			opts := PushImageOptions{Name: "registry.example.com/app", Tag: "v1", OutputStream: &buf, RawJSONStream: raw}
+			digest, err := client.PushImageWithDigest(opts, AuthConfiguration{})
+			if err != nil {
+				t.Fatalf("PushImageWithDigest (%s, raw: %v): %v", tt.name, raw, err)
+			}
+			if digest != "sha256:8f8ab5bd9d3e" {
+				t.Errorf("PushImageWithDigest (%s, raw: %v): wrong digest. Want %q. Got %q.", tt.name, raw, "sha256:8f8ab5bd9d3e", digest)
+			}
+			if raw && buf.String() != tt.body {
+				t.Errorf("PushImageWithDigest (%s): wrong raw output. Want %q. Got %q.", tt.name, tt.body, buf.String())
+			}
+			if !raw && !strings.Contains(buf.String(), "v1: digest: sha256:8f8ab5bd9d3e size: 528") {
+				t.Errorf("PushImageWithDigest (%s): wrong output: %q", tt.name, buf.String())
+			}
+		}
+	}
+}
+
+func TestPushImageWithDigestNoAux(t *testing.T) {
+	t.Parallel()
+	body := `{"status":"Pushing..."}
+{"status":"Image successfully pushed"}
+`
+	fakeRT := &FakeRoundTripper{message: body, status: http.StatusOK, header: map[string]string{"Content-Type": "application/json"}}
+	client := newTestClient(fakeRT)
+	digest, err := client.PushImageWithDigest(PushImageOptions{Name: "test"}, AuthConfiguration{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if digest != "" {
+		t.Errorf("PushImageWithDigest: wrong digest. Want empty. Got %q.", digest)
+	}
+}
+
+func TestResolveImageDigest(t *testing.T) {
+	t.Parallel()
+	body := `{
+     "Id":"b750fe79269d2ec9a3c593ef05b4332b1d1a02a62b4accb2c21d589ff2f5f2dc",
+     "RepoDigests":["busybox@sha256:aaaa","registry.example.com/app@sha256:bbbb"]
+}`
+	client := newTestClient(&FakeRoundTripper{message: body, status: http.StatusOK})
+	for name, expected := range map[string]string{
+		"registry.example.com/app:v1": "registry.example.com/app@sha256:bbbb",
+		"busybox":                     "busybox@sha256:aaaa",
+	} {
+		digest, err := client.ResolveImageDigest(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if digest != expected {
+			t.Errorf("ResolveImageDigest(%q): wrong digest. Want %q. Got %q.", name, expected, digest)
+		}
+	}
+	if _, err := client.ResolveImageDigest("localhost:5000/app:v1"); !errors.Is(err, ErrNoImageDigest) {
+		t.Errorf("ResolveImageDigest: wrong error. Want %v. Got %v.", ErrNoImageDigest, err)
+	}
+}
+
+func TestInspectImageByDigestInvalid(t *testing.T) {
+	t.Parallel()
+	fakeRT := &FakeRoundTripper{message: "{}", status: http.StatusOK}
+	client := newTestClient(fakeRT)
+	for _, digest := range []string{"busybox:latest", "busybox@latest", "sha256:abc"} {
+		if _, err := client.InspectImageByDigest(digest); !errors.Is(err, ErrInvalidReference) {
+			t.Errorf("InspectImageByDigest(%q): wrong error. Want %v. Got %v.", digest, ErrInvalidReference, err)
+		}
+	}
+	if len(fakeRT.requests) != 0 {
+		t.Errorf("InspectImageByDigest: unexpected requests for invalid digests: %d", len(fakeRT.requests))
+	}
+}
+
 func TestPushImageWithAuthentication(t *testing.T) {
 	t.Parallel()
 	fakeRT := &FakeRoundTripper{message: "Pushing 1/100", status: http.StatusOK}
@@ -465,6 +685,44 @@ func TestPullImage(t *testing.T) {
 	}
 }
 
+func TestPullImageWithHeaders(t *testing.T) {
+	t.Parallel()
+	fakeRT := &FakeRoundTripper{
+		message: "Pulling 1/100",
+		status:  http.StatusOK,
+		header:  map[string]string{"Docker-Content-Digest": "sha256:abc123", "Ratelimit-Remaining": "99"},
+	}
+	client := newTestClient(fakeRT)
+	var buf bytes.Buffer
+	headers, err := client.PullImageWithHeaders(PullImageOptions{Repository: "base", OutputStream: &buf}, AuthConfiguration{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := headers.Get("Docker-Content-Digest"); got != "sha256:abc123" {
+		t.Errorf("PullImageWithHeaders: wrong digest header. Want %q. Got %q.", "sha256:abc123", got)
+	}
+	if got := headers.Get("Ratelimit-Remaining"); got != "99" {
+		t.Errorf("PullImageWithHeaders: wrong rate limit header. Want %q. Got %q.", "99", got)
+	}
+}
+
+func TestPullImageWithHeadersError(t *testing.T) {
+	t.Parallel()
+	fakeRT := &FakeRoundTripper{
+		message: "too many requests",
+		status:  http.StatusTooManyRequests,
+		header:  map[string]string{"Ratelimit-Remaining": "0"},
+	}
+	client := newTestClient(fakeRT)
+	headers, err := client.PullImageWithHeaders(PullImageOptions{Repository: "base"}, AuthConfiguration{})
+	if err == nil {
+		t.Fatal("PullImageWithHeaders: expected error, got <nil>")
+	}
+	if got := headers.Get("Ratelimit-Remaining"); got != "0" {
+		t.Errorf("PullImageWithHeaders: wrong rate limit header. Want %q. Got %q.", "0", got)
+	}
+}
+
 func TestPullImageWithDigest(t *testing.T) {
 	t.Parallel()
 	fakeRT := &FakeRoundTripper{message: "Pulling 1/100", status: http.StatusOK}
@@ -583,6 +841,62 @@ func TestPullImageWithoutOutputStream(t *testing.T) {
 	}
 }
 
+func TestPullImagePlatform(t *testing.T) {
+	t.Parallel()
+	fakeRT := &FakeRoundTripper{message: "Pulling 1/100", status: http.StatusOK}
+	client := newTestClient(fakeRT)
+	opts := PullImageOptions{
+		Repository: "base",
+		Platform:   "linux/amd64",
+	}
+	err := client.PullImage(opts, AuthConfiguration{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := fakeRT.requests[0]
+	expected := map[string][]string{"fromImage": {"base"}, "platform": {"linux/amd64"}}
+	got := map[string][]string(req.URL.Query())
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("PullImage: wrong query string. Want %#v. Got %#v.", expected, got)
+	}
+	if !strings.HasPrefix(req.URL.Path, "/v1.32/") {
+		t.Errorf("PullImage: expected the request to use API 1.32. Got path %q.", req.URL.Path)
+	}
+}
+
+func TestPullImageAllTags(t *testing.T) {
+	t.Parallel()
+	fakeRT := &FakeRoundTripper{message: "Pulling 1/100", status: http.StatusOK}
+	client := newTestClient(fakeRT)
+	err := client.PullImage(PullImageOptions{Repository: "base", All: true}, AuthConfiguration{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string][]string{"fromImage": {"base"}}
+	got := map[string][]string(fakeRT.requests[0].URL.Query())
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("PullImage: wrong query string. Want %#v. Got %#v.", expected, got)
+	}
+}
+
+func TestPullImageAllTagsWithTag(t *testing.T) {
+	t.Parallel()
+	fakeRT := &FakeRoundTripper{message: "Pulling 1/100", status: http.StatusOK}
+	client := newTestClient(fakeRT)
+	for _, opts := range []PullImageOptions{
+		{Repository: "base", Tag: "latest", All: true},
+		{Repository: "localhost:5000/base:latest", All: true},
+		{Repository: "base@sha256:" + strings.Repeat("ab", 32), All: true},
+	} {
+		if err := client.PullImage(opts, AuthConfiguration{}); !errors.Is(err, ErrAllTagsWithTag) {
+			t.Errorf("PullImage(%#v): wrong error. Want %v. Got %v.", opts, ErrAllTagsWithTag, err)
+		}
+	}
+	if len(fakeRT.requests) != 0 {
+		t.Errorf("PullImage: unexpected requests: %d", len(fakeRT.requests))
+	}
+}
+
 func TestPullImageCustomRegistry(t *testing.T) {
 	t.Parallel()
 	fakeRT := &FakeRoundTripper{message: "Pulling 1/100", status: http.StatusOK}
@@ -862,6 +1176,64 @@ func TestBuildImageParametersForRemoteBuild(t *testing.T) {
 	}
 }
 
+func TestBuildImageCacheFrom(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		cacheFrom []string
+		expected  []string
+	}{
+		{cacheFrom: []string{"registry.example.com/app:latest"}, expected: []string{`["registry.example.com/app:latest"]`}},
+		{cacheFrom: []string{"app:latest", "app:builder"}, expected: []string{`["app:latest","app:builder"]`}},
+		{cacheFrom: nil, expected: nil},
+	}
+	for _, tt := range tests {
+		fakeRT := &FakeRoundTripper{message: "", status: http.StatusOK}
+		client := newTestClient(fakeRT)
+		opts := BuildImageOptions{
+			Name:         "app",
+			Remote:       "https://github.com/fsouza/go-dockerclient.git",
+			CacheFrom:    tt.cacheFrom,
+			OutputStream: ioutil.Discard,
+		}
+		if err := client.BuildImage(opts); err != nil {
+			t.Fatal(err)
+		}
+		req := fakeRT.requests[0]
+		if got := req.URL.Query()["cachefrom"]; !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("BuildImage(CacheFrom=%v): wrong cachefrom. Want %#v. Got %#v.", tt.cacheFrom, tt.expected, got)
+		}
+		if len(tt.cacheFrom) > 0 && req.URL.Path != "/v1.25/build" {
+			t.Errorf("BuildImage(CacheFrom=%v): wrong path. Want %q. Got %q.", tt.cacheFrom, "/v1.25/build", req.URL.Path)
+		}
+	}
+}
+
+func TestBuildImageBuildKitSession(t *testing.T) {
+	t.Parallel()
+	fakeRT := &FakeRoundTripper{message: "", status: http.StatusOK}
+	client := newTestClient(fakeRT)
+	var buf bytes.Buffer
+	opts := BuildImageOptions{
+		Name:         "testImage",
+		Remote:       "testing/data/container.tar",
+		Version:      BuilderBuildKit,
+		SessionID:    "abc123",
+		OutputStream: &buf,
+	}
+	if err := client.BuildImage(opts); err != nil {
+		t.Fatal(err)
+	}
+	req := fakeRT.requests[0]
+	expected := map[string][]string{"t": {opts.Name}, "remote": {opts.Remote}, "version": {"2"}, "session": {"abc123"}}
+	got := map[string][]string(req.URL.Query())
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("BuildImage: wrong query string. Want %#v. Got %#v.", expected, got)
+	}
+	if !strings.HasPrefix(req.URL.Path, "/v1.39/") {
+		t.Errorf("BuildImage: expected the request to use API 1.39. Got path %q.", req.URL.Path)
+	}
+}
+
 func TestBuildImageMissingRepoAndNilInput(t *testing.T) {
 	t.Parallel()
 	fakeRT := &FakeRoundTripper{message: "", status: http.StatusOK}
@@ -928,6 +1300,101 @@ func TestBuildImageWithRawJSON(t *testing.T) {
 	}
 }
 
+func TestBuildImageStream(t *testing.T) {
+	t.Parallel()
+	body := `{"stream":"Step 1/2 : FROM ubuntu:latest\n"}
+{"stream":" ---\u003e 4300eb9d3c8d\n"}
+{"status":"Downloading","progressDetail":{"current":1024,"total":4096},"progress":"[=\u003e   ]","id":"4300eb9d3c8d"}
+{"stream":"Step 2/2 : CMD /usr/bin/top\n"}
+{"aux":{"ID":"sha256:4b6188aebe39"}}
+{"stream":"Successfully built 4b6188aebe39\n"}
+`
+	fakeRT := &FakeRoundTripper{
+		message: body,
+		status:  http.StatusOK,
+		header: map[string]string{
+			"Content-Type": "application/json",
+		},
+	}
+	client := newTestClient(fakeRT)
+	var buf bytes.Buffer
+	messages, errC := client.BuildImageStream(BuildImageOptions{
+		Name:        "testImage",
+		InputStream: &buf,
+	})
+	var got []*BuildMessage
+	for msg := range messages {
+		got = append(got, msg)
+	}
+	if err := <-errC; err != nil {
+		t.Fatal(err)
+	}
+	expected := []*BuildMessage{
+		{Stream: "Step 1/2 : FROM ubuntu:latest\n"},
+		{Stream: " ---> 4300eb9d3c8d\n"},
+		{Status: "Downloading", ProgressDetail: &BuildProgressDetail{Current: 1024, Total: 4096}, Progress: "[=>   ]", ID: "4300eb9d3c8d"},
+		{Stream: "Step 2/2 : CMD /usr/bin/top\n"},
+		{Aux: &BuildAux{ID: "sha256:4b6188aebe39"}},
+		{Stream: "Successfully built 4b6188aebe39\n"},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("BuildImageStream: wrong messages. Want %#v. Got %#v.", expected, got)
+	}
+	if req := fakeRT.requests[0]; req.URL.Path != "/build" {
+		t.Errorf("BuildImageStream: wrong path. Want %q. Got %q.", "/build", req.URL.Path)
+	}
+}
+
+func TestBuildImageStreamError(t *testing.T) {
+	t.Parallel()
+	body := `{"stream":"Step 1/2 : FROM ubuntu:latest\n"}
+{"errorDetail":{"code":1,"message":"The command '/bin/sh -c exit 1' returned a non-zero code: 1"},"error":"The command '/bin/sh -c exit 1' returned a non-zero code: 1"}
+{"error":"something else failed"}
+`
+	fakeRT := &FakeRoundTripper{
+		message: body,
+		status:  http.StatusOK,
+		header: map[string]string{
+			"Content-Type": "application/json",
+		},
+	}
+	client := newTestClient(fakeRT)
+	var buf bytes.Buffer
+	messages, errC := client.BuildImageStream(BuildImageOptions{
+		Name:        "testImage",
+		InputStream: &buf,
+	})
+	var got []*BuildMessage
+	for msg := range messages {
+		got = append(got, msg)
+	}
+	if err := <-errC; err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 3 {
+		t.Fatalf("BuildImageStream: expected 3 messages. Got %d.", len(got))
+	}
+	expectedErr := &BuildError{Code: 1, Message: "The command '/bin/sh -c exit 1' returned a non-zero code: 1"}
+	if !reflect.DeepEqual(got[1].Error, expectedErr) {
+		t.Errorf("BuildImageStream: wrong error. Want %#v. Got %#v.", expectedErr, got[1].Error)
+	}
+	if got[2].Error == nil || got[2].Error.Error() != "something else failed" {
+		t.Errorf("BuildImageStream: wrong error. Want %q. Got %#v.", "something else failed", got[2].Error)
+	}
+}
+
+func TestBuildImageStreamMissingRepo(t *testing.T) {
+	t.Parallel()
+	client := newTestClient(&FakeRoundTripper{message: "", status: http.StatusOK})
+	messages, errC := client.BuildImageStream(BuildImageOptions{Name: "testImage"})
+	for range messages {
+		t.Error("BuildImageStream: unexpected message")
+	}
+	if err := <-errC; !errors.Is(err, ErrMissingRepo) {
+		t.Errorf("BuildImageStream: wrong error returned. Want %#v. Got %#v.", ErrMissingRepo, err)
+	}
+}
+
 func TestBuildImageRemoteWithoutName(t *testing.T) {
 	t.Parallel()
 	fakeRT := &FakeRoundTripper{message: "", status: http.StatusOK}
@@ -979,6 +1446,62 @@ func TestTagImageMissingRepo(t *testing.T) {
 	}
 }
 
+func TestTagImageDigestReference(t *testing.T) {
+	t.Parallel()
+	fakeRT := &FakeRoundTripper{message: "", status: http.StatusCreated}
+	client := newTestClient(fakeRT)
+	const name = "busybox@sha256:4a731fb46adc5cefe3ae374a8b6020fc1b6ad667a279647766e9a3cd89f6fa92"
+	err := client.TagImage(name, TagImageOptions{Repo: "localhost:5000/busybox:stable", Force: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := fakeRT.requests[0]
+	expectedPath := "/images/" + name + "/tag"
+	if req.URL.Path != expectedPath {
+		t.Errorf("TagImage: wrong path. Want %q. Got %q.", expectedPath, req.URL.Path)
+	}
+	expectedQuery := url.Values{"repo": {"localhost:5000/busybox"}, "tag": {"stable"}, "force": {"1"}}
+	if query := req.URL.Query(); !reflect.DeepEqual(query, expectedQuery) {
+		t.Errorf("TagImage: wrong query string. Want %#v. Got %#v.", expectedQuery, query)
+	}
+}
+
+func TestTagImageInvalidReference(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name     string
+		opts     TagImageOptions
+		expected error
+	}{
+		{"busybox", TagImageOptions{Repo: "busybox@sha256:4a731fb46adc5cefe3ae374a8b6020fc1b6ad667a279647766e9a3cd89f6fa92"}, ErrInvalidReference},
+		{"busybox", TagImageOptions{Repo: "busybox:v1", Tag: "v2"}, ErrInvalidReference},
+		{"busybox", TagImageOptions{Repo: "busybox", Tag: "-invalid"}, ErrInvalidReference},
+		{"busybox", TagImageOptions{Repo: "busybox", Tag: strings.Repeat("a", 129)}, ErrInvalidReference},
+		{"busybox@sha256:abc", TagImageOptions{Repo: "busybox"}, ErrInvalidReference},
+		{"busybox", TagImageOptions{Tag: "latest"}, ErrMissingRepo},
+	}
+	for _, tt := range tests {
+		fakeRT := &FakeRoundTripper{message: "", status: http.StatusCreated}
+		client := newTestClient(fakeRT)
+		err := client.TagImage(tt.name, tt.opts)
+		if !errors.Is(err, tt.expected) {
+			t.Errorf("TagImage(%q, %#v): wrong error. Want %v. Got %v.", tt.name, tt.opts, tt.expected, err)
+		}
+		if len(fakeRT.requests) != 0 {
+			t.Errorf("TagImage(%q, %#v): unexpected request", tt.name, tt.opts)
+		}
+	}
+}
+
+func TestTagImageNotFound(t *testing.T) {
+	t.Parallel()
+	client := newTestClient(&FakeRoundTripper{message: "no such image", status: http.StatusNotFound})
+	err := client.TagImage("busybox", TagImageOptions{Repo: "other"})
+	if !errors.Is(err, ErrNoSuchImage) {
+		t.Errorf("TagImage: wrong error. Want %#v. Got %#v.", ErrNoSuchImage, err)
+	}
+}
+
 func TestIsUrl(t *testing.T) {
 	t.Parallel()
 	url := "http://foo.bar/"
@@ -1017,6 +1540,164 @@ func TestLoadImage(t *testing.T) {
 	}
 }
 
+func writeImageArchive(t *testing.T, dir, name string, compress bool, files ...string) string {
+	var buf bytes.Buffer
+	var w io.Writer = &buf
+	var gz *gzip.Writer
+	if compress {
+		gz = gzip.NewWriter(&buf)
+		w = gz
+	}
+	tw := tar.NewWriter(w)
+	for _, file := range files {
+		content := []byte("{}")
+		if err := tw.WriteHeader(&tar.Header{Name: file, Mode: 0o644, Size: int64(len(content))}); err != nil {
+			t.Fatal(err)
+		}
+		tw.Write(content)
+	}
+	tw.Close()
+	if gz != nil {
+		gz.Close()
+	}
+	archive := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(archive, buf.Bytes(), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return archive
+}
+
+func TestLoadImageFromFile(t *testing.T) {
+	t.Parallel()
+	tmpDir, err := ioutil.TempDir("", "go-dockerclient-load-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+	archives := []string{
+		writeImageArchive(t, tmpDir, "image.tar", false, "abc/layer.tar", "manifest.json"),
+		writeImageArchive(t, tmpDir, "image.tar.gz", true, "./abc/layer.tar", "./manifest.json"),
+		writeImageArchive(t, tmpDir, "legacy.tar", false, "abc/layer.tar", "repositories"),
+	}
+	var body []byte
+	var path string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		body, _ = ioutil.ReadAll(r.Body)
+	}))
+	defer server.Close()
+	client, _ := NewClient(server.URL)
+	client.SkipServerVersionCheck = true
+	for _, archive := range archives {
+		if err := client.LoadImageFromFile(archive, LoadImageOptions{}); err != nil {
+			t.Fatalf("LoadImageFromFile(%q): %v", archive, err)
+		}
+		content, _ := ioutil.ReadFile(archive)
+		if !bytes.Equal(body, content) {
+			t.Errorf("LoadImageFromFile(%q): the request body isn't the content of the file", archive)
+		}
+		if path != "/images/load" {
+			t.Errorf("LoadImageFromFile(%q): wrong URL. Want %q. Got %q.", archive, "/images/load", path)
+		}
+	}
+}
+
+func TestLoadImageFromFileInvalid(t *testing.T) {
+	t.Parallel()
+	tmpDir, err := ioutil.TempDir("", "go-dockerclient-load-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+	notTar := filepath.Join(tmpDir, "image.txt")
+	if err := ioutil.WriteFile(notTar, []byte(strings.Repeat("not an archive\n", 100)), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	empty := filepath.Join(tmpDir, "empty.tar")
+	if err := ioutil.WriteFile(empty, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	archives := []string{
+		"testing/data/container.tar",
+		writeImageArchive(t, tmpDir, "nested.tar.gz", true, "abc/manifest.json"),
+		notTar,
+		empty,
+	}
+	fakeRT := &FakeRoundTripper{message: "", status: http.StatusOK}
+	client := newTestClient(fakeRT)
+	for _, archive := range archives {
+		err := client.LoadImageFromFile(archive, LoadImageOptions{})
+		if !errors.Is(err, ErrInvalidImageArchive) {
+			t.Errorf("LoadImageFromFile(%q): wrong error. Want %#v. Got %#v.", archive, ErrInvalidImageArchive, err)
+		}
+	}
+	if err := client.LoadImageFromFile(filepath.Join(tmpDir, "missing.tar"), LoadImageOptions{}); !os.IsNotExist(err) {
+		t.Errorf("LoadImageFromFile: wrong error for a missing file: %v", err)
+	}
+	if len(fakeRT.requests) != 0 {
+		t.Errorf("LoadImageFromFile: sent %d requests for invalid archives", len(fakeRT.requests))
+	}
+}
+
+func TestImageLoadWithProgress(t *testing.T) {
+	t.Parallel()
+	fakeRT := &FakeRoundTripper{
+		message: `{"stream":"Loaded image: busybox:latest\n"}
+{"stream":"Loaded image: example.com/app:v1\n"}
+{"stream":"Loaded image ID: sha256:8f8ab5bd9d3e\n"}
+`,
+		status: http.StatusOK,
+		header: map[string]string{"Content-Type": "application/json"},
+	}
+	client := newTestClient(fakeRT)
+	var buf bytes.Buffer
+	images, err := client.ImageLoadWithProgress(LoadImageOptions{InputStream: strings.NewReader("tarball"), OutputStream: &buf})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"busybox:latest", "example.com/app:v1", "sha256:8f8ab5bd9d3e"}
+	if !reflect.DeepEqual(images, expected) {
+		t.Errorf("ImageLoadWithProgress: wrong images. Want %#v. Got %#v.", expected, images)
+	}
+	expectedOutput := "Loaded image: busybox:latest\nLoaded image: example.com/app:v1\nLoaded image ID: sha256:8f8ab5bd9d3e\n"
+	if buf.String() != expectedOutput {
+		t.Errorf("ImageLoadWithProgress: wrong output. Want %q. Got %q.", expectedOutput, buf.String())
+	}
+	req := fakeRT.requests[0]
+	if req.Method != http.MethodPost || req.URL.Path != "/images/load" {
+		t.Errorf("ImageLoadWithProgress: wrong request. Want POST /images/load. Got %s %s.", req.Method, req.URL.Path)
+	}
+}
+
+func TestImageLoadWithProgressError(t *testing.T) {
+	t.Parallel()
+	fakeRT := &FakeRoundTripper{
+		message: `{"stream":"Loaded image: busybox:latest\n"}
+{"errorDetail":{"message":"invalid tar header"},"error":"invalid tar header"}
+`,
+		status: http.StatusOK,
+		header: map[string]string{"Content-Type": "application/json"},
+	}
+	client := newTestClient(fakeRT)
+	images, err := client.ImageLoadWithProgress(LoadImageOptions{InputStream: strings.NewReader("tarball")})
+	if err == nil || err.Error() != "invalid tar header" {
+		t.Errorf("ImageLoadWithProgress: wrong error. Want %q. Got %v.", "invalid tar header", err)
+	}
+	if expected := []string{"busybox:latest"}; !reflect.DeepEqual(images, expected) {
+		t.Errorf("ImageLoadWithProgress: wrong images. Want %#v. Got %#v.", expected, images)
+	}
+}
+
+func TestImageLoadWithProgressFailure(t *testing.T) {
+	t.Parallel()
+	client := newTestClient(&FakeRoundTripper{message: "no space left on device", status: http.StatusInternalServerError})
+	_, err := client.ImageLoadWithProgress(LoadImageOptions{InputStream: strings.NewReader("tarball")})
+	var e *Error
+	if !errors.As(err, &e) || e.Status != http.StatusInternalServerError {
+		t.Errorf("ImageLoadWithProgress: wrong error. Want status 500. Got %#v.", err)
+	}
+}
+
 func TestExportImage(t *testing.T) {
 	t.Parallel()
 	var buf bytes.Buffer
@@ -1169,6 +1850,54 @@ func TestSearchImagesEx(t *testing.T) {
 	}
 }
 
+func TestSearchImagesWithOptions(t *testing.T) {
+	t.Parallel()
+	body := `[{"description":"Official build of Nginx.","is_official":true,"name":"nginx","star_count":14000}]`
+	var expected []APIImageSearch
+	if err := json.Unmarshal([]byte(body), &expected); err != nil {
+		t.Fatal(err)
+	}
+	fakeRT := &FakeRoundTripper{message: body, status: http.StatusOK}
+	client := newTestClient(fakeRT)
+	result, err := client.SearchImagesWithOptions(SearchImagesOptions{
+		Term:    "nginx",
+		Limit:   10,
+		Filters: map[string][]string{"is-official": {"true"}},
+		Auth:    AuthConfiguration{Username: "gopher"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("SearchImagesWithOptions: Wrong return value. Want %#v. Got %#v.", expected, result)
+	}
+	req := fakeRT.requests[0]
+	expectedQuery := map[string][]string{
+		"term":    {"nginx"},
+		"limit":   {"10"},
+		"filters": {`{"is-official":["true"]}`},
+	}
+	if query := map[string][]string(req.URL.Query()); !reflect.DeepEqual(query, expectedQuery) {
+		t.Errorf("SearchImagesWithOptions: wrong query string. Want %#v. Got %#v.", expectedQuery, query)
+	}
+	if req.Header.Get("X-Registry-Auth") == "" {
+		t.Error("SearchImagesWithOptions: missing X-Registry-Auth header")
+	}
+}
+
+func TestSearchImagesDefaultLimit(t *testing.T) {
+	t.Parallel()
+	fakeRT := &FakeRoundTripper{message: "[]", status: http.StatusOK}
+	client := newTestClient(fakeRT)
+	if _, err := client.SearchImages("nginx"); err != nil {
+		t.Fatal(err)
+	}
+	expectedQuery := map[string][]string{"term": {"nginx"}}
+	if query := map[string][]string(fakeRT.requests[0].URL.Query()); !reflect.DeepEqual(query, expectedQuery) {
+		t.Errorf("SearchImages: wrong query string. Want %#v. Got %#v.", expectedQuery, query)
+	}
+}
+
 func TestPruneImages(t *testing.T) {
 	t.Parallel()
 	results := `{
@@ -1194,3 +1923,74 @@ func TestPruneImages(t *testing.T) {
 		t.Errorf("PruneImages: Expected %#v. Got %#v.", expected, got)
 	}
 }
+
+func TestPruneImagesTypedFilters(t *testing.T) {
+	t.Parallel()
+	fakeRT := &FakeRoundTripper{message: `{}`, status: http.StatusOK}
+	client := newTestClient(fakeRT)
+	filters := map[string][]string{"label": {"team=core"}}
+	_, err := client.PruneImages(PruneImagesOptions{
+		Filters: filters,
+		All:     true,
+		Until:   time.Unix(1600000000, 500),
+		Labels:  []string{"stage", "!keep", "!env=prod"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got map[string][]string
+	if err := json.Unmarshal([]byte(fakeRT.requests[0].URL.Query().Get("filters")), &got); err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string][]string{
+		"dangling": {"false"},
+		"until":    {"1600000000.000000500"},
+		"label":    {"team=core", "stage"},
+		"label!":   {"keep", "env=prod"},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("PruneImages: wrong filters. Want %#v. Got %#v.", expected, got)
+	}
+	if len(filters["label"]) != 1 {
+		t.Errorf("PruneImages: the filters of the options were modified: %#v", filters)
+	}
+}
+
+func TestPruneBuildCache(t *testing.T) {
+	t.Parallel()
+	results := `{
+		"CachesDeleted": [
+			"a", "b", "c"
+		],
+		"SpaceReclaimed": 123
+	}`
+
+	expected := &PruneBuildCacheResults{}
+	err := json.Unmarshal([]byte(results), expected)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fakeRT := &FakeRoundTripper{message: results, status: http.StatusOK}
+	client := newTestClient(fakeRT)
+	got, err := client.PruneBuildCache(PruneBuildCacheOptions{
+		Filters:     map[string][]string{"until": {"24h"}},
+		KeepStorage: 1024,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("PruneBuildCache: Expected %#v. Got %#v.", expected, got)
+	}
+	req := fakeRT.requests[0]
+	if req.Method != http.MethodPost {
+		t.Errorf("PruneBuildCache: wrong HTTP method. Want %q. Got %q.", http.MethodPost, req.Method)
+	}
+	if req.URL.Path != "/build/prune" {
+		t.Errorf("PruneBuildCache: wrong path. Want %q. Got %q.", "/build/prune", req.URL.Path)
+	}
+	expectedQuery := map[string][]string{"filters": {`{"until":["24h"]}`}, "keep-storage": {"1024"}}
+	if query := map[string][]string(req.URL.Query()); !reflect.DeepEqual(query, expectedQuery) {
+		t.Errorf("PruneBuildCache: wrong query string. Want %#v. Got %#v.", expectedQuery, query)
+	}
+}
@@ -7,6 +7,8 @@ package docker
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net"
 	"net/http"
 	"strings"
@@ -35,8 +37,34 @@ func (c *Client) VersionWithContext(ctx context.Context) (*Env, error) {
 	return &env, nil
 }
 
+// ServerAPIVersion returns the API version supported by the docker server, as
+// reported by the ApiVersion field of /version. The returned value can be
+// compared with other APIVersion values to check whether the server supports
+// a given feature.
+//
+// When cached is true and the client already knows the server version (for
+// example, because of a previous request), the known version is returned
+// without querying the server.
+func (c *Client) ServerAPIVersion(cached bool) (APIVersion, error) {
+	if !cached || c.serverAPIVersion == nil {
+		if err := c.checkAPIVersion(); err != nil {
+			return nil, err
+		}
+	}
+	version := make(APIVersion, len(c.serverAPIVersion))
+	copy(version, c.serverAPIVersion)
+	return version, nil
+}
+
 // DockerInfo contains information about the Docker server
 //
+// SecurityOptions lists the security features enabled in the daemon, in the
+// "name=seccomp,profile=default" format: use ParseSecurityOptions to decode
+// them. Runtimes are the OCI runtimes configured in the daemon, keyed by the
+// name used in HostConfig.Runtime, such as "runc", "nvidia" or "runsc", and
+// DefaultRuntime is the one used by containers that don't set
+// HostConfig.Runtime.
+//
 // See https://goo.gl/bHUoz9 for more details.
 type DockerInfo struct {
 	ID                 string
@@ -78,6 +106,8 @@ type DockerInfo struct {
 	Isolation          string
 	InitBinary         string
 	DefaultRuntime     string
+	CgroupVersion      string
+	Warnings           []string
 	Swarm              swarm.Info
 	LiveRestoreEnabled bool
 	MemoryLimit        bool
@@ -113,6 +143,60 @@ type PluginsInfo struct {
 	Network []string
 	// List of Authorization plugins registered
 	Authorization []string
+	// List of Log plugins registered
+	Log []string
+}
+
+// HasRuntime reports whether the daemon has the given OCI runtime configured,
+// so it can be used in HostConfig.Runtime.
+func (info *DockerInfo) HasRuntime(name string) bool {
+	_, ok := info.Runtimes[name]
+	return ok
+}
+
+// ErrInvalidSecurityOption is the error returned by ParseSecurityOptions when
+// a security option reported by the daemon can't be decoded.
+var ErrInvalidSecurityOption = errors.New("invalid security option")
+
+// SecurityOption is a security feature enabled in the daemon, like seccomp,
+// apparmor, selinux, rootless or userns, along with its options, for
+// instance the profile used by seccomp.
+type SecurityOption struct {
+	Name    string
+	Options map[string]string
+}
+
+// ParseSecurityOptions decodes the SecurityOptions reported by the daemon.
+// Options in the legacy format, used by daemons older than 1.13, are just
+// the name of the feature.
+func (info *DockerInfo) ParseSecurityOptions() ([]SecurityOption, error) {
+	options := make([]SecurityOption, 0, len(info.SecurityOptions))
+	for _, raw := range info.SecurityOptions {
+		if !strings.Contains(raw, "=") {
+			options = append(options, SecurityOption{Name: raw})
+			continue
+		}
+		var option SecurityOption
+		for _, field := range strings.Split(raw, ",") {
+			parts := strings.SplitN(field, "=", 2)
+			if len(parts) != 2 {
+				return nil, fmt.Errorf("%w: %q", ErrInvalidSecurityOption, raw)
+			}
+			if parts[0] == "name" {
+				option.Name = parts[1]
+				continue
+			}
+			if option.Options == nil {
+				option.Options = make(map[string]string)
+			}
+			option.Options[parts[0]] = parts[1]
+		}
+		if option.Name == "" {
+			return nil, fmt.Errorf("%w: %q has no name", ErrInvalidSecurityOption, raw)
+		}
+		options = append(options, option)
+	}
+	return options, nil
 }
 
 // ServiceConfig stores daemon registry services configuration.
@@ -163,7 +247,11 @@ type IndexInfo struct {
 //
 // See https://goo.gl/ElTHi2 for more details.
 func (c *Client) Info() (*DockerInfo, error) {
-	resp, err := c.do(http.MethodGet, "/info", doOptions{})
+	return c.info(context.Background())
+}
+
+func (c *Client) info(ctx context.Context) (*DockerInfo, error) {
+	resp, err := c.do(http.MethodGet, "/info", doOptions{context: ctx})
 	if err != nil {
 		return nil, err
 	}
@@ -5,6 +5,7 @@
 package docker
 
 import (
+	"errors"
 	"net"
 	"net/http"
 	"net/url"
@@ -56,6 +57,67 @@ func TestVersion(t *testing.T) {
 	}
 }
 
+func TestServerAPIVersion(t *testing.T) {
+	t.Parallel()
+	fakeRT := &FakeRoundTripper{message: `{"Version":"20.10.7","ApiVersion":"1.41"}`, status: http.StatusOK}
+	client := newTestClient(fakeRT)
+	version, err := client.ServerAPIVersion(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := APIVersion{1, 41}
+	if !reflect.DeepEqual(version, expected) {
+		t.Errorf("ServerAPIVersion: wrong version. Want %#v. Got %#v.", expected, version)
+	}
+	if !version.GreaterThan(APIVersion{1, 9}) {
+		t.Errorf("ServerAPIVersion: expected %s to be greater than 1.9", version)
+	}
+	req := fakeRT.requests[0]
+	if req.URL.Path != "/version" {
+		t.Errorf("ServerAPIVersion: wrong path. Want %q. Got %q.", "/version", req.URL.Path)
+	}
+}
+
+func TestServerAPIVersionCached(t *testing.T) {
+	t.Parallel()
+	fakeRT := &FakeRoundTripper{message: `{"ApiVersion":"1.41"}`, status: http.StatusOK}
+	client := newTestClient(fakeRT)
+	version, err := client.ServerAPIVersion(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := APIVersion{1, 17}
+	if !reflect.DeepEqual(version, expected) {
+		t.Errorf("ServerAPIVersion: wrong version. Want %#v. Got %#v.", expected, version)
+	}
+	if len(fakeRT.requests) != 0 {
+		t.Errorf("ServerAPIVersion: expected no requests, got %d", len(fakeRT.requests))
+	}
+	version, err = client.ServerAPIVersion(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = APIVersion{1, 41}
+	if !reflect.DeepEqual(version, expected) {
+		t.Errorf("ServerAPIVersion: wrong version. Want %#v. Got %#v.", expected, version)
+	}
+	if len(fakeRT.requests) != 1 {
+		t.Errorf("ServerAPIVersion: expected one request, got %d", len(fakeRT.requests))
+	}
+}
+
+func TestServerAPIVersionError(t *testing.T) {
+	t.Parallel()
+	client := newTestClient(&FakeRoundTripper{message: "internal error", status: http.StatusInternalServerError})
+	version, err := client.ServerAPIVersion(false)
+	if err == nil {
+		t.Error("ServerAPIVersion: unexpected <nil> error")
+	}
+	if version != nil {
+		t.Errorf("ServerAPIVersion: expected <nil> version, got %#v", version)
+	}
+}
+
 func TestVersionError(t *testing.T) {
 	t.Parallel()
 	fakeRT := &FakeRoundTripper{message: "internal error", status: http.StatusInternalServerError}
@@ -182,6 +244,102 @@ func TestInfoError(t *testing.T) {
 	}
 }
 
+func TestInfoRuntimesAndSecurityOptions(t *testing.T) {
+	t.Parallel()
+	body := `{
+     "DefaultRuntime": "runc",
+     "Runtimes": {
+       "runc": {"path": "runc"},
+       "nvidia": {"path": "nvidia-container-runtime", "runtimeArgs": ["--debug"]},
+       "runsc": {"path": "/usr/local/bin/runsc"}
+     },
+     "Plugins": {
+       "Volume": ["local"],
+       "Network": ["bridge", "host", "ipvlan", "macvlan", "null", "overlay"],
+       "Authorization": ["authz-broker"],
+       "Log": ["awslogs", "json-file", "local"]
+     },
+     "SecurityOptions": [
+       "name=apparmor",
+       "name=seccomp,profile=default",
+       "name=selinux",
+       "name=rootless",
+       "name=cgroupns"
+     ],
+     "CgroupVersion": "2",
+     "Warnings": ["WARNING: No swap limit support"]
+}`
+	client := newTestClient(&FakeRoundTripper{message: body, status: http.StatusOK})
+	info, err := client.Info()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.DefaultRuntime != "runc" {
+		t.Errorf("Info: wrong default runtime. Want %q. Got %q.", "runc", info.DefaultRuntime)
+	}
+	for _, name := range []string{"runc", "nvidia", "runsc"} {
+		if !info.HasRuntime(name) {
+			t.Errorf("Info: runtime %q not found in %#v", name, info.Runtimes)
+		}
+	}
+	if info.HasRuntime("kata") {
+		t.Error("Info: unexpected runtime kata")
+	}
+	expectedNvidia := Runtime{Path: "nvidia-container-runtime", Args: []string{"--debug"}}
+	if !reflect.DeepEqual(info.Runtimes["nvidia"], expectedNvidia) {
+		t.Errorf("Info: wrong nvidia runtime. Want %#v. Got %#v.", expectedNvidia, info.Runtimes["nvidia"])
+	}
+	expectedPlugins := PluginsInfo{
+		Volume:        []string{"local"},
+		Network:       []string{"bridge", "host", "ipvlan", "macvlan", "null", "overlay"},
+		Authorization: []string{"authz-broker"},
+		Log:           []string{"awslogs", "json-file", "local"},
+	}
+	if !reflect.DeepEqual(info.Plugins, expectedPlugins) {
+		t.Errorf("Info: wrong plugins. Want %#v. Got %#v.", expectedPlugins, info.Plugins)
+	}
+	if info.CgroupVersion != "2" || len(info.Warnings) != 1 {
+		t.Errorf("Info: wrong cgroup version or warnings: %q, %#v", info.CgroupVersion, info.Warnings)
+	}
+	options, err := info.ParseSecurityOptions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []SecurityOption{
+		{Name: "apparmor"},
+		{Name: "seccomp", Options: map[string]string{"profile": "default"}},
+		{Name: "selinux"},
+		{Name: "rootless"},
+		{Name: "cgroupns"},
+	}
+	if !reflect.DeepEqual(options, expected) {
+		t.Errorf("ParseSecurityOptions: want %#v. Got %#v.", expected, options)
+	}
+}
+
+func TestParseSecurityOptionsLegacy(t *testing.T) {
+	t.Parallel()
+	info := DockerInfo{SecurityOptions: []string{"apparmor", "seccomp"}}
+	options, err := info.ParseSecurityOptions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []SecurityOption{{Name: "apparmor"}, {Name: "seccomp"}}
+	if !reflect.DeepEqual(options, expected) {
+		t.Errorf("ParseSecurityOptions: want %#v. Got %#v.", expected, options)
+	}
+}
+
+func TestParseSecurityOptionsInvalid(t *testing.T) {
+	t.Parallel()
+	for _, raw := range []string{"profile=default", "name=seccomp,profile"} {
+		info := DockerInfo{SecurityOptions: []string{raw}}
+		if _, err := info.ParseSecurityOptions(); !errors.Is(err, ErrInvalidSecurityOption) {
+			t.Errorf("ParseSecurityOptions(%q): wrong error. Want %v. Got %v.", raw, ErrInvalidSecurityOption, err)
+		}
+	}
+}
+
 func TestParseRepositoryTag(t *testing.T) {
 	t.Parallel()
 	tests := []struct {
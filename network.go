@@ -9,14 +9,24 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net"
 	"net/http"
 	"net/url"
+	"time"
 )
 
 // ErrNetworkAlreadyExists is the error returned by CreateNetwork when the
 // network already exists.
 var ErrNetworkAlreadyExists = errors.New("network already exists")
 
+// ErrIPv6SubnetRequired is the error returned by CreateNetwork when EnableIPv6
+// is set but none of the subnets in the IPAM configuration is an IPv6 subnet.
+var ErrIPv6SubnetRequired = errors.New("an IPv6 subnet is required in the IPAM configuration when EnableIPv6 is set")
+
+// ErrIngressRequiresOverlay is the error returned by CreateNetwork when Ingress
+// is set for a network whose driver isn't "overlay".
+var ErrIngressRequiresOverlay = errors.New(`ingress networks must use the "overlay" driver`)
+
 // Network represents a network.
 //
 // See https://goo.gl/6GugX3 for more details.
@@ -30,7 +40,33 @@ type Network struct {
 	Options    map[string]string
 	Internal   bool
 	EnableIPv6 bool `json:"EnableIPv6"`
+	Ingress    bool
 	Labels     map[string]string
+	Services   map[string]NetworkService
+	Created    time.Time
+}
+
+// NetworkService contains the endpoints of a swarm service in an overlay
+// network. It's only reported by InspectNetworkWithOptions when Verbose is
+// set.
+//
+// See https://goo.gl/6GugX3 for more details.
+type NetworkService struct {
+	VIP          string
+	Ports        []string
+	LocalLBIndex int
+	Tasks        []NetworkTask
+}
+
+// NetworkTask contains the endpoint of a task of a swarm service in an
+// overlay network.
+//
+// See https://goo.gl/6GugX3 for more details.
+type NetworkTask struct {
+	Name       string
+	EndpointID string
+	EndpointIP string
+	Info       map[string]string
 }
 
 // Endpoint contains network resources allocated and used for a container in a network
@@ -91,12 +127,34 @@ func (c *Client) FilteredListNetworks(opts NetworkFilterOpts) ([]Network, error)
 //
 // See https://goo.gl/6GugX3 for more details.
 func (c *Client) NetworkInfo(id string) (*Network, error) {
-	path := "/networks/" + id
-	resp, err := c.do(http.MethodGet, path, doOptions{})
+	return c.InspectNetworkWithOptions(InspectNetworkOptions{ID: id})
+}
+
+// InspectNetworkOptions specifies parameters for InspectNetworkWithOptions.
+// Verbose requests the endpoints of the services and tasks attached to a
+// swarm-scoped network, and Scope restricts the lookup to networks of the
+// given scope ("swarm", "global" or "local").
+//
+// See https://goo.gl/6GugX3 for more details.
+type InspectNetworkOptions struct {
+	Context context.Context
+	ID      string `qs:"-"`
+	Verbose bool
+	Scope   string
+}
+
+// InspectNetworkWithOptions returns information about a network by its ID.
+// The Services field of the network is only populated when opts.Verbose is
+// true.
+//
+// See https://goo.gl/6GugX3 for more details.
+func (c *Client) InspectNetworkWithOptions(opts InspectNetworkOptions) (*Network, error) {
+	path := "/networks/" + opts.ID + "?" + queryString(opts)
+	resp, err := c.do(http.MethodGet, path, doOptions{context: opts.Context})
 	if err != nil {
 		var e *Error
 		if errors.As(err, &e) && e.Status == http.StatusNotFound {
-			return nil, &NoSuchNetwork{ID: id}
+			return nil, &NoSuchNetwork{ID: opts.ID}
 		}
 		return nil, err
 	}
@@ -125,7 +183,7 @@ type CreateNetworkOptions struct {
 	EnableIPv6     bool                   `json:"EnableIPv6" yaml:"EnableIPv6" toml:"EnableIPv6"`
 	Attachable     bool                   `json:"Attachable" yaml:"Attachable" toml:"Attachable"`
 	ConfigOnly     bool                   `json:"ConfigOnly" yaml:"ConfigOnly" toml:"ConfigOnly"`
-	Ingress        bool                   `json:"Ingress" yaml:"Ingress" toml:"Ingress"`
+	Ingress        bool                   `json:"Ingress,omitempty" yaml:"Ingress" toml:"Ingress"`
 	Context        context.Context        `json:"-"`
 }
 
@@ -157,8 +215,25 @@ type IPAMConfig struct {
 // CreateNetwork creates a new network, returning the network instance,
 // or an error in case of failure.
 //
+// The subnets in opts.IPAM are validated before sending the request, and
+// ErrIPv6SubnetRequired is returned when opts.EnableIPv6 is set without an
+// IPv6 subnet (unless the configuration comes from opts.ConfigFrom). For a
+// dual-stack network, include one IPv4 and one IPv6 entry in
+// opts.IPAM.Config; they are sent to the daemon in the given order.
+//
+// Setting opts.Ingress creates the routing-mesh network of the swarm, for
+// example to replace the default ingress network with one using a custom
+// subnet. It requires the "overlay" driver, and ErrIngressRequiresOverlay is
+// returned otherwise.
+//
 // See https://goo.gl/6GugX3 for more details.
 func (c *Client) CreateNetwork(opts CreateNetworkOptions) (*Network, error) {
+	if opts.Ingress && opts.Driver != "overlay" {
+		return nil, ErrIngressRequiresOverlay
+	}
+	if err := validateNetworkIPAM(opts); err != nil {
+		return nil, err
+	}
 	resp, err := c.do(
 		http.MethodPost,
 		"/networks/create",
@@ -190,6 +265,26 @@ func (c *Client) CreateNetwork(opts CreateNetworkOptions) (*Network, error) {
 	return &network, nil
 }
 
+func validateNetworkIPAM(opts CreateNetworkOptions) error {
+	var hasIPv6 bool
+	if opts.IPAM != nil {
+		for _, config := range opts.IPAM.Config {
+			if config.Subnet == "" {
+				continue
+			}
+			ip, _, err := net.ParseCIDR(config.Subnet)
+			if err != nil {
+				return fmt.Errorf("invalid subnet %q: %w", config.Subnet, err)
+			}
+			hasIPv6 = hasIPv6 || ip.To4() == nil
+		}
+	}
+	if opts.EnableIPv6 && !hasIPv6 && opts.ConfigFrom == nil {
+		return ErrIPv6SubnetRequired
+	}
+	return nil
+}
+
 // RemoveNetwork removes a network or returns an error in case of failure.
 //
 // See https://goo.gl/6GugX3 for more details.
@@ -211,13 +306,17 @@ func (c *Client) RemoveNetwork(id string) error {
 //
 // See https://goo.gl/RV7BJU for more details.
 type NetworkConnectionOptions struct {
+	// Container is the ID or the name of the container.
 	Container string
 
 	// EndpointConfig is only applicable to the ConnectNetwork call
 	EndpointConfig *EndpointConfig `json:"EndpointConfig,omitempty"`
 
-	// Force is only applicable to the DisconnectNetwork call
-	Force bool
+	// Force is only applicable to the DisconnectNetwork call. It removes
+	// the endpoint of the container from the network even when the
+	// container no longer exists, which cleans up endpoints left behind
+	// after a container dies ungracefully.
+	Force bool `json:"Force,omitempty"`
 
 	Context context.Context `json:"-"`
 }
@@ -242,7 +341,9 @@ type EndpointConfig struct {
 }
 
 // EndpointIPAMConfig represents IPAM configurations for an
-// endpoint
+// endpoint. When connecting a container to a network, IPv4Address and
+// IPv6Address request a static address for the container, which must be part
+// of one of the subnets configured for the network.
 //
 // See https://goo.gl/RV7BJU for more details.
 type EndpointIPAMConfig struct {
@@ -253,6 +354,11 @@ type EndpointIPAMConfig struct {
 // ConnectNetwork adds a container to a network or returns an error in case of
 // failure.
 //
+// Use opts.EndpointConfig to set aliases for the container in the network or
+// to pin its address through EndpointConfig.IPAMConfig. If the requested
+// address is not part of the subnets of the network, or is already in use,
+// the daemon rejects the request and the returned *Error describes why.
+//
 // See https://goo.gl/6GugX3 for more details.
 func (c *Client) ConnectNetwork(id string, opts NetworkConnectionOptions) error {
 	resp, err := c.do(http.MethodPost, "/networks/"+id+"/connect", doOptions{
@@ -273,9 +379,15 @@ func (c *Client) ConnectNetwork(id string, opts NetworkConnectionOptions) error
 // DisconnectNetwork removes a container from a network or returns an error in
 // case of failure.
 //
+// Set opts.Force to remove a stale endpoint whose container is gone, in which
+// case opts.Container is the ID or name the endpoint was created for.
+//
 // See https://goo.gl/6GugX3 for more details.
 func (c *Client) DisconnectNetwork(id string, opts NetworkConnectionOptions) error {
-	resp, err := c.do(http.MethodPost, "/networks/"+id+"/disconnect", doOptions{data: opts})
+	resp, err := c.do(http.MethodPost, "/networks/"+id+"/disconnect", doOptions{
+		data:    opts,
+		context: opts.Context,
+	})
 	if err != nil {
 		var e *Error
 		if errors.As(err, &e) && e.Status == http.StatusNotFound {
@@ -291,6 +403,14 @@ func (c *Client) DisconnectNetwork(id string, opts NetworkConnectionOptions) err
 //
 // See https://goo.gl/kX0S9h for more details.
 type PruneNetworksOptions struct {
+	// Filters restricts the networks that are removed. The daemon
+	// supports:
+	//
+	//	until: networks created before the given timestamp or duration
+	//	relative to the daemon time, like "24h"
+	//	label: networks with the given label, in the key or key=value
+	//	format
+	//	label!: networks without the given label
 	Filters map[string][]string
 	Context context.Context
 }
@@ -299,10 +419,13 @@ type PruneNetworksOptions struct {
 //
 // See https://goo.gl/kX0S9h for more details.
 type PruneNetworksResults struct {
+	// NetworksDeleted holds the names of the removed networks.
 	NetworksDeleted []string
 }
 
-// PruneNetworks deletes networks which are unused.
+// PruneNetworks deletes networks which are unused: networks without
+// containers or services attached, excluding the networks predefined by the
+// daemon.
 //
 // See https://goo.gl/kX0S9h for more details.
 func (c *Client) PruneNetworks(opts PruneNetworksOptions) (*PruneNetworksResults, error) {
@@ -8,9 +8,11 @@ import (
 	"bytes"
 	"encoding/json"
 	"errors"
+	"io/ioutil"
 	"net/http"
 	"net/url"
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -110,6 +112,62 @@ func TestNetworkInfo(t *testing.T) {
 	}
 }
 
+func TestInspectNetworkWithOptions(t *testing.T) {
+	t.Parallel()
+	jsonNetwork := `{
+             "Id": "8dfafdbc3a40",
+             "Name": "overlay",
+             "Scope": "swarm",
+             "Services": {
+                 "web": {
+                     "VIP": "10.0.0.2",
+                     "Ports": ["Target: 80, Publish: 8080"],
+                     "LocalLBIndex": 256,
+                     "Tasks": [{"Name": "web.1.abc", "EndpointID": "ep1", "EndpointIP": "10.0.0.3", "Info": {"Host IP": "192.168.1.10"}}]
+                 }
+             }
+        }`
+	fakeRT := &FakeRoundTripper{message: jsonNetwork, status: http.StatusOK}
+	client := newTestClient(fakeRT)
+	id := "8dfafdbc3a40"
+	network, err := client.InspectNetworkWithOptions(InspectNetworkOptions{ID: id, Verbose: true, Scope: "swarm"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]NetworkService{
+		"web": {
+			VIP:          "10.0.0.2",
+			Ports:        []string{"Target: 80, Publish: 8080"},
+			LocalLBIndex: 256,
+			Tasks: []NetworkTask{
+				{Name: "web.1.abc", EndpointID: "ep1", EndpointIP: "10.0.0.3", Info: map[string]string{"Host IP": "192.168.1.10"}},
+			},
+		},
+	}
+	if !reflect.DeepEqual(network.Services, expected) {
+		t.Errorf("InspectNetwork(%q): wrong services. Want %#v. Got %#v.", id, expected, network.Services)
+	}
+	req := fakeRT.requests[0]
+	expectedURL, _ := url.Parse(client.getURL("/networks/8dfafdbc3a40"))
+	if req.URL.Path != expectedURL.Path {
+		t.Errorf("InspectNetwork(%q): Wrong path in request. Want %q. Got %q.", id, expectedURL.Path, req.URL.Path)
+	}
+	expectedQuery := url.Values{"verbose": {"1"}, "scope": {"swarm"}}
+	if query := req.URL.Query(); !reflect.DeepEqual(query, expectedQuery) {
+		t.Errorf("InspectNetwork(%q): Wrong query string. Want %#v. Got %#v.", id, expectedQuery, query)
+	}
+}
+
+func TestInspectNetworkNotFound(t *testing.T) {
+	t.Parallel()
+	client := newTestClient(&FakeRoundTripper{message: "no such network", status: http.StatusNotFound})
+	_, err := client.InspectNetworkWithOptions(InspectNetworkOptions{ID: "8dfafdbc3a40", Scope: "local"})
+	var noSuchNetwork *NoSuchNetwork
+	if !errors.As(err, &noSuchNetwork) || noSuchNetwork.ID != "8dfafdbc3a40" {
+		t.Errorf("InspectNetwork: wrong error. Want NoSuchNetwork. Got %#v.", err)
+	}
+}
+
 func TestNetworkCreate(t *testing.T) {
 	jsonID := `{"ID": "8dfafdbc3a40"}`
 	jsonNetwork := `{
@@ -135,6 +193,119 @@ func TestNetworkCreate(t *testing.T) {
 	}
 }
 
+func TestNetworkCreateDualStack(t *testing.T) {
+	t.Parallel()
+	fakeRT := &FakeRoundTripper{message: `{"ID": "8dfafdbc3a40"}`, status: http.StatusOK}
+	client := newTestClient(fakeRT)
+	ipam := IPAMOptions{
+		Driver: "default",
+		Config: []IPAMConfig{
+			{Subnet: "172.28.0.0/16", Gateway: "172.28.0.1"},
+			{Subnet: "2001:db8:1::/64", Gateway: "2001:db8:1::1"},
+		},
+	}
+	opts := CreateNetworkOptions{Name: "dualstack", Driver: "bridge", EnableIPv6: true, Internal: true, IPAM: &ipam}
+	if _, err := client.CreateNetwork(opts); err != nil {
+		t.Fatal(err)
+	}
+	var body CreateNetworkOptions
+	if err := json.NewDecoder(fakeRT.requests[0].Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if !body.EnableIPv6 || !body.Internal {
+		t.Errorf("CreateNetwork: wrong flags. Want EnableIPv6 and Internal. Got %#v.", body)
+	}
+	if body.IPAM == nil || !reflect.DeepEqual(*body.IPAM, ipam) {
+		t.Errorf("CreateNetwork: wrong IPAM.\nWant %#v.\nGot  %#v.", ipam, body.IPAM)
+	}
+}
+
+func TestNetworkCreateInvalidIPAM(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name string
+		opts CreateNetworkOptions
+	}{
+		{
+			name: "IPv6 without IPAM",
+			opts: CreateNetworkOptions{Name: "v6", EnableIPv6: true},
+		},
+		{
+			name: "IPv6 with only IPv4 subnets",
+			opts: CreateNetworkOptions{Name: "v6", EnableIPv6: true, IPAM: &IPAMOptions{Config: []IPAMConfig{{Subnet: "172.28.0.0/16"}}}},
+		},
+		{
+			name: "invalid subnet",
+			opts: CreateNetworkOptions{Name: "v4", IPAM: &IPAMOptions{Config: []IPAMConfig{{Subnet: "172.28.0.0"}}}},
+		},
+	}
+	for _, tt := range tests {
+		fakeRT := &FakeRoundTripper{message: `{"ID": "8dfafdbc3a40"}`, status: http.StatusOK}
+		client := newTestClient(fakeRT)
+		if _, err := client.CreateNetwork(tt.opts); err == nil {
+			t.Errorf("CreateNetwork (%s): unexpected <nil> error", tt.name)
+		}
+		if len(fakeRT.requests) != 0 {
+			t.Errorf("CreateNetwork (%s): the request should not be sent", tt.name)
+		}
+	}
+	client := newTestClient(&FakeRoundTripper{message: `{"ID": "8dfafdbc3a40"}`, status: http.StatusOK})
+	_, err := client.CreateNetwork(CreateNetworkOptions{Name: "v6", EnableIPv6: true})
+	if !errors.Is(err, ErrIPv6SubnetRequired) {
+		t.Errorf("CreateNetwork: wrong error. Want %v. Got %v.", ErrIPv6SubnetRequired, err)
+	}
+	_, err = client.CreateNetwork(CreateNetworkOptions{Name: "v6", EnableIPv6: true, ConfigFrom: &NetworkConfigFrom{Network: "v6-config"}})
+	if err != nil {
+		t.Errorf("CreateNetwork: unexpected error with ConfigFrom: %v", err)
+	}
+}
+
+func TestNetworkCreateIngress(t *testing.T) {
+	t.Parallel()
+	fakeRT := &FakeRoundTripper{message: `{"ID": "8dfafdbc3a40"}`, status: http.StatusOK}
+	client := newTestClient(fakeRT)
+	opts := CreateNetworkOptions{
+		Name:    "ingress",
+		Driver:  "overlay",
+		Ingress: true,
+		IPAM:    &IPAMOptions{Config: []IPAMConfig{{Subnet: "10.11.0.0/16"}}},
+	}
+	if _, err := client.CreateNetwork(opts); err != nil {
+		t.Fatal(err)
+	}
+	var body map[string]interface{}
+	if err := json.NewDecoder(fakeRT.requests[0].Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if body["Ingress"] != true {
+		t.Errorf("CreateNetwork: wrong Ingress. Want true. Got %v.", body["Ingress"])
+	}
+	fakeRT.Reset()
+	if _, err := client.CreateNetwork(CreateNetworkOptions{Name: "foobar", Driver: "overlay"}); err != nil {
+		t.Fatal(err)
+	}
+	body = nil
+	if err := json.NewDecoder(fakeRT.requests[0].Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if value, ok := body["Ingress"]; ok {
+		t.Errorf("CreateNetwork: Ingress should not be serialized when false. Got %v.", value)
+	}
+}
+
+func TestNetworkCreateIngressRequiresOverlay(t *testing.T) {
+	t.Parallel()
+	fakeRT := &FakeRoundTripper{message: `{"ID": "8dfafdbc3a40"}`, status: http.StatusOK}
+	client := newTestClient(fakeRT)
+	_, err := client.CreateNetwork(CreateNetworkOptions{Name: "ingress", Driver: "bridge", Ingress: true})
+	if !errors.Is(err, ErrIngressRequiresOverlay) {
+		t.Errorf("CreateNetwork: wrong error. Want %v. Got %v.", ErrIngressRequiresOverlay, err)
+	}
+	if len(fakeRT.requests) != 0 {
+		t.Error("CreateNetwork: the request should not be sent")
+	}
+}
+
 func TestNetworkRemove(t *testing.T) {
 	t.Parallel()
 	id := "8dfafdbc3a40"
@@ -246,6 +417,25 @@ func TestNetworkDisconnect(t *testing.T) {
 	}
 }
 
+func TestNetworkDisconnectForce(t *testing.T) {
+	t.Parallel()
+	fakeRT := &FakeRoundTripper{message: "", status: http.StatusNoContent}
+	client := newTestClient(fakeRT)
+	for _, force := range []bool{false, true} {
+		opts := NetworkConnectionOptions{Container: "foobar", Force: force}
+		if err := client.DisconnectNetwork("8dfafdbc3a40", opts); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expected := []string{`{"Container":"foobar"}`, `{"Container":"foobar","Force":true}`}
+	for i, want := range expected {
+		body, _ := ioutil.ReadAll(fakeRT.requests[i].Body)
+		if got := strings.TrimSpace(string(body)); got != want {
+			t.Errorf("DisconnectNetwork: wrong body. Want %s. Got %s.", want, got)
+		}
+	}
+}
+
 func TestNetworkDisconnectNotFound(t *testing.T) {
 	t.Parallel()
 	client := newTestClient(&FakeRoundTripper{message: "no such network container", status: http.StatusNotFound})
@@ -279,3 +469,20 @@ func TestPruneNetworks(t *testing.T) {
 		t.Errorf("PruneNetworks: Expected %#v. Got %#v.", expected, got)
 	}
 }
+
+func TestPruneNetworksFilters(t *testing.T) {
+	t.Parallel()
+	fakeRT := &FakeRoundTripper{message: `{"NetworksDeleted": ["old"]}`, status: http.StatusOK}
+	client := newTestClient(fakeRT)
+	filters := map[string][]string{"until": {"24h"}, "label": {"env=dev"}}
+	if _, err := client.PruneNetworks(PruneNetworksOptions{Filters: filters}); err != nil {
+		t.Fatal(err)
+	}
+	var got map[string][]string
+	if err := json.Unmarshal([]byte(fakeRT.requests[0].URL.Query().Get("filters")), &got); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, filters) {
+		t.Errorf("PruneNetworks: wrong filters. Want %#v. Got %#v.", filters, got)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"io"
 	"io/ioutil"
 	"net/http"
 )
@@ -23,10 +24,19 @@ type PluginPrivilege struct {
 //
 // See https://goo.gl/C4t7Tz for more details.
 type InstallPluginOptions struct {
-	Remote  string
-	Name    string
+	Remote string
+	Name   string
+
+	// Plugins holds the privileges granted to the plugin. The API does not
+	// prompt for them, so every privilege returned by GetPluginPrivileges
+	// must be accepted up front.
 	Plugins []PluginPrivilege `qs:"-"`
 
+	// OutputStream receives the JSON progress messages streamed by the
+	// daemon while the plugin is pulled. When nil, the progress is
+	// discarded.
+	OutputStream io.Writer `qs:"-"`
+
 	Auth AuthConfiguration
 
 	Context context.Context
@@ -53,7 +63,11 @@ func (c *Client) InstallPlugins(opts InstallPluginOptions) error {
 	defer resp.Body.Close()
 	// PullPlugin streams back the progress of the pull, we must consume the whole body
 	// otherwise the pull will be canceled on the engine.
-	if _, err := ioutil.ReadAll(resp.Body); err != nil {
+	out := opts.OutputStream
+	if out == nil {
+		out = ioutil.Discard
+	}
+	if _, err := io.Copy(out, resp.Body); err != nil {
 		return err
 	}
 	return nil
@@ -332,6 +346,10 @@ func (c *Client) EnablePlugin(opts EnablePluginOptions) error {
 	path := "/plugins/" + opts.Name + "/enable?" + queryString(opts)
 	resp, err := c.do(http.MethodPost, path, doOptions{context: opts.Context})
 	if err != nil {
+		var e *Error
+		if errors.As(err, &e) && e.Status == http.StatusNotFound {
+			return &NoSuchPlugin{ID: opts.Name}
+		}
 		return err
 	}
 	resp.Body.Close()
@@ -355,6 +373,10 @@ func (c *Client) DisablePlugin(opts DisablePluginOptions) error {
 	path := "/plugins/" + opts.Name + "/disable"
 	resp, err := c.do(http.MethodPost, path, doOptions{context: opts.Context})
 	if err != nil {
+		var e *Error
+		if errors.As(err, &e) && e.Status == http.StatusNotFound {
+			return &NoSuchPlugin{ID: opts.Name}
+		}
 		return err
 	}
 	resp.Body.Close()
@@ -5,6 +5,7 @@
 package docker
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -231,6 +232,7 @@ func TestGetPluginPrivilegesWithOptions(t *testing.T) {
 }
 
 func TestInstallPlugins(t *testing.T) {
+	t.Parallel()
 	opts := InstallPluginOptions{
 		Remote: "", Name: "test",
 		Plugins: []PluginPrivilege{
@@ -259,6 +261,7 @@ func TestInstallPlugins(t *testing.T) {
 }
 
 func TestInspectPlugin(t *testing.T) {
+	t.Parallel()
 	name := "test_plugin"
 	fakeRT := &FakeRoundTripper{message: jsonPluginDetail, status: http.StatusNoContent}
 	client := newTestClient(fakeRT)
@@ -272,6 +275,7 @@ func TestInspectPlugin(t *testing.T) {
 }
 
 func TestRemovePlugin(t *testing.T) {
+	t.Parallel()
 	opts := RemovePluginOptions{
 		Name:    "test_plugin",
 		Force:   false,
@@ -289,6 +293,7 @@ func TestRemovePlugin(t *testing.T) {
 }
 
 func TestRemovePluginNoResponse(t *testing.T) {
+	t.Parallel()
 	opts := RemovePluginOptions{
 		Name:    "test_plugin",
 		Force:   false,
@@ -307,6 +312,7 @@ func TestRemovePluginNoResponse(t *testing.T) {
 }
 
 func TestEnablePlugin(t *testing.T) {
+	t.Parallel()
 	opts := EnablePluginOptions{
 		Name:    "test",
 		Timeout: 5,
@@ -320,6 +326,7 @@ func TestEnablePlugin(t *testing.T) {
 }
 
 func TestDisablePlugin(t *testing.T) {
+	t.Parallel()
 	opts := DisablePluginOptions{
 		Name:    "test",
 		Context: context.Background(),
@@ -332,6 +339,7 @@ func TestDisablePlugin(t *testing.T) {
 }
 
 func TestCreatePlugin(t *testing.T) {
+	t.Parallel()
 	opts := CreatePluginOptions{
 		Name:    "test",
 		Path:    "",
@@ -345,6 +353,7 @@ func TestCreatePlugin(t *testing.T) {
 }
 
 func TestPushPlugin(t *testing.T) {
+	t.Parallel()
 	opts := PushPluginOptions{
 		Name:    "test",
 		Context: context.Background(),
@@ -357,6 +366,7 @@ func TestPushPlugin(t *testing.T) {
 }
 
 func TestConfigurePlugin(t *testing.T) {
+	t.Parallel()
 	opts := ConfigurePluginOptions{
 		Name:    "test",
 		Envs:    []string{},
@@ -368,3 +378,39 @@ func TestConfigurePlugin(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestInstallPluginsOutputStream(t *testing.T) {
+	t.Parallel()
+	progress := `{"status":"Downloading"}
+{"status":"Digest: sha256:abc"}
+`
+	client := newTestClient(&FakeRoundTripper{message: progress, status: http.StatusOK})
+	var buf bytes.Buffer
+	err := client.InstallPlugins(InstallPluginOptions{Remote: "vieux/sshfs", OutputStream: &buf})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != progress {
+		t.Errorf("InstallPlugins: wrong output. Want %q. Got %q.", progress, buf.String())
+	}
+}
+
+func TestEnablePluginNotFound(t *testing.T) {
+	t.Parallel()
+	client := newTestClient(&FakeRoundTripper{message: "no such plugin", status: http.StatusNotFound})
+	err := client.EnablePlugin(EnablePluginOptions{Name: "test"})
+	expected := &NoSuchPlugin{ID: "test"}
+	if !reflect.DeepEqual(err, expected) {
+		t.Errorf("EnablePlugin: Wrong error returned. Want %#v. Got %#v.", expected, err)
+	}
+}
+
+func TestDisablePluginNotFound(t *testing.T) {
+	t.Parallel()
+	client := newTestClient(&FakeRoundTripper{message: "no such plugin", status: http.StatusNotFound})
+	err := client.DisablePlugin(DisablePluginOptions{Name: "test"})
+	expected := &NoSuchPlugin{ID: "test"}
+	if !reflect.DeepEqual(err, expected) {
+		t.Errorf("DisablePlugin: Wrong error returned. Want %#v. Got %#v.", expected, err)
+	}
+}
@@ -0,0 +1,120 @@
+// Copyright 2014 go-dockerclient authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package docker
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"net/http"
+	"time"
+)
+
+// RetryPolicy configures how the client retries idempotent requests (GET and
+// HEAD) that fail with a transient error, such as a connection error while
+// the daemon is restarting or a 502 or 504 response from a proxy in front
+// of it. 503 responses aren't retried: the daemon uses them for permanent
+// errors too, such as requests to a node that isn't a swarm manager or to a
+// locked swarm.
+//
+// Requests that may have side effects (create, start, remove, etc.) are never
+// retried.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of times a request is sent,
+	// including the first attempt. Values lower than 2 disable retries.
+	MaxAttempts int
+
+	// BaseDelay is the delay before the first retry. The delay doubles
+	// after every failed attempt.
+	BaseDelay time.Duration
+
+	// MaxDelay caps the delay between attempts. Zero means no cap.
+	MaxDelay time.Duration
+
+	// Jitter is the fraction (between 0 and 1) of the delay that is
+	// randomized, to avoid many clients retrying at the same time.
+	Jitter float64
+}
+
+// SetRetryPolicy configures the client to retry idempotent requests using the
+// given policy. Passing the zero value disables retries. It should not be
+// called concurrently with any other Client methods.
+//
+// Retries stop as soon as the context of the request is done, or when the
+// next attempt wouldn't start before the context deadline.
+func (c *Client) SetRetryPolicy(policy RetryPolicy) {
+	if policy.MaxAttempts < 2 {
+		c.retryPolicy = nil
+		return
+	}
+	c.retryPolicy = &policy
+}
+
+func (p *RetryPolicy) delay(attempt int) time.Duration {
+	maxDelay := p.MaxDelay
+	if maxDelay <= 0 {
+		maxDelay = math.MaxInt64
+	}
+	d := p.BaseDelay
+	if d > maxDelay {
+		d = maxDelay
+	}
+	// doubling saturates at maxDelay, so that large attempt numbers don't
+	// overflow
+	for i := 1; i < attempt && d > 0 && d < maxDelay; i++ {
+		if d > maxDelay/2 {
+			d = maxDelay
+		} else {
+			d *= 2
+		}
+	}
+	if p.Jitter > 0 && d > 0 {
+		jitter := p.Jitter
+		if jitter > 1 {
+			jitter = 1
+		}
+		spread := float64(d) * jitter
+		jittered := float64(d) - spread + rand.Float64()*2*spread // #nosec
+		if jittered >= math.MaxInt64 {
+			return math.MaxInt64
+		}
+		d = time.Duration(jittered)
+	}
+	return d
+}
+
+// maxAttempts returns the number of times a request with the given method
+// may be sent.
+func (c *Client) maxAttempts(method string) int {
+	if c.retryPolicy == nil || (method != http.MethodGet && method != http.MethodHead) {
+		return 1
+	}
+	return c.retryPolicy.MaxAttempts
+}
+
+func isRetryableStatus(status int) bool {
+	switch status {
+	case http.StatusBadGateway, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// waitRetry sleeps before the given retry attempt, returning false if the
+// context is done or its deadline would expire before the next attempt.
+func (c *Client) waitRetry(ctx context.Context, attempt int) bool {
+	d := c.retryPolicy.delay(attempt)
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < d {
+		return false
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
@@ -0,0 +1,199 @@
+// Copyright 2014 go-dockerclient authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package docker
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// flakyRoundTripper fails the first failures requests, either with a
+// transport error (when status is zero) or with the given status, and then
+// succeeds.
+type flakyRoundTripper struct {
+	mu       sync.Mutex
+	failures int
+	status   int
+	requests []*http.Request
+}
+
+func (rt *flakyRoundTripper) RoundTrip(r *http.Request) (*http.Response, error) {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	rt.requests = append(rt.requests, r)
+	if len(rt.requests) <= rt.failures {
+		if rt.status == 0 {
+			return nil, errors.New("unexpected EOF")
+		}
+		return &http.Response{StatusCode: rt.status, Body: ioutil.NopCloser(strings.NewReader("daemon restarting")), Header: make(http.Header)}, nil
+	}
+	return &http.Response{StatusCode: http.StatusOK, Body: ioutil.NopCloser(strings.NewReader(`{"Id":"abc"}`)), Header: make(http.Header)}, nil
+}
+
+func TestRetryPolicyTransportError(t *testing.T) {
+	t.Parallel()
+	rt := &flakyRoundTripper{failures: 2}
+	client := newTestClient(rt)
+	client.SetRetryPolicy(RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond})
+	container, err := client.InspectContainerWithOptions(InspectContainerOptions{ID: "abc"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if container.ID != "abc" {
+		t.Errorf("InspectContainer: wrong container. Want %q. Got %q.", "abc", container.ID)
+	}
+	if len(rt.requests) != 3 {
+		t.Errorf("InspectContainer: wrong number of requests. Want 3. Got %d.", len(rt.requests))
+	}
+}
+
+func TestRetryPolicyStatus(t *testing.T) {
+	t.Parallel()
+	rt := &flakyRoundTripper{failures: 1, status: http.StatusGatewayTimeout}
+	client := newTestClient(rt)
+	client.SetRetryPolicy(RetryPolicy{MaxAttempts: 2, BaseDelay: time.Millisecond, Jitter: 0.5})
+	if _, err := client.InspectContainerWithOptions(InspectContainerOptions{ID: "abc"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(rt.requests) != 2 {
+		t.Errorf("InspectContainer: wrong number of requests. Want 2. Got %d.", len(rt.requests))
+	}
+}
+
+func TestRetryPolicyMaxAttempts(t *testing.T) {
+	t.Parallel()
+	rt := &flakyRoundTripper{failures: 5, status: http.StatusBadGateway}
+	client := newTestClient(rt)
+	client.SetRetryPolicy(RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond})
+	_, err := client.InspectContainerWithOptions(InspectContainerOptions{ID: "abc"})
+	var e *Error
+	if !errors.As(err, &e) || e.Status != http.StatusBadGateway {
+		t.Errorf("InspectContainer: unexpected error: %v", err)
+	}
+	if len(rt.requests) != 3 {
+		t.Errorf("InspectContainer: wrong number of requests. Want 3. Got %d.", len(rt.requests))
+	}
+}
+
+func TestRetryPolicyNonRetryableStatus(t *testing.T) {
+	t.Parallel()
+	rt := &flakyRoundTripper{failures: 1, status: http.StatusNotFound}
+	client := newTestClient(rt)
+	client.SetRetryPolicy(RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond})
+	_, err := client.InspectContainerWithOptions(InspectContainerOptions{ID: "abc"})
+	expectNoSuchContainer(t, "abc", err)
+	if len(rt.requests) != 1 {
+		t.Errorf("InspectContainer: wrong number of requests. Want 1. Got %d.", len(rt.requests))
+	}
+}
+
+func TestRetryPolicyServiceUnavailable(t *testing.T) {
+	t.Parallel()
+	rt := &flakyRoundTripper{failures: 1, status: http.StatusServiceUnavailable}
+	client := newTestClient(rt)
+	client.SetRetryPolicy(RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond})
+	_, err := client.InspectContainerWithOptions(InspectContainerOptions{ID: "abc"})
+	var e *Error
+	if !errors.As(err, &e) || e.Status != http.StatusServiceUnavailable {
+		t.Errorf("InspectContainer: unexpected error: %v", err)
+	}
+	if len(rt.requests) != 1 {
+		t.Errorf("InspectContainer: wrong number of requests. Want 1. Got %d.", len(rt.requests))
+	}
+}
+
+func TestRetryPolicyNonIdempotent(t *testing.T) {
+	t.Parallel()
+	rt := &flakyRoundTripper{failures: 1}
+	client := newTestClient(rt)
+	client.SetRetryPolicy(RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond})
+	if err := client.StartContainer("abc", nil); err == nil {
+		t.Error("StartContainer: unexpected <nil> error")
+	}
+	if len(rt.requests) != 1 {
+		t.Errorf("StartContainer: wrong number of requests. Want 1. Got %d.", len(rt.requests))
+	}
+}
+
+func TestRetryPolicyContextDeadline(t *testing.T) {
+	t.Parallel()
+	rt := &flakyRoundTripper{failures: 5}
+	client := newTestClient(rt)
+	client.SetRetryPolicy(RetryPolicy{MaxAttempts: 5, BaseDelay: time.Hour})
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	start := time.Now()
+	_, err := client.InspectContainerWithOptions(InspectContainerOptions{ID: "abc", Context: ctx})
+	if err == nil {
+		t.Fatal("InspectContainer: unexpected <nil> error")
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("InspectContainer: waited %s for a retry that couldn't happen before the deadline", elapsed)
+	}
+	if len(rt.requests) != 1 {
+		t.Errorf("InspectContainer: wrong number of requests. Want 1. Got %d.", len(rt.requests))
+	}
+}
+
+func TestRetryPolicyDisabled(t *testing.T) {
+	t.Parallel()
+	rt := &flakyRoundTripper{failures: 1}
+	client := newTestClient(rt)
+	client.SetRetryPolicy(RetryPolicy{MaxAttempts: 3})
+	client.SetRetryPolicy(RetryPolicy{})
+	if _, err := client.InspectContainerWithOptions(InspectContainerOptions{ID: "abc"}); err == nil {
+		t.Error("InspectContainer: unexpected <nil> error")
+	}
+	if len(rt.requests) != 1 {
+		t.Errorf("InspectContainer: wrong number of requests. Want 1. Got %d.", len(rt.requests))
+	}
+}
+
+func TestRetryPolicyDelay(t *testing.T) {
+	t.Parallel()
+	policy := RetryPolicy{BaseDelay: 100 * time.Millisecond, MaxDelay: 300 * time.Millisecond}
+	expected := []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 300 * time.Millisecond, 300 * time.Millisecond}
+	for i, want := range expected {
+		if got := policy.delay(i + 1); got != want {
+			t.Errorf("delay(%d): want %s. Got %s.", i+1, want, got)
+		}
+	}
+	policy.Jitter = 0.5
+	for i := 0; i < 20; i++ {
+		if got := policy.delay(1); got < 50*time.Millisecond || got > 150*time.Millisecond {
+			t.Errorf("delay(1) with jitter: %s is out of range", got)
+		}
+	}
+}
+
+func TestRetryPolicyDelayLargeAttempts(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		policy RetryPolicy
+		want   time.Duration
+	}{
+		{RetryPolicy{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Minute}, time.Minute},
+		{RetryPolicy{BaseDelay: 100 * time.Millisecond}, math.MaxInt64},
+		{RetryPolicy{BaseDelay: 100 * time.Millisecond, Jitter: 0.5}, math.MaxInt64},
+	}
+	for _, tt := range tests {
+		for _, attempt := range []int{64, 100, 1000} {
+			got := tt.policy.delay(attempt)
+			if tt.policy.Jitter == 0 && got != tt.want {
+				t.Errorf("%#v.delay(%d): want %s. Got %s.", tt.policy, attempt, tt.want, got)
+			}
+			if got <= 0 {
+				t.Errorf("%#v.delay(%d): overflowed to %s", tt.policy, attempt, got)
+			}
+		}
+	}
+}
@@ -0,0 +1,198 @@
+package docker
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/moby/buildkit/session"
+	"github.com/moby/buildkit/session/secrets/secretsprovider"
+	"github.com/moby/buildkit/session/sshforward/sshprovider"
+)
+
+var (
+	// ErrBuildSessionRequiresBuildKit is the error returned by BuildImage
+	// when build secrets or SSH agents are given without selecting the
+	// BuildKit builder.
+	ErrBuildSessionRequiresBuildKit = errors.New("build secrets and SSH agents require the BuildKit builder, set Version to BuilderBuildKit")
+
+	// ErrBuildSessionWithSessionID is the error returned by BuildImage when
+	// build secrets or SSH agents are given along with the ID of a session
+	// attached by the caller.
+	ErrBuildSessionWithSessionID = errors.New("build secrets and SSH agents can't be used along with SessionID")
+
+	// ErrInvalidSSHAgent is the error returned by BuildImage when an entry
+	// of SSHAgents isn't valid.
+	ErrInvalidSSHAgent = errors.New("invalid SSH agent, use <ID>[=<socket>|<key>[,<key>]], with an agent socket, private keys or SSH_AUTH_SOCK set")
+)
+
+// DialSession opens a connection to the /session endpoint of the daemon, and
+// upgrades it to the given protocol, usually "h2c". The meta headers are sent
+// along with the upgrade request.
+//
+// The signature matches the dialer taken by the Run method of sessions from
+// github.com/moby/buildkit/session, which implements the gRPC services that
+// provide build secrets and SSH forwarding. Once the session is running, its
+// ID must be set in BuildImageOptions.SessionID, with Version set to
+// BuilderBuildKit:
+//
+//	sess, err := session.NewSession(ctx, "my-build", "")
+//	// handle error
+//	sess.Allow(secretsprovider.FromMap(map[string][]byte{"token": token}))
+//	ssh, err := sshprovider.NewSSHAgentProvider([]sshprovider.AgentConfig{
+//	    {ID: "default", Paths: []string{os.Getenv("SSH_AUTH_SOCK")}},
+//	})
+//	// handle error
+//	sess.Allow(ssh)
+//	go sess.Run(ctx, client.DialSession)
+//	defer sess.Close()
+//	err = client.BuildImage(docker.BuildImageOptions{
+//	    Version:   docker.BuilderBuildKit,
+//	    SessionID: sess.ID(),
+//	    // ...
+//	})
+//
+// The Dockerfile then accesses them with RUN --mount=type=secret,id=token
+// and RUN --mount=type=ssh. Secrets are read from the session only while the
+// RUN instruction that mounts them executes, so unlike files added to the
+// build context they're never stored in the image layers or the build cache.
+//
+// BuildImageOptions.Secrets and BuildImageOptions.SSHAgents cover the common
+// cases without a session of the caller's own.
+//
+// Sessions require Docker API 1.39 or greater.
+func (c *Client) DialSession(ctx context.Context, proto string, meta map[string][]string) (net.Conn, error) {
+	if c.serverAPIVersion == nil {
+		c.checkAPIVersion()
+	}
+	if c.serverAPIVersion != nil && c.serverAPIVersion.LessThan(apiVersion139) {
+		return nil, errors.New("sessions are only supported in API#1.39 and above")
+	}
+	req, err := http.NewRequest(http.MethodPost, c.getURL("/session"), nil)
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range meta {
+		req.Header[http.CanonicalHeaderKey(key)] = values
+	}
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", proto)
+	conn, err := c.dialRaw()
+	if err != nil {
+		return nil, err
+	}
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-stop:
+		}
+	}()
+	if err := req.Write(conn); err != nil {
+		conn.Close()
+		return nil, chooseError(ctx, err)
+	}
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		conn.Close()
+		return nil, chooseError(ctx, err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		conn.Close()
+		return nil, newError(resp)
+	}
+	return &bufferedConn{Conn: conn, r: br}, nil
+}
+
+// bufferedConn is a net.Conn that first returns the data buffered while
+// reading the HTTP handshake.
+type bufferedConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func (c *bufferedConn) Read(p []byte) (int, error) {
+	return c.r.Read(p)
+}
+
+// startBuildSession attaches a BuildKit session providing opts.Secrets and
+// opts.SSHAgents to the daemon, and sets opts.SessionID to its ID. The
+// returned function must be called once the build is over, to close the
+// session.
+func (c *Client) startBuildSession(opts *BuildImageOptions) (func(), error) {
+	if len(opts.Secrets) == 0 && len(opts.SSHAgents) == 0 {
+		return func() {}, nil
+	}
+	if opts.Version != BuilderBuildKit {
+		return nil, ErrBuildSessionRequiresBuildKit
+	}
+	if opts.SessionID != "" {
+		return nil, ErrBuildSessionWithSessionID
+	}
+	agents, err := parseSSHAgents(opts.SSHAgents)
+	if err != nil {
+		return nil, err
+	}
+	ctx := opts.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	sess, err := session.NewSession(ctx, "go-dockerclient", "")
+	if err != nil {
+		return nil, err
+	}
+	if len(opts.Secrets) > 0 {
+		sess.Allow(secretsprovider.FromMap(opts.Secrets))
+	}
+	if len(agents) > 0 {
+		provider, err := sshprovider.NewSSHAgentProvider(agents)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidSSHAgent, err)
+		}
+		sess.Allow(provider)
+	}
+	// the session is attached before returning, so that dial errors are
+	// reported by BuildImage and the daemon knows the session once the
+	// build starts.
+	dialed := make(chan error, 1)
+	go sess.Run(ctx, func(ctx context.Context, proto string, meta map[string][]string) (net.Conn, error) {
+		conn, err := c.DialSession(ctx, proto, meta)
+		dialed <- err
+		return conn, err
+	})
+	if err := <-dialed; err != nil {
+		return nil, err
+	}
+	opts.SessionID = sess.ID()
+	return func() { sess.Close() }, nil
+}
+
+// parseSSHAgents parses the SSHAgents of a build, in the format taken by
+// "docker build --ssh".
+func parseSSHAgents(specs []string) ([]sshprovider.AgentConfig, error) {
+	agents := make([]sshprovider.AgentConfig, 0, len(specs))
+	seen := make(map[string]bool, len(specs))
+	for _, spec := range specs {
+		id, paths := spec, os.Getenv("SSH_AUTH_SOCK")
+		if i := strings.Index(spec, "="); i >= 0 {
+			id, paths = spec[:i], spec[i+1:]
+			if paths == "" {
+				return nil, fmt.Errorf("%w: %q", ErrInvalidSSHAgent, spec)
+			}
+		}
+		if id == "" || seen[id] || paths == "" {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidSSHAgent, spec)
+		}
+		seen[id] = true
+		agents = append(agents, sshprovider.AgentConfig{ID: id, Paths: strings.Split(paths, ",")})
+	}
+	return agents, nil
+}
@@ -0,0 +1,480 @@
+package docker
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/moby/buildkit/session/sshforward/sshprovider"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/net/http2"
+)
+
+func TestDialSession(t *testing.T) {
+	t.Parallel()
+	reqs := make(chan *http.Request, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reqs <- r
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+		conn.Write([]byte("HTTP/1.1 101 Switching Protocols\r\nConnection: Upgrade\r\nUpgrade: h2c\r\n\r\nhello\n"))
+		line, _ := bufio.NewReader(conn).ReadString('\n')
+		conn.Write([]byte(line))
+	}))
+	defer server.Close()
+	client, err := NewVersionedClient(server.URL, "1.39")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.SkipServerVersionCheck = true
+	client.serverAPIVersion = apiVersion139
+	conn, err := client.DialSession(context.Background(), "h2c", map[string][]string{
+		"X-Docker-Expose-Session-Uuid": {"abc123"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	req := <-reqs
+	if req.Method != http.MethodPost || req.URL.Path != "/v1.39/session" {
+		t.Errorf("DialSession: wrong request. Want POST /v1.39/session. Got %s %s.", req.Method, req.URL.Path)
+	}
+	if got := req.Header.Get("Upgrade"); got != "h2c" {
+		t.Errorf("DialSession: wrong Upgrade header. Want %q. Got %q.", "h2c", got)
+	}
+	if got := req.Header.Get("X-Docker-Expose-Session-Uuid"); got != "abc123" {
+		t.Errorf("DialSession: meta header not sent. Want %q. Got %q.", "abc123", got)
+	}
+	r := bufio.NewReader(conn)
+	if line, _ := r.ReadString('\n'); line != "hello\n" {
+		t.Errorf("DialSession: wrong data after the handshake. Want %q. Got %q.", "hello\n", line)
+	}
+	conn.Write([]byte("ping\n"))
+	if line, _ := r.ReadString('\n'); line != "ping\n" {
+		t.Errorf("DialSession: wrong echoed data. Want %q. Got %q.", "ping\n", line)
+	}
+}
+
+func TestDialSessionError(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "session not supported", http.StatusBadRequest)
+	}))
+	defer server.Close()
+	client, err := NewClient(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.serverAPIVersion = apiVersion139
+	_, err = client.DialSession(context.Background(), "h2c", nil)
+	e, ok := err.(*Error)
+	if !ok || e.Status != http.StatusBadRequest {
+		t.Errorf("DialSession: wrong error. Want status %d. Got %#v.", http.StatusBadRequest, err)
+	}
+}
+
+func TestDialSessionOldAPI(t *testing.T) {
+	t.Parallel()
+	client := newTestClient(&FakeRoundTripper{status: http.StatusOK})
+	_, err := client.DialSession(context.Background(), "h2c", nil)
+	if err == nil || err.Error() != "sessions are only supported in API#1.39 and above" {
+		t.Errorf("DialSession: unexpected error: %v", err)
+	}
+}
+
+// grpcResult is the result of a gRPC call made by sessionDaemon.
+type grpcResult struct {
+	messages [][]byte
+	status   string
+}
+
+// sessionDaemon is a fake daemon that, once a session is attached, calls
+// the given gRPC methods on it before answering to builds.
+type sessionDaemon struct {
+	t       *testing.T
+	calls   []grpcCall
+	session chan *http.Request
+	results chan []grpcResult
+	called  chan struct{}
+	build   chan *http.Request
+}
+
+type grpcCall struct {
+	method   string
+	header   http.Header
+	messages [][]byte
+
+	// replies keeps the request of streaming calls open until that many
+	// messages of the response arrive, like the daemon does with
+	// ForwardAgent streams.
+	replies int
+}
+
+func newSessionDaemon(t *testing.T, calls ...grpcCall) *sessionDaemon {
+	return &sessionDaemon{
+		t:       t,
+		calls:   calls,
+		session: make(chan *http.Request, 1),
+		results: make(chan []grpcResult, 1),
+		called:  make(chan struct{}),
+		build:   make(chan *http.Request, 1),
+	}
+}
+
+func (d *sessionDaemon) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case strings.HasSuffix(r.URL.Path, "/session"):
+		d.session <- r
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			d.t.Error(err)
+			return
+		}
+		defer conn.Close()
+		conn.Write([]byte("HTTP/1.1 101 Switching Protocols\r\nConnection: Upgrade\r\nUpgrade: h2c\r\n\r\n"))
+		transport := http2.Transport{AllowHTTP: true}
+		cc, err := transport.NewClientConn(conn)
+		if err != nil {
+			d.t.Error(err)
+			return
+		}
+		var results []grpcResult
+		for _, call := range d.calls {
+			result, err := callGRPC(cc, call)
+			if err != nil {
+				d.t.Errorf("%s: %v", call.method, err)
+			}
+			results = append(results, result)
+		}
+		d.results <- results
+		close(d.called)
+	case strings.HasSuffix(r.URL.Path, "/build"):
+		// the build uses the session until it finishes
+		<-d.called
+		d.build <- r
+		w.Write([]byte(`{"stream":"done"}`))
+	}
+}
+
+func callGRPC(cc *http2.ClientConn, call grpcCall) (grpcResult, error) {
+	var buf bytes.Buffer
+	for _, msg := range call.messages {
+		var header [5]byte
+		binary.BigEndian.PutUint32(header[1:], uint32(len(msg)))
+		buf.Write(header[:])
+		buf.Write(msg)
+	}
+	body, w := io.Pipe()
+	go func() {
+		w.Write(buf.Bytes())
+		if call.replies == 0 {
+			w.Close()
+		}
+	}()
+	defer w.Close()
+	req, _ := http.NewRequest(http.MethodPost, "http://session"+call.method, body)
+	req.Header.Set("Content-Type", "application/grpc")
+	req.Header.Set("Te", "trailers")
+	for key, values := range call.header {
+		req.Header[key] = values
+	}
+	resp, err := cc.RoundTrip(req)
+	if err != nil {
+		return grpcResult{}, err
+	}
+	defer resp.Body.Close()
+	var result grpcResult
+	for {
+		var header [5]byte
+		if _, err := io.ReadFull(resp.Body, header[:]); err == io.EOF {
+			break
+		} else if err != nil {
+			return grpcResult{}, err
+		}
+		msg := make([]byte, binary.BigEndian.Uint32(header[1:]))
+		if _, err := io.ReadFull(resp.Body, msg); err != nil {
+			return grpcResult{}, err
+		}
+		result.messages = append(result.messages, msg)
+		if len(result.messages) == call.replies {
+			w.Close()
+		}
+	}
+	result.status = resp.Header.Get("Grpc-Status")
+	if result.status == "" {
+		result.status = resp.Trailer.Get("Grpc-Status")
+	}
+	return result, nil
+}
+
+func TestBuildImageSecrets(t *testing.T) {
+	t.Parallel()
+	daemon := newSessionDaemon(t,
+		grpcCall{method: "/grpc.health.v1.Health/Check", messages: [][]byte{nil}},
+		// GetSecretRequest{ID: "token", Annotations: {"a": "b"}}
+		grpcCall{method: "/moby.buildkit.secrets.v1.Secrets/GetSecret", messages: [][]byte{[]byte("\x0a\x05token\x12\x06\x0a\x01a\x12\x01b")}},
+		grpcCall{method: "/moby.buildkit.secrets.v1.Secrets/GetSecret", messages: [][]byte{[]byte("\x0a\x07missing")}},
+		grpcCall{method: "/moby.filesync.v1.FileSync/DiffCopy", messages: [][]byte{nil}},
+	)
+	server := httptest.NewServer(daemon)
+	defer server.Close()
+	client, err := NewVersionedClient(server.URL, "1.39")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.SkipServerVersionCheck = true
+	client.serverAPIVersion = apiVersion139
+	var buf bytes.Buffer
+	errC := make(chan error, 1)
+	go func() {
+		errC <- client.BuildImage(BuildImageOptions{
+			Name:         "secret-image",
+			Version:      BuilderBuildKit,
+			Secrets:      map[string][]byte{"token": []byte("s3cr3t")},
+			InputStream:  &buf,
+			OutputStream: ioutil.Discard,
+		})
+	}()
+	session := <-daemon.session
+	results := <-daemon.results
+	build := <-daemon.build
+	if err := <-errC; err != nil {
+		t.Fatal(err)
+	}
+	id := session.Header.Get("X-Docker-Expose-Session-Uuid")
+	if id == "" || build.URL.Query().Get("session") != id {
+		t.Errorf("BuildImage: wrong session. Want %q. Got %q.", id, build.URL.Query().Get("session"))
+	}
+	methods := session.Header["X-Docker-Expose-Session-Grpc-Method"]
+	sort.Strings(methods)
+	expectedMethods := []string{"/grpc.health.v1.Health/Check", "/grpc.health.v1.Health/Watch", "/moby.buildkit.secrets.v1.Secrets/GetSecret"}
+	if !reflect.DeepEqual(methods, expectedMethods) {
+		t.Errorf("BuildImage: wrong session methods. Want %#v. Got %#v.", expectedMethods, methods)
+	}
+	expected := []grpcResult{
+		{messages: [][]byte{{0x08, 0x01}}, status: "0"},
+		{messages: [][]byte{[]byte("\x0a\x06s3cr3t")}, status: "0"},
+		{status: "5"},
+		{status: "12"},
+	}
+	if !reflect.DeepEqual(results, expected) {
+		t.Errorf("BuildImage: wrong results from the session.\nWant %#v.\nGot  %#v.", expected, results)
+	}
+	if strings.Contains(build.URL.RawQuery, "s3cr3t") {
+		t.Errorf("BuildImage: the secret was sent in the build request: %s", build.URL.RawQuery)
+	}
+}
+
+func TestBuildImageSecretsErrors(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		opts BuildImageOptions
+		err  error
+	}{
+		{BuildImageOptions{Secrets: map[string][]byte{"token": nil}}, ErrBuildSessionRequiresBuildKit},
+		{BuildImageOptions{Secrets: map[string][]byte{"token": nil}, Version: BuilderV1}, ErrBuildSessionRequiresBuildKit},
+		{BuildImageOptions{Secrets: map[string][]byte{"token": nil}, Version: BuilderBuildKit, SessionID: "abc"}, ErrBuildSessionWithSessionID},
+		{BuildImageOptions{SSHAgents: []string{"default=/tmp/agent.sock"}}, ErrBuildSessionRequiresBuildKit},
+		{BuildImageOptions{SSHAgents: []string{"default=/tmp/agent.sock"}, Version: BuilderBuildKit, SessionID: "abc"}, ErrBuildSessionWithSessionID},
+		{BuildImageOptions{SSHAgents: []string{"default="}, Version: BuilderBuildKit}, ErrInvalidSSHAgent},
+		{BuildImageOptions{SSHAgents: []string{"default=/nonexistent/agent.sock"}, Version: BuilderBuildKit}, ErrInvalidSSHAgent},
+	}
+	for _, tt := range tests {
+		fakeRT := &FakeRoundTripper{message: "", status: http.StatusOK}
+		client := newTestClient(fakeRT)
+		tt.opts.Remote = "github.com/user/repo"
+		tt.opts.OutputStream = ioutil.Discard
+		if err := client.BuildImage(tt.opts); !errors.Is(err, tt.err) {
+			t.Errorf("BuildImage: wrong error. Want %#v. Got %#v.", tt.err, err)
+		}
+		if len(fakeRT.requests) != 0 {
+			t.Errorf("BuildImage: expected no requests, got %d", len(fakeRT.requests))
+		}
+	}
+}
+
+func TestBuildImageSSHAgents(t *testing.T) {
+	t.Parallel()
+	if runtime.GOOS == "windows" {
+		t.Skip("no unix sockets on windows")
+	}
+	dir, err := ioutil.TempDir("", "ssh-agent")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	socket := filepath.Join(dir, "agent.sock")
+	l, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go func() {
+		keyring := agent.NewKeyring()
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			agent.ServeAgent(keyring, conn)
+			conn.Close()
+		}
+	}()
+	daemon := newSessionDaemon(t,
+		grpcCall{method: "/moby.sshforward.v1.SSH/CheckAgent", messages: [][]byte{nil}},
+		grpcCall{method: "/moby.sshforward.v1.SSH/CheckAgent", messages: [][]byte{[]byte("\x0a\x07missing")}},
+		// BytesMessage{Data: SSH_AGENTC_REQUEST_IDENTITIES}
+		grpcCall{
+			method:   "/moby.sshforward.v1.SSH/ForwardAgent",
+			header:   http.Header{"Buildkit.ssh.id": {"default"}},
+			messages: [][]byte{[]byte("\x0a\x05\x00\x00\x00\x01\x0b")},
+			replies:  2,
+		},
+		grpcCall{
+			method:   "/moby.sshforward.v1.SSH/ForwardAgent",
+			header:   http.Header{"Buildkit.ssh.id": {"missing"}},
+			messages: [][]byte{[]byte("\x0a\x05\x00\x00\x00\x01\x0b")},
+		},
+	)
+	server := httptest.NewServer(daemon)
+	defer server.Close()
+	client, err := NewVersionedClient(server.URL, "1.39")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.SkipServerVersionCheck = true
+	client.serverAPIVersion = apiVersion139
+	var buf bytes.Buffer
+	errC := make(chan error, 1)
+	go func() {
+		errC <- client.BuildImage(BuildImageOptions{
+			Name:         "ssh-image",
+			Version:      BuilderBuildKit,
+			SSHAgents:    []string{"default=" + socket},
+			InputStream:  &buf,
+			OutputStream: ioutil.Discard,
+		})
+	}()
+	session := <-daemon.session
+	results := <-daemon.results
+	<-daemon.build
+	if err := <-errC; err != nil {
+		t.Fatal(err)
+	}
+	methods := session.Header["X-Docker-Expose-Session-Grpc-Method"]
+	sort.Strings(methods)
+	expectedMethods := []string{"/grpc.health.v1.Health/Check", "/grpc.health.v1.Health/Watch", "/moby.sshforward.v1.SSH/CheckAgent", "/moby.sshforward.v1.SSH/ForwardAgent"}
+	if !reflect.DeepEqual(methods, expectedMethods) {
+		t.Errorf("BuildImage: wrong session methods. Want %#v. Got %#v.", expectedMethods, methods)
+	}
+	expected := []grpcResult{
+		{messages: [][]byte{{}}, status: "0"},
+		{status: "2"},
+		// BytesMessage{Data: SSH_AGENT_IDENTITIES_ANSWER without keys},
+		// BuildKit ends forwarded streams with the EOF of the agent
+		// connection as an error
+		{messages: [][]byte{[]byte("\x0a\x04\x00\x00\x00\x05"), []byte("\x0a\x05\x0c\x00\x00\x00\x00")}, status: "2"},
+		{status: "2"},
+	}
+	if !reflect.DeepEqual(results, expected) {
+		t.Errorf("BuildImage: wrong results from the session.\nWant %#v.\nGot  %#v.", expected, results)
+	}
+}
+
+func TestBuildImageSSHAgentUnavailable(t *testing.T) {
+	t.Parallel()
+	if runtime.GOOS == "windows" {
+		t.Skip("no unix sockets on windows")
+	}
+	dir, err := ioutil.TempDir("", "ssh-agent")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	socket := filepath.Join(dir, "agent.sock")
+	l, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// an agent that is gone once the build runs, leaving its socket behind
+	l.(*net.UnixListener).SetUnlinkOnClose(false)
+	l.Close()
+	daemon := newSessionDaemon(t,
+		grpcCall{
+			method:   "/moby.sshforward.v1.SSH/ForwardAgent",
+			header:   http.Header{"Buildkit.ssh.id": {"default"}},
+			messages: [][]byte{[]byte("\x0a\x05\x00\x00\x00\x01\x0b")},
+		},
+	)
+	server := httptest.NewServer(daemon)
+	defer server.Close()
+	client, err := NewVersionedClient(server.URL, "1.39")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.SkipServerVersionCheck = true
+	client.serverAPIVersion = apiVersion139
+	var buf bytes.Buffer
+	errC := make(chan error, 1)
+	go func() {
+		errC <- client.BuildImage(BuildImageOptions{
+			Name:         "ssh-image",
+			Version:      BuilderBuildKit,
+			SSHAgents:    []string{"default=" + socket},
+			InputStream:  &buf,
+			OutputStream: ioutil.Discard,
+		})
+	}()
+	<-daemon.session
+	results := <-daemon.results
+	<-daemon.build
+	if err := <-errC; err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || len(results[0].messages) != 0 || results[0].status == "0" || results[0].status == "" {
+		t.Errorf("BuildImage: the failure to reach the agent didn't end the stream with an error: %#v", results)
+	}
+}
+
+func TestParseSSHAgents(t *testing.T) {
+	t.Parallel()
+	agents, err := parseSSHAgents([]string{"default=/tmp/agent.sock", "work=/tmp/id_rsa,/tmp/id_ed25519"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []sshprovider.AgentConfig{
+		{ID: "default", Paths: []string{"/tmp/agent.sock"}},
+		{ID: "work", Paths: []string{"/tmp/id_rsa", "/tmp/id_ed25519"}},
+	}
+	if !reflect.DeepEqual(agents, expected) {
+		t.Errorf("parseSSHAgents: wrong agents. Want %#v. Got %#v.", expected, agents)
+	}
+	tests := [][]string{
+		{"=/tmp/agent.sock"},
+		{"default="},
+		{"default=/tmp/a.sock", "default=/tmp/b.sock"},
+	}
+	for _, specs := range tests {
+		if _, err := parseSSHAgents(specs); !errors.Is(err, ErrInvalidSSHAgent) {
+			t.Errorf("parseSSHAgents(%q): wrong error. Want %#v. Got %#v.", specs, ErrInvalidSSHAgent, err)
+		}
+	}
+}
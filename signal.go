@@ -4,6 +4,17 @@
 
 package docker
 
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidSignal is the error returned by ParseSignal when the given name
+// doesn't match any known signal.
+var ErrInvalidSignal = errors.New("invalid signal")
+
 // Signal represents a signal that can be send to the container on
 // KillContainer call.
 type Signal int
@@ -47,3 +58,58 @@ const (
 	SIGXCPU   = Signal(0x18)
 	SIGXFSZ   = Signal(0x19)
 )
+
+var signalNames = map[string]Signal{
+	"ABRT":   SIGABRT,
+	"ALRM":   SIGALRM,
+	"BUS":    SIGBUS,
+	"CHLD":   SIGCHLD,
+	"CLD":    SIGCLD,
+	"CONT":   SIGCONT,
+	"FPE":    SIGFPE,
+	"HUP":    SIGHUP,
+	"ILL":    SIGILL,
+	"INT":    SIGINT,
+	"IO":     SIGIO,
+	"IOT":    SIGIOT,
+	"KILL":   SIGKILL,
+	"PIPE":   SIGPIPE,
+	"POLL":   SIGPOLL,
+	"PROF":   SIGPROF,
+	"PWR":    SIGPWR,
+	"QUIT":   SIGQUIT,
+	"SEGV":   SIGSEGV,
+	"STKFLT": SIGSTKFLT,
+	"STOP":   SIGSTOP,
+	"SYS":    SIGSYS,
+	"TERM":   SIGTERM,
+	"TRAP":   SIGTRAP,
+	"TSTP":   SIGTSTP,
+	"TTIN":   SIGTTIN,
+	"TTOU":   SIGTTOU,
+	"UNUSED": SIGUNUSED,
+	"URG":    SIGURG,
+	"USR1":   SIGUSR1,
+	"USR2":   SIGUSR2,
+	"VTALRM": SIGVTALRM,
+	"WINCH":  SIGWINCH,
+	"XCPU":   SIGXCPU,
+	"XFSZ":   SIGXFSZ,
+}
+
+// ParseSignal returns the signal with the given name, in the same formats
+// accepted by `docker kill -s`: the name with or without the "SIG" prefix,
+// in any case ("SIGTERM", "TERM" or "term"), or the signal number ("15").
+func ParseSignal(name string) (Signal, error) {
+	if n, err := strconv.Atoi(name); err == nil {
+		if n <= 0 || n > 64 {
+			return 0, fmt.Errorf("%w: %q", ErrInvalidSignal, name)
+		}
+		return Signal(n), nil
+	}
+	sig, ok := signalNames[strings.TrimPrefix(strings.ToUpper(name), "SIG")]
+	if !ok {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidSignal, name)
+	}
+	return sig, nil
+}
@@ -0,0 +1,44 @@
+// Copyright 2014 go-dockerclient authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package docker
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseSignal(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name     string
+		expected Signal
+	}{
+		{"SIGTERM", SIGTERM},
+		{"TERM", SIGTERM},
+		{"sighup", SIGHUP},
+		{"usr1", SIGUSR1},
+		{"9", SIGKILL},
+		{"34", Signal(34)},
+	}
+	for _, test := range tests {
+		sig, err := ParseSignal(test.name)
+		if err != nil {
+			t.Errorf("ParseSignal(%q): unexpected error: %v", test.name, err)
+			continue
+		}
+		if sig != test.expected {
+			t.Errorf("ParseSignal(%q): wrong signal. Want %d. Got %d.", test.name, test.expected, sig)
+		}
+	}
+}
+
+func TestParseSignalInvalid(t *testing.T) {
+	t.Parallel()
+	for _, name := range []string{"", "SIG", "SIGFOO", "0", "-1", "65"} {
+		if _, err := ParseSignal(name); !errors.Is(err, ErrInvalidSignal) {
+			t.Errorf("ParseSignal(%q): wrong error. Want %v. Got %v.", name, ErrInvalidSignal, err)
+		}
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"net/http"
 	"net/url"
 	"strconv"
+	"strings"
 
 	"github.com/docker/docker/api/types/swarm"
 )
@@ -20,12 +21,25 @@ var (
 	// when the node is already part of a Swarm.
 	ErrNodeAlreadyInSwarm = errors.New("node already in a Swarm")
 
-	// ErrNodeNotInSwarm is the error returned by LeaveSwarm and UpdateSwarm
-	// when the node is not part of a Swarm.
+	// ErrNodeNotInSwarm is the error returned by LeaveSwarm, UpdateSwarm and
+	// the other Swarm operations when the node is not part of a Swarm.
 	ErrNodeNotInSwarm = errors.New("node is not in a Swarm")
+
+	// ErrSwarmLocked is the error returned by Swarm operations when the
+	// Swarm has autolock enabled and the manager hasn't been unlocked since
+	// it restarted. Use UnlockSwarm to unlock it.
+	ErrSwarmLocked = errors.New("swarm is locked")
 )
 
 // InitSwarmOptions specify parameters to the InitSwarm function.
+//
+// DefaultAddrPool and SubnetSize (API 1.39 and above) control the subnets
+// allocated to overlay networks created without an IPAM configuration,
+// including the ingress network, which by default come from 10.0.0.0/8 in
+// /24 blocks. DataPathPort (API 1.40 and above) changes the UDP port used for
+// VXLAN traffic, 4789 by default. The daemon reports these settings back in
+// the ClusterInfo returned by InspectSwarm.
+//
 // See https://goo.gl/hzkgWu for more details.
 type InitSwarmOptions struct {
 	swarm.InitRequest
@@ -77,9 +91,10 @@ func (c *Client) JoinSwarm(opts JoinSwarmOptions) error {
 		if errors.As(err, &e) && (e.Status == http.StatusNotAcceptable || e.Status == http.StatusServiceUnavailable) {
 			return ErrNodeAlreadyInSwarm
 		}
+		return err
 	}
 	resp.Body.Close()
-	return err
+	return nil
 }
 
 // LeaveSwarmOptions specify parameters to the LeaveSwarm function.
@@ -99,23 +114,28 @@ func (c *Client) LeaveSwarm(opts LeaveSwarmOptions) error {
 		context: opts.Context,
 	})
 	if err != nil {
-		var e *Error
-		if errors.As(err, &e) && (e.Status == http.StatusNotAcceptable || e.Status == http.StatusServiceUnavailable) {
-			return ErrNodeNotInSwarm
-		}
+		return swarmError(err)
 	}
 	resp.Body.Close()
-	return err
+	return nil
 }
 
 // UpdateSwarmOptions specify parameters to the UpdateSwarm function.
+//
+// Version must be the current Version.Index of the Swarm, as returned by
+// InspectSwarm; the daemon rejects updates based on an outdated version. The
+// rotation flags generate new join tokens (or a new unlock key, when
+// autolock is enabled) without re-initializing the cluster, which is useful
+// when a token is compromised.
+//
 // See https://goo.gl/vFbq36 for more details.
 type UpdateSwarmOptions struct {
-	Version            int
-	RotateWorkerToken  bool
-	RotateManagerToken bool
-	Swarm              swarm.Spec
-	Context            context.Context
+	Version                int
+	RotateWorkerToken      bool
+	RotateManagerToken     bool
+	RotateManagerUnlockKey bool
+	Swarm                  swarm.Spec
+	Context                context.Context
 }
 
 // UpdateSwarm updates a Swarm.
@@ -125,6 +145,9 @@ func (c *Client) UpdateSwarm(opts UpdateSwarmOptions) error {
 	params.Set("version", strconv.Itoa(opts.Version))
 	params.Set("rotateWorkerToken", strconv.FormatBool(opts.RotateWorkerToken))
 	params.Set("rotateManagerToken", strconv.FormatBool(opts.RotateManagerToken))
+	if opts.RotateManagerUnlockKey {
+		params.Set("rotateManagerUnlockKey", "true")
+	}
 	path := "/swarm/update?" + params.Encode()
 	resp, err := c.do(http.MethodPost, path, doOptions{
 		data:      opts.Swarm,
@@ -132,13 +155,10 @@ func (c *Client) UpdateSwarm(opts UpdateSwarmOptions) error {
 		context:   opts.Context,
 	})
 	if err != nil {
-		var e *Error
-		if errors.As(err, &e) && (e.Status == http.StatusNotAcceptable || e.Status == http.StatusServiceUnavailable) {
-			return ErrNodeNotInSwarm
-		}
+		return swarmError(err)
 	}
 	resp.Body.Close()
-	return err
+	return nil
 }
 
 // InspectSwarm inspects a Swarm.
@@ -149,13 +169,68 @@ func (c *Client) InspectSwarm(ctx context.Context) (swarm.Swarm, error) {
 		context: ctx,
 	})
 	if err != nil {
-		var e *Error
-		if errors.As(err, &e) && (e.Status == http.StatusNotAcceptable || e.Status == http.StatusServiceUnavailable) {
-			return response, ErrNodeNotInSwarm
-		}
-		return response, err
+		return response, swarmError(err)
 	}
 	defer resp.Body.Close()
 	err = json.NewDecoder(resp.Body).Decode(&response)
 	return response, err
 }
+
+// UnlockSwarmOptions specify parameters to the UnlockSwarm function.
+type UnlockSwarmOptions struct {
+	UnlockKey string
+	Context   context.Context
+}
+
+// UnlockSwarm unlocks a manager of a Swarm with autolock enabled, which is
+// required after the manager restarts.
+//
+// See https://docs.docker.com/engine/swarm/swarm_manager_locking/ for more
+// details.
+func (c *Client) UnlockSwarm(opts UnlockSwarmOptions) error {
+	resp, err := c.do(http.MethodPost, "/swarm/unlock", doOptions{
+		data:    swarm.UnlockRequest{UnlockKey: opts.UnlockKey},
+		context: opts.Context,
+	})
+	if err != nil {
+		return swarmError(err)
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// GetSwarmUnlockKey returns the key used to unlock the managers of a Swarm
+// with autolock enabled. The key is empty when autolock is disabled.
+//
+// See https://docs.docker.com/engine/swarm/swarm_manager_locking/ for more
+// details.
+func (c *Client) GetSwarmUnlockKey(ctx context.Context) (string, error) {
+	resp, err := c.do(http.MethodGet, "/swarm/unlockkey", doOptions{context: ctx})
+	if err != nil {
+		return "", swarmError(err)
+	}
+	defer resp.Body.Close()
+	var response struct {
+		UnlockKey string
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return "", err
+	}
+	return response.UnlockKey, nil
+}
+
+// swarmError maps the errors returned by the daemon when the node is not part
+// of a Swarm, or when the Swarm is locked, to the corresponding sentinel.
+func swarmError(err error) error {
+	var e *Error
+	if !errors.As(err, &e) {
+		return err
+	}
+	if e.Status == http.StatusServiceUnavailable && strings.Contains(strings.ToLower(e.Message), "locked") {
+		return ErrSwarmLocked
+	}
+	if e.Status == http.StatusNotAcceptable || e.Status == http.StatusServiceUnavailable {
+		return ErrNodeNotInSwarm
+	}
+	return err
+}
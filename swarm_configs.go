@@ -15,6 +15,10 @@ import (
 	"github.com/docker/docker/api/types/swarm"
 )
 
+// ErrConfigAlreadyExists is the error returned by CreateConfig when a config
+// with the same name already exists.
+var ErrConfigAlreadyExists = errors.New("config already exists")
+
 // NoSuchConfig is the error returned when a given config does not exist.
 type NoSuchConfig struct {
 	ID  string
@@ -54,6 +58,10 @@ func (c *Client) CreateConfig(opts CreateConfigOptions) (*swarm.Config, error) {
 		context:   opts.Context,
 	})
 	if err != nil {
+		var e *Error
+		if errors.As(err, &e) && e.Status == http.StatusConflict {
+			return nil, &sentinelError{sentinel: ErrConfigAlreadyExists, err: e}
+		}
 		return nil, err
 	}
 	defer resp.Body.Close()
@@ -7,6 +7,7 @@ package docker
 import (
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/url"
 	"reflect"
@@ -51,6 +52,19 @@ func TestCreateConfig(t *testing.T) {
 	}
 }
 
+func TestCreateConfigAlreadyExists(t *testing.T) {
+	t.Parallel()
+	client := newTestClient(&FakeRoundTripper{message: "config nginx.conf already exists", status: http.StatusConflict})
+	_, err := client.CreateConfig(CreateConfigOptions{})
+	if !errors.Is(err, ErrConfigAlreadyExists) {
+		t.Errorf("CreateConfig: wrong error. Want %#v. Got %#v.", ErrConfigAlreadyExists, err)
+	}
+	var e *Error
+	if !errors.As(err, &e) || e.Status != http.StatusConflict || e.Message != "config nginx.conf already exists" {
+		t.Errorf("CreateConfig: the error doesn't wrap the API error: %#v", err)
+	}
+}
+
 func TestRemoveConfig(t *testing.T) {
 	t.Parallel()
 	fakeRT := &FakeRoundTripper{message: "", status: http.StatusOK}
@@ -8,6 +8,7 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/url"
 	"strconv"
@@ -15,6 +16,10 @@ import (
 	"github.com/docker/docker/api/types/swarm"
 )
 
+// ErrNodeAlreadyInRole is the error returned by PromoteNode and DemoteNode
+// when the node already has the requested role.
+var ErrNodeAlreadyInRole = errors.New("node is already in the requested role")
+
 // NoSuchNode is the error returned when a given node does not exist.
 type NoSuchNode struct {
 	ID  string
@@ -30,6 +35,9 @@ func (err *NoSuchNode) Error() string {
 
 // ListNodesOptions specify parameters to the ListNodes function.
 //
+// Filters are applied by the daemon, and accept the same keys as `docker node
+// ls --filter`, e.g. {"role": {"manager"}} or {"membership": {"accepted"}}.
+//
 // See http://goo.gl/3K4GwU for more details.
 type ListNodesOptions struct {
 	Filters map[string][]string
@@ -105,6 +113,45 @@ func (c *Client) UpdateNode(id string, opts UpdateNodeOptions) error {
 	return nil
 }
 
+// PromoteNode changes the role of the given node to manager, like `docker
+// node promote`. It returns ErrNodeAlreadyInRole if the node is already a
+// manager.
+func (c *Client) PromoteNode(id string) error {
+	return c.setNodeRole(id, swarm.NodeRoleManager)
+}
+
+// DemoteNode changes the role of the given node to worker, like `docker node
+// demote`. It returns ErrNodeAlreadyInRole if the node is already a worker.
+func (c *Client) DemoteNode(id string) error {
+	return c.setNodeRole(id, swarm.NodeRoleWorker)
+}
+
+// setNodeRole updates the role of the node using the version it was
+// inspected at, so the update fails instead of overwriting a concurrent
+// change to the node.
+func (c *Client) setNodeRole(id string, role swarm.NodeRole) error {
+	node, err := c.InspectNode(id)
+	if err != nil {
+		return err
+	}
+	current := node.Spec.Role
+	if current == "" {
+		current = swarm.NodeRoleWorker
+		if node.ManagerStatus != nil {
+			current = swarm.NodeRoleManager
+		}
+	}
+	if current == role {
+		return fmt.Errorf("%w: node %s is already a %s", ErrNodeAlreadyInRole, id, role)
+	}
+	spec := node.Spec
+	spec.Role = role
+	return c.UpdateNode(id, UpdateNodeOptions{
+		NodeSpec: spec,
+		Version:  node.Version.Index,
+	})
+}
+
 // RemoveNodeOptions specify parameters to the RemoveNode function.
 //
 // See http://goo.gl/0SNvYg for more details.
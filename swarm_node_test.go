@@ -6,6 +6,7 @@ package docker
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/url"
 	"reflect"
@@ -223,6 +224,46 @@ func TestUpdateNodeNotFound(t *testing.T) {
 	expectNoSuchNode(t, "notfound", err)
 }
 
+func TestPromoteNode(t *testing.T) {
+	t.Parallel()
+	fakeRT := &FakeRoundTripper{message: `{"ID":"node1","Version":{"Index":7},"Spec":{"Role":"worker","Availability":"active"}}`, status: http.StatusOK}
+	client := newTestClient(fakeRT)
+	if err := client.PromoteNode("node1"); err != nil {
+		t.Fatal(err)
+	}
+	if len(fakeRT.requests) != 2 {
+		t.Fatalf("PromoteNode: wrong number of requests. Want 2. Got %d.", len(fakeRT.requests))
+	}
+	req := fakeRT.requests[1]
+	if req.Method != http.MethodPost {
+		t.Errorf("PromoteNode: wrong HTTP method. Want %q. Got %q.", http.MethodPost, req.Method)
+	}
+	if version := req.URL.Query().Get("version"); version != "7" {
+		t.Errorf("PromoteNode: wrong version. Want %q. Got %q.", "7", version)
+	}
+	var spec swarm.NodeSpec
+	if err := json.NewDecoder(req.Body).Decode(&spec); err != nil {
+		t.Fatal(err)
+	}
+	expected := swarm.NodeSpec{Role: swarm.NodeRoleManager, Availability: swarm.NodeAvailabilityActive}
+	if !reflect.DeepEqual(spec, expected) {
+		t.Errorf("PromoteNode: wrong spec. Want %#v. Got %#v.", expected, spec)
+	}
+}
+
+func TestDemoteNodeAlreadyWorker(t *testing.T) {
+	t.Parallel()
+	fakeRT := &FakeRoundTripper{message: `{"ID":"node1","Version":{"Index":7},"Spec":{"Role":"worker"}}`, status: http.StatusOK}
+	client := newTestClient(fakeRT)
+	err := client.DemoteNode("node1")
+	if !errors.Is(err, ErrNodeAlreadyInRole) {
+		t.Errorf("DemoteNode: wrong error. Want %v. Got %v.", ErrNodeAlreadyInRole, err)
+	}
+	if len(fakeRT.requests) != 1 {
+		t.Errorf("DemoteNode: wrong number of requests. Want 1. Got %d.", len(fakeRT.requests))
+	}
+}
+
 func TestRemoveNode(t *testing.T) {
 	t.Parallel()
 	fakeRT := &FakeRoundTripper{message: "", status: http.StatusOK}
@@ -248,3 +289,20 @@ func TestRemoveNodeNotFound(t *testing.T) {
 	err := client.RemoveNode(RemoveNodeOptions{ID: "notfound"})
 	expectNoSuchNode(t, "notfound", err)
 }
+
+func TestListNodesFilters(t *testing.T) {
+	t.Parallel()
+	fakeRT := &FakeRoundTripper{message: "[]", status: http.StatusOK}
+	client := newTestClient(fakeRT)
+	filters := map[string][]string{"role": {"manager"}, "membership": {"accepted"}}
+	if _, err := client.ListNodes(ListNodesOptions{Filters: filters}); err != nil {
+		t.Fatal(err)
+	}
+	var got map[string][]string
+	if err := json.Unmarshal([]byte(fakeRT.requests[0].URL.Query().Get("filters")), &got); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, filters) {
+		t.Errorf("ListNodes: wrong filters. Want %#v. Got %#v.", filters, got)
+	}
+}
@@ -15,6 +15,10 @@ import (
 	"github.com/docker/docker/api/types/swarm"
 )
 
+// ErrSecretAlreadyExists is the error returned by CreateSecret when a secret
+// with the same name already exists.
+var ErrSecretAlreadyExists = errors.New("secret already exists")
+
 // NoSuchSecret is the error returned when a given secret does not exist.
 type NoSuchSecret struct {
 	ID  string
@@ -40,6 +44,9 @@ type CreateSecretOptions struct {
 // CreateSecret creates a new secret, returning the secret instance
 // or an error in case of failure.
 //
+// The Data field of the spec holds the raw secret payload; it is sent
+// base64-encoded, as expected by the daemon.
+//
 // See https://goo.gl/KrVjHz for more details.
 func (c *Client) CreateSecret(opts CreateSecretOptions) (*swarm.Secret, error) {
 	headers, err := headersWithAuth(opts.Auth)
@@ -54,6 +61,10 @@ func (c *Client) CreateSecret(opts CreateSecretOptions) (*swarm.Secret, error) {
 		context:   opts.Context,
 	})
 	if err != nil {
+		var e *Error
+		if errors.As(err, &e) && e.Status == http.StatusConflict {
+			return nil, &sentinelError{sentinel: ErrSecretAlreadyExists, err: e}
+		}
 		return nil, err
 	}
 	defer resp.Body.Close()
@@ -7,6 +7,7 @@ package docker
 import (
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/url"
 	"reflect"
@@ -51,6 +52,39 @@ func TestCreateSecret(t *testing.T) {
 	}
 }
 
+func TestCreateSecretEncodesData(t *testing.T) {
+	t.Parallel()
+	fakeRT := &FakeRoundTripper{message: `{"Id":"abc"}`, status: http.StatusCreated}
+	client := newTestClient(fakeRT)
+	opts := CreateSecretOptions{SecretSpec: swarm.SecretSpec{
+		Annotations: swarm.Annotations{Name: "db-password"},
+		Data:        []byte("s3cr3t"),
+	}}
+	if _, err := client.CreateSecret(opts); err != nil {
+		t.Fatal(err)
+	}
+	var gotBody map[string]interface{}
+	if err := json.NewDecoder(fakeRT.requests[0].Body).Decode(&gotBody); err != nil {
+		t.Fatal(err)
+	}
+	if expected := "czNjcjN0"; gotBody["Data"] != expected {
+		t.Errorf("CreateSecret: wrong Data in body. Want %q. Got %v.", expected, gotBody["Data"])
+	}
+}
+
+func TestCreateSecretAlreadyExists(t *testing.T) {
+	t.Parallel()
+	client := newTestClient(&FakeRoundTripper{message: "secret db-password already exists", status: http.StatusConflict})
+	_, err := client.CreateSecret(CreateSecretOptions{})
+	if !errors.Is(err, ErrSecretAlreadyExists) {
+		t.Errorf("CreateSecret: wrong error. Want %#v. Got %#v.", ErrSecretAlreadyExists, err)
+	}
+	var e *Error
+	if !errors.As(err, &e) || e.Status != http.StatusConflict || e.Message != "secret db-password already exists" {
+		t.Errorf("CreateSecret: the error doesn't wrap the API error: %#v", err)
+	}
+}
+
 func TestRemoveSecret(t *testing.T) {
 	t.Parallel()
 	fakeRT := &FakeRoundTripper{message: "", status: http.StatusOK}
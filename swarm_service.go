@@ -8,7 +8,9 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
+	"io/ioutil"
 	"net/http"
 	"time"
 
@@ -91,6 +93,17 @@ func (c *Client) RemoveService(opts RemoveServiceOptions) error {
 
 // UpdateServiceOptions specify parameters to the UpdateService function.
 //
+// Version must be set to the current version index of the service, as
+// returned by InspectService. Setting Rollback to "previous" makes the daemon
+// revert the service to its PreviousSpec, ignoring the given ServiceSpec
+// (requires Docker API 1.27 or greater).
+//
+// Setting ForceUpdate makes the daemon redeploy the tasks of the service
+// even if the spec didn't change, for example to pull a new version of a
+// mutable tag like "latest". It increments TaskTemplate.ForceUpdate, so
+// ServiceSpec must be the current spec of the service, as returned by
+// InspectService.
+//
 // See https://goo.gl/wu3MmS for more details.
 type UpdateServiceOptions struct {
 	Auth              AuthConfiguration `qs:"-"`
@@ -98,6 +111,7 @@ type UpdateServiceOptions struct {
 	Context           context.Context
 	Version           uint64
 	Rollback          string
+	ForceUpdate       bool `qs:"-"`
 }
 
 // UpdateService updates the service at ID with the options
@@ -108,6 +122,9 @@ func (c *Client) UpdateService(id string, opts UpdateServiceOptions) error {
 	if err != nil {
 		return err
 	}
+	if opts.ForceUpdate {
+		opts.ServiceSpec.TaskTemplate.ForceUpdate++
+	}
 	resp, err := c.do(http.MethodPost, "/services/"+id+"/update?"+queryString(opts), doOptions{
 		headers:   headers,
 		data:      opts.ServiceSpec,
@@ -125,25 +142,68 @@ func (c *Client) UpdateService(id string, opts UpdateServiceOptions) error {
 	return nil
 }
 
+// ErrServiceNotReplicated is the error returned by ScaleService for services
+// that aren't in replicated mode, like global services, which run one task
+// on every node and can't be scaled.
+var ErrServiceNotReplicated = errors.New("service is not in replicated mode")
+
+// ScaleService sets the number of replicas of the given replicated service,
+// like `docker service scale`, and returns the updated service. The update is
+// sent with the version of the service it was inspected at, so it fails
+// instead of overwriting a concurrent change to the service.
+func (c *Client) ScaleService(id string, replicas uint64) (*swarm.Service, error) {
+	service, err := c.InspectService(id)
+	if err != nil {
+		return nil, err
+	}
+	if service.Spec.Mode.Replicated == nil {
+		return nil, fmt.Errorf("%w: %s", ErrServiceNotReplicated, id)
+	}
+	spec := service.Spec
+	spec.Mode.Replicated = &swarm.ReplicatedService{Replicas: &replicas}
+	err = c.UpdateService(service.ID, UpdateServiceOptions{
+		ServiceSpec: spec,
+		Version:     service.Version.Index,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return c.InspectService(service.ID)
+}
+
 // InspectService returns information about a service by its ID.
 //
 // See https://goo.gl/dHmr75 for more details.
 func (c *Client) InspectService(id string) (*swarm.Service, error) {
+	service, _, err := c.InspectServiceWithRaw(id)
+	return service, err
+}
+
+// InspectServiceWithRaw returns information about a service by its ID, along
+// with the JSON document sent by the daemon, which may include fields that
+// swarm.Service doesn't model.
+//
+// See https://goo.gl/dHmr75 for more details.
+func (c *Client) InspectServiceWithRaw(id string) (*swarm.Service, []byte, error) {
 	path := "/services/" + id
 	resp, err := c.do(http.MethodGet, path, doOptions{})
 	if err != nil {
 		var e *Error
 		if errors.As(err, &e) && e.Status == http.StatusNotFound {
-			return nil, &NoSuchService{ID: id}
+			return nil, nil, &NoSuchService{ID: id}
 		}
-		return nil, err
+		return nil, nil, err
 	}
 	defer resp.Body.Close()
+	raw, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, err
+	}
 	var service swarm.Service
-	if err := json.NewDecoder(resp.Body).Decode(&service); err != nil {
-		return nil, err
+	if err := json.Unmarshal(raw, &service); err != nil {
+		return nil, nil, err
 	}
-	return &service, nil
+	return &service, raw, nil
 }
 
 // ListServicesOptions specify parameters to the ListServices function.
@@ -151,7 +211,13 @@ func (c *Client) InspectService(id string) (*swarm.Service, error) {
 // See https://goo.gl/DwvNMd for more details.
 type ListServicesOptions struct {
 	Filters map[string][]string
-	Status  bool
+
+	// Status populates the ServiceStatus of the returned services with
+	// the number of running and desired tasks, like the REPLICAS column of
+	// docker service ls. It requires Docker API 1.41 or greater, older
+	// daemons leave ServiceStatus nil.
+	Status bool
+
 	Context context.Context
 }
 
@@ -189,7 +255,11 @@ type LogsServiceOptions struct {
 	Stdout      bool
 	Stderr      bool
 	Timestamps  bool
-	Details     bool
+
+	// Details makes the daemon prefix each line with the task context
+	// (node, service and task IDs, plus any extra log attributes). The
+	// prefix is written as is, for callers to parse.
+	Details bool
 }
 
 // GetServiceLogs gets stdout and stderr logs from the specified service.
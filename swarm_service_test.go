@@ -177,6 +177,31 @@ func TestUpdateService(t *testing.T) {
 	}
 }
 
+func TestUpdateServiceForceUpdate(t *testing.T) {
+	t.Parallel()
+	fakeRT := &FakeRoundTripper{message: "", status: http.StatusOK}
+	client := newTestClient(fakeRT)
+	spec := swarm.ServiceSpec{TaskTemplate: swarm.TaskSpec{ForceUpdate: 2}}
+	err := client.UpdateService("test", UpdateServiceOptions{ServiceSpec: spec, Version: 23, ForceUpdate: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := fakeRT.requests[0]
+	if query := req.URL.RawQuery; query != "version=23" {
+		t.Errorf("UpdateService: wrong query string. Want %q. Got %q.", "version=23", query)
+	}
+	var out swarm.ServiceSpec
+	if err := json.NewDecoder(req.Body).Decode(&out); err != nil {
+		t.Fatal(err)
+	}
+	if out.TaskTemplate.ForceUpdate != 3 {
+		t.Errorf("UpdateService: wrong ForceUpdate. Want 3. Got %d.", out.TaskTemplate.ForceUpdate)
+	}
+	if spec.TaskTemplate.ForceUpdate != 2 {
+		t.Errorf("UpdateService: modified the given spec. Got ForceUpdate %d.", spec.TaskTemplate.ForceUpdate)
+	}
+}
+
 func TestUpdateServiceRollback(t *testing.T) {
 	t.Parallel()
 	fakeRT := &FakeRoundTripper{message: "", status: http.StatusOK}
@@ -356,6 +381,80 @@ func TestInspectService(t *testing.T) {
 	}
 }
 
+func TestScaleService(t *testing.T) {
+	t.Parallel()
+	body := `{"ID":"svc1","Version":{"Index":5},"Spec":{"Name":"web","Mode":{"Replicated":{"Replicas":1}}}}`
+	fakeRT := &FakeRoundTripper{message: body, status: http.StatusOK}
+	client := newTestClient(fakeRT)
+	if _, err := client.ScaleService("web", 3); err != nil {
+		t.Fatal(err)
+	}
+	if len(fakeRT.requests) != 3 {
+		t.Fatalf("ScaleService: wrong number of requests. Want 3. Got %d.", len(fakeRT.requests))
+	}
+	req := fakeRT.requests[1]
+	if expected := "/services/svc1/update"; req.Method != http.MethodPost || req.URL.Path != expected {
+		t.Errorf("ScaleService: wrong request. Want POST %s. Got %s %s.", expected, req.Method, req.URL.Path)
+	}
+	if version := req.URL.Query().Get("version"); version != "5" {
+		t.Errorf("ScaleService: wrong version. Want %q. Got %q.", "5", version)
+	}
+	var spec swarm.ServiceSpec
+	if err := json.NewDecoder(req.Body).Decode(&spec); err != nil {
+		t.Fatal(err)
+	}
+	if spec.Name != "web" || spec.Mode.Replicated == nil || *spec.Mode.Replicated.Replicas != 3 {
+		t.Errorf("ScaleService: wrong spec: %#v", spec)
+	}
+}
+
+func TestScaleServiceGlobal(t *testing.T) {
+	t.Parallel()
+	fakeRT := &FakeRoundTripper{message: `{"ID":"svc1","Spec":{"Mode":{"Global":{}}}}`, status: http.StatusOK}
+	client := newTestClient(fakeRT)
+	if _, err := client.ScaleService("svc1", 3); !errors.Is(err, ErrServiceNotReplicated) {
+		t.Errorf("ScaleService: wrong error. Want %v. Got %v.", ErrServiceNotReplicated, err)
+	}
+	if len(fakeRT.requests) != 1 {
+		t.Errorf("ScaleService: wrong number of requests. Want 1. Got %d.", len(fakeRT.requests))
+	}
+}
+
+func TestInspectServiceWithRaw(t *testing.T) {
+	t.Parallel()
+	jsonService := `{"ID":"ak7w3gjqoa3kuz8xcpnyy0pvl","Spec":{"Name":"redis"},"UnmodeledField":{"Key":"value"}}`
+	fakeRT := &FakeRoundTripper{message: jsonService, status: http.StatusOK}
+	client := newTestClient(fakeRT)
+	id := "ak7w3gjqoa3kuz8xcpnyy0pvl"
+	service, raw, err := client.InspectServiceWithRaw(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if service.ID != id || service.Spec.Name != "redis" {
+		t.Errorf("InspectServiceWithRaw(%q): wrong service: %#v", id, service)
+	}
+	if string(raw) != jsonService {
+		t.Errorf("InspectServiceWithRaw(%q): wrong raw JSON. Want %s. Got %s.", id, jsonService, raw)
+	}
+	expectedURL, _ := url.Parse(client.getURL("/services/" + id))
+	if gotPath := fakeRT.requests[0].URL.Path; gotPath != expectedURL.Path {
+		t.Errorf("InspectServiceWithRaw(%q): Wrong path in request. Want %q. Got %q.", id, expectedURL.Path, gotPath)
+	}
+}
+
+func TestInspectServiceWithRawNotFound(t *testing.T) {
+	t.Parallel()
+	client := newTestClient(&FakeRoundTripper{message: "no such service", status: http.StatusNotFound})
+	service, raw, err := client.InspectServiceWithRaw("notfound")
+	if service != nil || raw != nil {
+		t.Errorf("InspectServiceWithRaw: unexpected result on error: %#v, %q", service, raw)
+	}
+	expected := &NoSuchService{ID: "notfound"}
+	if !reflect.DeepEqual(err, expected) {
+		t.Errorf("InspectServiceWithRaw: Wrong error returned. Want %#v. Got %#v.", expected, err)
+	}
+}
+
 func TestListServices(t *testing.T) {
 	t.Parallel()
 	jsonServices := `[
@@ -437,6 +536,24 @@ func TestListServices(t *testing.T) {
 	}
 }
 
+func TestListServicesStatus(t *testing.T) {
+	t.Parallel()
+	body := `[{"ID": "9mnpnzenvg8p8tdbtq4wvbkcz", "Spec": {"Name": "web"}, "ServiceStatus": {"RunningTasks": 3, "DesiredTasks": 5}}]`
+	fakeRT := &FakeRoundTripper{message: body, status: http.StatusOK}
+	client := newTestClient(fakeRT)
+	services, err := client.ListServices(ListServicesOptions{Status: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := fakeRT.requests[0].URL.Query().Get("status"); got != "1" {
+		t.Errorf("ListServices: wrong status parameter. Want %q. Got %q.", "1", got)
+	}
+	expected := swarm.ServiceStatus{RunningTasks: 3, DesiredTasks: 5}
+	if len(services) != 1 || services[0].ServiceStatus == nil || *services[0].ServiceStatus != expected {
+		t.Errorf("ListServices: wrong services: %#v", services)
+	}
+}
+
 func TestGetServiceLogs(t *testing.T) {
 	var req http.Request
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -8,7 +8,9 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"io"
 	"net/http"
+	"time"
 
 	"github.com/docker/docker/api/types/swarm"
 )
@@ -70,3 +72,57 @@ func (c *Client) InspectTask(id string) (*swarm.Task, error) {
 	}
 	return &task, nil
 }
+
+// LogsTaskOptions represents the set of options used when getting logs from a
+// task.
+type LogsTaskOptions struct {
+	Context           context.Context
+	Task              string        `qs:"-"`
+	OutputStream      io.Writer     `qs:"-"`
+	ErrorStream       io.Writer     `qs:"-"`
+	InactivityTimeout time.Duration `qs:"-"`
+	Tail              string
+	Since             int64
+
+	// Use raw terminal? Usually true when the container contains a TTY.
+	RawTerminal bool `qs:"-"`
+	Follow      bool
+	Stdout      bool
+	Stderr      bool
+	Timestamps  bool
+
+	// Details makes the daemon prefix each line with the task context
+	// (node, service and task IDs, plus any extra log attributes).
+	Details bool
+}
+
+// GetTaskLogs gets stdout and stderr logs from the specified task, which is
+// useful for debugging a single replica of a service.
+//
+// When LogsTaskOptions.RawTerminal is set to false, go-dockerclient will
+// multiplex the streams and send the task's stdout to
+// LogsTaskOptions.OutputStream, and stderr to LogsTaskOptions.ErrorStream.
+//
+// When LogsTaskOptions.RawTerminal is true, callers will get the raw stream on
+// LogsTaskOptions.OutputStream.
+func (c *Client) GetTaskLogs(opts LogsTaskOptions) error {
+	if opts.Task == "" {
+		return &NoSuchTask{ID: opts.Task}
+	}
+	if opts.Tail == "" {
+		opts.Tail = "all"
+	}
+	path := "/tasks/" + opts.Task + "/logs?" + queryString(opts)
+	err := c.stream(http.MethodGet, path, streamOptions{
+		setRawTerminal:    opts.RawTerminal,
+		stdout:            opts.OutputStream,
+		stderr:            opts.ErrorStream,
+		inactivityTimeout: opts.InactivityTimeout,
+		context:           opts.Context,
+	})
+	var e *Error
+	if errors.As(err, &e) && e.Status == http.StatusNotFound {
+		return &NoSuchTask{ID: opts.Task, Err: err}
+	}
+	return err
+}
@@ -5,9 +5,11 @@
 package docker
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"reflect"
 	"testing"
@@ -377,3 +379,70 @@ func TestInspectTaskNotFound(t *testing.T) {
 		t.Errorf("wrong taskID\nwant %q\ngot  %q", taskID, taskErr.ID)
 	}
 }
+
+func TestGetTaskLogs(t *testing.T) {
+	t.Parallel()
+	var req http.Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte{1, 0, 0, 0, 0, 0, 0, 6})
+		w.Write([]byte("hello\n"))
+		w.Write([]byte{2, 0, 0, 0, 0, 0, 0, 5})
+		w.Write([]byte("oops\n"))
+		req = *r
+	}))
+	defer server.Close()
+	client, _ := NewClient(server.URL)
+	client.SkipServerVersionCheck = true
+	var stdout, stderr bytes.Buffer
+	opts := LogsTaskOptions{
+		Task:         "t123456",
+		OutputStream: &stdout,
+		ErrorStream:  &stderr,
+		Follow:       true,
+		Stdout:       true,
+		Stderr:       true,
+		Timestamps:   true,
+		Tail:         "10",
+	}
+	if err := client.GetTaskLogs(opts); err != nil {
+		t.Fatal(err)
+	}
+	if stdout.String() != "hello\n" {
+		t.Errorf("GetTaskLogs: wrong stdout. Want %q. Got %q.", "hello\n", stdout.String())
+	}
+	if stderr.String() != "oops\n" {
+		t.Errorf("GetTaskLogs: wrong stderr. Want %q. Got %q.", "oops\n", stderr.String())
+	}
+	u, _ := url.Parse(client.getURL("/tasks/t123456/logs"))
+	if req.Method != http.MethodGet || req.URL.Path != u.Path {
+		t.Errorf("GetTaskLogs: wrong request. Want GET %s. Got %s %s.", u.Path, req.Method, req.URL.Path)
+	}
+	expectedQs := map[string][]string{
+		"follow":     {"1"},
+		"stdout":     {"1"},
+		"stderr":     {"1"},
+		"timestamps": {"1"},
+		"tail":       {"10"},
+	}
+	if got := map[string][]string(req.URL.Query()); !reflect.DeepEqual(got, expectedQs) {
+		t.Errorf("GetTaskLogs: wrong query string. Want %#v. Got %#v.", expectedQs, got)
+	}
+}
+
+func TestGetTaskLogsNotFound(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		http.Error(w, "task not found", http.StatusNotFound)
+	}))
+	defer server.Close()
+	client, _ := NewClient(server.URL)
+	client.SkipServerVersionCheck = true
+	err := client.GetTaskLogs(LogsTaskOptions{Task: "t123456", Stdout: true})
+	var e *NoSuchTask
+	if !errors.As(err, &e) || e.ID != "t123456" {
+		t.Errorf("GetTaskLogs: wrong error. Want NoSuchTask. Got %#v.", err)
+	}
+	if err := client.GetTaskLogs(LogsTaskOptions{}); !errors.As(err, &e) {
+		t.Errorf("GetTaskLogs: wrong error for empty task. Want NoSuchTask. Got %#v.", err)
+	}
+}
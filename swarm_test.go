@@ -6,6 +6,7 @@ package docker
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"net/http"
 	"net/url"
@@ -38,6 +39,29 @@ func TestInitSwarm(t *testing.T) {
 	}
 }
 
+func TestInitSwarmAddrPools(t *testing.T) {
+	t.Parallel()
+	fakeRT := &FakeRoundTripper{message: `"node-id"`, status: http.StatusOK}
+	client := newTestClient(fakeRT)
+	opts := InitSwarmOptions{
+		InitRequest: swarm.InitRequest{
+			DefaultAddrPool: []string{"172.80.0.0/16", "172.90.0.0/16"},
+			SubnetSize:      26,
+			DataPathPort:    7789,
+		},
+	}
+	if _, err := client.InitSwarm(opts); err != nil {
+		t.Fatal(err)
+	}
+	var got swarm.InitRequest
+	if err := json.NewDecoder(fakeRT.requests[0].Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, opts.InitRequest) {
+		t.Errorf("InitSwarm: wrong request body. Want %#v. Got %#v.", opts.InitRequest, got)
+	}
+}
+
 func TestInitSwarmAlreadyInSwarm(t *testing.T) {
 	t.Parallel()
 	client := newTestClient(&FakeRoundTripper{message: "", status: http.StatusNotAcceptable})
@@ -160,6 +184,36 @@ func TestUpdateSwarm(t *testing.T) {
 	}
 }
 
+func TestUpdateSwarmRotateUnlockKey(t *testing.T) {
+	t.Parallel()
+	fakeRT := &FakeRoundTripper{message: "", status: http.StatusOK}
+	client := newTestClient(fakeRT)
+	err := client.UpdateSwarm(UpdateSwarmOptions{Version: 3, RotateManagerUnlockKey: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string][]string{
+		"version":                {"3"},
+		"rotateManagerToken":     {"false"},
+		"rotateWorkerToken":      {"false"},
+		"rotateManagerUnlockKey": {"true"},
+	}
+	got := map[string][]string(fakeRT.requests[0].URL.Query())
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("UpdateSwarm: Wrong request query. Want %v. Got %v", expected, got)
+	}
+}
+
+func TestUpdateSwarmOutOfSequence(t *testing.T) {
+	t.Parallel()
+	client := newTestClient(&FakeRoundTripper{message: "update out of sequence", status: http.StatusBadRequest})
+	err := client.UpdateSwarm(UpdateSwarmOptions{Version: 1})
+	var e *Error
+	if !errors.As(err, &e) || e.Status != http.StatusBadRequest {
+		t.Errorf("UpdateSwarm: Wrong error. Want API error 400. Got %#v", err)
+	}
+}
+
 func TestUpdateSwarmNotInSwarm(t *testing.T) {
 	t.Parallel()
 	client := newTestClient(&FakeRoundTripper{message: "", status: http.StatusNotAcceptable})
@@ -210,3 +264,65 @@ func TestInspectSwarmNotInSwarm(t *testing.T) {
 		t.Errorf("InspectSwarm: Wrong error type. Want %#v. Got %#v", ErrNodeNotInSwarm, err)
 	}
 }
+
+func TestUnlockSwarm(t *testing.T) {
+	t.Parallel()
+	fakeRT := &FakeRoundTripper{message: "", status: http.StatusOK}
+	client := newTestClient(fakeRT)
+	err := client.UnlockSwarm(UnlockSwarmOptions{UnlockKey: "SWMKEY-1-abc"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := fakeRT.requests[0]
+	if req.Method != http.MethodPost {
+		t.Errorf("UnlockSwarm: Wrong HTTP method. Want %s. Got %s.", http.MethodPost, req.Method)
+	}
+	if req.URL.Path != "/swarm/unlock" {
+		t.Errorf("UnlockSwarm: Wrong request path. Want %q. Got %q.", "/swarm/unlock", req.URL.Path)
+	}
+	var body swarm.UnlockRequest
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if body.UnlockKey != "SWMKEY-1-abc" {
+		t.Errorf("UnlockSwarm: Wrong unlock key. Want %q. Got %q.", "SWMKEY-1-abc", body.UnlockKey)
+	}
+}
+
+func TestUnlockSwarmNotInSwarm(t *testing.T) {
+	t.Parallel()
+	client := newTestClient(&FakeRoundTripper{message: "This node is not part of a swarm", status: http.StatusServiceUnavailable})
+	err := client.UnlockSwarm(UnlockSwarmOptions{UnlockKey: "SWMKEY-1-abc"})
+	if !errors.Is(err, ErrNodeNotInSwarm) {
+		t.Errorf("UnlockSwarm: Wrong error type. Want %#v. Got %#v", ErrNodeNotInSwarm, err)
+	}
+}
+
+func TestGetSwarmUnlockKey(t *testing.T) {
+	t.Parallel()
+	fakeRT := &FakeRoundTripper{message: `{"UnlockKey":"SWMKEY-1-abc"}`, status: http.StatusOK}
+	client := newTestClient(fakeRT)
+	key, err := client.GetSwarmUnlockKey(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key != "SWMKEY-1-abc" {
+		t.Errorf("GetSwarmUnlockKey: Wrong key. Want %q. Got %q.", "SWMKEY-1-abc", key)
+	}
+	req := fakeRT.requests[0]
+	if req.Method != http.MethodGet || req.URL.Path != "/swarm/unlockkey" {
+		t.Errorf("GetSwarmUnlockKey: Wrong request. Want GET /swarm/unlockkey. Got %s %s.", req.Method, req.URL.Path)
+	}
+}
+
+func TestSwarmLocked(t *testing.T) {
+	t.Parallel()
+	message := `Swarm is encrypted and needs to be unlocked before it can be used. Please use "docker swarm unlock" to unlock it.`
+	client := newTestClient(&FakeRoundTripper{message: message, status: http.StatusServiceUnavailable})
+	if _, err := client.InspectSwarm(context.Background()); !errors.Is(err, ErrSwarmLocked) {
+		t.Errorf("InspectSwarm: Wrong error type. Want %#v. Got %#v", ErrSwarmLocked, err)
+	}
+	if _, err := client.GetSwarmUnlockKey(context.Background()); !errors.Is(err, ErrSwarmLocked) {
+		t.Errorf("GetSwarmUnlockKey: Wrong error type. Want %#v. Got %#v", ErrSwarmLocked, err)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"net/http"
+	"time"
 )
 
 // VolumeUsageData represents usage data from the docker system api
@@ -41,17 +42,49 @@ type ImageSummary struct {
 	VirtualSize int64             `json:"VirtualSize"`
 }
 
+// BuildCacheRecord represents an entry of the builder cache, as reported by
+// the docker system api
+// More Info Here https://dockr.ly/2PNzQyO
+type BuildCacheRecord struct {
+	ID          string     `json:"ID"`
+	Parent      string     `json:"Parent,omitempty"`
+	Type        string     `json:"Type"`
+	Description string     `json:"Description"`
+	InUse       bool       `json:"InUse"`
+	Shared      bool       `json:"Shared"`
+	Size        int64      `json:"Size"`
+	CreatedAt   time.Time  `json:"CreatedAt"`
+	LastUsedAt  *time.Time `json:"LastUsedAt"`
+	UsageCount  int        `json:"UsageCount"`
+}
+
 // DiskUsage holds information about what docker is using disk space on.
 // More Info Here https://dockr.ly/2PNzQyO
 type DiskUsage struct {
-	LayersSize int64
-	Images     []*ImageSummary
-	Containers []*APIContainers
-	Volumes    []*Volume
+	LayersSize  int64
+	Images      []*ImageSummary
+	Containers  []*APIContainers
+	Volumes     []*Volume
+	BuildCache  []*BuildCacheRecord
+	BuilderSize int64 `json:"BuilderSize,omitempty"`
 }
 
-// DiskUsageOptions only contains a context for canceling.
+// Object types accepted by DiskUsageOptions.Type.
+const (
+	DiskUsageContainers = "container"
+	DiskUsageImages     = "image"
+	DiskUsageVolumes    = "volume"
+	DiskUsageBuildCache = "build-cache"
+)
+
+// DiskUsageOptions specify parameters to the DiskUsage function.
+//
+// Type restricts the report to the given object types (see the DiskUsage*
+// constants), which is useful when computing the full report is expensive. It
+// requires Docker API 1.42 or greater; older daemons ignore it and return all
+// object types.
 type DiskUsageOptions struct {
+	Type    []string
 	Context context.Context
 }
 
@@ -59,7 +92,7 @@ type DiskUsageOptions struct {
 //
 // More Info Here https://dockr.ly/2PNzQyO
 func (c *Client) DiskUsage(opts DiskUsageOptions) (*DiskUsage, error) {
-	path := "/system/df"
+	path := "/system/df?" + queryString(opts)
 	resp, err := c.do(http.MethodGet, path, doOptions{context: opts.Context})
 	if err != nil {
 		return nil, err
@@ -106,3 +106,44 @@ func TestDiskUsage(t *testing.T) {
 		t.Errorf("DiskUsage: Wrong return value. Want %#v. Got %#v.", expected, du)
 	}
 }
+
+func TestDiskUsageType(t *testing.T) {
+	t.Parallel()
+	duData := `{
+  "LayersSize": 0,
+  "BuildCache": [
+    {
+      "ID": "hw53o5aio51xtltp5xjp8v7fx",
+      "Parent": "",
+      "Type": "regular",
+      "Description": "mount / from exec /bin/sh -c apt-get update",
+      "InUse": false,
+      "Shared": true,
+      "Size": 51,
+      "CreatedAt": "2021-06-28T13:31:01.474619385Z",
+      "LastUsedAt": "2021-07-07T22:02:32.738075951Z",
+      "UsageCount": 26
+    }
+  ]
+}`
+	fakeRT := &FakeRoundTripper{message: duData, status: http.StatusOK}
+	client := newTestClient(fakeRT)
+	du, err := client.DiskUsage(DiskUsageOptions{Type: []string{DiskUsageBuildCache}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedQuery := map[string][]string{"type": {"build-cache"}}
+	if query := map[string][]string(fakeRT.requests[0].URL.Query()); !reflect.DeepEqual(query, expectedQuery) {
+		t.Errorf("DiskUsage: wrong query string. Want %#v. Got %#v.", expectedQuery, query)
+	}
+	if len(du.BuildCache) != 1 {
+		t.Fatalf("DiskUsage: wrong number of build cache records. Want 1. Got %d.", len(du.BuildCache))
+	}
+	record := du.BuildCache[0]
+	if record.ID != "hw53o5aio51xtltp5xjp8v7fx" || record.Size != 51 || !record.Shared || record.UsageCount != 26 {
+		t.Errorf("DiskUsage: wrong build cache record. Got %#v.", record)
+	}
+	if record.LastUsedAt == nil || record.LastUsedAt.IsZero() {
+		t.Errorf("DiskUsage: expected LastUsedAt to be set. Got %#v.", record.LastUsedAt)
+	}
+}
@@ -5,11 +5,11 @@
 package docker
 
 import (
+	"bufio"
+	"bytes"
 	"fmt"
 	"io"
-	"io/ioutil"
 	"os"
-	"path"
 	"path/filepath"
 	"strings"
 
@@ -39,12 +39,13 @@ func createTarStream(srcPath, dockerfilePath string) (io.ReadCloser, error) {
 	//
 	// https://github.com/docker/docker/issues/8330
 	//
+	if dockerfilePath == "" {
+		// the daemon's default
+		dockerfilePath = "Dockerfile"
+	}
 	forceIncludeFiles := []string{".dockerignore", dockerfilePath}
 
 	for _, includeFile := range forceIncludeFiles {
-		if includeFile == "" {
-			continue
-		}
 		keepThem, err := fileutils.Matches(includeFile, excludes)
 		if err != nil {
 			return nil, fmt.Errorf("cannot match .dockerfileignore: '%s', error: %w", includeFile, err)
@@ -70,6 +71,10 @@ func createTarStream(srcPath, dockerfilePath string) (io.ReadCloser, error) {
 // can be read and returns an error if some files can't be read.
 // Symlinks which point to non-existing files don't trigger an error
 func validateContextDirectory(srcPath string, excludes []string) error {
+	var exceptions bool
+	for _, pattern := range excludes {
+		exceptions = exceptions || strings.HasPrefix(pattern, "!")
+	}
 	return filepath.Walk(filepath.Join(srcPath, "."), func(filePath string, f os.FileInfo, err error) error {
 		// skip this directory/file if it's not in the path, it won't get added to the context
 		if relFilePath, relErr := filepath.Rel(srcPath, filePath); relErr != nil {
@@ -77,7 +82,9 @@ func validateContextDirectory(srcPath string, excludes []string) error {
 		} else if skip, matchErr := fileutils.Matches(relFilePath, excludes); matchErr != nil {
 			return matchErr
 		} else if skip {
-			if f.IsDir() {
+			// an exception may include files from excluded directories,
+			// so they must still be walked.
+			if f.IsDir() && !exceptions {
 				return filepath.SkipDir
 			}
 			return nil
@@ -111,12 +118,57 @@ func validateContextDirectory(srcPath string, excludes []string) error {
 }
 
 func parseDockerignore(root string) ([]string, error) {
-	var excludes []string
-	ignore, err := ioutil.ReadFile(path.Join(root, ".dockerignore"))
-	if err != nil && !os.IsNotExist(err) {
-		return excludes, fmt.Errorf("error reading .dockerignore: %w", err)
+	f, err := os.Open(filepath.Join(root, ".dockerignore"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error reading .dockerignore: %w", err)
+	}
+	defer f.Close()
+	excludes, err := readDockerignore(f)
+	if err != nil {
+		return nil, fmt.Errorf("error reading .dockerignore: %w", err)
 	}
-	excludes = strings.Split(string(ignore), "\n")
+	return excludes, nil
+}
 
+// readDockerignore reads the patterns in a .dockerignore file following the
+// same rules as the docker CLI: blank lines and lines starting with # are
+// ignored, patterns are cleaned (so "dir/" matches the directory dir and
+// everything in it) and relative to the root of the context, even when they
+// start with a slash, and patterns starting with ! are exceptions that
+// re-include files matched by previous patterns.
+func readDockerignore(r io.Reader) ([]string, error) {
+	var excludes []string
+	scanner := bufio.NewScanner(r)
+	utf8bom := []byte{0xEF, 0xBB, 0xBF}
+	for lineNo := 0; scanner.Scan(); lineNo++ {
+		line := scanner.Bytes()
+		if lineNo == 0 {
+			line = bytes.TrimPrefix(line, utf8bom)
+		}
+		pattern := strings.TrimSpace(string(line))
+		if pattern == "" || strings.HasPrefix(pattern, "#") {
+			continue
+		}
+		invert := pattern[0] == '!'
+		if invert {
+			pattern = strings.TrimSpace(pattern[1:])
+		}
+		if pattern != "" {
+			pattern = filepath.ToSlash(filepath.Clean(pattern))
+			if len(pattern) > 1 && pattern[0] == '/' {
+				pattern = pattern[1:]
+			}
+		}
+		if invert {
+			pattern = "!" + pattern
+		}
+		excludes = append(excludes, pattern)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
 	return excludes, nil
 }
@@ -9,8 +9,10 @@ package testing
 import (
 	"archive/tar"
 	"crypto/rand"
+	"crypto/sha256"
 	"crypto/tls"
 	"crypto/x509"
+	"encoding/binary"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -20,19 +22,25 @@ import (
 	"net"
 	"net/http"
 	libpath "path"
+	"reflect"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/docker/docker/api/types/swarm"
+	"github.com/docker/docker/pkg/jsonmessage"
 	"github.com/docker/docker/pkg/stdcopy"
 	docker "github.com/fsouza/go-dockerclient"
 	"github.com/gorilla/mux"
 )
 
-var nameRegexp = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]+$`)
+var (
+	nameRegexp = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]+$`)
+	tagRegexp  = regexp.MustCompile(`^[\w][\w.-]{0,127}$`)
+)
 
 // DockerServer represents a programmable, concurrent (not much), HTTP server
 // implementing a fake version of the Docker remote API.
@@ -45,6 +53,7 @@ type DockerServer struct {
 	containers     map[string]*docker.Container
 	contNameToID   map[string]string
 	uploadedFiles  map[string]string
+	containerFiles map[string]map[string]*containerFile
 	execs          []*docker.ExecInspect
 	execMut        sync.RWMutex
 	cMut           sync.RWMutex
@@ -58,22 +67,52 @@ type DockerServer struct {
 	hook           func(*http.Request)
 	failures       map[string]string
 	multiFailures  []map[string]string
+	failureRules   []*failureRule
+	failureMut     sync.Mutex
 	execCallbacks  map[string]func()
+	execResults    []PrepareExecOptions
 	statsCallbacks map[string]func(string) docker.Stats
+	inspectRaw     map[string]json.RawMessage
+	logs           map[string]containerLogs
+	cMutator       func(*docker.Container)
 	customHandlers map[string]http.Handler
 	handlerMutex   sync.RWMutex
 	cChan          chan<- *docker.Container
 	volStore       map[string]*volumeCounter
 	volMut         sync.RWMutex
+	plugins        map[string]*docker.PluginDetail
+	pluginMut      sync.RWMutex
 	swarmMut       sync.RWMutex
 	swarm          *swarm.Swarm
+	swarmUnlockKey string
+	swarmLocked    bool
 	swarmServer    *swarmServer
 	nodes          []swarm.Node
 	nodeID         string
 	tasks          []*swarm.Task
 	services       []*swarm.Service
+	secrets        []*swarm.Secret
+	configs        []*swarm.Config
 	nodeRR         int
 	servicePorts   int
+	eventMut       sync.RWMutex
+	eventDelay     time.Duration
+	eventSubs      []*eventSubscriber
+	eventLog       []docker.APIEvents
+	remoteTags     map[string][]string
+	imagePulls     map[string]PrepareImagePullOptions
+}
+
+type containerLogs struct {
+	stdout string
+	stderr string
+}
+
+// containerFile is a file uploaded to a container, stored by its absolute
+// path in the filesystem of the container.
+type containerFile struct {
+	header  tar.Header
+	content []byte
 }
 
 type volumeCounter struct {
@@ -90,8 +129,14 @@ func baseDockerServer() DockerServer {
 		failures:       make(map[string]string),
 		execCallbacks:  make(map[string]func()),
 		statsCallbacks: make(map[string]func(string) docker.Stats),
+		inspectRaw:     make(map[string]json.RawMessage),
+		logs:           make(map[string]containerLogs),
 		customHandlers: make(map[string]http.Handler),
 		uploadedFiles:  make(map[string]string),
+		containerFiles: make(map[string]map[string]*containerFile),
+		plugins:        make(map[string]*docker.PluginDetail),
+		remoteTags:     make(map[string][]string),
+		imagePulls:     make(map[string]PrepareImagePullOptions),
 	}
 }
 
@@ -178,6 +223,7 @@ func (s *DockerServer) addMuxerRoutes(m *mux.Router) {
 	m.Path("/containers/create").Methods(http.MethodPost).HandlerFunc(s.handlerWrapper(s.createContainer))
 	m.Path("/containers/{id:.*}/json").Methods(http.MethodGet).HandlerFunc(s.handlerWrapper(s.inspectContainer))
 	m.Path("/containers/{id:.*}/rename").Methods(http.MethodPost).HandlerFunc(s.handlerWrapper(s.renameContainer))
+	m.Path("/containers/{id:.*}/update").Methods(http.MethodPost).HandlerFunc(s.handlerWrapper(s.updateContainer))
 	m.Path("/containers/{id:.*}/top").Methods(http.MethodGet).HandlerFunc(s.handlerWrapper(s.topContainer))
 	m.Path("/containers/{id:.*}/start").Methods(http.MethodPost).HandlerFunc(s.handlerWrapper(s.startContainer))
 	m.Path("/containers/{id:.*}/kill").Methods(http.MethodPost).HandlerFunc(s.handlerWrapper(s.stopContainer))
@@ -198,40 +244,69 @@ func (s *DockerServer) addMuxerRoutes(m *mux.Router) {
 	m.Path("/images/create").Methods(http.MethodPost).HandlerFunc(s.handlerWrapper(s.pullImage))
 	m.Path("/build").Methods(http.MethodPost).HandlerFunc(s.handlerWrapper(s.buildImage))
 	m.Path("/images/json").Methods(http.MethodGet).HandlerFunc(s.handlerWrapper(s.listImages))
+	m.Path("/images/prune").Methods(http.MethodPost).HandlerFunc(s.handlerWrapper(s.pruneImages))
 	m.Path("/images/{id:.*}").Methods(http.MethodDelete).HandlerFunc(s.handlerWrapper(s.removeImage))
 	m.Path("/images/{name:.*}/json").Methods(http.MethodGet).HandlerFunc(s.handlerWrapper(s.inspectImage))
 	m.Path("/images/{name:.*}/push").Methods(http.MethodPost).HandlerFunc(s.handlerWrapper(s.pushImage))
 	m.Path("/images/{name:.*}/tag").Methods(http.MethodPost).HandlerFunc(s.handlerWrapper(s.tagImage))
 	m.Path("/events").Methods(http.MethodGet).HandlerFunc(s.listEvents)
 	m.Path("/_ping").Methods(http.MethodGet).HandlerFunc(s.handlerWrapper(s.pingDocker))
+	m.Path("/system/df").Methods(http.MethodGet).HandlerFunc(s.handlerWrapper(s.diskUsage))
 	m.Path("/images/load").Methods(http.MethodPost).HandlerFunc(s.handlerWrapper(s.loadImage))
+	m.Path("/images/get").Methods(http.MethodGet).HandlerFunc(s.handlerWrapper(s.getImages))
 	m.Path("/images/{id:.*}/get").Methods(http.MethodGet).HandlerFunc(s.handlerWrapper(s.getImage))
 	m.Path("/networks").Methods(http.MethodGet).HandlerFunc(s.handlerWrapper(s.listNetworks))
 	m.Path("/networks/{id:.*}").Methods(http.MethodGet).HandlerFunc(s.handlerWrapper(s.networkInfo))
 	m.Path("/networks/{id:.*}").Methods(http.MethodDelete).HandlerFunc(s.handlerWrapper(s.removeNetwork))
+	m.Path("/networks/prune").Methods(http.MethodPost).HandlerFunc(s.handlerWrapper(s.pruneNetworks))
 	m.Path("/networks/create").Methods(http.MethodPost).HandlerFunc(s.handlerWrapper(s.createNetwork))
 	m.Path("/networks/{id:.*}/connect").Methods(http.MethodPost).HandlerFunc(s.handlerWrapper(s.networksConnect))
+	m.Path("/networks/{id:.*}/disconnect").Methods(http.MethodPost).HandlerFunc(s.handlerWrapper(s.networksDisconnect))
 	m.Path("/volumes").Methods(http.MethodGet).HandlerFunc(s.handlerWrapper(s.listVolumes))
 	m.Path("/volumes/create").Methods(http.MethodPost).HandlerFunc(s.handlerWrapper(s.createVolume))
 	m.Path("/volumes/{name:.*}").Methods(http.MethodGet).HandlerFunc(s.handlerWrapper(s.inspectVolume))
 	m.Path("/volumes/{name:.*}").Methods(http.MethodDelete).HandlerFunc(s.handlerWrapper(s.removeVolume))
+	m.Path("/volumes/{name:.*}").Methods(http.MethodPut).HandlerFunc(s.handlerWrapper(s.updateVolume))
+	m.Path("/plugins/privileges").Methods(http.MethodGet).HandlerFunc(s.handlerWrapper(s.pluginPrivileges))
+	m.Path("/plugins/pull").Methods(http.MethodPost).HandlerFunc(s.handlerWrapper(s.installPlugin))
+	m.Path("/plugins").Methods(http.MethodGet).HandlerFunc(s.handlerWrapper(s.listPlugins))
+	m.Path("/plugins/json").Methods(http.MethodGet).HandlerFunc(s.handlerWrapper(s.listPlugins))
+	m.Path("/plugins/{name:.+}/json").Methods(http.MethodGet).HandlerFunc(s.handlerWrapper(s.inspectPlugin))
+	m.Path("/plugins/{name:.+}/enable").Methods(http.MethodPost).HandlerFunc(s.handlerWrapper(s.enablePlugin))
+	m.Path("/plugins/{name:.+}/disable").Methods(http.MethodPost).HandlerFunc(s.handlerWrapper(s.disablePlugin))
+	m.Path("/plugins/{name:.+}").Methods(http.MethodDelete).HandlerFunc(s.handlerWrapper(s.removePlugin))
 	m.Path("/info").Methods(http.MethodGet).HandlerFunc(s.handlerWrapper(s.infoDocker))
 	m.Path("/version").Methods(http.MethodGet).HandlerFunc(s.handlerWrapper(s.versionDocker))
 	m.Path("/swarm/init").Methods(http.MethodPost).HandlerFunc(s.handlerWrapper(s.swarmInit))
 	m.Path("/swarm").Methods(http.MethodGet).HandlerFunc(s.handlerWrapper(s.swarmInspect))
 	m.Path("/swarm/join").Methods(http.MethodPost).HandlerFunc(s.handlerWrapper(s.swarmJoin))
 	m.Path("/swarm/leave").Methods(http.MethodPost).HandlerFunc(s.handlerWrapper(s.swarmLeave))
+	m.Path("/swarm/update").Methods(http.MethodPost).HandlerFunc(s.handlerWrapper(s.swarmUpdate))
+	m.Path("/swarm/unlock").Methods(http.MethodPost).HandlerFunc(s.handlerWrapper(s.swarmUnlock))
+	m.Path("/swarm/unlockkey").Methods(http.MethodGet).HandlerFunc(s.handlerWrapper(s.swarmUnlockKeyHandler))
 	m.Path("/nodes/{id:.+}/update").Methods(http.MethodPost).HandlerFunc(s.handlerWrapper(s.nodeUpdate))
 	m.Path("/nodes/{id:.+}").Methods(http.MethodGet).HandlerFunc(s.handlerWrapper(s.nodeInspect))
 	m.Path("/nodes/{id:.+}").Methods(http.MethodDelete).HandlerFunc(s.handlerWrapper(s.nodeDelete))
 	m.Path("/nodes").Methods(http.MethodGet).HandlerFunc(s.handlerWrapper(s.nodeList))
 	m.Path("/services/create").Methods(http.MethodPost).HandlerFunc(s.handlerWrapper(s.serviceCreate))
+	m.Path("/services/{id:.+}/logs").Methods(http.MethodGet).HandlerFunc(s.handlerWrapper(s.serviceLogs))
 	m.Path("/services/{id:.+}").Methods(http.MethodGet).HandlerFunc(s.handlerWrapper(s.serviceInspect))
 	m.Path("/services").Methods(http.MethodGet).HandlerFunc(s.handlerWrapper(s.serviceList))
 	m.Path("/services/{id:.+}").Methods(http.MethodDelete).HandlerFunc(s.handlerWrapper(s.serviceDelete))
 	m.Path("/services/{id:.+}/update").Methods(http.MethodPost).HandlerFunc(s.handlerWrapper(s.serviceUpdate))
 	m.Path("/tasks").Methods(http.MethodGet).HandlerFunc(s.handlerWrapper(s.taskList))
+	m.Path("/tasks/{id:.+}/logs").Methods(http.MethodGet).HandlerFunc(s.handlerWrapper(s.taskLogs))
 	m.Path("/tasks/{id:.+}").Methods(http.MethodGet).HandlerFunc(s.handlerWrapper(s.taskInspect))
+	m.Path("/secrets/create").Methods(http.MethodPost).HandlerFunc(s.handlerWrapper(s.secretCreate))
+	m.Path("/secrets/{id:.+}").Methods(http.MethodGet).HandlerFunc(s.handlerWrapper(s.secretInspect))
+	m.Path("/secrets").Methods(http.MethodGet).HandlerFunc(s.handlerWrapper(s.secretList))
+	m.Path("/secrets/{id:.+}").Methods(http.MethodDelete).HandlerFunc(s.handlerWrapper(s.secretDelete))
+	m.Path("/secrets/{id:.+}/update").Methods(http.MethodPost).HandlerFunc(s.handlerWrapper(s.secretUpdate))
+	m.Path("/configs/create").Methods(http.MethodPost).HandlerFunc(s.handlerWrapper(s.configCreate))
+	m.Path("/configs/{id:.+}").Methods(http.MethodGet).HandlerFunc(s.handlerWrapper(s.configInspect))
+	m.Path("/configs").Methods(http.MethodGet).HandlerFunc(s.handlerWrapper(s.configList))
+	m.Path("/configs/{id:.+}").Methods(http.MethodDelete).HandlerFunc(s.handlerWrapper(s.configDelete))
+	m.Path("/configs/{id:.+}/update").Methods(http.MethodPost).HandlerFunc(s.handlerWrapper(s.configUpdate))
 }
 
 // SetHook changes the hook function used by the server.
@@ -265,6 +340,70 @@ func (s *DockerServer) PrepareExec(id string, callback func()) {
 	s.execCallbacks[id] = callback
 }
 
+// PrepareExecOptions describes the canned result of an exec instance in the
+// fake server. It's used by PrepareExecResult.
+type PrepareExecOptions struct {
+	// Cmd is the command of the exec instances that get this result. When
+	// empty, the result is used by the next started exec that doesn't match
+	// any other prepared command.
+	Cmd []string
+
+	// Stdout and Stderr are sent to the client attached to the exec. When
+	// the exec uses a TTY, they're sent as a single raw stream, otherwise
+	// they're multiplexed.
+	Stdout []byte
+	Stderr []byte
+
+	// ExitCode is reported by InspectExec after the exec finishes.
+	ExitCode int
+}
+
+// PrepareExecResult scripts the output and exit code of exec instances
+// started in the fake server.
+//
+// Results with a Cmd apply to every exec created with that command, which is
+// useful for commands that run multiple times, such as health checks. Results
+// without a Cmd are used once each, in the order they were prepared. For
+// example:
+//
+//    server.PrepareExecResult(testing.PrepareExecOptions{
+//        Cmd:      []string{"pg_isready"},
+//        Stdout:   []byte("accepting connections\n"),
+//        ExitCode: 0,
+//    })
+//
+// Callbacks registered with PrepareExec run before the output is sent.
+func (s *DockerServer) PrepareExecResult(opts PrepareExecOptions) {
+	s.execMut.Lock()
+	defer s.execMut.Unlock()
+	s.execResults = append(s.execResults, opts)
+}
+
+// execResult returns, and consumes when it's not bound to a command, the
+// result prepared for the given exec instance. It must be called with
+// execMut held.
+func (s *DockerServer) execResult(exec *docker.ExecInspect) (PrepareExecOptions, bool) {
+	cmd := append([]string{exec.ProcessConfig.EntryPoint}, exec.ProcessConfig.Arguments...)
+	sequence := -1
+	for i, result := range s.execResults {
+		if len(result.Cmd) == 0 {
+			if sequence < 0 {
+				sequence = i
+			}
+			continue
+		}
+		if reflect.DeepEqual(result.Cmd, cmd) {
+			return result, true
+		}
+	}
+	if sequence < 0 {
+		return PrepareExecOptions{}, false
+	}
+	result := s.execResults[sequence]
+	s.execResults = append(s.execResults[:sequence], s.execResults[sequence+1:]...)
+	return result, true
+}
+
 // PrepareStats adds a callback that will be called for each container stats
 // call.
 //
@@ -277,25 +416,118 @@ func (s *DockerServer) PrepareStats(id string, callback func(string) docker.Stat
 // PrepareFailure adds a new expected failure based on a URL regexp it receives
 // an id for the failure.
 func (s *DockerServer) PrepareFailure(id string, urlRegexp string) {
+	s.failureMut.Lock()
+	defer s.failureMut.Unlock()
 	s.failures[id] = urlRegexp
 }
 
+// FailureMatcher selects the requests that fail in PrepareFailureMatcher.
+type FailureMatcher struct {
+	// Method is the HTTP method of the requests, matching any method when
+	// empty.
+	Method string
+
+	// Path is matched against the path of the requests, matching any path
+	// when nil.
+	Path *regexp.Regexp
+
+	// Calls, when set, is called with the number of requests that matched
+	// Method and Path so far, including the current one (starting at 1), and
+	// the request only fails when it returns true. For example, to fail the
+	// fifth request:
+	//
+	//     Calls: func(n int) bool { return n == 5 }
+	Calls func(n int) bool
+
+	// Status is the status code of the failure, defaulting to 400 (Bad
+	// Request), like PrepareFailure.
+	Status int
+
+	// OneShot makes the failure fire only once, being removed afterwards.
+	OneShot bool
+}
+
+type failureRule struct {
+	id      string
+	matcher FailureMatcher
+	calls   int
+}
+
+// PrepareFailureMatcher adds a new expected failure for the requests selected
+// by the given matcher, replacing any failure previously prepared with the
+// same id. The id is used as the error message, and the failure can be
+// removed with ResetFailure.
+//
+// For example, to make only DELETE requests to containers fail with 500:
+//
+//     server.PrepareFailureMatcher("remove failed", testing.FailureMatcher{
+//         Method: http.MethodDelete,
+//         Path:   regexp.MustCompile("^/containers/"),
+//         Status: http.StatusInternalServerError,
+//     })
+func (s *DockerServer) PrepareFailureMatcher(id string, matcher FailureMatcher) {
+	s.failureMut.Lock()
+	defer s.failureMut.Unlock()
+	s.removeFailureRule(id)
+	s.failureRules = append(s.failureRules, &failureRule{id: id, matcher: matcher})
+}
+
 // PrepareMultiFailures enqueues a new expected failure based on a URL regexp
 // it receives an id for the failure.
 func (s *DockerServer) PrepareMultiFailures(id string, urlRegexp string) {
+	s.failureMut.Lock()
+	defer s.failureMut.Unlock()
 	s.multiFailures = append(s.multiFailures, map[string]string{"error": id, "url": urlRegexp})
 }
 
 // ResetFailure removes an expected failure identified by the given id.
 func (s *DockerServer) ResetFailure(id string) {
+	s.failureMut.Lock()
+	defer s.failureMut.Unlock()
 	delete(s.failures, id)
+	s.removeFailureRule(id)
 }
 
 // ResetMultiFailures removes all enqueued failures.
 func (s *DockerServer) ResetMultiFailures() {
+	s.failureMut.Lock()
+	defer s.failureMut.Unlock()
 	s.multiFailures = []map[string]string{}
 }
 
+// removeFailureRule must be called with failureMut held.
+func (s *DockerServer) removeFailureRule(id string) {
+	for i, rule := range s.failureRules {
+		if rule.id == id {
+			s.failureRules = append(s.failureRules[:i], s.failureRules[i+1:]...)
+			return
+		}
+	}
+}
+
+// matchFailureRules returns the failure rule that applies to the request, if
+// any. All the rules matching the method and path of the request count it as
+// a call. It must be called with failureMut held.
+func (s *DockerServer) matchFailureRules(r *http.Request) *failureRule {
+	var fired *failureRule
+	for _, rule := range s.failureRules {
+		if rule.matcher.Method != "" && rule.matcher.Method != r.Method {
+			continue
+		}
+		if rule.matcher.Path != nil && !rule.matcher.Path.MatchString(r.URL.Path) {
+			continue
+		}
+		rule.calls++
+		if fired == nil && (rule.matcher.Calls == nil || rule.matcher.Calls(rule.calls)) {
+			fired = rule
+		}
+	}
+	if fired != nil && fired.matcher.OneShot {
+		s.removeFailureRule(fired.id)
+	}
+	return fired
+}
+
 // CustomHandler registers a custom handler for a specific path.
 //
 // For example:
@@ -309,6 +541,19 @@ func (s *DockerServer) CustomHandler(path string, handler http.Handler) {
 	s.handlerMutex.Unlock()
 }
 
+// SetContainerMutator sets a function that is called with each matched
+// container right before the inspect and list handlers encode it, allowing
+// tests to simulate state transitions deterministically (e.g. flipping a
+// container to exited after a number of inspects).
+//
+// The mutator is called with the containers lock held, so it must not call
+// other methods of the server. Passing nil removes the mutator.
+func (s *DockerServer) SetContainerMutator(mutator func(*docker.Container)) {
+	s.cMut.Lock()
+	defer s.cMut.Unlock()
+	s.cMutator = mutator
+}
+
 // MutateContainer changes the state of a container, returning an error if the
 // given id does not match to any container "running" in the server.
 func (s *DockerServer) MutateContainer(id string, state docker.State) error {
@@ -321,6 +566,139 @@ func (s *DockerServer) MutateContainer(id string, state docker.State) error {
 	return errors.New("container not found")
 }
 
+// ExitContainer simulates the exit of the given running container, with the
+// given exit code. As in the daemon, a container created with
+// HostConfig.AutoRemove is removed as soon as it exits.
+func (s *DockerServer) ExitContainer(id string, exitCode int) error {
+	s.cMut.Lock()
+	defer s.cMut.Unlock()
+	container, err := s.findContainerWithLock(id, false)
+	if err != nil {
+		return err
+	}
+	if !container.State.Running {
+		return errors.New("container not running")
+	}
+	container.State.Running = false
+	container.State.ExitCode = exitCode
+	container.State.FinishedAt = time.Now()
+	s.notify(container)
+	s.containerEvent("die", container)
+	if container.HostConfig != nil && container.HostConfig.AutoRemove {
+		delete(s.containers, container.ID)
+		delete(s.contNameToID, container.Name)
+		s.containerEvent("destroy", container)
+	}
+	return nil
+}
+
+// SetContainerLogs stores the output that the logs endpoints replay for the
+// container identified by id. When logs are stored for a container, the
+// container logs endpoint and the logs endpoint of the service that owns the
+// container write them as a multiplexed stream, honoring the stdout and stderr
+// parameters.
+func (s *DockerServer) SetContainerLogs(id, stdout, stderr string) {
+	s.cMut.Lock()
+	defer s.cMut.Unlock()
+	s.logs[id] = containerLogs{stdout: stdout, stderr: stderr}
+}
+
+// SetContainerInspectOverride makes the inspect endpoint return the given raw
+// JSON payload for the container identified by id, instead of the response
+// generated from the container stored in the server. The id may also be a
+// name, or refer to a container that does not exist in the server.
+func (s *DockerServer) SetContainerInspectOverride(id string, raw json.RawMessage) {
+	s.cMut.Lock()
+	s.inspectRaw[id] = raw
+	s.cMut.Unlock()
+}
+
+// ClearContainerInspectOverride removes the inspect override previously set
+// with SetContainerInspectOverride for the given id.
+func (s *DockerServer) ClearContainerInspectOverride(id string) {
+	s.cMut.Lock()
+	delete(s.inspectRaw, id)
+	s.cMut.Unlock()
+}
+
+// SetRemoteTags sets the tags available in the registry for the given
+// repository. Pulling the repository without a tag, like PullImage does when
+// All is set, pulls every one of these tags, storing an image for each of
+// them and writing the progress of all of them to the response.
+func (s *DockerServer) SetRemoteTags(repository string, tags ...string) {
+	s.iMut.Lock()
+	defer s.iMut.Unlock()
+	s.remoteTags[repository] = tags
+}
+
+// PullProgress is a progress message sent by a pull scripted with
+// PrepareImagePull. It's sent in the same JSON format used by the daemon,
+// with the progress bar rendered from Current and Total.
+type PullProgress struct {
+	// ID is the ID of the layer, or the tag for the first message of the
+	// pull.
+	ID string
+
+	// Status is the status of the layer, like "Pulling fs layer",
+	// "Downloading", "Extracting" or "Pull complete".
+	Status string
+
+	Current int64
+	Total   int64
+}
+
+// PrepareImagePullOptions describes the behavior of the fake server when
+// pulling an image. It's used by PrepareImagePull.
+type PrepareImagePullOptions struct {
+	// Status makes the pull fail before the progress stream starts, like
+	// the daemon does when the image doesn't exist in the registry. Zero
+	// means the pull is accepted.
+	Status int
+
+	// Message is the error message sent along with Status. It defaults to
+	// the text of the status code.
+	Message string
+
+	// Progress holds the messages sent to the client, in order, before the
+	// pull completes.
+	Progress []PullProgress
+
+	// Delay is the time the server waits before sending each message in
+	// Progress.
+	Delay time.Duration
+
+	// Error makes the pull fail after the messages in Progress are sent,
+	// with an error sent in the progress stream. The image isn't stored.
+	Error string
+}
+
+// PrepareImagePull scripts the pulls of the image identified by the given
+// reference, in the repository:tag or repository@digest format (or just the
+// repository, for pulls without a tag). Pulls not prepared succeed right
+// away. For example, to make a pull fail like the daemon does when the image
+// doesn't exist:
+//
+//    server.PrepareImagePull("registry.example.com/app:v2", testing.PrepareImagePullOptions{
+//        Status:  http.StatusNotFound,
+//        Message: "manifest for registry.example.com/app:v2 not found: manifest unknown: manifest unknown",
+//    })
+//
+// The behavior applies to every pull of the reference, until it's removed
+// with ResetImagePull.
+func (s *DockerServer) PrepareImagePull(name string, opts PrepareImagePullOptions) {
+	s.iMut.Lock()
+	defer s.iMut.Unlock()
+	s.imagePulls[name] = opts
+}
+
+// ResetImagePull removes the behavior prepared with PrepareImagePull for the
+// given reference.
+func (s *DockerServer) ResetImagePull(name string) {
+	s.iMut.Lock()
+	defer s.iMut.Unlock()
+	delete(s.imagePulls, name)
+}
+
 // Stop stops the server.
 func (s *DockerServer) Stop() {
 	if s.listener != nil {
@@ -363,37 +741,63 @@ func (s *DockerServer) DefaultHandler() http.Handler {
 
 func (s *DockerServer) handlerWrapper(f http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		for errorID, urlRegexp := range s.failures {
-			matched, err := regexp.MatchString(urlRegexp, r.URL.Path)
-			if err != nil {
-				http.Error(w, err.Error(), http.StatusBadRequest)
-				return
-			}
-			if !matched {
-				continue
-			}
-			http.Error(w, errorID, http.StatusBadRequest)
+		if s.failRequest(w, r) {
 			return
 		}
-		for i, failure := range s.multiFailures {
-			matched, err := regexp.MatchString(failure["url"], r.URL.Path)
-			if err != nil {
-				http.Error(w, err.Error(), http.StatusBadRequest)
-				return
-			}
-			if !matched {
-				continue
-			}
-			http.Error(w, failure["error"], http.StatusBadRequest)
-			s.multiFailures = append(s.multiFailures[:i], s.multiFailures[i+1:]...)
+		// matching the path first avoids taking swarmMut on internal node
+		// operations, which run while swarm handlers hold it.
+		if lockedSwarmPath.MatchString(r.URL.Path) && s.swarmIsLocked() {
+			http.Error(w, "swarm is locked", http.StatusServiceUnavailable)
 			return
 		}
 		f(w, r)
 	}
 }
 
+// failRequest writes the prepared failure that applies to the request, if
+// any, reporting whether the request failed.
+func (s *DockerServer) failRequest(w http.ResponseWriter, r *http.Request) bool {
+	s.failureMut.Lock()
+	defer s.failureMut.Unlock()
+	for errorID, urlRegexp := range s.failures {
+		matched, err := regexp.MatchString(urlRegexp, r.URL.Path)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return true
+		}
+		if !matched {
+			continue
+		}
+		http.Error(w, errorID, http.StatusBadRequest)
+		return true
+	}
+	for i, failure := range s.multiFailures {
+		matched, err := regexp.MatchString(failure["url"], r.URL.Path)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return true
+		}
+		if !matched {
+			continue
+		}
+		http.Error(w, failure["error"], http.StatusBadRequest)
+		s.multiFailures = append(s.multiFailures[:i], s.multiFailures[i+1:]...)
+		return true
+	}
+	if rule := s.matchFailureRules(r); rule != nil {
+		status := rule.matcher.Status
+		if status == 0 {
+			status = http.StatusBadRequest
+		}
+		http.Error(w, rule.id, status)
+		return true
+	}
+	return false
+}
+
 func (s *DockerServer) listContainers(w http.ResponseWriter, r *http.Request) {
 	all := r.URL.Query().Get("all")
+	size, _ := strconv.ParseBool(r.URL.Query().Get("size"))
 	filtersRaw := r.FormValue("filters")
 	filters := make(map[string][]string)
 	json.Unmarshal([]byte(filtersRaw), &filters)
@@ -406,10 +810,13 @@ func (s *DockerServer) listContainers(w http.ResponseWriter, r *http.Request) {
 		}
 		labelFilters[parts[0]] = nil
 	}
-	s.cMut.RLock()
+	s.cMut.Lock()
 	result := make([]docker.APIContainers, 0, len(s.containers))
 loop:
 	for _, container := range s.containers {
+		if s.cMutator != nil {
+			s.cMutator(container)
+		}
 		if all == "1" || container.State.Running {
 			var ports []docker.APIPort
 			if container.NetworkSettings != nil {
@@ -424,7 +831,7 @@ loop:
 					continue loop
 				}
 			}
-			result = append(result, docker.APIContainers{
+			apiContainer := docker.APIContainers{
 				ID:      container.ID,
 				Image:   container.Image,
 				Command: fmt.Sprintf("%s %s", container.Path, strings.Join(container.Args, " ")),
@@ -433,32 +840,199 @@ loop:
 				State:   container.State.StateString(),
 				Ports:   ports,
 				Names:   []string{fmt.Sprintf("/%s", container.Name)},
-			})
+			}
+			if size {
+				apiContainer.SizeRw = s.containerSizeRw(container.ID)
+				apiContainer.SizeRootFs = apiContainer.SizeRw + s.imageSize(container.Image)
+			}
+			result = append(result, apiContainer)
 		}
 	}
-	s.cMut.RUnlock()
+	s.cMut.Unlock()
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(result)
 }
 
 func (s *DockerServer) listImages(w http.ResponseWriter, r *http.Request) {
+	filters := make(map[string][]string)
+	json.Unmarshal([]byte(r.FormValue("filters")), &filters)
+	digests := r.URL.Query().Get("digests") == "1"
+	s.iMut.RLock()
+	defer s.iMut.RUnlock()
+	var since, before *time.Time
+	for _, bound := range []struct {
+		filter string
+		dest   **time.Time
+	}{{"since", &since}, {"before", &before}} {
+		for _, ref := range filters[bound.filter] {
+			id, ok := s.imgIDs[ref]
+			if !ok {
+				id = ref
+			}
+			image, ok := s.images[id]
+			if !ok {
+				http.Error(w, "no such image: "+ref, http.StatusNotFound)
+				return
+			}
+			created := image.Created
+			*bound.dest = &created
+		}
+	}
+	result := make([]docker.APIImages, 0, len(s.images))
+	for _, image := range s.images {
+		if since != nil && !image.Created.After(*since) {
+			continue
+		}
+		if before != nil && !image.Created.Before(*before) {
+			continue
+		}
+		var labels map[string]string
+		if image.Config != nil {
+			labels = image.Config.Labels
+		}
+		if !matchLabels(labels, filters["label"]) {
+			continue
+		}
+		var tags []string
+		for tag, id := range s.imgIDs {
+			if id == image.ID && matchReference(tag, filters["reference"]) {
+				tags = append(tags, tag)
+			}
+		}
+		if len(filters["reference"]) > 0 && len(tags) == 0 {
+			continue
+		}
+		if dangling := filters["dangling"]; len(dangling) > 0 && (dangling[0] == "true") != (len(tags) == 0) {
+			continue
+		}
+		sort.Strings(tags)
+		apiImage := docker.APIImages{
+			ID:       image.ID,
+			Created:  image.Created.Unix(),
+			RepoTags: tags,
+		}
+		if digests {
+			apiImage.RepoDigests = image.RepoDigests
+		}
+		result = append(result, apiImage)
+	}
+	// like the daemon, list the most recent images first.
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Created != result[j].Created {
+			return result[i].Created > result[j].Created
+		}
+		return result[i].ID < result[j].ID
+	})
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(result)
+}
+
+// matchReference reports whether the given repository:tag matches any of the
+// reference filters, which are glob patterns. A pattern without a tag also
+// matches on the repository alone, so "nginx" matches every nginx tag.
+func matchReference(ref string, patterns []string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	repo := ref
+	if i := strings.LastIndex(ref, ":"); i > strings.LastIndex(ref, "/") {
+		repo = ref[:i]
+	}
+	for _, pattern := range patterns {
+		if ok, _ := libpath.Match(pattern, ref); ok {
+			return true
+		}
+		if ok, _ := libpath.Match(pattern, repo); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// matchLabels reports whether the labels satisfy all the label filters, in
+// the key or key=value format.
+func matchLabels(labels map[string]string, filters []string) bool {
+	for _, f := range filters {
+		parts := strings.SplitN(f, "=", 2)
+		value, ok := labels[parts[0]]
+		if !ok || (len(parts) == 2 && value != parts[1]) {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *DockerServer) diskUsage(w http.ResponseWriter, r *http.Request) {
+	types := r.URL.Query()["type"]
+	wants := func(t string) bool {
+		if len(types) == 0 {
+			return true
+		}
+		for _, want := range types {
+			if want == t {
+				return true
+			}
+		}
+		return false
+	}
+	containerImages := make(map[string]string)
+	var result docker.DiskUsage
 	s.cMut.RLock()
-	result := make([]docker.APIImages, len(s.images))
-	i := 0
+	for _, container := range s.containers {
+		containerImages[container.ID] = container.Image
+		if !wants(docker.DiskUsageContainers) {
+			continue
+		}
+		result.Containers = append(result.Containers, &docker.APIContainers{
+			ID:      container.ID,
+			Image:   container.Image,
+			Command: fmt.Sprintf("%s %s", container.Path, strings.Join(container.Args, " ")),
+			Created: container.Created.Unix(),
+			Status:  container.State.String(),
+			State:   container.State.StateString(),
+			Names:   []string{fmt.Sprintf("/%s", container.Name)},
+		})
+	}
+	s.cMut.RUnlock()
+	s.iMut.RLock()
 	for _, image := range s.images {
-		result[i] = docker.APIImages{
-			ID:      image.ID,
-			Created: image.Created.Unix(),
+		result.LayersSize += image.Size
+		if !wants(docker.DiskUsageImages) {
+			continue
+		}
+		summary := docker.ImageSummary{
+			ID:          image.ID,
+			Created:     image.Created.Unix(),
+			Size:        image.Size,
+			VirtualSize: image.VirtualSize,
 		}
 		for tag, id := range s.imgIDs {
 			if id == image.ID {
-				result[i].RepoTags = append(result[i].RepoTags, tag)
+				summary.RepoTags = append(summary.RepoTags, tag)
+			}
+		}
+		for _, name := range containerImages {
+			if name == image.ID || s.imgIDs[name] == image.ID {
+				summary.Containers++
 			}
 		}
-		i++
+		result.Images = append(result.Images, &summary)
+	}
+	s.iMut.RUnlock()
+	if wants(docker.DiskUsageVolumes) {
+		s.volMut.RLock()
+		for _, volumeCounter := range s.volStore {
+			volume := volumeCounter.volume
+			volume.UsageData = &docker.VolumeUsageData{RefCount: int64(volumeCounter.count)}
+			result.Volumes = append(result.Volumes, &volume)
+		}
+		s.volMut.RUnlock()
+	}
+	if wants(docker.DiskUsageBuildCache) {
+		result.BuildCache = []*docker.BuildCacheRecord{}
 	}
-	s.cMut.RUnlock()
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(result)
@@ -474,6 +1048,13 @@ func (s *DockerServer) findImage(id string) (string, error) {
 	if _, ok := s.images[id]; ok {
 		return id, nil
 	}
+	if strings.Contains(id, "@") {
+		for imageID, image := range s.images {
+			if containsString(image.RepoDigests, id) {
+				return imageID, nil
+			}
+		}
+	}
 	return "", errors.New("no such image")
 }
 
@@ -498,6 +1079,12 @@ func (s *DockerServer) createContainer(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusNotFound)
 		return
 	}
+	if config.HostConfig != nil {
+		if err := validateDeviceRequests(config.HostConfig.DeviceRequests); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
 	ports := map[docker.Port][]docker.PortBinding{}
 	for port := range config.ExposedPorts {
 		ports[port] = []docker.PortBinding{{
@@ -531,7 +1118,9 @@ func (s *DockerServer) createContainer(w http.ResponseWriter, r *http.Request) {
 			Pid:      mathrand.Int() % 50000,
 			ExitCode: 0,
 		},
-		Image: config.Image,
+		Image:    config.Image,
+		Platform: r.URL.Query().Get("platform"),
+		Mounts:   s.containerMounts(config.Config, config.HostConfig),
 		NetworkSettings: &docker.NetworkSettings{
 			IPAddress:   fmt.Sprintf("172.16.42.%d", mathrand.Int()%250+2),
 			IPPrefixLen: 24,
@@ -556,10 +1145,110 @@ func (s *DockerServer) createContainer(w http.ResponseWriter, r *http.Request) {
 	s.cMut.Unlock()
 	w.WriteHeader(http.StatusCreated)
 	s.notify(&container)
+	s.containerEvent("create", &container)
 
 	json.NewEncoder(w).Encode(container)
 }
 
+// validateDeviceRequests mimics the checks done by the daemon on the device
+// requests of a container. As the server doesn't have any device driver,
+// requests with an empty driver are accepted for any capability.
+func validateDeviceRequests(requests []docker.DeviceRequest) error {
+	for _, request := range requests {
+		if request.Count != 0 && len(request.DeviceIDs) > 0 {
+			return errors.New("cannot set both Count and DeviceIDs on device request")
+		}
+		if request.Driver == "" && len(request.Capabilities) == 0 {
+			return errors.New("could not select device driver \"\" with capabilities: []")
+		}
+	}
+	return nil
+}
+
+var bindPropagations = []string{"rprivate", "private", "rshared", "shared", "rslave", "slave"}
+
+// containerMounts returns the mount points of a container created with the
+// given configuration, in the format reported by the inspect endpoint.
+func (s *DockerServer) containerMounts(config *docker.Config, hostConfig *docker.HostConfig) []docker.Mount {
+	var mounts []docker.Mount
+	if hostConfig != nil {
+		for _, bind := range hostConfig.Binds {
+			parts := strings.SplitN(bind, ":", 3)
+			if len(parts) < 2 {
+				continue
+			}
+			var mode string
+			if len(parts) == 3 {
+				mode = parts[2]
+			}
+			options := strings.Split(mode, ",")
+			mount := docker.Mount{Destination: parts[1], Mode: mode, RW: !containsString(options, "ro")}
+			if strings.HasPrefix(parts[0], "/") {
+				mount.Type = "bind"
+				mount.Source = parts[0]
+				mount.Propagation = "rprivate"
+				for _, option := range options {
+					if containsString(bindPropagations, option) {
+						mount.Propagation = option
+					}
+				}
+			} else {
+				mount = s.volumeMount(parts[0], "", mount)
+			}
+			mounts = append(mounts, mount)
+		}
+		for _, m := range hostConfig.Mounts {
+			mount := docker.Mount{Type: m.Type, Destination: m.Target, RW: !m.ReadOnly}
+			switch m.Type {
+			case "bind":
+				mount.Source = m.Source
+				mount.Propagation = "rprivate"
+				if m.BindOptions != nil && m.BindOptions.Propagation != "" {
+					mount.Propagation = m.BindOptions.Propagation
+				}
+			case "volume":
+				var driver string
+				if m.VolumeOptions != nil {
+					driver = m.VolumeOptions.DriverConfig.Name
+				}
+				mount = s.volumeMount(m.Source, driver, mount)
+			}
+			mounts = append(mounts, mount)
+		}
+	}
+	if config != nil {
+		for destination := range config.Volumes {
+			mounts = append(mounts, s.volumeMount("", "", docker.Mount{Destination: destination, RW: true}))
+		}
+	}
+	return mounts
+}
+
+// volumeMount fills in the details of a mount of the given volume, generating
+// a name for anonymous volumes.
+func (s *DockerServer) volumeMount(name, driver string, mount docker.Mount) docker.Mount {
+	if name == "" {
+		name = s.generateID()
+	}
+	if driver == "" {
+		driver = "local"
+	}
+	mount.Type = "volume"
+	mount.Name = name
+	mount.Source = "/var/lib/docker/volumes/" + name + "/_data"
+	mount.Driver = driver
+	return mount
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
 func (s *DockerServer) addContainer(container *docker.Container) {
 	s.containers[container.ID] = container
 	if container.Name != "" {
@@ -582,28 +1271,82 @@ func (s *DockerServer) renameContainer(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusNotFound)
 		return
 	}
+	name := strings.TrimPrefix(r.URL.Query().Get("name"), "/")
+	if !nameRegexp.MatchString(name) {
+		http.Error(w, "Invalid container name ("+name+"), only [a-zA-Z0-9][a-zA-Z0-9_.-] are allowed", http.StatusBadRequest)
+		return
+	}
+	if otherID, ok := s.contNameToID[name]; ok && otherID != container.ID {
+		http.Error(w, "Conflict. The container name \"/"+name+"\" is already in use by container \""+otherID+"\".", http.StatusConflict)
+		return
+	}
 	delete(s.contNameToID, container.Name)
-	container.Name = r.URL.Query().Get("name")
+	container.Name = name
 	s.contNameToID[container.Name] = container.ID
 	w.WriteHeader(http.StatusNoContent)
 }
 
 func (s *DockerServer) inspectContainer(w http.ResponseWriter, r *http.Request) {
 	id := mux.Vars(r)["id"]
+	s.cMut.RLock()
+	raw, ok := s.inspectRaw[id]
+	s.cMut.RUnlock()
 	container, err := s.findContainer(id)
-	if err != nil {
+	if err != nil && !ok {
 		http.Error(w, err.Error(), http.StatusNotFound)
 		return
 	}
+	size, _ := strconv.ParseBool(r.URL.Query().Get("size"))
+	var imageSize int64
+	if size && container != nil {
+		imageSize = s.imageSize(container.Image)
+	}
+	s.cMut.Lock()
+	defer s.cMut.Unlock()
+	if container != nil && s.cMutator != nil {
+		s.cMutator(container)
+	}
+	if !ok {
+		raw, ok = s.inspectRaw[container.ID]
+	}
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	s.cMut.RLock()
-	defer s.cMut.RUnlock()
-	json.NewEncoder(w).Encode(container)
-}
-
-func (s *DockerServer) statsContainer(w http.ResponseWriter, r *http.Request) {
-	id := mux.Vars(r)["id"]
+	if ok {
+		w.Write(raw)
+		return
+	}
+	result := *container
+	result.SizeRw, result.SizeRootFs = 0, 0
+	if size {
+		result.SizeRw = s.containerSizeRw(container.ID)
+		result.SizeRootFs = result.SizeRw + imageSize
+	}
+	json.NewEncoder(w).Encode(result)
+}
+
+// containerSizeRw returns the size of the files uploaded to the container,
+// which make up its writable layer. It must be called with cMut held.
+func (s *DockerServer) containerSizeRw(id string) int64 {
+	var size int64
+	for _, file := range s.containerFiles[id] {
+		size += int64(len(file.content))
+	}
+	return size
+}
+
+// imageSize returns the size of the image with the given ID or tag, or zero
+// if the image doesn't exist.
+func (s *DockerServer) imageSize(id string) int64 {
+	s.iMut.RLock()
+	defer s.iMut.RUnlock()
+	if imageID, ok := s.imgIDs[id]; ok {
+		id = imageID
+	}
+	return s.images[id].Size
+}
+
+func (s *DockerServer) statsContainer(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
 	_, err := s.findContainer(id)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusNotFound)
@@ -628,42 +1371,134 @@ func (s *DockerServer) statsContainer(w http.ResponseWriter, r *http.Request) {
 
 func (s *DockerServer) uploadToContainer(w http.ResponseWriter, r *http.Request) {
 	id := mux.Vars(r)["id"]
-	_, err := s.findContainer(id)
+	container, err := s.findContainer(id)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusNotFound)
 		return
 	}
-	path := r.URL.Query().Get("path")
+	query := r.URL.Query()
+	path := query.Get("path")
+	noOverwriteDirNonDir, _ := strconv.ParseBool(query.Get("noOverwriteDirNonDir"))
+	copyUIDGID, _ := strconv.ParseBool(query.Get("copyUIDGID"))
+	var files []*containerFile
 	if r.Body != nil {
 		tr := tar.NewReader(r.Body)
-		if hdr, _ := tr.Next(); hdr != nil {
-			path = libpath.Join(path, hdr.Name)
+		for {
+			hdr, err := tr.Next()
+			if err != nil {
+				break
+			}
+			content, err := ioutil.ReadAll(tr)
+			if err != nil {
+				break
+			}
+			files = append(files, &containerFile{header: *hdr, content: content})
 		}
 	}
+	uploadedPath := path
+	if len(files) > 0 {
+		uploadedPath = libpath.Join(path, files[0].header.Name)
+	}
 	s.cMut.Lock()
-	s.uploadedFiles[id] = path
-	s.cMut.Unlock()
+	defer s.cMut.Unlock()
+	stored := s.containerFiles[container.ID]
+	for _, file := range files {
+		existing, ok := stored[libpath.Join("/", path, file.header.Name)]
+		if !noOverwriteDirNonDir || !ok {
+			continue
+		}
+		if isDir := file.header.Typeflag == tar.TypeDir; isDir != (existing.header.Typeflag == tar.TypeDir) {
+			http.Error(w, fmt.Sprintf("cannot overwrite %q: the types of the files don't match", libpath.Join("/", path, file.header.Name)), http.StatusBadRequest)
+			return
+		}
+	}
+	if stored == nil && len(files) > 0 {
+		if s.containerFiles == nil {
+			s.containerFiles = make(map[string]map[string]*containerFile)
+		}
+		stored = make(map[string]*containerFile)
+		s.containerFiles[container.ID] = stored
+	}
+	for _, file := range files {
+		if copyUIDGID {
+			file.header.Uid, file.header.Gid = containerUIDGID(container)
+		}
+		stored[libpath.Join("/", path, file.header.Name)] = file
+	}
+	s.uploadedFiles[id] = uploadedPath
 	w.WriteHeader(http.StatusOK)
 }
 
+// containerUIDGID returns the numeric user and group of the container, which
+// the daemon uses as the owner of the uploaded files when copyUIDGID is set.
+// Users that aren't numeric are treated as root.
+func containerUIDGID(container *docker.Container) (int, int) {
+	if container.Config == nil {
+		return 0, 0
+	}
+	parts := strings.SplitN(container.Config.User, ":", 2)
+	uid, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, 0
+	}
+	gid := uid
+	if len(parts) == 2 {
+		if gid, err = strconv.Atoi(parts[1]); err != nil {
+			gid = uid
+		}
+	}
+	return uid, gid
+}
+
 func (s *DockerServer) downloadFromContainer(w http.ResponseWriter, r *http.Request) {
 	id := mux.Vars(r)["id"]
-	_, err := s.findContainer(id)
+	container, err := s.findContainer(id)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusNotFound)
 		return
 	}
 	path := r.URL.Query().Get("path")
+	root := libpath.Join("/", path)
 	s.cMut.RLock()
-	val, ok := s.uploadedFiles[id]
-	s.cMut.RUnlock()
-	if !ok || val != path {
-		w.WriteHeader(http.StatusNotFound)
-		fmt.Fprintf(w, "Path %s not found", path)
-		return
+	defer s.cMut.RUnlock()
+	var paths []string
+	for filePath := range s.containerFiles[container.ID] {
+		if filePath == root || strings.HasPrefix(filePath, strings.TrimSuffix(root, "/")+"/") {
+			paths = append(paths, filePath)
+		}
+	}
+	if len(paths) == 0 {
+		if val, ok := s.uploadedFiles[id]; !ok || val != path {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprintf(w, "Path %s not found", path)
+			return
+		}
 	}
+	sort.Strings(paths)
 	w.Header().Set("Content-Type", "application/x-tar")
 	w.WriteHeader(http.StatusOK)
+	if len(paths) == 0 {
+		return
+	}
+	// like the daemon, name the entries relative to the parent of the
+	// requested path.
+	parent := libpath.Dir(root)
+	tw := tar.NewWriter(w)
+	for _, filePath := range paths {
+		file := s.containerFiles[container.ID][filePath]
+		header := file.header
+		header.Name = strings.TrimPrefix(strings.TrimPrefix(filePath, parent), "/")
+		if header.Typeflag == tar.TypeDir {
+			header.Name += "/"
+		}
+		if err := tw.WriteHeader(&header); err != nil {
+			return
+		}
+		if _, err := tw.Write(file.content); err != nil {
+			return
+		}
+	}
+	tw.Close()
 }
 
 func (s *DockerServer) topContainer(w http.ResponseWriter, r *http.Request) {
@@ -740,6 +1575,7 @@ func (s *DockerServer) startContainer(w http.ResponseWriter, r *http.Request) {
 	container.State.Running = true
 	container.State.StartedAt = time.Now()
 	s.notify(container)
+	s.containerEvent("start", container)
 }
 
 func (s *DockerServer) stopContainer(w http.ResponseWriter, r *http.Request) {
@@ -758,6 +1594,112 @@ func (s *DockerServer) stopContainer(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNoContent)
 	container.State.Running = false
 	s.notify(container)
+	action := "stop"
+	if strings.HasSuffix(r.URL.Path, "/kill") {
+		action = "kill"
+	}
+	s.containerEvent(action, container)
+	if container.HostConfig != nil && container.HostConfig.AutoRemove {
+		delete(s.containers, container.ID)
+		delete(s.contNameToID, container.Name)
+		s.containerEvent("destroy", container)
+	}
+}
+
+// containerUpdate is the body of requests to update containers.
+type containerUpdate struct {
+	BlkioWeight         uint16
+	BlkioWeightDevice   []docker.BlockWeight
+	BlkioDeviceReadBps  []docker.BlockLimit
+	BlkioDeviceWriteBps []docker.BlockLimit
+	CPUShares           int64 `json:"CpuShares"`
+	CPUPeriod           int64 `json:"CpuPeriod"`
+	CPUQuota            int64 `json:"CpuQuota"`
+	CpusetCpus          string
+	CpusetMems          string
+	Memory              int64
+	MemorySwap          int64
+	MemoryReservation   int64
+	RestartPolicy       docker.RestartPolicy
+}
+
+func (s *DockerServer) updateContainer(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	container, err := s.findContainer(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	var opts containerUpdate
+	if err := json.NewDecoder(r.Body).Decode(&opts); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if opts.BlkioWeight != 0 && (opts.BlkioWeight < 10 || opts.BlkioWeight > 1000) {
+		http.Error(w, docker.ErrInvalidBlkioWeight.Error(), http.StatusBadRequest)
+		return
+	}
+	switch opts.RestartPolicy.Name {
+	case "", "no", "always", "unless-stopped", "on-failure":
+	default:
+		http.Error(w, fmt.Sprintf("invalid restart policy %q", opts.RestartPolicy.Name), http.StatusBadRequest)
+		return
+	}
+	if opts.RestartPolicy.MaximumRetryCount != 0 && opts.RestartPolicy.Name != "on-failure" {
+		http.Error(w, docker.ErrInvalidMaximumRetryCount.Error(), http.StatusBadRequest)
+		return
+	}
+	s.cMut.Lock()
+	defer s.cMut.Unlock()
+	if container.HostConfig == nil {
+		container.HostConfig = &docker.HostConfig{}
+	}
+	hc := container.HostConfig
+	if name := opts.RestartPolicy.Name; name != "" && name != "no" && hc.AutoRemove {
+		http.Error(w, "restart policy cannot be updated because AutoRemove is enabled for the container", http.StatusConflict)
+		return
+	}
+	if opts.BlkioWeight != 0 {
+		hc.BlkioWeight = int64(opts.BlkioWeight)
+	}
+	if opts.BlkioWeightDevice != nil {
+		hc.BlkioWeightDevice = opts.BlkioWeightDevice
+	}
+	if opts.BlkioDeviceReadBps != nil {
+		hc.BlkioDeviceReadBps = opts.BlkioDeviceReadBps
+	}
+	if opts.BlkioDeviceWriteBps != nil {
+		hc.BlkioDeviceWriteBps = opts.BlkioDeviceWriteBps
+	}
+	if opts.CPUShares != 0 {
+		hc.CPUShares = opts.CPUShares
+	}
+	if opts.CPUPeriod != 0 {
+		hc.CPUPeriod = opts.CPUPeriod
+	}
+	if opts.CPUQuota != 0 {
+		hc.CPUQuota = opts.CPUQuota
+	}
+	if opts.CpusetCpus != "" {
+		hc.CPUSetCPUs = opts.CpusetCpus
+	}
+	if opts.CpusetMems != "" {
+		hc.CPUSetMEMs = opts.CpusetMems
+	}
+	if opts.Memory != 0 {
+		hc.Memory = opts.Memory
+	}
+	if opts.MemorySwap != 0 {
+		hc.MemorySwap = opts.MemorySwap
+	}
+	if opts.MemoryReservation != 0 {
+		hc.MemoryReservation = opts.MemoryReservation
+	}
+	if opts.RestartPolicy.Name != "" {
+		hc.RestartPolicy = opts.RestartPolicy
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string][]string{"Warnings": {}})
 }
 
 func (s *DockerServer) pauseContainer(w http.ResponseWriter, r *http.Request) {
@@ -775,6 +1717,7 @@ func (s *DockerServer) pauseContainer(w http.ResponseWriter, r *http.Request) {
 	}
 	w.WriteHeader(http.StatusNoContent)
 	container.State.Paused = true
+	s.containerEvent("pause", container)
 }
 
 func (s *DockerServer) unpauseContainer(w http.ResponseWriter, r *http.Request) {
@@ -792,6 +1735,7 @@ func (s *DockerServer) unpauseContainer(w http.ResponseWriter, r *http.Request)
 	}
 	w.WriteHeader(http.StatusNoContent)
 	container.State.Paused = false
+	s.containerEvent("unpause", container)
 }
 
 func (s *DockerServer) attachContainer(w http.ResponseWriter, r *http.Request) {
@@ -853,16 +1797,46 @@ func (s *DockerServer) waitContainer(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusNotFound)
 		return
 	}
+	condition := r.URL.Query().Get("condition")
+	switch condition {
+	case "", "not-running", "next-exit", "removed":
+	default:
+		http.Error(w, "invalid condition: "+condition, http.StatusBadRequest)
+		return
+	}
 	var exitCode int
+	s.cMut.RLock()
+	seenRunning := container.State.Running
+	startedAt := container.State.StartedAt
+	s.cMut.RUnlock()
+	// like the daemon, send the headers once the wait is registered, so
+	// clients can start the container knowing its exit won't be missed.
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if flusher, ok := w.(http.Flusher); ok {
+		flusher.Flush()
+	}
 	for {
-		time.Sleep(1e6)
+		select {
+		case <-r.Context().Done():
+			return
+		case <-time.After(time.Millisecond):
+		}
 		s.cMut.RLock()
-		if !container.State.Running {
-			exitCode = container.State.ExitCode
-			s.cMut.RUnlock()
+		running := container.State.Running
+		exitCode = container.State.ExitCode
+		_, exists := s.containers[container.ID]
+		// a container that started and exited between two checks
+		// has a new start time.
+		seenRunning = seenRunning || running || !container.State.StartedAt.Equal(startedAt)
+		s.cMut.RUnlock()
+		if condition == "removed" {
+			if !exists {
+				break
+			}
+		} else if !running && (condition != "next-exit" || seenRunning) {
 			break
 		}
-		s.cMut.RUnlock()
 	}
 	result := map[string]int{"StatusCode": exitCode}
 	json.NewEncoder(w).Encode(result)
@@ -886,6 +1860,7 @@ func (s *DockerServer) removeContainer(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNoContent)
 	delete(s.containers, container.ID)
 	delete(s.contNameToID, container.Name)
+	s.containerEvent("destroy", container)
 }
 
 func (s *DockerServer) commitContainer(w http.ResponseWriter, r *http.Request) {
@@ -903,13 +1878,28 @@ func (s *DockerServer) commitContainer(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
+	} else if r.Body != nil {
+		// the client sends the run config in the body.
+		err = json.NewDecoder(r.Body).Decode(config)
+		if err != nil && !errors.Is(err, io.EOF) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	if err = applyCommitChanges(config, r.URL.Query()["changes"]); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	comment := r.URL.Query().Get("comment")
+	if comment == "" {
+		comment = r.URL.Query().Get("m")
 	}
 	w.WriteHeader(http.StatusOK)
 	image := docker.Image{
 		ID:        "img-" + container.ID,
 		Parent:    container.Image,
 		Container: container.ID,
-		Comment:   r.URL.Query().Get("m"),
+		Comment:   comment,
 		Author:    r.URL.Query().Get("author"),
 		Config:    config,
 	}
@@ -932,6 +1922,209 @@ func (s *DockerServer) commitContainer(w http.ResponseWriter, r *http.Request) {
 	fmt.Fprintf(w, `{"ID":%q}`, image.ID)
 }
 
+// applyCommitChanges applies the Dockerfile instructions sent in the changes
+// of a commit to the config of the new image.
+func applyCommitChanges(config *docker.Config, changes []string) error {
+	for _, change := range changes {
+		change = strings.TrimSpace(change)
+		fields := strings.Fields(change)
+		if len(fields) < 2 {
+			return fmt.Errorf("invalid change %q", change)
+		}
+		args := strings.TrimSpace(change[len(fields[0]):])
+		switch strings.ToUpper(fields[0]) {
+		case "CMD":
+			cmd, err := commitCommand(args)
+			if err != nil {
+				return err
+			}
+			config.Cmd = cmd
+		case "ENTRYPOINT":
+			entrypoint, err := commitCommand(args)
+			if err != nil {
+				return err
+			}
+			config.Entrypoint = entrypoint
+		case "ENV":
+			pairs, err := commitPairs(args)
+			if err != nil {
+				return err
+			}
+			for _, pair := range pairs {
+				config.Env = setEnv(config.Env, pair[0], pair[1])
+			}
+		case "LABEL":
+			pairs, err := commitPairs(args)
+			if err != nil {
+				return err
+			}
+			if config.Labels == nil {
+				config.Labels = make(map[string]string)
+			}
+			for _, pair := range pairs {
+				config.Labels[pair[0]] = pair[1]
+			}
+		case "EXPOSE":
+			if config.ExposedPorts == nil {
+				config.ExposedPorts = make(map[docker.Port]struct{})
+			}
+			for _, port := range fields[1:] {
+				if !strings.Contains(port, "/") {
+					port += "/tcp"
+				}
+				config.ExposedPorts[docker.Port(port)] = struct{}{}
+			}
+		case "VOLUME":
+			volumes := fields[1:]
+			if strings.HasPrefix(args, "[") {
+				if err := json.Unmarshal([]byte(args), &volumes); err != nil {
+					return fmt.Errorf("invalid change %q: %w", change, err)
+				}
+			}
+			if config.Volumes == nil {
+				config.Volumes = make(map[string]struct{})
+			}
+			for _, volume := range volumes {
+				config.Volumes[volume] = struct{}{}
+			}
+		case "HEALTHCHECK":
+			if strings.EqualFold(args, "NONE") {
+				config.Healthcheck = &docker.HealthConfig{Test: []string{"NONE"}}
+				continue
+			}
+			if !strings.EqualFold(fields[1], "CMD") || len(fields) < 3 {
+				return fmt.Errorf("invalid change %q", change)
+			}
+			test := []string{"CMD-SHELL", strings.TrimSpace(args[len(fields[1]):])}
+			if cmd := test[1]; strings.HasPrefix(cmd, "[") {
+				var exec []string
+				if err := json.Unmarshal([]byte(cmd), &exec); err != nil {
+					return fmt.Errorf("invalid change %q: %w", change, err)
+				}
+				test = append([]string{"CMD"}, exec...)
+			}
+			config.Healthcheck = &docker.HealthConfig{Test: test}
+		case "ONBUILD":
+			config.OnBuild = append(config.OnBuild, args)
+		case "STOPSIGNAL":
+			config.StopSignal = args
+		case "USER":
+			config.User = args
+		case "WORKDIR":
+			config.WorkingDir = args
+		default:
+			return fmt.Errorf("%s is not a valid change command", fields[0])
+		}
+	}
+	return nil
+}
+
+// commitCommand parses the arguments of CMD and ENTRYPOINT, either in the
+// JSON form or in the shell form.
+func commitCommand(args string) ([]string, error) {
+	if !strings.HasPrefix(args, "[") {
+		return []string{"/bin/sh", "-c", args}, nil
+	}
+	var cmd []string
+	if err := json.Unmarshal([]byte(args), &cmd); err != nil {
+		return nil, fmt.Errorf("invalid command %q: %w", args, err)
+	}
+	return cmd, nil
+}
+
+// commitPairs parses the key=value arguments of ENV and LABEL, whose values
+// may be double quoted, or the legacy "key value" form.
+func commitPairs(args string) ([][2]string, error) {
+	words, err := splitChangeWords(args)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.Contains(words[0], "=") {
+		key := words[0]
+		return [][2]string{{key, strings.TrimSpace(args[len(key):])}}, nil
+	}
+	pairs := make([][2]string, len(words))
+	for i, word := range words {
+		parts := strings.SplitN(word, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("invalid key=value pair %q", word)
+		}
+		pairs[i] = [2]string{parts[0], parts[1]}
+	}
+	return pairs, nil
+}
+
+// splitChangeWords splits the arguments of a change on spaces, handling
+// double quotes and backslash escapes like the Dockerfile parser.
+func splitChangeWords(args string) ([]string, error) {
+	var (
+		words   []string
+		word    strings.Builder
+		inWord  bool
+		quoted  bool
+		escaped bool
+	)
+	for _, r := range args {
+		switch {
+		case escaped:
+			word.WriteRune(r)
+			escaped = false
+		case r == '\\':
+			escaped = true
+			inWord = true
+		case r == '"':
+			quoted = !quoted
+			inWord = true
+		case !quoted && (r == ' ' || r == '\t'):
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quoted || escaped {
+		return nil, fmt.Errorf("unterminated quote in %q", args)
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
+
+// setEnv sets the value of the given variable in env, replacing its previous
+// value.
+func setEnv(env []string, key, value string) []string {
+	for i, kv := range env {
+		if strings.HasPrefix(kv, key+"=") {
+			env[i] = key + "=" + value
+			return env
+		}
+	}
+	return append(env, key+"="+value)
+}
+
+// writeContainerLogs writes the stored logs as a multiplexed stream, adding
+// prefix to the beginning of every line.
+func writeContainerLogs(w io.Writer, logs containerLogs, stdout, stderr bool, prefix string) {
+	write := func(dst io.Writer, output string) {
+		for _, line := range strings.SplitAfter(output, "\n") {
+			if line != "" {
+				io.WriteString(dst, prefix+line)
+			}
+		}
+	}
+	if stdout {
+		write(stdcopy.NewStdWriter(w, stdcopy.Stdout), logs.stdout)
+	}
+	if stderr {
+		write(stdcopy.NewStdWriter(w, stdcopy.Stderr), logs.stderr)
+	}
+}
+
 func (s *DockerServer) findContainer(idOrName string) (*docker.Container, error) {
 	return s.findContainerWithLock(idOrName, true)
 }
@@ -941,7 +2134,7 @@ func (s *DockerServer) findContainerWithLock(idOrName string, shouldLock bool) (
 		s.cMut.RLock()
 		defer s.cMut.RUnlock()
 	}
-	if contID, ok := s.contNameToID[idOrName]; ok {
+	if contID, ok := s.contNameToID[strings.TrimPrefix(idOrName, "/")]; ok {
 		idOrName = contID
 	}
 	if cont, ok := s.containers[idOrName]; ok {
@@ -960,6 +2153,12 @@ func (s *DockerServer) logContainer(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/vnd.docker.raw-stream")
 	w.WriteHeader(http.StatusOK)
 	s.cMut.RLock()
+	if logs, ok := s.logs[container.ID]; ok {
+		s.cMut.RUnlock()
+		query := r.URL.Query()
+		writeContainerLogs(w, logs, query.Get("stdout") == "1", query.Get("stderr") == "1", "")
+		return
+	}
 	if container.State.Running {
 		fmt.Fprintf(w, "Container is running\n")
 	} else {
@@ -1000,13 +2199,20 @@ func (s *DockerServer) buildImage(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 	}
+	query := r.URL.Query()
+	if cacheFrom := query.Get("cachefrom"); cacheFrom != "" {
+		var images []string
+		if err := json.Unmarshal([]byte(cacheFrom), &images); err != nil {
+			http.Error(w, "invalid cachefrom: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
 	// we did not use that Dockerfile to build image cause we are a fake Docker daemon
 	image := docker.Image{
 		ID:      s.generateID(),
 		Created: time.Now(),
 	}
 
-	query := r.URL.Query()
 	repository := image.ID
 	if t := query.Get("t"); t != "" {
 		repository = t
@@ -1021,6 +2227,16 @@ func (s *DockerServer) buildImage(w http.ResponseWriter, r *http.Request) {
 func (s *DockerServer) pullImage(w http.ResponseWriter, r *http.Request) {
 	fromImageName := r.URL.Query().Get("fromImage")
 	tag := r.URL.Query().Get("tag")
+	platform := r.URL.Query().Get("platform")
+	if fromImageName != "" && tag == "" {
+		s.iMut.RLock()
+		tags, ok := s.remoteTags[fromImageName]
+		s.iMut.RUnlock()
+		if ok {
+			s.pullAllTags(w, fromImageName, tags, platform)
+			return
+		}
+	}
 	if fromImageName != "" {
 		if tag != "" {
 			separator := ":"
@@ -1030,18 +2246,114 @@ func (s *DockerServer) pullImage(w http.ResponseWriter, r *http.Request) {
 			fromImageName = fmt.Sprintf("%s%s%s", fromImageName, separator, tag)
 		}
 	}
+	s.iMut.RLock()
+	pull, ok := s.imagePulls[fromImageName]
+	s.iMut.RUnlock()
+	if ok && !scriptPull(w, r, pull) {
+		return
+	}
+	s.storePulledImage(fromImageName, platform)
+}
+
+// scriptPull sends the response prepared for a pull, reporting whether the
+// pull succeeded.
+func scriptPull(w http.ResponseWriter, r *http.Request, pull PrepareImagePullOptions) bool {
+	if pull.Status != 0 {
+		message := pull.Message
+		if message == "" {
+			message = http.StatusText(pull.Status)
+		}
+		http.Error(w, message, pull.Status)
+		return false
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	flusher, _ := w.(http.Flusher)
+	encoder := json.NewEncoder(w)
+	for _, progress := range pull.Progress {
+		select {
+		case <-r.Context().Done():
+			return false
+		case <-time.After(pull.Delay):
+		}
+		msg := jsonmessage.JSONMessage{ID: progress.ID, Status: progress.Status}
+		if progress.Current > 0 || progress.Total > 0 {
+			msg.Progress = &jsonmessage.JSONProgress{Current: progress.Current, Total: progress.Total}
+			msg.ProgressMessage = msg.Progress.String()
+		}
+		encoder.Encode(msg)
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+	if pull.Error != "" {
+		encoder.Encode(jsonmessage.JSONMessage{
+			Error:        &jsonmessage.JSONError{Message: pull.Error},
+			ErrorMessage: pull.Error,
+		})
+		return false
+	}
+	return true
+}
+
+// pullAllTags pulls every tag of the repository, writing the progress of all
+// of them to w.
+func (s *DockerServer) pullAllTags(w http.ResponseWriter, repository string, tags []string, platform string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	encoder := json.NewEncoder(w)
+	for _, tag := range tags {
+		encoder.Encode(map[string]string{"status": "Pulling from " + repository, "id": tag})
+		id := s.storePulledImage(repository+":"+tag, platform)
+		encoder.Encode(map[string]interface{}{"status": "Pull complete", "progressDetail": map[string]interface{}{}, "id": id[:12]})
+		encoder.Encode(map[string]string{"status": fmt.Sprintf("Digest: sha256:%x", sha256.Sum256([]byte(id)))})
+	}
+	encoder.Encode(map[string]string{"status": "Status: Downloaded newer image for " + repository})
+}
+
+// storePulledImage stores the image pulled with the given name, unless it's
+// already stored, and returns its ID.
+func (s *DockerServer) storePulledImage(fromImageName, platform string) string {
 	image := docker.Image{
 		ID:     s.generateID(),
 		Config: &docker.Config{},
 	}
-	s.iMut.Lock()
-	if _, exists := s.imgIDs[fromImageName]; fromImageName == "" || !exists {
-		s.images[image.ID] = image
-		if fromImageName != "" {
-			s.imgIDs[fromImageName] = image.ID
+	if platform != "" {
+		parts := strings.SplitN(platform, "/", 3)
+		image.OS = parts[0]
+		if len(parts) > 1 {
+			image.Architecture = parts[1]
 		}
 	}
-	s.iMut.Unlock()
+	s.iMut.Lock()
+	defer s.iMut.Unlock()
+	_, exists := s.imgIDs[fromImageName]
+	if exists && platform != "" {
+		// pulling another variant of a multi-arch image replaces the
+		// one stored locally under the same name.
+		stored := s.images[s.imgIDs[fromImageName]]
+		exists = stored.OS == image.OS && stored.Architecture == image.Architecture
+	}
+	if exists {
+		return s.imgIDs[fromImageName]
+	}
+	if fromImageName != "" {
+		image.RepoDigests = []string{pulledRepoDigest(fromImageName, image.ID)}
+		s.imgIDs[fromImageName] = image.ID
+	}
+	s.images[image.ID] = image
+	return image.ID
+}
+
+// pulledRepoDigest returns the repo digest of an image pulled with the given
+// reference, deriving the digest from the ID of the image like pushImage does
+// when the image wasn't pulled by digest.
+func pulledRepoDigest(ref, id string) string {
+	if strings.Contains(ref, "@") {
+		return ref
+	}
+	repository, _ := docker.ParseRepositoryTag(ref)
+	return fmt.Sprintf("%s@sha256:%x", repository, sha256.Sum256([]byte(id)))
 }
 
 func (s *DockerServer) pushImage(w http.ResponseWriter, r *http.Request) {
@@ -1050,15 +2362,35 @@ func (s *DockerServer) pushImage(w http.ResponseWriter, r *http.Request) {
 	if tag != "" {
 		name += ":" + tag
 	}
-	s.iMut.RLock()
-	if _, ok := s.imgIDs[name]; !ok {
-		s.iMut.RUnlock()
+	s.iMut.Lock()
+	id, ok := s.imgIDs[name]
+	if !ok {
+		s.iMut.Unlock()
 		http.Error(w, "No such image", http.StatusNotFound)
 		return
 	}
-	s.iMut.RUnlock()
-	fmt.Fprintln(w, "Pushing...")
-	fmt.Fprintln(w, "Pushed")
+	// like the registry, derive the digest from the content of the image
+	repository, _ := docker.ParseRepositoryTag(name)
+	digest := fmt.Sprintf("sha256:%x", sha256.Sum256([]byte(id)))
+	image := s.images[id]
+	repoDigest := repository + "@" + digest
+	if !containsString(image.RepoDigests, repoDigest) {
+		image.RepoDigests = append(image.RepoDigests, repoDigest)
+		s.images[id] = image
+	}
+	s.iMut.Unlock()
+	if tag == "" {
+		tag = "latest"
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	encoder := json.NewEncoder(w)
+	encoder.Encode(map[string]string{"status": "The push refers to repository [" + repository + "]"})
+	encoder.Encode(map[string]string{"status": fmt.Sprintf("%s: digest: %s size: %d", tag, digest, len(id))})
+	encoder.Encode(map[string]interface{}{
+		"progressDetail": map[string]interface{}{},
+		"aux":            map[string]interface{}{"Tag": tag, "Digest": digest, "Size": len(id)},
+	})
 }
 
 func (s *DockerServer) tagImage(w http.ResponseWriter, r *http.Request) {
@@ -1068,13 +2400,24 @@ func (s *DockerServer) tagImage(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "No such image", http.StatusNotFound)
 		return
 	}
-	s.iMut.Lock()
-	defer s.iMut.Unlock()
 	newRepo := r.URL.Query().Get("repo")
 	newTag := r.URL.Query().Get("tag")
+	switch {
+	case newRepo == "":
+		http.Error(w, "repository name must have at least one component", http.StatusBadRequest)
+		return
+	case strings.Contains(newRepo, "@"):
+		http.Error(w, "refusing to create a tag with a digest reference", http.StatusBadRequest)
+		return
+	case newTag != "" && !tagRegexp.MatchString(newTag):
+		http.Error(w, "invalid tag format", http.StatusBadRequest)
+		return
+	}
 	if newTag != "" {
 		newRepo += ":" + newTag
 	}
+	s.iMut.Lock()
+	defer s.iMut.Unlock()
 	s.imgIDs[newRepo] = id
 	w.WriteHeader(http.StatusCreated)
 }
@@ -1118,73 +2461,486 @@ func (s *DockerServer) removeImage(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-func (s *DockerServer) inspectImage(w http.ResponseWriter, r *http.Request) {
-	name := mux.Vars(r)["name"]
-	s.iMut.RLock()
-	defer s.iMut.RUnlock()
-	if id, ok := s.imgIDs[name]; ok {
-		name = id
+// pruneImages removes the images that aren't used by any container, only the
+// dangling ones unless the dangling filter is false, applying the until,
+// label and label! filters.
+func (s *DockerServer) pruneImages(w http.ResponseWriter, r *http.Request) {
+	filters := make(map[string][]string)
+	json.Unmarshal([]byte(r.FormValue("filters")), &filters)
+	danglingOnly := true
+	if dangling := filters["dangling"]; len(dangling) > 0 {
+		danglingOnly, _ = strconv.ParseBool(dangling[0])
 	}
-	img, ok := s.images[name]
-	if !ok {
-		http.Error(w, "not found", http.StatusNotFound)
+	until, err := parseUntilFilter(filters["until"])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	w.Header().Set("Content-Type", "application/json")
+	used := make(map[string]bool)
+	s.cMut.RLock()
+	for _, container := range s.containers {
+		used[container.Image] = true
+	}
+	s.cMut.RUnlock()
+	s.iMut.Lock()
+	defer s.iMut.Unlock()
+	for ref := range used {
+		if id, ok := s.imgIDs[ref]; ok {
+			used[id] = true
+		}
+	}
+	var result docker.PruneImagesResults
+	for id, image := range s.images {
+		var tags []string
+		for tag, taggedID := range s.imgIDs {
+			if taggedID == id {
+				tags = append(tags, tag)
+			}
+		}
+		if used[id] || (danglingOnly && len(tags) > 0) {
+			continue
+		}
+		if !until.IsZero() && !image.Created.Before(until) {
+			continue
+		}
+		var labels map[string]string
+		if image.Config != nil {
+			labels = image.Config.Labels
+		}
+		if !matchLabels(labels, filters["label"]) {
+			continue
+		}
+		if excluded := filters["label!"]; len(excluded) > 0 && matchAnyLabel(labels, excluded) {
+			continue
+		}
+		sort.Strings(tags)
+		for _, tag := range tags {
+			delete(s.imgIDs, tag)
+			result.ImagesDeleted = append(result.ImagesDeleted, struct{ Untagged, Deleted string }{Untagged: tag})
+		}
+		delete(s.images, id)
+		result.ImagesDeleted = append(result.ImagesDeleted, struct{ Untagged, Deleted string }{Deleted: id})
+		result.SpaceReclaimed += image.Size
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(result)
+}
+
+// matchAnyLabel reports whether the labels satisfy at least one of the label
+// filters, in the key or key=value format.
+func matchAnyLabel(labels map[string]string, filters []string) bool {
+	for _, f := range filters {
+		if matchLabels(labels, []string{f}) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *DockerServer) inspectImage(w http.ResponseWriter, r *http.Request) {
+	id, err := s.findImage(mux.Vars(r)["name"])
+	if err != nil {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+	s.iMut.RLock()
+	img, ok := s.images[id]
+	s.iMut.RUnlock()
+	if !ok {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(img)
 }
 
 func (s *DockerServer) listEvents(w http.ResponseWriter, r *http.Request) {
+	var since, until int64
+	for param, value := range map[string]*int64{"since": &since, "until": &until} {
+		if str := r.URL.Query().Get(param); str != "" {
+			var err error
+			if *value, err = parseEventTimestamp(str); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+	}
+	filters := make(map[string][]string)
+	json.Unmarshal([]byte(r.URL.Query().Get("filters")), &filters)
+	sub := s.subscribeEvents(since)
+	defer s.unsubscribeEvents(sub)
+	var untilC <-chan time.Time
+	if until != 0 {
+		timer := time.NewTimer(time.Until(time.Unix(0, until)))
+		defer timer.Stop()
+		untilC = timer.C
+	}
 	w.Header().Set("Content-Type", "application/json")
-	var events [][]byte
-	count := mathrand.Intn(20)
-	for i := 0; i < count; i++ {
-		data, err := json.Marshal(s.generateEvent())
-		if err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
+	w.WriteHeader(http.StatusOK)
+	flusher, _ := w.(http.Flusher)
+	if flusher != nil {
+		flusher.Flush()
+	}
+	encoder := json.NewEncoder(w)
+	for {
+		var done bool
+		select {
+		case <-r.Context().Done():
+			return
+		case <-sub.signal:
+		case <-untilC:
+			// write the events that happened before until, then end the
+			// stream.
+			done = true
+		}
+		for _, e := range sub.drain() {
+			if !matchEvent(e.event, filters) || (until != 0 && eventTimeNano(e.event) > until) {
+				continue
+			}
+			if d := time.Until(e.deliverAt); d > 0 {
+				timer := time.NewTimer(d)
+				select {
+				case <-r.Context().Done():
+					timer.Stop()
+					return
+				case <-timer.C:
+				}
+			}
+			if err := encoder.Encode(e.event); err != nil {
+				return
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+		if done {
 			return
 		}
-		events = append(events, data)
 	}
-	w.WriteHeader(http.StatusOK)
-	for _, d := range events {
-		fmt.Fprintln(w, d)
-		time.Sleep(time.Duration(mathrand.Intn(200)) * time.Millisecond)
+}
+
+// matchEvent reports whether the event satisfies the type, event, container,
+// image and label filters of /events. Other filters are ignored.
+func matchEvent(event docker.APIEvents, filters map[string][]string) bool {
+	if types := filters["type"]; len(types) > 0 && !containsString(types, event.Type) {
+		return false
 	}
+	if actions := filters["event"]; len(actions) > 0 && !containsString(actions, event.Action) {
+		return false
+	}
+	if containers := filters["container"]; len(containers) > 0 {
+		if event.Type != "container" || !(containsString(containers, event.Actor.ID) || containsString(containers, event.Actor.Attributes["name"])) {
+			return false
+		}
+	}
+	if images := filters["image"]; len(images) > 0 {
+		image := event.Actor.Attributes["image"]
+		if event.Type == "image" {
+			image = event.Actor.ID
+		}
+		if !containsString(images, image) {
+			return false
+		}
+	}
+	return matchLabels(event.Actor.Attributes, filters["label"])
 }
 
 func (s *DockerServer) pingDocker(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Api-Version", "1.22")
+	w.Header().Set("Ostype", "linux")
+	w.Header().Set("Docker-Experimental", "false")
+	w.Header().Set("Swarm", s.swarmState())
 	w.WriteHeader(http.StatusOK)
 }
 
-func (s *DockerServer) generateEvent() *docker.APIEvents {
-	var eventType string
-	switch mathrand.Intn(4) {
-	case 0:
-		eventType = "create"
-	case 1:
-		eventType = "start"
-	case 2:
-		eventType = "stop"
-	case 3:
-		eventType = "destroy"
+// swarmState returns the state of the node in the Swarm, as reported in the
+// Swarm header of the response to a ping.
+func (s *DockerServer) swarmState() string {
+	s.swarmMut.RLock()
+	defer s.swarmMut.RUnlock()
+	if s.swarm == nil {
+		return "inactive"
 	}
-	return &docker.APIEvents{
-		ID:     s.generateID(),
-		Status: eventType,
-		From:   "mybase:latest",
-		Time:   time.Now().Unix(),
+	if s.swarmLocked {
+		return "locked"
+	}
+	for _, node := range s.nodes {
+		if node.ID == s.nodeID && node.ManagerStatus == nil {
+			return "active/worker"
+		}
+	}
+	return "active/manager"
+}
+
+// SetEventDelay makes the server hold every event for the given duration
+// before writing it to the clients listening on /events. Events are still
+// delivered in the order they happened. A zero duration (the default) means
+// events are delivered immediately.
+func (s *DockerServer) SetEventDelay(d time.Duration) {
+	s.eventMut.Lock()
+	defer s.eventMut.Unlock()
+	s.eventDelay = d
+}
+
+// InjectEvent sends the given event to all clients listening on /events, as
+// if it had happened in the server. If the time of the event is not set, the
+// current time is used.
+//
+// Like the Docker daemon, the server keeps the most recent events, so clients
+// connecting later (or reconnecting) with the "since" parameter receive them
+// too.
+func (s *DockerServer) InjectEvent(event docker.APIEvents) {
+	if event.Time == 0 && event.TimeNano == 0 {
+		now := time.Now()
+		event.Time = now.Unix()
+		event.TimeNano = now.UnixNano()
+	} else if event.Time == 0 {
+		event.Time = event.TimeNano / int64(time.Second)
+	}
+	s.eventMut.Lock()
+	defer s.eventMut.Unlock()
+	s.eventLog = append(s.eventLog, event)
+	if len(s.eventLog) > maxEventLog {
+		s.eventLog = s.eventLog[len(s.eventLog)-maxEventLog:]
+	}
+	deliverAt := time.Now().Add(s.eventDelay)
+	for _, sub := range s.eventSubs {
+		sub.push(queuedEvent{event: event, deliverAt: deliverAt})
+	}
+}
+
+func (s *DockerServer) containerEvent(action string, container *docker.Container) {
+	s.InjectEvent(docker.APIEvents{
+		Action: action,
+		Type:   "container",
+		Actor: docker.APIActor{
+			ID: container.ID,
+			Attributes: map[string]string{
+				"image": container.Image,
+				"name":  strings.TrimPrefix(container.Name, "/"),
+			},
+		},
+		Status: action,
+		ID:     container.ID,
+		From:   container.Image,
+	})
+}
+
+// subscribeEvents registers a new client of /events. When since is not zero,
+// the recorded events that happened at or after since (in nanoseconds) are
+// queued for delivery.
+func (s *DockerServer) subscribeEvents(since int64) *eventSubscriber {
+	sub := &eventSubscriber{signal: make(chan struct{}, 1)}
+	s.eventMut.Lock()
+	defer s.eventMut.Unlock()
+	if since != 0 {
+		deliverAt := time.Now().Add(s.eventDelay)
+		for _, event := range s.eventLog {
+			if eventTimeNano(event) >= since {
+				sub.push(queuedEvent{event: event, deliverAt: deliverAt})
+			}
+		}
+	}
+	s.eventSubs = append(s.eventSubs, sub)
+	return sub
+}
+
+func eventTimeNano(event docker.APIEvents) int64 {
+	if event.TimeNano != 0 {
+		return event.TimeNano
+	}
+	return event.Time * int64(time.Second)
+}
+
+// parseEventTimestamp parses timestamps in the format used by the since
+// parameter of /events (seconds since epoch, with optional nanoseconds after
+// a dot), returning the number of nanoseconds since epoch.
+func parseEventTimestamp(value string) (int64, error) {
+	parts := strings.SplitN(value, ".", 2)
+	sec, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid timestamp %q", value)
 	}
+	var nsec int64
+	if len(parts) == 2 {
+		frac := parts[1]
+		if len(frac) > 9 {
+			frac = frac[:9]
+		}
+		frac += strings.Repeat("0", 9-len(frac))
+		if nsec, err = strconv.ParseInt(frac, 10, 64); err != nil {
+			return 0, fmt.Errorf("invalid timestamp %q", value)
+		}
+	}
+	return sec*int64(time.Second) + nsec, nil
+}
+
+func (s *DockerServer) unsubscribeEvents(sub *eventSubscriber) {
+	s.eventMut.Lock()
+	defer s.eventMut.Unlock()
+	for i, item := range s.eventSubs {
+		if item == sub {
+			s.eventSubs = append(s.eventSubs[:i], s.eventSubs[i+1:]...)
+			return
+		}
+	}
+}
+
+// maxEventLog is the number of events kept by the server for clients that
+// connect with the since parameter.
+const maxEventLog = 256
+
+type queuedEvent struct {
+	event     docker.APIEvents
+	deliverAt time.Time
+}
+
+// eventSubscriber holds the events waiting to be written to a client of
+// /events, so emitting events never blocks the handler that generates them.
+type eventSubscriber struct {
+	mu     sync.Mutex
+	queue  []queuedEvent
+	signal chan struct{}
 }
 
+func (sub *eventSubscriber) push(e queuedEvent) {
+	sub.mu.Lock()
+	sub.queue = append(sub.queue, e)
+	sub.mu.Unlock()
+	select {
+	case sub.signal <- struct{}{}:
+	default:
+	}
+}
+
+func (sub *eventSubscriber) drain() []queuedEvent {
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+	events := sub.queue
+	sub.queue = nil
+	return events
+}
+
+// loadImage reads a tarball in the format written by writeImagesTarball,
+// storing the images described in its manifest.json and reporting each of
+// them the way docker load does.
 func (s *DockerServer) loadImage(w http.ResponseWriter, r *http.Request) {
+	files := make(map[string][]byte)
+	tr := tar.NewReader(r.Body)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		data, err := ioutil.ReadAll(tr)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		files[libpath.Clean(header.Name)] = data
+	}
+	manifestData, ok := files["manifest.json"]
+	if !ok {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	var manifest []imageManifest
+	if err := json.Unmarshal(manifestData, &manifest); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var lines []string
+	s.iMut.Lock()
+	for _, m := range manifest {
+		var image docker.Image
+		if err := json.Unmarshal(files[libpath.Clean(m.Config)], &image); err != nil || image.ID == "" {
+			image = docker.Image{ID: s.generateID(), Created: time.Now()}
+		}
+		s.images[image.ID] = image
+		if len(m.RepoTags) == 0 {
+			lines = append(lines, "Loaded image ID: "+image.ID)
+		}
+		for _, tag := range m.RepoTags {
+			s.imgIDs[tag] = image.ID
+			lines = append(lines, "Loaded image: "+tag)
+		}
+	}
+	s.iMut.Unlock()
+	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
+	encoder := json.NewEncoder(w)
+	for _, line := range lines {
+		encoder.Encode(map[string]string{"stream": line + "\n"})
+	}
 }
 
 func (s *DockerServer) getImage(w http.ResponseWriter, r *http.Request) {
+	s.writeImagesTarball(w, []string{mux.Vars(r)["id"]})
+}
+
+func (s *DockerServer) getImages(w http.ResponseWriter, r *http.Request) {
+	var names []string
+	for _, name := range r.URL.Query()["names"] {
+		// API 1.25 and above send the names as a comma separated list.
+		names = append(names, strings.Split(name, ",")...)
+	}
+	s.writeImagesTarball(w, names)
+}
+
+type imageManifest struct {
+	Config   string
+	RepoTags []string
+	Layers   []string
+}
+
+// writeImagesTarball writes a tarball in the format produced by docker save,
+// with the configuration of each image and a manifest.json listing the
+// repositories that were requested.
+func (s *DockerServer) writeImagesTarball(w http.ResponseWriter, names []string) {
+	var manifest []imageManifest
+	indexes := make(map[string]int)
+	configs := make(map[string][]byte)
+	for _, name := range names {
+		id, err := s.findImage(name)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		i, ok := indexes[id]
+		if !ok {
+			s.iMut.RLock()
+			config, _ := json.Marshal(s.images[id])
+			s.iMut.RUnlock()
+			configs[id] = config
+			i = len(manifest)
+			indexes[id] = i
+			manifest = append(manifest, imageManifest{Config: id + ".json", Layers: []string{}})
+		}
+		if name != id {
+			manifest[i].RepoTags = append(manifest[i].RepoTags, name)
+		}
+	}
+	manifestData, _ := json.Marshal(manifest)
+	w.Header().Set("Content-Type", "application/x-tar")
 	w.WriteHeader(http.StatusOK)
-	w.Header().Set("Content-Type", "application/tar")
+	tw := tar.NewWriter(w)
+	for _, m := range manifest {
+		id := strings.TrimSuffix(m.Config, ".json")
+		writeTarFile(tw, m.Config, configs[id])
+	}
+	writeTarFile(tw, "manifest.json", manifestData)
+	tw.Close()
+}
+
+func writeTarFile(tw *tar.Writer, name string, data []byte) {
+	tw.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: int64(len(data))})
+	tw.Write(data)
 }
 
 func (s *DockerServer) createExecContainer(w http.ResponseWriter, r *http.Request) {
@@ -1220,6 +2976,7 @@ func (s *DockerServer) createExecContainer(w http.ResponseWriter, r *http.Reques
 
 	exec.ProcessConfig.User = params.User
 	exec.ProcessConfig.Tty = params.Tty
+	exec.ProcessConfig.Env = params.Env
 
 	s.execMut.Lock()
 	s.execs = append(s.execs, &exec)
@@ -1232,8 +2989,12 @@ func (s *DockerServer) createExecContainer(w http.ResponseWriter, r *http.Reques
 func (s *DockerServer) startExecContainer(w http.ResponseWriter, r *http.Request) {
 	id := mux.Vars(r)["id"]
 	if exec, err := s.getExec(id, false); err == nil {
+		var opts docker.StartExecOptions
+		json.NewDecoder(r.Body).Decode(&opts)
 		s.execMut.Lock()
 		exec.Running = true
+		result, hasResult := s.execResult(exec)
+		tty := exec.ProcessConfig.Tty || opts.Tty
 		s.execMut.Unlock()
 		if callback, ok := s.execCallbacks[id]; ok {
 			callback()
@@ -1242,15 +3003,51 @@ func (s *DockerServer) startExecContainer(w http.ResponseWriter, r *http.Request
 			callback()
 			delete(s.execCallbacks, "*")
 		}
-		s.execMut.Lock()
-		exec.Running = false
-		s.execMut.Unlock()
+		if !hasResult || opts.Detach {
+			s.finishExec(exec, result.ExitCode)
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		hijacker, ok := w.(http.Hijacker)
+		if !ok {
+			s.finishExec(exec, result.ExitCode)
+			http.Error(w, "cannot hijack connection", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/vnd.docker.raw-stream")
 		w.WriteHeader(http.StatusOK)
+		conn, _, err := hijacker.Hijack()
+		if err != nil {
+			s.finishExec(exec, result.ExitCode)
+			return
+		}
+		if tty {
+			conn.Write(result.Stdout)
+			conn.Write(result.Stderr)
+		} else {
+			if len(result.Stdout) > 0 {
+				stdcopy.NewStdWriter(conn, stdcopy.Stdout).Write(result.Stdout)
+			}
+			if len(result.Stderr) > 0 {
+				stdcopy.NewStdWriter(conn, stdcopy.Stderr).Write(result.Stderr)
+			}
+		}
+		// the exec must be done before the client sees the end of the
+		// stream, so it can inspect the exit code right away.
+		s.finishExec(exec, result.ExitCode)
+		conn.Close()
 		return
 	}
 	w.WriteHeader(http.StatusNotFound)
 }
 
+func (s *DockerServer) finishExec(exec *docker.ExecInspect, exitCode int) {
+	s.execMut.Lock()
+	defer s.execMut.Unlock()
+	exec.Running = false
+	exec.ExitCode = exitCode
+}
+
 func (s *DockerServer) resizeExecContainer(w http.ResponseWriter, r *http.Request) {
 	id := mux.Vars(r)["id"]
 	if _, err := s.getExec(id, false); err == nil {
@@ -1316,9 +3113,144 @@ func (s *DockerServer) networkInfo(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusNotFound)
 		return
 	}
+	query := r.URL.Query()
+	scope := network.Scope
+	if scope == "" {
+		scope = "local"
+	}
+	if want := query.Get("scope"); want != "" && want != scope {
+		http.Error(w, "no such network", http.StatusNotFound)
+		return
+	}
+	result := *network
+	result.Services = nil
+	if verbose, _ := strconv.ParseBool(query.Get("verbose")); verbose && scope == "swarm" {
+		result.Services = s.networkServices(network)
+	}
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(network)
+	json.NewEncoder(w).Encode(result)
+}
+
+// networkServices returns the endpoints of the services attached to the given
+// network, as reported by the verbose network inspect. Addresses are assigned
+// in order from the first IPv4 subnet of the network: virtual IPs first, then
+// the IPs of the tasks.
+func (s *DockerServer) networkServices(network *docker.Network) map[string]docker.NetworkService {
+	s.swarmMut.Lock()
+	defer s.swarmMut.Unlock()
+	nodeAddrs := make(map[string]string, len(s.nodes))
+	for _, node := range s.nodes {
+		nodeAddrs[node.ID] = node.Status.Addr
+	}
+	var services []*swarm.Service
+	for _, service := range s.services {
+		for _, attachment := range service.Spec.TaskTemplate.Networks {
+			if attachment.Target == network.ID || attachment.Target == network.Name {
+				services = append(services, service)
+				break
+			}
+		}
+	}
+	result := make(map[string]docker.NetworkService, len(services))
+	offset := 2
+	for i, service := range services {
+		info := docker.NetworkService{
+			VIP:          networkAddress(network, offset+i),
+			LocalLBIndex: 256 + i,
+			Tasks:        []docker.NetworkTask{},
+		}
+		for _, port := range service.Endpoint.Ports {
+			info.Ports = append(info.Ports, fmt.Sprintf("Target: %d, Publish: %d", port.TargetPort, port.PublishedPort))
+		}
+		result[service.Spec.Name] = info
+	}
+	offset += len(services)
+	for _, service := range services {
+		info := result[service.Spec.Name]
+		for _, task := range s.tasks {
+			if task.ServiceID != service.ID {
+				continue
+			}
+			info.Tasks = append(info.Tasks, docker.NetworkTask{
+				Name:       fmt.Sprintf("%s.%d.%s", service.Spec.Name, len(info.Tasks)+1, task.ID),
+				EndpointID: task.ID,
+				EndpointIP: networkAddress(network, offset),
+				Info:       map[string]string{"Host IP": nodeAddrs[task.NodeID]},
+			})
+			offset++
+		}
+		result[service.Spec.Name] = info
+	}
+	return result
+}
+
+// allocateSubnet returns the first subnet with the given size, taken from the
+// given address pools, that doesn't overlap with the subnets of the existing
+// networks, as the daemon does for overlay networks created without an IPAM
+// configuration. It returns nil if every subnet is in use. It must be called
+// with netMut held.
+func (s *DockerServer) allocateSubnet(pools []string, size uint32) *net.IPNet {
+	var used []*net.IPNet
+	for _, network := range s.networks {
+		for _, config := range network.IPAM.Config {
+			if _, subnet, err := net.ParseCIDR(config.Subnet); err == nil {
+				used = append(used, subnet)
+			}
+		}
+	}
+	for _, pool := range pools {
+		_, poolNet, err := net.ParseCIDR(pool)
+		if err != nil || poolNet.IP.To4() == nil {
+			continue
+		}
+		ones, _ := poolNet.Mask.Size()
+		if int(size) < ones || size > 32 {
+			continue
+		}
+		base := binary.BigEndian.Uint32(poolNet.IP.To4())
+		count := uint64(1) << (size - uint32(ones))
+		for i := uint64(0); i < count; i++ {
+			ip := make(net.IP, net.IPv4len)
+			binary.BigEndian.PutUint32(ip, base+uint32(i<<(32-size)))
+			candidate := &net.IPNet{IP: ip, Mask: net.CIDRMask(int(size), 32)}
+			overlaps := false
+			for _, subnet := range used {
+				if subnet.Contains(candidate.IP) || candidate.Contains(subnet.IP) {
+					overlaps = true
+					break
+				}
+			}
+			if !overlaps {
+				return candidate
+			}
+		}
+	}
+	return nil
+}
+
+// networkAddress returns the n-th address of the first IPv4 subnet of the
+// given network, or an empty string if the network has no IPv4 subnet.
+func networkAddress(network *docker.Network, n int) string {
+	for _, config := range network.IPAM.Config {
+		_, subnet, err := net.ParseCIDR(config.Subnet)
+		if err != nil {
+			continue
+		}
+		if subnet.IP.To4() == nil {
+			continue
+		}
+		return nthAddress(subnet, n)
+	}
+	return ""
+}
+
+// nthAddress returns the n-th address of the given IPv4 subnet.
+func nthAddress(subnet *net.IPNet, n int) string {
+	addr := binary.BigEndian.Uint32(subnet.IP.To4()) + uint32(n)
+	result := make(net.IP, net.IPv4len)
+	binary.BigEndian.PutUint32(result, addr)
+	return result.String()
 }
 
 // isValidName validates configuration objects supported by libnetwork
@@ -1345,15 +3277,52 @@ func (s *DockerServer) createNetwork(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "network already exists", http.StatusForbidden)
 		return
 	}
+	if config.Ingress && config.Driver != "overlay" {
+		http.Error(w, "ingress network must use the overlay driver", http.StatusBadRequest)
+		return
+	}
 
 	generatedID := s.generateID()
 	network := docker.Network{
 		Name:       config.Name,
 		ID:         generatedID,
+		Scope:      config.Scope,
 		Driver:     config.Driver,
 		Containers: map[string]docker.Endpoint{},
+		Internal:   config.Internal,
+		EnableIPv6: config.EnableIPv6,
+		Ingress:    config.Ingress,
+		Labels:     config.Labels,
+		Created:    time.Now(),
+	}
+	if config.IPAM != nil {
+		network.IPAM = *config.IPAM
+	}
+	var pools []string
+	var subnetSize uint32
+	if config.Driver == "overlay" && len(network.IPAM.Config) == 0 {
+		s.swarmMut.RLock()
+		if s.swarm != nil {
+			pools, subnetSize = s.swarm.DefaultAddrPool, s.swarm.SubnetSize
+		}
+		s.swarmMut.RUnlock()
+	}
+	if len(config.Options) > 0 {
+		network.Options = make(map[string]string, len(config.Options))
+		for k, v := range config.Options {
+			network.Options[k] = fmt.Sprint(v)
+		}
 	}
 	s.netMut.Lock()
+	if len(pools) > 0 {
+		subnet := s.allocateSubnet(pools, subnetSize)
+		if subnet == nil {
+			s.netMut.Unlock()
+			http.Error(w, "could not find an available, non-overlapping IPv4 address pool among the defaults to assign to the network", http.StatusForbidden)
+			return
+		}
+		network.IPAM.Config = []docker.IPAMConfig{{Subnet: subnet.String(), Gateway: nthAddress(subnet, 1)}}
+	}
 	s.networks = append(s.networks, &network)
 	s.netMut.Unlock()
 	w.WriteHeader(http.StatusCreated)
@@ -1375,6 +3344,82 @@ func (s *DockerServer) removeNetwork(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// parseUntilFilter parses the until filter of prune requests, either a
+// duration relative to now or a timestamp, returning the zero time when
+// there's no filter.
+func parseUntilFilter(values []string) (time.Time, error) {
+	var until time.Time
+	for _, value := range values {
+		if d, err := time.ParseDuration(value); err == nil {
+			until = time.Now().Add(-d)
+		} else if nsec, err := parseEventTimestamp(value); err == nil {
+			until = time.Unix(0, nsec)
+		} else {
+			return time.Time{}, errors.New("invalid until filter: " + value)
+		}
+	}
+	return until, nil
+}
+
+// pruneNetworks removes the networks without containers or services attached
+// that match the until, label and label! filters. Like in the daemon, the
+// predefined networks are never removed.
+func (s *DockerServer) pruneNetworks(w http.ResponseWriter, r *http.Request) {
+	filters := make(map[string][]string)
+	json.Unmarshal([]byte(r.FormValue("filters")), &filters)
+	until, err := parseUntilFilter(filters["until"])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	used := make(map[string]bool)
+	s.swarmMut.RLock()
+	for _, service := range s.services {
+		for _, attachment := range service.Spec.TaskTemplate.Networks {
+			used[attachment.Target] = true
+		}
+	}
+	s.swarmMut.RUnlock()
+	s.netMut.Lock()
+	defer s.netMut.Unlock()
+	result := docker.PruneNetworksResults{NetworksDeleted: []string{}}
+	kept := s.networks[:0]
+	for _, network := range s.networks {
+		if !pruneNetwork(network, used, until, filters) {
+			kept = append(kept, network)
+			continue
+		}
+		result.NetworksDeleted = append(result.NetworksDeleted, network.Name)
+	}
+	for i := len(kept); i < len(s.networks); i++ {
+		s.networks[i] = nil
+	}
+	s.networks = kept
+	sort.Strings(result.NetworksDeleted)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(result)
+}
+
+// pruneNetwork reports whether pruneNetworks should remove the network.
+func pruneNetwork(network *docker.Network, used map[string]bool, until time.Time, filters map[string][]string) bool {
+	switch network.Name {
+	case "bridge", "host", "none":
+		return false
+	}
+	if len(network.Containers) > 0 || used[network.ID] || used[network.Name] || network.Ingress {
+		return false
+	}
+	if !until.IsZero() && !network.Created.Before(until) {
+		return false
+	}
+	if !matchLabels(network.Labels, filters["label"]) {
+		return false
+	}
+	excluded := filters["label!"]
+	return len(excluded) == 0 || !matchAnyLabel(network.Labels, excluded)
+}
+
 func (s *DockerServer) networksConnect(w http.ResponseWriter, r *http.Request) {
 	id := mux.Vars(r)["id"]
 	var config *docker.NetworkConnectionOptions
@@ -1385,25 +3430,160 @@ func (s *DockerServer) networksConnect(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	network, index, _ := s.findNetwork(id)
+	network, _, _ := s.findNetwork(id)
 	container, _ := s.findContainer(config.Container)
 	if network == nil || container == nil {
 		http.Error(w, "network or container not found", http.StatusNotFound)
 		return
 	}
 
+	s.netMut.Lock()
 	if _, found := network.Containers[container.ID]; found {
+		s.netMut.Unlock()
 		http.Error(w, "endpoint already exists in network", http.StatusBadRequest)
 		return
 	}
+	endpoint := docker.Endpoint{Name: strings.TrimPrefix(container.Name, "/"), ID: s.generateID()}
+	var aliases []string
+	if ep := config.EndpointConfig; ep != nil {
+		aliases = ep.Aliases
+		if ep.IPAMConfig != nil {
+			if endpoint.IPv4Address, err = endpointAddress(network, ep.IPAMConfig.IPv4Address); err == nil {
+				endpoint.IPv6Address, err = endpointAddress(network, ep.IPAMConfig.IPv6Address)
+			}
+			if err != nil {
+				s.netMut.Unlock()
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+	}
+	network.Containers[container.ID] = endpoint
+	s.netMut.Unlock()
+
+	ipAddress, prefixLen := splitEndpointAddress(endpoint.IPv4Address)
+	ipv6Address, ipv6PrefixLen := splitEndpointAddress(endpoint.IPv6Address)
+	s.cMut.Lock()
+	if container.NetworkSettings == nil {
+		container.NetworkSettings = &docker.NetworkSettings{}
+	}
+	if container.NetworkSettings.Networks == nil {
+		container.NetworkSettings.Networks = map[string]docker.ContainerNetwork{}
+	}
+	container.NetworkSettings.Networks[network.Name] = docker.ContainerNetwork{
+		Aliases:             aliases,
+		NetworkID:           network.ID,
+		EndpointID:          endpoint.ID,
+		IPAddress:           ipAddress,
+		IPPrefixLen:         prefixLen,
+		GlobalIPv6Address:   ipv6Address,
+		GlobalIPv6PrefixLen: ipv6PrefixLen,
+	}
+	s.cMut.Unlock()
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// networksDisconnect removes the endpoint of a container from a network. With
+// Force, the endpoint of a container that no longer exists is found by the ID
+// or name it was created for.
+func (s *DockerServer) networksDisconnect(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	var config *docker.NetworkConnectionOptions
+	defer r.Body.Close()
+	err := json.NewDecoder(r.Body).Decode(&config)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	network, _, _ := s.findNetwork(id)
+	container, _ := s.findContainer(config.Container)
+	if network == nil || (container == nil && !config.Force) {
+		http.Error(w, "network or container not found", http.StatusNotFound)
+		return
+	}
 
+	endpointID := ""
 	s.netMut.Lock()
-	s.networks[index].Containers[config.Container] = docker.Endpoint{}
+	if container != nil {
+		if _, found := network.Containers[container.ID]; found {
+			endpointID = container.ID
+		}
+	} else {
+		name := strings.TrimPrefix(config.Container, "/")
+		for cID, endpoint := range network.Containers {
+			if cID == config.Container || endpoint.Name == name {
+				endpointID = cID
+				break
+			}
+		}
+	}
+	if endpointID == "" {
+		s.netMut.Unlock()
+		http.Error(w, fmt.Sprintf("container %s is not connected to network %s", config.Container, network.Name), http.StatusBadRequest)
+		return
+	}
+	delete(network.Containers, endpointID)
 	s.netMut.Unlock()
 
+	if container != nil {
+		s.cMut.Lock()
+		if container.NetworkSettings != nil {
+			delete(container.NetworkSettings.Networks, network.Name)
+		}
+		s.cMut.Unlock()
+	}
 	w.WriteHeader(http.StatusOK)
 }
 
+// endpointAddress validates an address requested for an endpoint in the
+// given network, returning it in CIDR notation. It mimics the errors returned
+// by the daemon when the address is not part of the subnets of the network or
+// is already in use. It must be called with netMut held.
+func endpointAddress(network *docker.Network, address string) (string, error) {
+	if address == "" {
+		return "", nil
+	}
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return "", fmt.Errorf("invalid address %s: not an IP address", address)
+	}
+	var subnets []*net.IPNet
+	for _, config := range network.IPAM.Config {
+		if _, subnet, err := net.ParseCIDR(config.Subnet); err == nil {
+			subnets = append(subnets, subnet)
+		}
+	}
+	if len(subnets) == 0 {
+		return "", errors.New("user specified IP address is supported only when connecting to networks with user configured subnets")
+	}
+	for _, subnet := range subnets {
+		if !subnet.Contains(ip) {
+			continue
+		}
+		for _, endpoint := range network.Containers {
+			for _, used := range []string{endpoint.IPv4Address, endpoint.IPv6Address} {
+				if usedIP, _, err := net.ParseCIDR(used); err == nil && usedIP.Equal(ip) {
+					return "", fmt.Errorf("address %s already in use in network %s", address, network.Name)
+				}
+			}
+		}
+		ones, _ := subnet.Mask.Size()
+		return fmt.Sprintf("%s/%d", ip, ones), nil
+	}
+	return "", fmt.Errorf("invalid address %s: it does not belong to any of the subnets of network %s", address, network.Name)
+}
+
+func splitEndpointAddress(cidr string) (string, int) {
+	ip, subnet, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return "", 0
+	}
+	ones, _ := subnet.Mask.Size()
+	return ip.String(), ones
+}
+
 func (s *DockerServer) listVolumes(w http.ResponseWriter, r *http.Request) {
 	s.volMut.RLock()
 	result := make([]docker.Volume, 0, len(s.volStore))
@@ -1427,30 +3607,55 @@ func (s *DockerServer) createVolume(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	volume := &docker.Volume{
-		Name:   data.CreateVolumeOptions.Name,
-		Driver: data.CreateVolumeOptions.Driver,
-	}
-	// If the name is not specified, generate one.  Just using generateID for now
-	if len(volume.Name) == 0 {
-		volume.Name = s.generateID()
+		Name:      data.CreateVolumeOptions.Name,
+		Driver:    data.CreateVolumeOptions.Driver,
+		Labels:    data.CreateVolumeOptions.Labels,
+		Options:   data.CreateVolumeOptions.DriverOpts,
+		CreatedAt: time.Now(),
 	}
 	// If driver is not specified, use local
 	if len(volume.Driver) == 0 {
 		volume.Driver = "local"
 	}
+	// If the name is not specified, generate one.  Just using generateID for now
+	if len(volume.Name) == 0 {
+		volume.Name = s.generateID()
+	} else if volume.Driver == "local" && !nameRegexp.MatchString(volume.Name) {
+		http.Error(w, fmt.Sprintf("%q includes invalid characters for a local volume name", volume.Name), http.StatusBadRequest)
+		return
+	}
 	// Mount point is a default one with name
 	volume.Mountpoint = "/var/lib/docker/volumes/" + volume.Name
+	if spec := data.CreateVolumeOptions.ClusterVolumeSpec; spec != nil {
+		if !s.swarmEnabled() {
+			http.Error(w, "cluster volumes require a swarm manager", http.StatusServiceUnavailable)
+			return
+		}
+		now := time.Now()
+		volume.ClusterVolume = &docker.ClusterVolume{
+			ID: s.generateID(),
+			Meta: swarm.Meta{
+				Version:   swarm.Version{Index: 1},
+				CreatedAt: now,
+				UpdatedAt: now,
+			},
+			Spec: *spec,
+		}
+		if volume.ClusterVolume.Spec.Availability == "" {
+			volume.ClusterVolume.Spec.Availability = docker.VolumeAvailabilityActive
+		}
+	}
 
-	// If the volume already exists, don't re-add it.
-	exists := false
+	// If the volume already exists, don't re-add it, returning the existing
+	// one instead.
 	s.volMut.Lock()
-	if s.volStore != nil {
-		_, exists = s.volStore[volume.Name]
-	} else {
+	if s.volStore == nil {
 		// No volumes, create volStore
 		s.volStore = make(map[string]*volumeCounter)
 	}
-	if !exists {
+	if existing, ok := s.volStore[volume.Name]; ok {
+		*volume = existing.volume
+	} else {
 		s.volStore[volume.Name] = &volumeCounter{
 			volume: *volume,
 			count:  0,
@@ -1475,6 +3680,48 @@ func (s *DockerServer) inspectVolume(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(vol.volume)
 }
 
+func (s *DockerServer) updateVolume(w http.ResponseWriter, r *http.Request) {
+	var data struct {
+		Spec docker.ClusterVolumeSpec
+	}
+	defer r.Body.Close()
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	version, err := strconv.ParseUint(r.URL.Query().Get("version"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid volume version", http.StatusBadRequest)
+		return
+	}
+	if !s.swarmEnabled() {
+		http.Error(w, "volume update only valid for cluster volumes, but swarm is unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	s.volMut.Lock()
+	defer s.volMut.Unlock()
+	vol, err := s.findVolume(mux.Vars(r)["name"])
+	if err != nil || vol.volume.ClusterVolume == nil {
+		http.Error(w, "no such cluster volume", http.StatusNotFound)
+		return
+	}
+	cv := vol.volume.ClusterVolume
+	if cv.Version.Index != version {
+		http.Error(w, "update out of sequence", http.StatusBadRequest)
+		return
+	}
+	cv.Spec = data.Spec
+	cv.Version.Index++
+	cv.UpdatedAt = time.Now()
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *DockerServer) swarmEnabled() bool {
+	s.swarmMut.RLock()
+	defer s.swarmMut.RUnlock()
+	return s.swarm != nil
+}
+
 func (s *DockerServer) findVolume(name string) (*volumeCounter, error) {
 	vol, ok := s.volStore[name]
 	if !ok {
@@ -1500,6 +3747,137 @@ func (s *DockerServer) removeVolume(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNoContent)
 }
 
+func (s *DockerServer) pluginPrivileges(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode([]docker.PluginPrivilege{})
+}
+
+func (s *DockerServer) installPlugin(w http.ResponseWriter, r *http.Request) {
+	var privileges []docker.PluginPrivilege
+	defer r.Body.Close()
+	if err := json.NewDecoder(r.Body).Decode(&privileges); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	remote := r.URL.Query().Get("remote")
+	if remote == "" {
+		http.Error(w, "remote is required", http.StatusBadRequest)
+		return
+	}
+	name := r.URL.Query().Get("name")
+	if name == "" {
+		name = remote
+	}
+	name = pluginReference(name)
+	s.pluginMut.Lock()
+	if _, ok := s.plugins[name]; ok {
+		s.pluginMut.Unlock()
+		http.Error(w, "plugin "+name+" already exists", http.StatusConflict)
+		return
+	}
+	s.plugins[name] = &docker.PluginDetail{
+		ID:   s.generateID(),
+		Name: name,
+		Tag:  name[strings.LastIndex(name, ":")+1:],
+	}
+	s.pluginMut.Unlock()
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintln(w, `{"status":"Downloading"}`)
+	fmt.Fprintf(w, "{\"status\":\"Downloaded newer image for %s\"}\n", remote)
+}
+
+func (s *DockerServer) listPlugins(w http.ResponseWriter, r *http.Request) {
+	s.pluginMut.RLock()
+	result := make([]docker.PluginDetail, 0, len(s.plugins))
+	for _, plugin := range s.plugins {
+		result = append(result, *plugin)
+	}
+	s.pluginMut.RUnlock()
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(result)
+}
+
+func (s *DockerServer) inspectPlugin(w http.ResponseWriter, r *http.Request) {
+	s.pluginMut.RLock()
+	defer s.pluginMut.RUnlock()
+	plugin, err := s.findPlugin(mux.Vars(r)["name"])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(plugin)
+}
+
+func (s *DockerServer) enablePlugin(w http.ResponseWriter, r *http.Request) {
+	s.setPluginActive(w, mux.Vars(r)["name"], true)
+}
+
+func (s *DockerServer) disablePlugin(w http.ResponseWriter, r *http.Request) {
+	s.setPluginActive(w, mux.Vars(r)["name"], false)
+}
+
+func (s *DockerServer) setPluginActive(w http.ResponseWriter, name string, active bool) {
+	s.pluginMut.Lock()
+	defer s.pluginMut.Unlock()
+	plugin, err := s.findPlugin(name)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if plugin.Active == active {
+		state := "disabled"
+		if active {
+			state = "enabled"
+		}
+		http.Error(w, "plugin "+plugin.Name+" is already "+state, http.StatusInternalServerError)
+		return
+	}
+	plugin.Active = active
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *DockerServer) removePlugin(w http.ResponseWriter, r *http.Request) {
+	s.pluginMut.Lock()
+	defer s.pluginMut.Unlock()
+	plugin, err := s.findPlugin(mux.Vars(r)["name"])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if plugin.Active && r.URL.Query().Get("force") != "1" {
+		http.Error(w, "plugin "+plugin.Name+" is enabled", http.StatusConflict)
+		return
+	}
+	delete(s.plugins, plugin.Name)
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *DockerServer) findPlugin(idOrName string) (*docker.PluginDetail, error) {
+	if plugin, ok := s.plugins[pluginReference(idOrName)]; ok {
+		return plugin, nil
+	}
+	for _, plugin := range s.plugins {
+		if plugin.ID == idOrName {
+			return plugin, nil
+		}
+	}
+	return nil, errors.New("plugin " + idOrName + " not found")
+}
+
+// pluginReference adds the default tag to plugin names that don't have one,
+// so "vieux/sshfs" and "vieux/sshfs:latest" refer to the same plugin.
+func pluginReference(name string) string {
+	if strings.LastIndex(name, ":") <= strings.LastIndex(name, "/") {
+		return name + ":latest"
+	}
+	return name
+}
+
 func (s *DockerServer) infoDocker(w http.ResponseWriter, r *http.Request) {
 	s.cMut.RLock()
 	defer s.cMut.RUnlock()
@@ -1548,7 +3926,21 @@ func (s *DockerServer) infoDocker(w http.ResponseWriter, r *http.Request) {
 				"host",
 			},
 			"Authorization": nil,
+			"Log": []string{
+				"json-file",
+				"local",
+				"syslog",
+			},
+		},
+		"SecurityOptions": []string{
+			"name=seccomp,profile=default",
 		},
+		"Runtimes": map[string]interface{}{
+			"runc": map[string]interface{}{
+				"path": "runc",
+			},
+		},
+		"DefaultRuntime":     "runc",
 		"MemoryLimit":        true,
 		"SwapLimit":          false,
 		"CpuCfsPeriod":       true,
@@ -1641,8 +4033,14 @@ func (s *DockerServer) initSwarmNode(listenAddr, advertiseAddr string) (swarm.No
 		_, portPart, _ = net.SplitHostPort(s.SwarmAddress())
 	}
 	s.nodeID = s.generateID()
+	now := time.Now()
 	return swarm.Node{
 		ID: s.nodeID,
+		Meta: swarm.Meta{
+			Version:   swarm.Version{Index: 1},
+			CreatedAt: now,
+			UpdatedAt: now,
+		},
 		Status: swarm.NodeStatus{
 			Addr:  hostPart,
 			State: swarm.NodeStateReady,
@@ -8,7 +8,9 @@ import (
 	"archive/tar"
 	"bufio"
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"math/rand"
@@ -18,13 +20,16 @@ import (
 	"net/url"
 	"os"
 	"reflect"
+	"regexp"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"testing"
 	"time"
 
 	"github.com/docker/docker/api/types/swarm"
+	"github.com/docker/docker/pkg/stdcopy"
 	docker "github.com/fsouza/go-dockerclient"
 )
 
@@ -339,6 +344,95 @@ func TestCreateContainer(t *testing.T) {
 	}
 }
 
+func TestCreateContainerInit(t *testing.T) {
+	t.Parallel()
+	server, err := NewServer("127.0.0.1:0", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Stop()
+	client, err := docker.NewClient(server.URL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := client.PullImage(docker.PullImageOptions{Repository: "busybox"}, docker.AuthConfiguration{}); err != nil {
+		t.Fatal(err)
+	}
+	enabled := true
+	container, err := client.CreateContainer(docker.CreateContainerOptions{
+		Config:     &docker.Config{Image: "busybox"},
+		HostConfig: &docker.HostConfig{Init: &enabled},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	container, err = client.InspectContainerWithOptions(docker.InspectContainerOptions{ID: container.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if container.HostConfig.Init == nil || !*container.HostConfig.Init {
+		t.Errorf("CreateContainer: Init was not stored. Got %v.", container.HostConfig.Init)
+	}
+	container, err = client.CreateContainer(docker.CreateContainerOptions{
+		Config:     &docker.Config{Image: "busybox"},
+		HostConfig: &docker.HostConfig{},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	container, err = client.InspectContainerWithOptions(docker.InspectContainerOptions{ID: container.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if container.HostConfig.Init != nil {
+		t.Errorf("CreateContainer: Init should be nil when not set. Got %v.", *container.HostConfig.Init)
+	}
+}
+
+func TestCreateContainerMounts(t *testing.T) {
+	t.Parallel()
+	server, err := NewServer("127.0.0.1:0", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Stop()
+	client, err := docker.NewClient(server.URL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := client.PullImage(docker.PullImageOptions{Repository: "busybox"}, docker.AuthConfiguration{}); err != nil {
+		t.Fatal(err)
+	}
+	container, err := client.CreateContainer(docker.CreateContainerOptions{
+		Config: &docker.Config{Image: "busybox"},
+		HostConfig: &docker.HostConfig{
+			Binds: []string{"/etc/ssl:/etc/ssl:ro,rslave", "data:/data"},
+			Mounts: []docker.HostMount{
+				{Type: "bind", Source: "/var/run/docker.sock", Target: "/var/run/docker.sock", BindOptions: &docker.BindOptions{Propagation: "rshared"}},
+				{Type: "volume", Source: "cache", Target: "/cache", ReadOnly: true, VolumeOptions: &docker.VolumeOptions{DriverConfig: docker.VolumeDriverConfig{Name: "nfs"}}},
+				{Type: "tmpfs", Target: "/run"},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	container, err = client.InspectContainerWithOptions(docker.InspectContainerOptions{ID: container.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []docker.Mount{
+		{Type: "bind", Source: "/etc/ssl", Destination: "/etc/ssl", Mode: "ro,rslave", Propagation: "rslave"},
+		{Type: "volume", Name: "data", Source: "/var/lib/docker/volumes/data/_data", Destination: "/data", Driver: "local", RW: true},
+		{Type: "bind", Source: "/var/run/docker.sock", Destination: "/var/run/docker.sock", RW: true, Propagation: "rshared"},
+		{Type: "volume", Name: "cache", Source: "/var/lib/docker/volumes/cache/_data", Destination: "/cache", Driver: "nfs"},
+		{Type: "tmpfs", Destination: "/run", RW: true},
+	}
+	if !reflect.DeepEqual(container.Mounts, expected) {
+		t.Errorf("CreateContainer: wrong mounts.\nWant %#v.\nGot  %#v.", expected, container.Mounts)
+	}
+}
+
 func getContainer(server *DockerServer) *docker.Container {
 	var cont *docker.Container
 	for _, cont = range server.containers {
@@ -346,6 +440,80 @@ func getContainer(server *DockerServer) *docker.Container {
 	return cont
 }
 
+func TestCreateContainerPlatform(t *testing.T) {
+	t.Parallel()
+	server := baseDockerServer()
+	server.imgIDs = map[string]string{"base": "a1234"}
+	server.buildMuxer()
+	recorder := httptest.NewRecorder()
+	body := `{"Cmd":["date"], "Image":"base"}`
+	request, _ := http.NewRequest(http.MethodPost, "/containers/create?platform=linux%2Farm64", strings.NewReader(body))
+	server.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusCreated {
+		t.Fatalf("CreateContainer: wrong status. Want %d. Got %d.", http.StatusCreated, recorder.Code)
+	}
+	stored := getContainer(&server)
+	if stored.Platform != "linux/arm64" {
+		t.Errorf("CreateContainer: wrong platform. Want %q. Got %q.", "linux/arm64", stored.Platform)
+	}
+}
+
+func TestCreateContainerDeviceRequests(t *testing.T) {
+	t.Parallel()
+	server, err := NewServer("127.0.0.1:0", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Stop()
+	client, err := docker.NewClient(server.URL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := client.PullImage(docker.PullImageOptions{Repository: "nvidia/cuda"}, docker.AuthConfiguration{}); err != nil {
+		t.Fatal(err)
+	}
+	requests := []docker.DeviceRequest{{Driver: "nvidia", Count: 2, Capabilities: [][]string{{"gpu"}}}}
+	container, err := client.CreateContainer(docker.CreateContainerOptions{
+		Config:     &docker.Config{Image: "nvidia/cuda"},
+		HostConfig: &docker.HostConfig{DeviceRequests: requests, DeviceCgroupRules: []string{"c 195:* rwm"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	inspected, err := client.InspectContainerWithOptions(docker.InspectContainerOptions{ID: container.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(inspected.HostConfig.DeviceRequests, requests) {
+		t.Errorf("CreateContainer: wrong DeviceRequests. Want %#v. Got %#v.", requests, inspected.HostConfig.DeviceRequests)
+	}
+	if expected := []string{"c 195:* rwm"}; !reflect.DeepEqual(inspected.HostConfig.DeviceCgroupRules, expected) {
+		t.Errorf("CreateContainer: wrong DeviceCgroupRules. Want %#v. Got %#v.", expected, inspected.HostConfig.DeviceCgroupRules)
+	}
+}
+
+func TestCreateContainerInvalidDeviceRequests(t *testing.T) {
+	t.Parallel()
+	server := baseDockerServer()
+	server.imgIDs = map[string]string{"base": "a1234"}
+	server.buildMuxer()
+	for _, requests := range []string{
+		`[{"Count":1,"DeviceIDs":["0"],"Capabilities":[["gpu"]]}]`,
+		`[{"Count":1}]`,
+	} {
+		recorder := httptest.NewRecorder()
+		body := `{"Image":"base","HostConfig":{"DeviceRequests":` + requests + `}}`
+		request, _ := http.NewRequest(http.MethodPost, "/containers/create", strings.NewReader(body))
+		server.ServeHTTP(recorder, request)
+		if recorder.Code != http.StatusBadRequest {
+			t.Errorf("CreateContainer(%s): wrong status. Want %d. Got %d.", requests, http.StatusBadRequest, recorder.Code)
+		}
+	}
+	if len(server.containers) != 0 {
+		t.Errorf("CreateContainer: unexpected containers stored: %d", len(server.containers))
+	}
+}
+
 func TestCreateContainerWithNotifyChannel(t *testing.T) {
 	t.Parallel()
 	ch := make(chan *docker.Container, 1)
@@ -500,6 +668,56 @@ func TestRenameContainerNotFound(t *testing.T) {
 	}
 }
 
+func TestRenameContainerErrors(t *testing.T) {
+	t.Parallel()
+	server, err := NewServer("127.0.0.1:0", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Stop()
+	containers := addContainers(server, 2)
+	client, err := docker.NewClient(server.URL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = client.RenameContainer(docker.RenameContainerOptions{ID: containers[0].ID, Name: containers[1].Name})
+	if !errors.Is(err, docker.ErrContainerAlreadyExists) {
+		t.Errorf("RenameContainer: wrong error for a name in use. Want %v. Got %v.", docker.ErrContainerAlreadyExists, err)
+	}
+	var e *docker.Error
+	if !errors.As(err, &e) || e.Status != http.StatusConflict {
+		t.Errorf("RenameContainer: wrong API error for a name in use: %#v", err)
+	}
+	err = client.RenameContainer(docker.RenameContainerOptions{ID: "unknown", Name: "something"})
+	var notFound *docker.NoSuchContainer
+	if !errors.As(err, &notFound) || notFound.ID != "unknown" {
+		t.Errorf("RenameContainer: wrong error for an unknown container. Want *docker.NoSuchContainer. Got %#v.", err)
+	}
+	if err = client.RenameContainer(docker.RenameContainerOptions{ID: containers[0].ID, Name: "/" + containers[0].Name}); err != nil {
+		t.Errorf("RenameContainer: unexpected error renaming a container to its own name: %v", err)
+	}
+	container, err := client.InspectContainerWithOptions(docker.InspectContainerOptions{ID: containers[0].ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if container.Name == containers[1].Name {
+		t.Errorf("RenameContainer: the container was renamed to the name in use %q", container.Name)
+	}
+}
+
+func TestRenameContainerInvalidName(t *testing.T) {
+	t.Parallel()
+	server := baseDockerServer()
+	containers := addContainers(&server, 1)
+	server.buildMuxer()
+	recorder := httptest.NewRecorder()
+	request, _ := http.NewRequest(http.MethodPost, "/containers/"+containers[0].ID+"/rename?name=-invalid", nil)
+	server.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusBadRequest {
+		t.Errorf("RenameContainer: wrong status. Want %d. Got %d.", http.StatusBadRequest, recorder.Code)
+	}
+}
+
 func TestCommitContainer(t *testing.T) {
 	t.Parallel()
 	server := baseDockerServer()
@@ -595,6 +813,80 @@ func TestCommitContainerWithTag(t *testing.T) {
 	}
 }
 
+func TestCommitContainerChanges(t *testing.T) {
+	t.Parallel()
+	server, err := NewServer("127.0.0.1:0", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Stop()
+	addContainers(server, 1)
+	client, err := docker.NewClient(server.URL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := docker.CommitContainerOptions{
+		Container:  getContainer(server).ID,
+		Repository: "tsuru/python",
+		Message:    "with changes",
+		Run:        &docker.Config{Env: []string{"DEBUG=0"}},
+	}
+	for _, change := range [][]string{
+		{"CMD", "/app", "--listen", ":8080"},
+		{"ENV", "DEBUG=1", "GREETING=hello \"world\""},
+		{"LABEL", "team=storage"},
+		{"EXPOSE", "8080", "53/udp"},
+		{"WORKDIR", "/srv"},
+		{"USER", "nobody"},
+		{"VOLUME", "/data"},
+	} {
+		if err = opts.AddChange(change[0], change[1:]...); err != nil {
+			t.Fatal(err)
+		}
+	}
+	opts.Changes = append(opts.Changes, "HEALTHCHECK CMD curl -f http://localhost:8080/")
+	committed, err := client.CommitContainer(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	image, err := client.InspectImage(committed.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if image.Comment != "with changes" {
+		t.Errorf("CommitContainer: wrong comment. Want %q. Got %q.", "with changes", image.Comment)
+	}
+	expected := &docker.Config{
+		Cmd:          []string{"/app", "--listen", ":8080"},
+		Env:          []string{"DEBUG=1", `GREETING=hello "world"`},
+		Labels:       map[string]string{"team": "storage"},
+		ExposedPorts: map[docker.Port]struct{}{"8080/tcp": {}, "53/udp": {}},
+		WorkingDir:   "/srv",
+		User:         "nobody",
+		Volumes:      map[string]struct{}{"/data": {}},
+		Healthcheck:  &docker.HealthConfig{Test: []string{"CMD-SHELL", "curl -f http://localhost:8080/"}},
+	}
+	if !reflect.DeepEqual(image.Config, expected) {
+		t.Errorf("CommitContainer: wrong config.\nWant %#v.\nGot  %#v.", expected, image.Config)
+	}
+}
+
+func TestCommitContainerInvalidChange(t *testing.T) {
+	t.Parallel()
+	server := baseDockerServer()
+	addContainers(&server, 1)
+	server.buildMuxer()
+	recorder := httptest.NewRecorder()
+	qs := make(url.Values)
+	qs.Add("container", getContainer(&server).ID)
+	qs.Add("changes", "RUN apt-get update")
+	request, _ := http.NewRequest(http.MethodPost, "/commit?"+qs.Encode(), nil)
+	server.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusBadRequest {
+		t.Errorf("CommitContainer: wrong status. Want %d. Got %d.", http.StatusBadRequest, recorder.Code)
+	}
+}
+
 func TestCommitContainerInvalidRun(t *testing.T) {
 	t.Parallel()
 	server := baseDockerServer()
@@ -654,6 +946,83 @@ func TestInspectContainer(t *testing.T) {
 	}
 }
 
+func TestListContainersSize(t *testing.T) {
+	t.Parallel()
+	server := baseDockerServer()
+	containers := addContainers(&server, 2)
+	for _, container := range containers {
+		server.images[container.Image] = docker.Image{ID: container.Image, Size: 100}
+	}
+	server.containerFiles[containers[0].ID] = map[string]*containerFile{
+		"/etc/app.yaml": {header: tar.Header{Name: "app.yaml"}, content: []byte("key: val\n")},
+	}
+	server.buildMuxer()
+	for _, query := range []string{"all=1", "all=1&size=1"} {
+		recorder := httptest.NewRecorder()
+		request, _ := http.NewRequest(http.MethodGet, "/containers/json?"+query, nil)
+		server.ServeHTTP(recorder, request)
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("ListContainers(%q): wrong status. Want %d. Got %d.", query, http.StatusOK, recorder.Code)
+		}
+		var got []docker.APIContainers
+		if err := json.NewDecoder(recorder.Body).Decode(&got); err != nil {
+			t.Fatal(err)
+		}
+		sized := strings.HasSuffix(query, "size=1")
+		for _, container := range got {
+			var wantSizeRw, wantSizeRootFs int64
+			if sized {
+				wantSizeRootFs = 100
+				if container.ID == containers[0].ID {
+					wantSizeRw = 9
+					wantSizeRootFs = 109
+				}
+			}
+			if container.SizeRw != wantSizeRw || container.SizeRootFs != wantSizeRootFs {
+				t.Errorf("ListContainers(%q): wrong sizes for %s. Want %d/%d. Got %d/%d.", query, container.ID, wantSizeRw, wantSizeRootFs, container.SizeRw, container.SizeRootFs)
+			}
+		}
+	}
+}
+
+func TestInspectContainerSize(t *testing.T) {
+	t.Parallel()
+	server := baseDockerServer()
+	containers := addContainers(&server, 1)
+	server.images[containers[0].Image] = docker.Image{ID: containers[0].Image, Size: 100}
+	server.containerFiles[containers[0].ID] = map[string]*containerFile{
+		"/etc/app.yaml": {header: tar.Header{Name: "app.yaml"}, content: []byte("key: val\n")},
+		"/etc/other":    {header: tar.Header{Name: "other"}, content: []byte("x")},
+	}
+	server.buildMuxer()
+	tests := []struct {
+		query          string
+		wantSizeRw     int64
+		wantSizeRootFs int64
+	}{
+		{"", 0, 0},
+		{"?size=0", 0, 0},
+		{"?size=1", 10, 110},
+		{"?size=true", 10, 110},
+	}
+	for _, tt := range tests {
+		recorder := httptest.NewRecorder()
+		path := fmt.Sprintf("/containers/%s/json%s", containers[0].ID, tt.query)
+		request, _ := http.NewRequest(http.MethodGet, path, nil)
+		server.ServeHTTP(recorder, request)
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("InspectContainer(%q): wrong status. Want %d. Got %d.", tt.query, http.StatusOK, recorder.Code)
+		}
+		var got docker.Container
+		if err := json.NewDecoder(recorder.Body).Decode(&got); err != nil {
+			t.Fatal(err)
+		}
+		if got.SizeRw != tt.wantSizeRw || got.SizeRootFs != tt.wantSizeRootFs {
+			t.Errorf("InspectContainer(%q): wrong sizes. Want SizeRw=%d, SizeRootFs=%d. Got SizeRw=%d, SizeRootFs=%d.", tt.query, tt.wantSizeRw, tt.wantSizeRootFs, got.SizeRw, got.SizeRootFs)
+		}
+	}
+}
+
 func TestInspectContainerNotFound(t *testing.T) {
 	t.Parallel()
 	server := baseDockerServer()
@@ -666,6 +1035,53 @@ func TestInspectContainerNotFound(t *testing.T) {
 	}
 }
 
+func TestInspectContainerOverride(t *testing.T) {
+	t.Parallel()
+	server := baseDockerServer()
+	containers := addContainers(&server, 1)
+	server.buildMuxer()
+	raw := json.RawMessage(`{"Id":"` + containers[0].ID + `","RestartCount":3,"GraphDriver":{"Name":"overlay2"}}`)
+	server.SetContainerInspectOverride(containers[0].ID, raw)
+	path := fmt.Sprintf("/containers/%s/json", containers[0].ID)
+	recorder := httptest.NewRecorder()
+	request, _ := http.NewRequest(http.MethodGet, path, nil)
+	server.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusOK {
+		t.Errorf("InspectContainer: wrong status. Want %d. Got %d.", http.StatusOK, recorder.Code)
+	}
+	if got := recorder.Body.String(); got != string(raw) {
+		t.Errorf("InspectContainer: wrong body. Want %q. Got %q.", raw, got)
+	}
+	server.ClearContainerInspectOverride(containers[0].ID)
+	recorder = httptest.NewRecorder()
+	request, _ = http.NewRequest(http.MethodGet, path, nil)
+	server.ServeHTTP(recorder, request)
+	var got docker.Container
+	if err := json.NewDecoder(recorder.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if got.RestartCount != 0 || got.GraphDriver != nil {
+		t.Errorf("InspectContainer: override was not cleared. Got %#v.", got)
+	}
+}
+
+func TestInspectContainerOverrideNotStored(t *testing.T) {
+	t.Parallel()
+	server := baseDockerServer()
+	server.buildMuxer()
+	raw := json.RawMessage(`{"Id":"abc123","State":{"Status":"dead","Dead":true}}`)
+	server.SetContainerInspectOverride("abc123", raw)
+	recorder := httptest.NewRecorder()
+	request, _ := http.NewRequest(http.MethodGet, "/containers/abc123/json", nil)
+	server.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusOK {
+		t.Errorf("InspectContainer: wrong status. Want %d. Got %d.", http.StatusOK, recorder.Code)
+	}
+	if got := recorder.Body.String(); got != string(raw) {
+		t.Errorf("InspectContainer: wrong body. Want %q. Got %q.", raw, got)
+	}
+}
+
 func TestTopContainer(t *testing.T) {
 	t.Parallel()
 	server := baseDockerServer()
@@ -934,54 +1350,187 @@ func TestStopContainerNotRunning(t *testing.T) {
 	}
 }
 
-func TestPauseContainer(t *testing.T) {
+func TestUpdateContainerBlkio(t *testing.T) {
 	t.Parallel()
 	server := baseDockerServer()
 	addContainers(&server, 1)
 	server.buildMuxer()
 	recorder := httptest.NewRecorder()
-	path := fmt.Sprintf("/containers/%s/pause", getContainer(&server).ID)
-	request, _ := http.NewRequest(http.MethodPost, path, nil)
+	opts := docker.UpdateContainerOptions{
+		BlkioWeight:         300,
+		BlkioWeightDevice:   []docker.BlockWeight{{Path: "/dev/sda", Weight: "200"}},
+		BlkioDeviceReadBps:  []docker.BlockLimit{{Path: "/dev/sda", Rate: 1048576}},
+		BlkioDeviceWriteBps: []docker.BlockLimit{{Path: "/dev/sda", Rate: 524288}},
+	}
+	body, _ := json.Marshal(opts)
+	path := fmt.Sprintf("/containers/%s/update", getContainer(&server).ID)
+	request, _ := http.NewRequest(http.MethodPost, path, bytes.NewReader(body))
 	server.ServeHTTP(recorder, request)
-	if recorder.Code != http.StatusNoContent {
-		t.Errorf("PauseContainer: wrong status code. Want %d. Got %d.", http.StatusNoContent, recorder.Code)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("UpdateContainer: wrong status code. Want %d. Got %d.", http.StatusOK, recorder.Code)
 	}
-	if !getContainer(&server).State.Paused {
-		t.Error("PauseContainer: did not pause the container")
+	hc := getContainer(&server).HostConfig
+	if hc.BlkioWeight != 300 {
+		t.Errorf("UpdateContainer: wrong BlkioWeight. Want 300. Got %d.", hc.BlkioWeight)
+	}
+	if !reflect.DeepEqual(hc.BlkioWeightDevice, opts.BlkioWeightDevice) {
+		t.Errorf("UpdateContainer: wrong BlkioWeightDevice. Want %#v. Got %#v.", opts.BlkioWeightDevice, hc.BlkioWeightDevice)
+	}
+	if !reflect.DeepEqual(hc.BlkioDeviceReadBps, opts.BlkioDeviceReadBps) {
+		t.Errorf("UpdateContainer: wrong BlkioDeviceReadBps. Want %#v. Got %#v.", opts.BlkioDeviceReadBps, hc.BlkioDeviceReadBps)
+	}
+	if !reflect.DeepEqual(hc.BlkioDeviceWriteBps, opts.BlkioDeviceWriteBps) {
+		t.Errorf("UpdateContainer: wrong BlkioDeviceWriteBps. Want %#v. Got %#v.", opts.BlkioDeviceWriteBps, hc.BlkioDeviceWriteBps)
 	}
 }
 
-func TestPauseContainerAlreadyPaused(t *testing.T) {
+func TestUpdateContainerInvalidBlkioWeight(t *testing.T) {
 	t.Parallel()
 	server := baseDockerServer()
 	addContainers(&server, 1)
-	getContainer(&server).State.Paused = true
 	server.buildMuxer()
 	recorder := httptest.NewRecorder()
-	path := fmt.Sprintf("/containers/%s/pause", getContainer(&server).ID)
-	request, _ := http.NewRequest(http.MethodPost, path, nil)
+	path := fmt.Sprintf("/containers/%s/update", getContainer(&server).ID)
+	request, _ := http.NewRequest(http.MethodPost, path, strings.NewReader(`{"BlkioWeight":5}`))
 	server.ServeHTTP(recorder, request)
 	if recorder.Code != http.StatusBadRequest {
-		t.Errorf("PauseContainer: wrong status code. Want %d. Got %d.", http.StatusBadRequest, recorder.Code)
+		t.Errorf("UpdateContainer: wrong status code. Want %d. Got %d.", http.StatusBadRequest, recorder.Code)
 	}
 }
 
-func TestPauseContainerNotFound(t *testing.T) {
+func TestUpdateContainerRestartPolicy(t *testing.T) {
 	t.Parallel()
-	server := baseDockerServer()
-	server.buildMuxer()
-	recorder := httptest.NewRecorder()
-	path := "/containers/abc123/pause"
-	request, _ := http.NewRequest(http.MethodPost, path, nil)
-	server.ServeHTTP(recorder, request)
-	if recorder.Code != http.StatusNotFound {
-		t.Errorf("PauseContainer: wrong status code. Want %d. Got %d.", http.StatusNotFound, recorder.Code)
+	server, err := NewServer("127.0.0.1:0", nil, nil)
+	if err != nil {
+		t.Fatal(err)
 	}
-}
-
-func TestUnpauseContainer(t *testing.T) {
-	t.Parallel()
-	server := baseDockerServer()
+	defer server.Stop()
+	client, err := docker.NewClient(server.URL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := client.PullImage(docker.PullImageOptions{Repository: "busybox"}, docker.AuthConfiguration{}); err != nil {
+		t.Fatal(err)
+	}
+	container, err := client.CreateContainer(docker.CreateContainerOptions{
+		Config:     &docker.Config{Image: "busybox"},
+		HostConfig: &docker.HostConfig{RestartPolicy: docker.NeverRestart()},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = client.UpdateContainer(container.ID, docker.UpdateContainerOptions{RestartPolicy: docker.RestartUnlessStopped()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	container, err = client.InspectContainerWithOptions(docker.InspectContainerOptions{ID: container.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if policy := container.HostConfig.RestartPolicy; policy != docker.RestartUnlessStopped() {
+		t.Errorf("UpdateContainer: wrong restart policy. Want %#v. Got %#v.", docker.RestartUnlessStopped(), policy)
+	}
+	err = client.UpdateContainer(container.ID, docker.UpdateContainerOptions{Memory: 1024})
+	if err != nil {
+		t.Fatal(err)
+	}
+	container, err = client.InspectContainerWithOptions(docker.InspectContainerOptions{ID: container.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if policy := container.HostConfig.RestartPolicy; policy != docker.RestartUnlessStopped() {
+		t.Errorf("UpdateContainer: restart policy changed by an update without it. Got %#v.", policy)
+	}
+}
+
+func TestUpdateContainerRestartPolicyErrors(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name       string
+		autoRemove bool
+		body       string
+		status     int
+	}{
+		{name: "invalid name", body: `{"RestartPolicy":{"Name":"sometimes"}}`, status: http.StatusBadRequest},
+		{name: "retry count", body: `{"RestartPolicy":{"Name":"always","MaximumRetryCount":3}}`, status: http.StatusBadRequest},
+		{name: "auto remove", autoRemove: true, body: `{"RestartPolicy":{"Name":"always"}}`, status: http.StatusConflict},
+		{name: "auto remove no restart", autoRemove: true, body: `{"RestartPolicy":{"Name":"no"}}`, status: http.StatusOK},
+	}
+	for _, tt := range tests {
+		server := baseDockerServer()
+		container := addContainers(&server, 1)[0]
+		container.HostConfig = &docker.HostConfig{AutoRemove: tt.autoRemove}
+		server.buildMuxer()
+		recorder := httptest.NewRecorder()
+		path := fmt.Sprintf("/containers/%s/update", container.ID)
+		request, _ := http.NewRequest(http.MethodPost, path, strings.NewReader(tt.body))
+		server.ServeHTTP(recorder, request)
+		if recorder.Code != tt.status {
+			t.Errorf("UpdateContainer (%s): wrong status code. Want %d. Got %d.", tt.name, tt.status, recorder.Code)
+		}
+	}
+}
+
+func TestUpdateContainerNotFound(t *testing.T) {
+	t.Parallel()
+	server := baseDockerServer()
+	server.buildMuxer()
+	recorder := httptest.NewRecorder()
+	request, _ := http.NewRequest(http.MethodPost, "/containers/abc123/update", strings.NewReader("{}"))
+	server.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusNotFound {
+		t.Errorf("UpdateContainer: wrong status code. Want %d. Got %d.", http.StatusNotFound, recorder.Code)
+	}
+}
+
+func TestPauseContainer(t *testing.T) {
+	t.Parallel()
+	server := baseDockerServer()
+	addContainers(&server, 1)
+	server.buildMuxer()
+	recorder := httptest.NewRecorder()
+	path := fmt.Sprintf("/containers/%s/pause", getContainer(&server).ID)
+	request, _ := http.NewRequest(http.MethodPost, path, nil)
+	server.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusNoContent {
+		t.Errorf("PauseContainer: wrong status code. Want %d. Got %d.", http.StatusNoContent, recorder.Code)
+	}
+	if !getContainer(&server).State.Paused {
+		t.Error("PauseContainer: did not pause the container")
+	}
+}
+
+func TestPauseContainerAlreadyPaused(t *testing.T) {
+	t.Parallel()
+	server := baseDockerServer()
+	addContainers(&server, 1)
+	getContainer(&server).State.Paused = true
+	server.buildMuxer()
+	recorder := httptest.NewRecorder()
+	path := fmt.Sprintf("/containers/%s/pause", getContainer(&server).ID)
+	request, _ := http.NewRequest(http.MethodPost, path, nil)
+	server.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusBadRequest {
+		t.Errorf("PauseContainer: wrong status code. Want %d. Got %d.", http.StatusBadRequest, recorder.Code)
+	}
+}
+
+func TestPauseContainerNotFound(t *testing.T) {
+	t.Parallel()
+	server := baseDockerServer()
+	server.buildMuxer()
+	recorder := httptest.NewRecorder()
+	path := "/containers/abc123/pause"
+	request, _ := http.NewRequest(http.MethodPost, path, nil)
+	server.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusNotFound {
+		t.Errorf("PauseContainer: wrong status code. Want %d. Got %d.", http.StatusNotFound, recorder.Code)
+	}
+}
+
+func TestUnpauseContainer(t *testing.T) {
+	t.Parallel()
+	server := baseDockerServer()
 	addContainers(&server, 1)
 	getContainer(&server).State.Paused = true
 	server.buildMuxer()
@@ -1080,6 +1629,185 @@ func TestWaitContainerNotFound(t *testing.T) {
 	}
 }
 
+func TestWaitContainerRemoved(t *testing.T) {
+	t.Parallel()
+	server, err := NewServer("127.0.0.1:0", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Stop()
+	server.CustomHandler("/version", http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"ApiVersion":"1.30"}`))
+	}))
+	client, err := docker.NewClient(server.URL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the headers of the wait response are sent once the wait is
+	// registered, so the container can't be removed unnoticed after them.
+	registered := make(chan struct{}, 1)
+	client.HTTPClient.Transport = headersNotifier{RoundTripper: client.HTTPClient.Transport, received: registered}
+	if err := client.PullImage(docker.PullImageOptions{Repository: "busybox"}, docker.AuthConfiguration{}); err != nil {
+		t.Fatal(err)
+	}
+	container, err := client.CreateContainer(docker.CreateContainerOptions{
+		Config:     &docker.Config{Image: "busybox"},
+		HostConfig: &docker.HostConfig{AutoRemove: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := client.StartContainer(container.ID, nil); err != nil {
+		t.Fatal(err)
+	}
+	errCh := make(chan error, 1)
+	go func() {
+		_, err := client.WaitContainerWithOptions(container.ID, docker.WaitContainerOptions{Condition: docker.WaitConditionRemoved})
+		errCh <- err
+	}()
+	select {
+	case <-registered:
+	case <-time.After(5 * time.Second):
+		t.Fatal("WaitContainer: timed out waiting for the wait to be registered")
+	}
+	if err := client.StopContainer(container.ID, 0); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-errCh:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("WaitContainer: timed out waiting for the container to be removed")
+	}
+	if _, err := client.InspectContainerWithOptions(docker.InspectContainerOptions{ID: container.ID}); err == nil {
+		t.Error("WaitContainer: container with AutoRemove was not removed")
+	}
+}
+
+// headersNotifier is a transport that signals when the headers of a wait
+// response are received.
+type headersNotifier struct {
+	http.RoundTripper
+	received chan<- struct{}
+}
+
+func (n headersNotifier) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := n.RoundTripper.RoundTrip(req)
+	if err == nil && strings.HasSuffix(req.URL.Path, "/wait") {
+		n.received <- struct{}{}
+	}
+	return resp, err
+}
+
+func TestWaitContainerNextExit(t *testing.T) {
+	t.Parallel()
+	server := baseDockerServer()
+	addContainers(&server, 1)
+	server.buildMuxer()
+	container := getContainer(&server)
+	recorder := httptest.NewRecorder()
+	path := fmt.Sprintf("/containers/%s/wait?condition=next-exit", container.ID)
+	request, _ := http.NewRequest(http.MethodPost, path, nil)
+	done := make(chan struct{})
+	go func() {
+		server.ServeHTTP(recorder, request)
+		close(done)
+	}()
+	select {
+	case <-done:
+		t.Fatal("WaitContainer: returned for a stopped container when waiting for the next exit")
+	case <-time.After(50 * time.Millisecond):
+	}
+	server.cMut.Lock()
+	container.State.Running = true
+	server.cMut.Unlock()
+	time.Sleep(50 * time.Millisecond)
+	server.cMut.Lock()
+	container.State.Running = false
+	container.State.ExitCode = 7
+	server.cMut.Unlock()
+	<-done
+	expected := `{"StatusCode":7}` + "\n"
+	if body := recorder.Body.String(); body != expected {
+		t.Errorf("WaitContainer: wrong body. Want %q. Got %q.", expected, body)
+	}
+}
+
+func TestRunAndWaitAutoRemove(t *testing.T) {
+	t.Parallel()
+	server, err := NewServer("127.0.0.1:0", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Stop()
+	server.CustomHandler("/version", http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"ApiVersion":"1.30"}`))
+	}))
+	client, err := docker.NewClient(server.URL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = client.PullImage(docker.PullImageOptions{Repository: "busybox"}, docker.AuthConfiguration{}); err != nil {
+		t.Fatal(err)
+	}
+	container, err := client.CreateContainer(docker.CreateContainerOptions{
+		Config:     &docker.Config{Image: "busybox", Cmd: []string{"false"}},
+		HostConfig: &docker.HostConfig{AutoRemove: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// exit and remove the container as soon as it starts, before the
+	// client gets a chance to inspect it.
+	go func() {
+		for server.ExitContainer(container.ID, 3) != nil {
+			time.Sleep(time.Millisecond)
+		}
+	}()
+	status, err := client.RunAndWait(context.Background(), container.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status != 3 {
+		t.Errorf("RunAndWait: wrong exit code. Want 3. Got %d.", status)
+	}
+	var notFound *docker.NoSuchContainer
+	if _, err = client.InspectContainerWithOptions(docker.InspectContainerOptions{ID: container.ID}); !errors.As(err, &notFound) {
+		t.Errorf("InspectContainer: the container wasn't removed: %v", err)
+	}
+}
+
+func TestExitContainerNotRunning(t *testing.T) {
+	t.Parallel()
+	server := baseDockerServer()
+	addContainers(&server, 1)
+	container := getContainer(&server)
+	if err := server.ExitContainer(container.ID, 1); err == nil {
+		t.Error("ExitContainer: unexpected <nil> error for a stopped container")
+	}
+	if err := server.ExitContainer("unknown", 1); err == nil {
+		t.Error("ExitContainer: unexpected <nil> error for an unknown container")
+	}
+}
+
+func TestWaitContainerInvalidCondition(t *testing.T) {
+	t.Parallel()
+	server := baseDockerServer()
+	addContainers(&server, 1)
+	server.buildMuxer()
+	recorder := httptest.NewRecorder()
+	path := fmt.Sprintf("/containers/%s/wait?condition=exploded", getContainer(&server).ID)
+	request, _ := http.NewRequest(http.MethodPost, path, nil)
+	server.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusBadRequest {
+		t.Errorf("WaitContainer: wrong status code. Want %d. Got %d.", http.StatusBadRequest, recorder.Code)
+	}
+}
+
 type HijackableResponseRecorder struct {
 	httptest.ResponseRecorder
 	readCh chan []byte
@@ -1114,6 +1842,28 @@ func TestLogContainer(t *testing.T) {
 	}
 }
 
+func TestLogContainerStoredLogs(t *testing.T) {
+	t.Parallel()
+	server := baseDockerServer()
+	addContainers(&server, 1)
+	server.SetContainerLogs(getContainer(&server).ID, "hello\n", "world\n")
+	server.buildMuxer()
+	recorder := httptest.NewRecorder()
+	path := fmt.Sprintf("/containers/%s/logs?stdout=1&stderr=1", getContainer(&server).ID)
+	request, _ := http.NewRequest(http.MethodGet, path, nil)
+	server.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("LogContainer: wrong status. Want %d. Got %d.", http.StatusOK, recorder.Code)
+	}
+	var stdout, stderr bytes.Buffer
+	if _, err := stdcopy.StdCopy(&stdout, &stderr, recorder.Body); err != nil {
+		t.Fatal(err)
+	}
+	if stdout.String() != "hello\n" || stderr.String() != "world\n" {
+		t.Errorf("LogContainer: wrong output. Got stdout %q and stderr %q.", stdout.String(), stderr.String())
+	}
+}
+
 func TestLogContainerNotFound(t *testing.T) {
 	t.Parallel()
 	server := baseDockerServer()
@@ -1382,6 +2132,27 @@ func TestPullImageWithShaTag(t *testing.T) {
 	}
 }
 
+func TestPullImagePlatform(t *testing.T) {
+	t.Parallel()
+	server := baseDockerServer()
+	server.buildMuxer()
+	for _, platform := range []string{"linux/amd64", "linux/arm64/v8", "linux/arm64/v8"} {
+		recorder := httptest.NewRecorder()
+		request, _ := http.NewRequest(http.MethodPost, "/v1.32/images/create?fromImage=base&platform="+platform, nil)
+		server.ServeHTTP(recorder, request)
+		if recorder.Code != http.StatusOK {
+			t.Errorf("PullImage: wrong status. Want %d. Got %d.", http.StatusOK, recorder.Code)
+		}
+	}
+	if len(server.images) != 2 {
+		t.Errorf("PullImage: Want 2 images. Got %d.", len(server.images))
+	}
+	image := server.images[server.imgIDs["base"]]
+	if image.OS != "linux" || image.Architecture != "arm64" {
+		t.Errorf("PullImage: wrong platform recorded. Want linux/arm64. Got %s/%s.", image.OS, image.Architecture)
+	}
+}
+
 func TestPullImageExisting(t *testing.T) {
 	t.Parallel()
 	server := baseDockerServer()
@@ -1443,30 +2214,276 @@ func TestPushImageWithTag(t *testing.T) {
 	}
 }
 
-func TestPushImageNotFound(t *testing.T) {
+func TestPushImageDigest(t *testing.T) {
 	t.Parallel()
-	server := baseDockerServer()
-	server.buildMuxer()
-	recorder := httptest.NewRecorder()
-	request, _ := http.NewRequest(http.MethodPost, "/images/tsuru/python/push", nil)
-	server.ServeHTTP(recorder, request)
-	if recorder.Code != http.StatusNotFound {
-		t.Errorf("PushImage: wrong status. Want %d. Got %d.", http.StatusNotFound, recorder.Code)
+	server, err := NewServer("127.0.0.1:0", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Stop()
+	client, err := docker.NewClient(server.URL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := client.PullImage(docker.PullImageOptions{Repository: "registry.example.com/app", Tag: "v1"}, docker.AuthConfiguration{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.TagImage("registry.example.com/app:v1", docker.TagImageOptions{Repo: "registry.example.com/other", Tag: "v1"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.ResolveImageDigest("registry.example.com/other:v1"); !errors.Is(err, docker.ErrNoImageDigest) {
+		t.Errorf("ResolveImageDigest: wrong error before push. Want %v. Got %v.", docker.ErrNoImageDigest, err)
+	}
+	digest, err := client.PushImageWithDigest(docker.PushImageOptions{Name: "registry.example.com/other", Tag: "v1"}, docker.AuthConfiguration{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(digest, "sha256:") {
+		t.Fatalf("PushImage: wrong digest: %q", digest)
+	}
+	resolved, err := client.ResolveImageDigest("registry.example.com/other:v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "registry.example.com/other@" + digest; resolved != expected {
+		t.Errorf("ResolveImageDigest: wrong digest. Want %q. Got %q.", expected, resolved)
 	}
 }
 
-func TestTagImage(t *testing.T) {
+func TestPullImageRepoDigests(t *testing.T) {
 	t.Parallel()
-	server := baseDockerServer()
-	server.imgIDs = map[string]string{"tsuru/python": "a123"}
-	server.buildMuxer()
-	recorder := httptest.NewRecorder()
-	request, _ := http.NewRequest(http.MethodPost, "/images/tsuru/python/tag?repo=tsuru/new-python", nil)
-	server.ServeHTTP(recorder, request)
-	if recorder.Code != http.StatusCreated {
-		t.Errorf("TagImage: wrong status. Want %d. Got %d.", http.StatusCreated, recorder.Code)
-	}
-	if server.imgIDs["tsuru/python"] != server.imgIDs["tsuru/new-python"] {
+	server, err := NewServer("127.0.0.1:0", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Stop()
+	client, err := docker.NewClient(server.URL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = client.PullImage(docker.PullImageOptions{Repository: "registry.example.com/app", Tag: "v1"}, docker.AuthConfiguration{}); err != nil {
+		t.Fatal(err)
+	}
+	repoDigest, err := client.ResolveImageDigest("registry.example.com/app:v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	image, err := client.InspectImageByDigest(repoDigest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	byDigest, err := client.InspectImageByDigest(repoDigest[strings.Index(repoDigest, "@")+1:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if byDigest.ID != image.ID {
+		t.Errorf("InspectImageByDigest: wrong image for bare digest. Want %q. Got %q.", image.ID, byDigest.ID)
+	}
+	pinned := "registry.example.com/pinned@sha256:" + strings.Repeat("ab", 32)
+	if err = client.PullImage(docker.PullImageOptions{Repository: "registry.example.com/pinned", Tag: pinned[strings.Index(pinned, "@")+1:]}, docker.AuthConfiguration{}); err != nil {
+		t.Fatal(err)
+	}
+	image, err = client.InspectImageByDigest(pinned)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(image.RepoDigests, []string{pinned}) {
+		t.Errorf("PullImage: wrong RepoDigests. Want %q. Got %q.", []string{pinned}, image.RepoDigests)
+	}
+	if _, err = client.InspectImageByDigest("sha256:" + strings.Repeat("cd", 32)); !errors.Is(err, docker.ErrNoSuchImage) {
+		t.Errorf("InspectImageByDigest: wrong error. Want %v. Got %v.", docker.ErrNoSuchImage, err)
+	}
+}
+
+func TestPullImageAllTags(t *testing.T) {
+	t.Parallel()
+	server, err := NewServer("127.0.0.1:0", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Stop()
+	server.SetRemoteTags("registry.example.com/app", "v1", "v2", "latest")
+	client, err := docker.NewClient(server.URL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	opts := docker.PullImageOptions{Repository: "registry.example.com/app", All: true, OutputStream: &buf, RawJSONStream: true}
+	if err = client.PullImage(opts, docker.AuthConfiguration{}); err != nil {
+		t.Fatal(err)
+	}
+	ids := make(map[string]bool)
+	for _, tag := range []string{"v1", "v2", "latest"} {
+		image, err := client.InspectImage("registry.example.com/app:" + tag)
+		if err != nil {
+			t.Fatalf("InspectImage(%q): %v", tag, err)
+		}
+		ids[image.ID] = true
+	}
+	if len(ids) != 3 {
+		t.Errorf("PullImage: expected one image per tag. Got %d images.", len(ids))
+	}
+	if _, err = client.InspectImage("registry.example.com/app"); !errors.Is(err, docker.ErrNoSuchImage) {
+		t.Errorf("InspectImage: wrong error for the untagged repository. Want %v. Got %v.", docker.ErrNoSuchImage, err)
+	}
+	var pulled []string
+	decoder := json.NewDecoder(&buf)
+	for {
+		var msg struct {
+			Status string `json:"status"`
+			ID     string `json:"id"`
+		}
+		if err := decoder.Decode(&msg); err != nil {
+			break
+		}
+		if strings.HasPrefix(msg.Status, "Pulling from ") {
+			pulled = append(pulled, msg.ID)
+		}
+	}
+	if expected := []string{"v1", "v2", "latest"}; !reflect.DeepEqual(pulled, expected) {
+		t.Errorf("PullImage: wrong tags in the progress stream. Want %#v. Got %#v.", expected, pulled)
+	}
+}
+
+func TestPrepareImagePullFailure(t *testing.T) {
+	t.Parallel()
+	server, err := NewServer("127.0.0.1:0", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Stop()
+	message := "manifest for registry.example.com/app:v2 not found: manifest unknown: manifest unknown"
+	server.PrepareImagePull("registry.example.com/app:v2", PrepareImagePullOptions{Status: http.StatusNotFound, Message: message})
+	client, err := docker.NewClient(server.URL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = client.PullImage(docker.PullImageOptions{Repository: "registry.example.com/app", Tag: "v2"}, docker.AuthConfiguration{})
+	var e *docker.Error
+	if !errors.As(err, &e) || e.Status != http.StatusNotFound || !strings.Contains(e.Message, "manifest unknown") {
+		t.Errorf("PullImage: wrong error: %#v", err)
+	}
+	if _, err = client.InspectImage("registry.example.com/app:v2"); !errors.Is(err, docker.ErrNoSuchImage) {
+		t.Errorf("InspectImage: wrong error. Want %v. Got %v.", docker.ErrNoSuchImage, err)
+	}
+	if err = client.PullImage(docker.PullImageOptions{Repository: "registry.example.com/app", Tag: "v1"}, docker.AuthConfiguration{}); err != nil {
+		t.Errorf("PullImage: unexpected error pulling another tag: %v", err)
+	}
+}
+
+func TestPrepareImagePullProgress(t *testing.T) {
+	t.Parallel()
+	server, err := NewServer("127.0.0.1:0", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Stop()
+	server.PrepareImagePull("busybox:latest", PrepareImagePullOptions{
+		Progress: []PullProgress{
+			{ID: "latest", Status: "Pulling from library/busybox"},
+			{ID: "9ad63333ebc9", Status: "Downloading", Current: 512, Total: 2048},
+			{ID: "9ad63333ebc9", Status: "Downloading", Current: 2048, Total: 2048},
+			{ID: "9ad63333ebc9", Status: "Pull complete"},
+		},
+		Delay: 10 * time.Millisecond,
+	})
+	client, err := docker.NewClient(server.URL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	start := time.Now()
+	opts := docker.PullImageOptions{Repository: "busybox", Tag: "latest", OutputStream: &buf, RawJSONStream: true}
+	if err = client.PullImage(opts, docker.AuthConfiguration{}); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 40*time.Millisecond {
+		t.Errorf("PullImage: the progress wasn't delayed, the pull took %s", elapsed)
+	}
+	type progressMessage struct {
+		ID             string `json:"id"`
+		Status         string `json:"status"`
+		Progress       string `json:"progress"`
+		ProgressDetail *struct {
+			Current int64 `json:"current"`
+			Total   int64 `json:"total"`
+		} `json:"progressDetail"`
+	}
+	var messages []progressMessage
+	decoder := json.NewDecoder(&buf)
+	for decoder.More() {
+		var msg progressMessage
+		if err := decoder.Decode(&msg); err != nil {
+			t.Fatal(err)
+		}
+		messages = append(messages, msg)
+	}
+	if len(messages) != 4 {
+		t.Fatalf("PullImage: wrong number of progress messages. Want 4. Got %d.", len(messages))
+	}
+	if msg := messages[1]; msg.ID != "9ad63333ebc9" || msg.ProgressDetail == nil || msg.ProgressDetail.Current != 512 || msg.ProgressDetail.Total != 2048 || msg.Progress == "" {
+		t.Errorf("PullImage: wrong progress message: %#v", msg)
+	}
+	if msg := messages[3]; msg.Status != "Pull complete" || msg.ProgressDetail != nil {
+		t.Errorf("PullImage: wrong final message: %#v", msg)
+	}
+	if _, err = client.InspectImage("busybox:latest"); err != nil {
+		t.Errorf("InspectImage: %v", err)
+	}
+}
+
+func TestPrepareImagePullStreamError(t *testing.T) {
+	t.Parallel()
+	server, err := NewServer("127.0.0.1:0", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Stop()
+	server.PrepareImagePull("private/app", PrepareImagePullOptions{
+		Progress: []PullProgress{{ID: "latest", Status: "Pulling from private/app"}},
+		Error:    "unauthorized: authentication required",
+	})
+	client, err := docker.NewClient(server.URL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := docker.PullImageOptions{Repository: "private/app", OutputStream: ioutil.Discard}
+	if err = client.PullImage(opts, docker.AuthConfiguration{}); err == nil || !strings.Contains(err.Error(), "unauthorized") {
+		t.Errorf("PullImage: wrong error: %v", err)
+	}
+	if _, err = client.InspectImage("private/app"); !errors.Is(err, docker.ErrNoSuchImage) {
+		t.Errorf("InspectImage: wrong error. Want %v. Got %v.", docker.ErrNoSuchImage, err)
+	}
+	server.ResetImagePull("private/app")
+	if err = client.PullImage(opts, docker.AuthConfiguration{}); err != nil {
+		t.Errorf("PullImage: unexpected error after ResetImagePull: %v", err)
+	}
+}
+
+func TestPushImageNotFound(t *testing.T) {
+	t.Parallel()
+	server := baseDockerServer()
+	server.buildMuxer()
+	recorder := httptest.NewRecorder()
+	request, _ := http.NewRequest(http.MethodPost, "/images/tsuru/python/push", nil)
+	server.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusNotFound {
+		t.Errorf("PushImage: wrong status. Want %d. Got %d.", http.StatusNotFound, recorder.Code)
+	}
+}
+
+func TestTagImage(t *testing.T) {
+	t.Parallel()
+	server := baseDockerServer()
+	server.imgIDs = map[string]string{"tsuru/python": "a123"}
+	server.buildMuxer()
+	recorder := httptest.NewRecorder()
+	request, _ := http.NewRequest(http.MethodPost, "/images/tsuru/python/tag?repo=tsuru/new-python", nil)
+	server.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusCreated {
+		t.Errorf("TagImage: wrong status. Want %d. Got %d.", http.StatusCreated, recorder.Code)
+	}
+	if server.imgIDs["tsuru/python"] != server.imgIDs["tsuru/new-python"] {
 		t.Errorf("TagImage: did not tag the image")
 	}
 }
@@ -1515,6 +2532,57 @@ func TestTagImageNotFound(t *testing.T) {
 	}
 }
 
+func TestTagImageByDigestOverwritesTag(t *testing.T) {
+	t.Parallel()
+	server, err := NewServer("127.0.0.1:0", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Stop()
+	client, err := docker.NewClient(server.URL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	const digestRef = "busybox@sha256:4a731fb46adc5cefe3ae374a8b6020fc1b6ad667a279647766e9a3cd89f6fa92"
+	if err := client.PullImage(docker.PullImageOptions{Repository: digestRef}, docker.AuthConfiguration{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.PullImage(docker.PullImageOptions{Repository: "alpine"}, docker.AuthConfiguration{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.TagImage("alpine", docker.TagImageOptions{Repo: "app", Tag: "stable"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.TagImage(digestRef, docker.TagImageOptions{Repo: "app", Tag: "stable", Force: true}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := server.imgIDs["app:stable"], server.imgIDs[digestRef]; got != want {
+		t.Errorf("TagImage: the tag wasn't overwritten. Want %q. Got %q.", want, got)
+	}
+	server.images["pushed"] = docker.Image{ID: "pushed", RepoDigests: []string{"registry.example.com/pushed@sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"}}
+	if err := client.TagImage("registry.example.com/pushed@sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef", docker.TagImageOptions{Repo: "pushed:local"}); err != nil {
+		t.Fatal(err)
+	}
+	if server.imgIDs["pushed:local"] != "pushed" {
+		t.Errorf("TagImage: image known only by its repo digest wasn't tagged: %q", server.imgIDs["pushed:local"])
+	}
+}
+
+func TestTagImageInvalidTarget(t *testing.T) {
+	t.Parallel()
+	server := baseDockerServer()
+	server.imgIDs = map[string]string{"tsuru/python": "a123"}
+	server.buildMuxer()
+	for _, query := range []string{"", "repo=tsuru/new@sha256:abc", "repo=tsuru/new&tag=-bad"} {
+		recorder := httptest.NewRecorder()
+		request, _ := http.NewRequest(http.MethodPost, "/images/tsuru/python/tag?"+query, nil)
+		server.ServeHTTP(recorder, request)
+		if recorder.Code != http.StatusBadRequest {
+			t.Errorf("TagImage(%q): wrong status. Want %d. Got %d.", query, http.StatusBadRequest, recorder.Code)
+		}
+	}
+}
+
 func TestInspectImage(t *testing.T) {
 	t.Parallel()
 	server := baseDockerServer()
@@ -1874,6 +2942,98 @@ func TestRemoveFailure(t *testing.T) {
 	}
 }
 
+func TestPrepareFailureMatcherCalls(t *testing.T) {
+	t.Parallel()
+	server := baseDockerServer()
+	server.imgIDs = map[string]string{"base": "a1234"}
+	server.buildMuxer()
+	errorID := "create failed"
+	server.PrepareFailureMatcher(errorID, FailureMatcher{
+		Method: http.MethodPost,
+		Path:   regexp.MustCompile("^/containers/create$"),
+		Calls:  func(n int) bool { return n == 5 },
+		Status: http.StatusInternalServerError,
+	})
+	body := `{"Cmd":["date"], "Image":"base"}`
+	for i := 1; i <= 6; i++ {
+		recorder := httptest.NewRecorder()
+		request, _ := http.NewRequest(http.MethodPost, "/containers/create?name=test"+strconv.Itoa(i), strings.NewReader(body))
+		server.ServeHTTP(recorder, request)
+		expected := http.StatusCreated
+		if i == 5 {
+			expected = http.StatusInternalServerError
+		}
+		if recorder.Code != expected {
+			t.Errorf("PrepareFailureMatcher: wrong status for call %d. Want %d. Got %d.", i, expected, recorder.Code)
+		}
+		if i == 5 && recorder.Body.String() != errorID+"\n" {
+			t.Errorf("PrepareFailureMatcher: wrong message. Want %s. Got %s.", errorID, recorder.Body.String())
+		}
+	}
+}
+
+func TestPrepareFailureMatcherMethod(t *testing.T) {
+	t.Parallel()
+	server := baseDockerServer()
+	server.buildMuxer()
+	id := addContainers(&server, 1)[0].ID
+	server.PrepareFailureMatcher("remove failed", FailureMatcher{
+		Method: http.MethodDelete,
+		Path:   regexp.MustCompile("^/containers/"),
+		Status: http.StatusInternalServerError,
+	})
+	for i := 0; i < 2; i++ {
+		recorder := httptest.NewRecorder()
+		request, _ := http.NewRequest(http.MethodGet, "/containers/"+id+"/json", nil)
+		server.ServeHTTP(recorder, request)
+		if recorder.Code != http.StatusOK {
+			t.Errorf("PrepareFailureMatcher: wrong status for GET. Want %d. Got %d.", http.StatusOK, recorder.Code)
+		}
+		recorder = httptest.NewRecorder()
+		request, _ = http.NewRequest(http.MethodDelete, "/containers/"+id+"?force=1", nil)
+		server.ServeHTTP(recorder, request)
+		if recorder.Code != http.StatusInternalServerError {
+			t.Errorf("PrepareFailureMatcher: wrong status for DELETE. Want %d. Got %d.", http.StatusInternalServerError, recorder.Code)
+		}
+	}
+	server.ResetFailure("remove failed")
+	recorder := httptest.NewRecorder()
+	request, _ := http.NewRequest(http.MethodDelete, "/containers/"+id+"?force=1", nil)
+	server.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusNoContent {
+		t.Errorf("ResetFailure: wrong status. Want %d. Got %d.", http.StatusNoContent, recorder.Code)
+	}
+}
+
+func TestPrepareFailureMatcherOneShot(t *testing.T) {
+	t.Parallel()
+	server := baseDockerServer()
+	server.buildMuxer()
+	errorID := "list failed"
+	server.PrepareFailureMatcher(errorID, FailureMatcher{
+		Path:    regexp.MustCompile("containers/json"),
+		OneShot: true,
+	})
+	recorder := httptest.NewRecorder()
+	request, _ := http.NewRequest(http.MethodGet, "/containers/json?all=1", nil)
+	server.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusBadRequest {
+		t.Errorf("PrepareFailureMatcher: wrong status. Want %d. Got %d.", http.StatusBadRequest, recorder.Code)
+	}
+	if recorder.Body.String() != errorID+"\n" {
+		t.Errorf("PrepareFailureMatcher: wrong message. Want %s. Got %s.", errorID, recorder.Body.String())
+	}
+	recorder = httptest.NewRecorder()
+	request, _ = http.NewRequest(http.MethodGet, "/containers/json?all=1", nil)
+	server.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusOK {
+		t.Errorf("PrepareFailureMatcher: wrong status after firing. Want %d. Got %d.", http.StatusOK, recorder.Code)
+	}
+	if len(server.failureRules) != 0 {
+		t.Errorf("PrepareFailureMatcher: one-shot failure was not removed: %#v", server.failureRules)
+	}
+}
+
 func TestResetMultiFailures(t *testing.T) {
 	t.Parallel()
 	server := baseDockerServer()
@@ -1954,6 +3114,26 @@ func TestBuildImageWithRemoteDockerfile(t *testing.T) {
 	}
 }
 
+func TestBuildImageCacheFrom(t *testing.T) {
+	t.Parallel()
+	server := baseDockerServer()
+	recorder := httptest.NewRecorder()
+	request, _ := http.NewRequest(http.MethodPost, `/build?t=app&remote=http://localhost/Dockerfile&cachefrom=["app:latest","app:builder"]`, nil)
+	server.buildImage(recorder, request)
+	if _, ok := server.imgIDs["app"]; !ok {
+		t.Errorf("BuildImage: image app not built")
+	}
+	recorder = httptest.NewRecorder()
+	request, _ = http.NewRequest(http.MethodPost, "/build?t=other&remote=http://localhost/Dockerfile&cachefrom=app:latest", nil)
+	server.buildImage(recorder, request)
+	if recorder.Code != http.StatusBadRequest {
+		t.Errorf("BuildImage: wrong status for invalid cachefrom. Want %d. Got %d.", http.StatusBadRequest, recorder.Code)
+	}
+	if _, ok := server.imgIDs["other"]; ok {
+		t.Errorf("BuildImage: image built with invalid cachefrom")
+	}
+}
+
 func TestPing(t *testing.T) {
 	t.Parallel()
 	server := baseDockerServer()
@@ -1968,6 +3148,37 @@ func TestPing(t *testing.T) {
 	}
 }
 
+func TestPingHeaders(t *testing.T) {
+	t.Parallel()
+	server, err := NewServer("127.0.0.1:0", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Stop()
+	client, err := docker.NewClient(server.URL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := client.PingHealthcheck(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := docker.PingResult{APIVersion: "1.22", OSType: "linux", Swarm: "inactive"}
+	if !reflect.DeepEqual(*result, expected) {
+		t.Errorf("Ping: wrong result. Want %#v. Got %#v.", expected, *result)
+	}
+	if _, err := client.InitSwarm(docker.InitSwarmOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	result, err = client.PingHealthcheck(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Swarm != "active/manager" {
+		t.Errorf("Ping: wrong swarm state. Want %q. Got %q.", "active/manager", result.Swarm)
+	}
+}
+
 func TestDefaultHandler(t *testing.T) {
 	t.Parallel()
 	server, err := NewServer("127.0.0.1:0", nil, nil)
@@ -2017,13 +3228,99 @@ func TestCreateExecContainer(t *testing.T) {
 	}
 }
 
-func TestInspectExecContainer(t *testing.T) {
+func TestPruneImages(t *testing.T) {
+	t.Parallel()
+	old := time.Now().Add(-48 * time.Hour)
+	tests := []struct {
+		name     string
+		opts     docker.PruneImagesOptions
+		expected []string
+		size     int64
+	}{
+		{
+			name:     "dangling only",
+			expected: []string{"dangling-keep", "dangling-new", "dangling-old"},
+			size:     7,
+		},
+		{
+			name:     "dangling without label",
+			opts:     docker.PruneImagesOptions{Labels: []string{"!keep"}},
+			expected: []string{"dangling-new", "dangling-old"},
+			size:     5,
+		},
+		{
+			name:     "all unused",
+			opts:     docker.PruneImagesOptions{All: true},
+			expected: []string{"app:old", "dangling-keep", "dangling-new", "dangling-old", "tagged-unused"},
+			size:     15,
+		},
+		{
+			name:     "all unused until",
+			opts:     docker.PruneImagesOptions{All: true, Until: time.Now().Add(-24 * time.Hour)},
+			expected: []string{"app:old", "dangling-keep", "dangling-old", "tagged-unused"},
+			size:     11,
+		},
+		{
+			name:     "all unused with label",
+			opts:     docker.PruneImagesOptions{All: true, Labels: []string{"keep=true"}},
+			expected: []string{"dangling-keep"},
+			size:     2,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			server, err := NewServer("127.0.0.1:0", nil, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer server.Stop()
+			server.iMut.Lock()
+			server.images["dangling-old"] = docker.Image{ID: "dangling-old", Created: old, Size: 1}
+			server.images["dangling-keep"] = docker.Image{ID: "dangling-keep", Created: old, Size: 2, Config: &docker.Config{Labels: map[string]string{"keep": "true"}}}
+			server.images["dangling-new"] = docker.Image{ID: "dangling-new", Created: time.Now(), Size: 4}
+			server.images["tagged-unused"] = docker.Image{ID: "tagged-unused", Created: old, Size: 8}
+			server.images["tagged-used"] = docker.Image{ID: "tagged-used", Created: old, Size: 16}
+			server.imgIDs["app:old"] = "tagged-unused"
+			server.imgIDs["busybox:latest"] = "tagged-used"
+			server.iMut.Unlock()
+			client, err := docker.NewClient(server.URL())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := client.CreateContainer(docker.CreateContainerOptions{Config: &docker.Config{Image: "busybox:latest"}}); err != nil {
+				t.Fatal(err)
+			}
+			results, err := client.PruneImages(tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var removed []string
+			for _, item := range results.ImagesDeleted {
+				removed = append(removed, item.Untagged+item.Deleted)
+				if image, ok := server.images[item.Deleted]; ok {
+					t.Errorf("PruneImages: image %s was not removed from the server", image.ID)
+				}
+			}
+			sort.Strings(removed)
+			if !reflect.DeepEqual(removed, tt.expected) {
+				t.Errorf("PruneImages: wrong images removed. Want %v. Got %v.", tt.expected, removed)
+			}
+			if results.SpaceReclaimed != tt.size {
+				t.Errorf("PruneImages: wrong space reclaimed. Want %d. Got %d.", tt.size, results.SpaceReclaimed)
+			}
+		})
+	}
+}
+
+func TestInspectExecContainerEnv(t *testing.T) {
 	t.Parallel()
 	server := baseDockerServer()
 	addContainers(&server, 1)
 	server.buildMuxer()
 	recorder := httptest.NewRecorder()
-	body := `{"Cmd": ["bash", "-c", "ls"]}`
+	body := `{"Cmd": ["env"], "Env": ["PAGER=", "LANG=C"]}`
 	path := fmt.Sprintf("/containers/%s/exec", getContainer(&server).ID)
 	request, _ := http.NewRequest(http.MethodPost, path, strings.NewReader(body))
 	server.ServeHTTP(recorder, request)
@@ -2031,18 +3328,50 @@ func TestInspectExecContainer(t *testing.T) {
 		t.Fatalf("CreateExec: wrong status. Want %d. Got %d.", http.StatusOK, recorder.Code)
 	}
 	var got docker.Exec
-	err := json.NewDecoder(recorder.Body).Decode(&got)
-	if err != nil {
+	if err := json.NewDecoder(recorder.Body).Decode(&got); err != nil {
 		t.Fatal(err)
 	}
-	path = fmt.Sprintf("/exec/%s/json", got.ID)
-	request, _ = http.NewRequest(http.MethodGet, path, nil)
+	recorder = httptest.NewRecorder()
+	request, _ = http.NewRequest(http.MethodGet, fmt.Sprintf("/exec/%s/json", got.ID), nil)
 	server.ServeHTTP(recorder, request)
 	if recorder.Code != http.StatusOK {
-		t.Fatalf("CreateExec: wrong status. Want %d. Got %d.", http.StatusOK, recorder.Code)
+		t.Fatalf("InspectExec: wrong status. Want %d. Got %d.", http.StatusOK, recorder.Code)
 	}
-	var got2 docker.ExecInspect
-	err = json.NewDecoder(recorder.Body).Decode(&got2)
+	var exec docker.ExecInspect
+	if err := json.NewDecoder(recorder.Body).Decode(&exec); err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"PAGER=", "LANG=C"}; !reflect.DeepEqual(exec.ProcessConfig.Env, expected) {
+		t.Errorf("InspectExec: wrong Env. Want %#v. Got %#v.", expected, exec.ProcessConfig.Env)
+	}
+}
+
+func TestInspectExecContainer(t *testing.T) {
+	t.Parallel()
+	server := baseDockerServer()
+	addContainers(&server, 1)
+	server.buildMuxer()
+	recorder := httptest.NewRecorder()
+	body := `{"Cmd": ["bash", "-c", "ls"]}`
+	path := fmt.Sprintf("/containers/%s/exec", getContainer(&server).ID)
+	request, _ := http.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	server.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("CreateExec: wrong status. Want %d. Got %d.", http.StatusOK, recorder.Code)
+	}
+	var got docker.Exec
+	err := json.NewDecoder(recorder.Body).Decode(&got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path = fmt.Sprintf("/exec/%s/json", got.ID)
+	request, _ = http.NewRequest(http.MethodGet, path, nil)
+	server.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("CreateExec: wrong status. Want %d. Got %d.", http.StatusOK, recorder.Code)
+	}
+	var got2 docker.ExecInspect
+	err = json.NewDecoder(recorder.Body).Decode(&got2)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -2363,6 +3692,81 @@ func TestCreateNetwork(t *testing.T) {
 	}
 }
 
+func TestCreateNetworkDualStack(t *testing.T) {
+	t.Parallel()
+	server, err := NewServer("127.0.0.1:0", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Stop()
+	client, err := docker.NewClient(server.URL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ipam := docker.IPAMOptions{
+		Driver: "default",
+		Config: []docker.IPAMConfig{
+			{Subnet: "172.28.0.0/16", Gateway: "172.28.0.1"},
+			{Subnet: "2001:db8:1::/64", Gateway: "2001:db8:1::1"},
+		},
+	}
+	network, err := client.CreateNetwork(docker.CreateNetworkOptions{
+		Name:       "dualstack",
+		Driver:     "bridge",
+		EnableIPv6: true,
+		Internal:   true,
+		IPAM:       &ipam,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	network, err = client.NetworkInfo(network.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !network.EnableIPv6 || !network.Internal {
+		t.Errorf("CreateNetwork: wrong flags. Want EnableIPv6 and Internal. Got %#v.", network)
+	}
+	if !reflect.DeepEqual(network.IPAM, ipam) {
+		t.Errorf("CreateNetwork: wrong IPAM.\nWant %#v.\nGot  %#v.", ipam, network.IPAM)
+	}
+}
+
+func TestCreateNetworkIngress(t *testing.T) {
+	t.Parallel()
+	server, err := NewServer("127.0.0.1:0", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Stop()
+	client, err := docker.NewClient(server.URL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	network, err := client.CreateNetwork(docker.CreateNetworkOptions{
+		Name:    "custom-ingress",
+		Driver:  "overlay",
+		Ingress: true,
+		IPAM:    &docker.IPAMOptions{Config: []docker.IPAMConfig{{Subnet: "10.11.0.0/16"}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	network, err = client.NetworkInfo(network.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !network.Ingress {
+		t.Error("CreateNetwork: Ingress was not stored")
+	}
+	recorder := httptest.NewRecorder()
+	request, _ := http.NewRequest(http.MethodPost, "/networks/create", strings.NewReader(`{"Name":"bridge-ingress","Driver":"bridge","Ingress":true}`))
+	server.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusBadRequest {
+		t.Errorf("CreateNetwork: wrong status for a bridge ingress network. Want %d. Got %d.", http.StatusBadRequest, recorder.Code)
+	}
+}
+
 func TestCreateNetworkInvalidBody(t *testing.T) {
 	t.Parallel()
 	server := baseDockerServer()
@@ -2390,6 +3794,77 @@ func TestCreateNetworkDuplicateName(t *testing.T) {
 	}
 }
 
+func TestPruneNetworks(t *testing.T) {
+	t.Parallel()
+	server, err := NewServer("127.0.0.1:0", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Stop()
+	addContainers(server, 1)
+	client, err := docker.NewClient(server.URL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	networks := map[string]map[string]string{
+		"bridge":   nil,
+		"unused":   {"env": "dev"},
+		"keep":     {"env": "dev", "keep": "true"},
+		"attached": {"env": "dev"},
+		"prod":     {"env": "prod"},
+	}
+	for name, labels := range networks {
+		if _, err = client.CreateNetwork(docker.CreateNetworkOptions{Name: name, Labels: labels}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = client.ConnectNetwork("attached", docker.NetworkConnectionOptions{Container: getContainer(server).ID}); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		filters  map[string][]string
+		expected []string
+	}{
+		{map[string][]string{"until": {"1h"}}, []string{}},
+		{map[string][]string{"label": {"env=dev"}, "label!": {"keep"}}, []string{"unused"}},
+		{nil, []string{"keep", "prod"}},
+		{nil, []string{}},
+	}
+	for _, tt := range tests {
+		results, err := client.PruneNetworks(docker.PruneNetworksOptions{Filters: tt.filters})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(results.NetworksDeleted, tt.expected) {
+			t.Errorf("PruneNetworks(%v): wrong deleted networks. Want %#v. Got %#v.", tt.filters, tt.expected, results.NetworksDeleted)
+		}
+	}
+	remaining, err := client.ListNetworks()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, network := range remaining {
+		names = append(names, network.Name)
+	}
+	sort.Strings(names)
+	if expected := []string{"attached", "bridge"}; !reflect.DeepEqual(names, expected) {
+		t.Errorf("PruneNetworks: wrong remaining networks. Want %#v. Got %#v.", expected, names)
+	}
+}
+
+func TestPruneNetworksInvalidUntil(t *testing.T) {
+	t.Parallel()
+	server := baseDockerServer()
+	server.buildMuxer()
+	recorder := httptest.NewRecorder()
+	request, _ := http.NewRequest(http.MethodPost, `/networks/prune?filters={"until":["tomorrow"]}`, nil)
+	server.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusBadRequest {
+		t.Errorf("PruneNetworks: wrong status. Want %d. Got %d.", http.StatusBadRequest, recorder.Code)
+	}
+}
+
 func TestRemoveNetwork(t *testing.T) {
 	t.Parallel()
 	server := baseDockerServer()
@@ -2439,6 +3914,134 @@ func TestNetworkConnect(t *testing.T) {
 	}
 }
 
+func TestNetworkConnectStaticIP(t *testing.T) {
+	t.Parallel()
+	server, err := NewServer("127.0.0.1:0", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Stop()
+	client, err := docker.NewClient(server.URL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	network, err := client.CreateNetwork(docker.CreateNetworkOptions{
+		Name: "backend",
+		IPAM: &docker.IPAMOptions{Config: []docker.IPAMConfig{{Subnet: "172.28.0.0/16"}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain, err := client.CreateNetwork(docker.CreateNetworkOptions{Name: "plain"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	containers := addContainers(server, 2)
+	connect := func(networkID, containerID, address string) error {
+		return client.ConnectNetwork(networkID, docker.NetworkConnectionOptions{
+			Container: containerID,
+			EndpointConfig: &docker.EndpointConfig{
+				IPAMConfig: &docker.EndpointIPAMConfig{IPv4Address: address},
+				Aliases:    []string{"db"},
+			},
+		})
+	}
+	if err := connect(network.ID, containers[0].ID, "172.28.5.10"); err != nil {
+		t.Fatal(err)
+	}
+	container, err := client.InspectContainerWithOptions(docker.InspectContainerOptions{ID: containers[0].ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	endpoint := container.NetworkSettings.Networks["backend"]
+	if endpoint.IPAddress != "172.28.5.10" || endpoint.IPPrefixLen != 16 || endpoint.NetworkID != network.ID {
+		t.Errorf("NetworkConnect: wrong endpoint in container: %#v", endpoint)
+	}
+	if !reflect.DeepEqual(endpoint.Aliases, []string{"db"}) {
+		t.Errorf("NetworkConnect: wrong aliases. Want %#v. Got %#v.", []string{"db"}, endpoint.Aliases)
+	}
+	info, err := client.NetworkInfo(network.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := info.Containers[containers[0].ID].IPv4Address; got != "172.28.5.10/16" {
+		t.Errorf("NetworkConnect: wrong address in network. Want %q. Got %q.", "172.28.5.10/16", got)
+	}
+	tests := []struct {
+		network string
+		address string
+		message string
+	}{
+		{network.ID, "10.1.0.5", "invalid address 10.1.0.5: it does not belong to any of the subnets of network backend"},
+		{network.ID, "172.28.5.10", "address 172.28.5.10 already in use in network backend"},
+		{network.ID, "172.28.5", "invalid address 172.28.5: not an IP address"},
+		{plain.ID, "172.28.5.11", "user specified IP address is supported only when connecting to networks with user configured subnets"},
+	}
+	for _, tt := range tests {
+		err := connect(tt.network, containers[1].ID, tt.address)
+		var e *docker.Error
+		if !errors.As(err, &e) || e.Status != http.StatusBadRequest || strings.TrimSpace(e.Message) != tt.message {
+			t.Errorf("NetworkConnect(%q): wrong error. Want 400 with %q. Got %v.", tt.address, tt.message, err)
+		}
+	}
+}
+
+func TestNetworkDisconnectForce(t *testing.T) {
+	t.Parallel()
+	server, err := NewServer("127.0.0.1:0", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Stop()
+	client, err := docker.NewClient(server.URL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	network, err := client.CreateNetwork(docker.CreateNetworkOptions{Name: "backend"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	containers := addContainers(server, 2)
+	for _, container := range containers {
+		if err := client.ConnectNetwork(network.ID, docker.NetworkConnectionOptions{Container: "/" + container.Name}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := client.DisconnectNetwork(network.ID, docker.NetworkConnectionOptions{Container: containers[0].Name}); err != nil {
+		t.Fatal(err)
+	}
+	container, err := client.InspectContainerWithOptions(docker.InspectContainerOptions{ID: containers[0].ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, found := container.NetworkSettings.Networks["backend"]; found {
+		t.Error("DisconnectNetwork: container still connected to the network")
+	}
+	if err := client.RemoveContainer(docker.RemoveContainerOptions{ID: containers[1].ID}); err != nil {
+		t.Fatal(err)
+	}
+	opts := docker.NetworkConnectionOptions{Container: containers[1].Name}
+	var serr *docker.NoSuchNetworkOrContainer
+	if err := client.DisconnectNetwork(network.ID, opts); !errors.As(err, &serr) {
+		t.Errorf("DisconnectNetwork: wrong error. Want NoSuchNetworkOrContainer. Got %#v.", err)
+	}
+	opts.Force = true
+	if err := client.DisconnectNetwork(network.ID, opts); err != nil {
+		t.Fatal(err)
+	}
+	info, err := client.NetworkInfo(network.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(info.Containers) != 0 {
+		t.Errorf("DisconnectNetwork: stale endpoints left in the network: %#v", info.Containers)
+	}
+	var e *docker.Error
+	if err := client.DisconnectNetwork(network.ID, opts); !errors.As(err, &e) || e.Status != http.StatusBadRequest {
+		t.Errorf("DisconnectNetwork: wrong error. Want 400. Got %v.", err)
+	}
+}
+
 func TestListVolumes(t *testing.T) {
 	t.Parallel()
 	server := baseDockerServer()
@@ -2503,6 +4106,72 @@ func TestCreateVolume(t *testing.T) {
 	}
 }
 
+func TestCreateVolumeLabelsAndDriverOpts(t *testing.T) {
+	t.Parallel()
+	server, err := NewServer("127.0.0.1:0", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Stop()
+	client, err := docker.NewClient(server.URL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	labels := map[string]string{"app": "db", "tier": "storage"}
+	driverOpts := map[string]string{"type": "tmpfs", "device": "tmpfs", "o": "size=100m"}
+	created, err := client.CreateVolume(docker.CreateVolumeOptions{
+		Name:       "db-data",
+		Labels:     labels,
+		DriverOpts: driverOpts,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created.CreatedAt.IsZero() {
+		t.Error("CreateVolume: CreatedAt wasn't set")
+	}
+	volume, err := client.InspectVolume("db-data")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(volume.Labels, labels) {
+		t.Errorf("InspectVolume: wrong labels. Want %#v. Got %#v.", labels, volume.Labels)
+	}
+	if !reflect.DeepEqual(volume.Options, driverOpts) {
+		t.Errorf("InspectVolume: wrong options. Want %#v. Got %#v.", driverOpts, volume.Options)
+	}
+	again, err := client.CreateVolume(docker.CreateVolumeOptions{Name: "db-data", Labels: map[string]string{"app": "other"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(again.Labels, labels) {
+		t.Errorf("CreateVolume: existing volume should be returned unchanged. Want labels %#v. Got %#v.", labels, again.Labels)
+	}
+}
+
+func TestCreateVolumeInvalidName(t *testing.T) {
+	t.Parallel()
+	server := baseDockerServer()
+	server.buildMuxer()
+	recorder := httptest.NewRecorder()
+	body := `{"Name":"my/volume"}`
+	request, _ := http.NewRequest(http.MethodPost, "/volumes/create", strings.NewReader(body))
+	server.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusBadRequest {
+		t.Errorf("CreateVolume: wrong status. Want %d. Got %d.", http.StatusBadRequest, recorder.Code)
+	}
+	if len(server.volStore) != 0 {
+		t.Errorf("CreateVolume: volume with invalid name was stored: %#v", server.volStore)
+	}
+	recorder = httptest.NewRecorder()
+	body = `{"Name":"my/volume","Driver":"s3fs"}`
+	request, _ = http.NewRequest(http.MethodPost, "/volumes/create", strings.NewReader(body))
+	server.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusCreated {
+		t.Errorf("CreateVolume: wrong status for a plugin volume. Want %d. Got %d.", http.StatusCreated, recorder.Code)
+	}
+}
+
 func TestCreateVolumeAlreadExists(t *testing.T) {
 	t.Parallel()
 	server := baseDockerServer()
@@ -2770,6 +4439,34 @@ func TestInfoDocker(t *testing.T) {
 	}
 }
 
+func TestInfoDockerRuntimes(t *testing.T) {
+	t.Parallel()
+	server, err := NewServer("127.0.0.1:0", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Stop()
+	client, err := docker.NewClient(server.URL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := client.Info()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.DefaultRuntime != "runc" || !info.HasRuntime("runc") {
+		t.Errorf("Info: wrong runtimes. Default: %q. Runtimes: %#v.", info.DefaultRuntime, info.Runtimes)
+	}
+	options, err := info.ParseSecurityOptions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []docker.SecurityOption{{Name: "seccomp", Options: map[string]string{"profile": "default"}}}
+	if !reflect.DeepEqual(options, expected) {
+		t.Errorf("ParseSecurityOptions: want %#v. Got %#v.", expected, options)
+	}
+}
+
 func TestInfoDockerWithSwarm(t *testing.T) {
 	t.Parallel()
 	srv1, srv2 := setUpSwarm(t)
@@ -2837,15 +4534,790 @@ func TestDownloadFromContainer(t *testing.T) {
 	}
 }
 
-func TestSupportVersionPathPrefix(t *testing.T) {
+func TestUploadAndDownloadFromContainer(t *testing.T) {
 	t.Parallel()
-	server, _ := NewServer("127.0.0.1:0", nil, nil)
+	server, err := NewServer("127.0.0.1:0", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
 	defer server.Stop()
-	server.buildMuxer()
-	recorder := httptest.NewRecorder()
-	request, _ := http.NewRequest(http.MethodGet, "/v1.16/version", nil)
-	server.ServeHTTP(recorder, request)
-	if recorder.Code != http.StatusOK {
-		t.Fatalf("VersionDocker: wrong status. Want %d. Got %d.", http.StatusOK, recorder.Code)
+	client, err := docker.NewClient(server.URL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := client.PullImage(docker.PullImageOptions{Repository: "busybox"}, docker.AuthConfiguration{}); err != nil {
+		t.Fatal(err)
+	}
+	container, err := client.CreateContainer(docker.CreateContainerOptions{
+		Config: &docker.Config{Image: "busybox", User: "1000:1001"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	tw.WriteHeader(&tar.Header{Name: "config/", Typeflag: tar.TypeDir, Mode: 0o755})
+	tw.WriteHeader(&tar.Header{Name: "config/app.yaml", Mode: 0o644, Size: 9, Uid: 42, Gid: 42})
+	tw.Write([]byte("key: val\n"))
+	tw.Close()
+	err = client.UploadToContainer(container.ID, docker.UploadToContainerOptions{
+		Path:        "/etc",
+		InputStream: &buf,
+		CopyUIDGID:  true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	err = client.DownloadFromContainer(container.ID, docker.DownloadFromContainerOptions{
+		Path:         "/etc/config",
+		OutputStream: &out,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := tar.NewReader(&out)
+	var names []string
+	for {
+		header, err := tr.Next()
+		if err != nil {
+			break
+		}
+		names = append(names, header.Name)
+		if header.Uid != 1000 || header.Gid != 1001 {
+			t.Errorf("DownloadFromContainer: wrong owner of %s. Want 1000:1001. Got %d:%d.", header.Name, header.Uid, header.Gid)
+		}
+		if header.Name == "config/app.yaml" {
+			content, _ := ioutil.ReadAll(tr)
+			if string(content) != "key: val\n" {
+				t.Errorf("DownloadFromContainer: wrong content. Want %q. Got %q.", "key: val\n", content)
+			}
+		}
+	}
+	if expected := []string{"config/", "config/app.yaml"}; !reflect.DeepEqual(names, expected) {
+		t.Errorf("DownloadFromContainer: wrong files. Want %v. Got %v.", expected, names)
+	}
+	buf.Reset()
+	tw = tar.NewWriter(&buf)
+	tw.WriteHeader(&tar.Header{Name: "config", Mode: 0o644})
+	tw.Close()
+	err = client.UploadToContainer(container.ID, docker.UploadToContainerOptions{
+		Path:                 "/etc",
+		InputStream:          &buf,
+		NoOverwriteDirNonDir: true,
+	})
+	var e *docker.Error
+	if !errors.As(err, &e) || e.Status != http.StatusBadRequest {
+		t.Errorf("UploadToContainer: expected bad request replacing a directory with a file, got %v", err)
+	}
+}
+
+func TestSupportVersionPathPrefix(t *testing.T) {
+	t.Parallel()
+	server, _ := NewServer("127.0.0.1:0", nil, nil)
+	defer server.Stop()
+	server.buildMuxer()
+	recorder := httptest.NewRecorder()
+	request, _ := http.NewRequest(http.MethodGet, "/v1.16/version", nil)
+	server.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("VersionDocker: wrong status. Want %d. Got %d.", http.StatusOK, recorder.Code)
+	}
+}
+
+func TestDiskUsage(t *testing.T) {
+	t.Parallel()
+	server := baseDockerServer()
+	containers := addContainers(&server, 2)
+	server.images = map[string]docker.Image{
+		containers[0].Image: {ID: containers[0].Image, Size: 100},
+		"unused":            {ID: "unused", Size: 50},
+	}
+	server.volStore = map[string]*volumeCounter{
+		"vol1": {volume: docker.Volume{Name: "vol1", Driver: "local"}, count: 1},
+	}
+	server.buildMuxer()
+	recorder := httptest.NewRecorder()
+	request, _ := http.NewRequest(http.MethodGet, "/system/df", nil)
+	server.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("DiskUsage: wrong status. Want %d. Got %d.", http.StatusOK, recorder.Code)
+	}
+	var du docker.DiskUsage
+	if err := json.NewDecoder(recorder.Body).Decode(&du); err != nil {
+		t.Fatal(err)
+	}
+	if du.LayersSize != 150 {
+		t.Errorf("DiskUsage: wrong LayersSize. Want 150. Got %d.", du.LayersSize)
+	}
+	if len(du.Containers) != 2 {
+		t.Errorf("DiskUsage: wrong number of containers. Want 2. Got %d.", len(du.Containers))
+	}
+	if len(du.Images) != 2 {
+		t.Fatalf("DiskUsage: wrong number of images. Want 2. Got %d.", len(du.Images))
+	}
+	for _, image := range du.Images {
+		var expected int64
+		if image.ID == containers[0].Image {
+			expected = 2
+		}
+		if image.Containers != expected {
+			t.Errorf("DiskUsage: wrong container count for image %q. Want %d. Got %d.", image.ID, expected, image.Containers)
+		}
+	}
+	if len(du.Volumes) != 1 || du.Volumes[0].UsageData == nil || du.Volumes[0].UsageData.RefCount != 1 {
+		t.Errorf("DiskUsage: wrong volumes. Got %#v.", du.Volumes)
+	}
+}
+
+func TestDiskUsageType(t *testing.T) {
+	t.Parallel()
+	server := baseDockerServer()
+	addContainers(&server, 1)
+	server.images = map[string]docker.Image{"img": {ID: "img", Size: 10}}
+	server.buildMuxer()
+	recorder := httptest.NewRecorder()
+	request, _ := http.NewRequest(http.MethodGet, "/system/df?type=image", nil)
+	server.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("DiskUsage: wrong status. Want %d. Got %d.", http.StatusOK, recorder.Code)
+	}
+	var du docker.DiskUsage
+	if err := json.NewDecoder(recorder.Body).Decode(&du); err != nil {
+		t.Fatal(err)
+	}
+	if len(du.Images) != 1 {
+		t.Errorf("DiskUsage: wrong number of images. Want 1. Got %d.", len(du.Images))
+	}
+	if len(du.Containers) != 0 || len(du.Volumes) != 0 || du.BuildCache != nil {
+		t.Errorf("DiskUsage: expected only images, got %#v.", du)
+	}
+}
+
+func TestContainerMutatorInspect(t *testing.T) {
+	t.Parallel()
+	server := baseDockerServer()
+	addContainers(&server, 1)
+	container := getContainer(&server)
+	container.State.Running = true
+	var inspects int
+	server.SetContainerMutator(func(c *docker.Container) {
+		inspects++
+		if inspects == 2 {
+			c.State.Running = false
+			c.State.ExitCode = 137
+		}
+	})
+	server.buildMuxer()
+	path := fmt.Sprintf("/containers/%s/json", container.ID)
+	for i, expectRunning := range []bool{true, false} {
+		recorder := httptest.NewRecorder()
+		request, _ := http.NewRequest(http.MethodGet, path, nil)
+		server.ServeHTTP(recorder, request)
+		var got docker.Container
+		if err := json.NewDecoder(recorder.Body).Decode(&got); err != nil {
+			t.Fatal(err)
+		}
+		if got.State.Running != expectRunning {
+			t.Errorf("InspectContainer #%d: wrong running state. Want %v. Got %v.", i+1, expectRunning, got.State.Running)
+		}
+	}
+	if got := getContainer(&server).State.ExitCode; got != 137 {
+		t.Errorf("InspectContainer: mutator did not change the stored container. Want exit code 137. Got %d.", got)
+	}
+}
+
+func TestContainerMutatorList(t *testing.T) {
+	t.Parallel()
+	server := baseDockerServer()
+	addContainers(&server, 2)
+	server.SetContainerMutator(func(c *docker.Container) {
+		c.State.Running = true
+	})
+	server.buildMuxer()
+	recorder := httptest.NewRecorder()
+	request, _ := http.NewRequest(http.MethodGet, "/containers/json", nil)
+	server.ServeHTTP(recorder, request)
+	var got []docker.APIContainers
+	if err := json.NewDecoder(recorder.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 {
+		t.Errorf("ListContainers: expected mutated containers to be listed as running. Want 2. Got %d.", len(got))
+	}
+	server.SetContainerMutator(nil)
+	recorder = httptest.NewRecorder()
+	server.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusOK {
+		t.Errorf("ListContainers: wrong status. Want %d. Got %d.", http.StatusOK, recorder.Code)
+	}
+}
+
+func TestPluginLifecycle(t *testing.T) {
+	t.Parallel()
+	server, err := NewServer("127.0.0.1:0", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Stop()
+	client, err := docker.NewClient(server.URL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	privileges, err := client.GetPluginPrivileges("vieux/sshfs", context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var progress bytes.Buffer
+	err = client.InstallPlugins(docker.InstallPluginOptions{
+		Remote:       "vieux/sshfs",
+		Plugins:      privileges,
+		OutputStream: &progress,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(progress.String(), "Downloaded newer image for vieux/sshfs") {
+		t.Errorf("InstallPlugins: unexpected progress output: %q", progress.String())
+	}
+	plugin, err := client.InspectPlugins("vieux/sshfs", context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plugin.Name != "vieux/sshfs:latest" || plugin.Tag != "latest" || plugin.Active {
+		t.Errorf("InspectPlugins: unexpected plugin: %#v", plugin)
+	}
+	if err := client.EnablePlugin(docker.EnablePluginOptions{Name: plugin.ID}); err != nil {
+		t.Fatal(err)
+	}
+	plugins, err := client.ListPlugins(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plugins) != 1 || !plugins[0].Active {
+		t.Errorf("ListPlugins: expected one enabled plugin. Got %#v.", plugins)
+	}
+	_, err = client.RemovePlugin(docker.RemovePluginOptions{Name: "vieux/sshfs"})
+	var apiErr *docker.Error
+	if !errors.As(err, &apiErr) || apiErr.Status != http.StatusConflict {
+		t.Errorf("RemovePlugin: expected conflict removing an enabled plugin. Got %#v.", err)
+	}
+	if err := client.DisablePlugin(docker.DisablePluginOptions{Name: "vieux/sshfs:latest"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.RemovePlugin(docker.RemovePluginOptions{Name: "vieux/sshfs"}); err != nil {
+		t.Fatal(err)
+	}
+	_, err = client.InspectPlugins("vieux/sshfs", context.Background())
+	var notFound *docker.NoSuchPlugin
+	if !errors.As(err, &notFound) {
+		t.Errorf("InspectPlugins: expected NoSuchPlugin after removal. Got %#v.", err)
+	}
+	err = client.EnablePlugin(docker.EnablePluginOptions{Name: "vieux/sshfs"})
+	if !errors.As(err, &notFound) {
+		t.Errorf("EnablePlugin: expected NoSuchPlugin after removal. Got %#v.", err)
+	}
+}
+
+func TestInstallPluginDuplicate(t *testing.T) {
+	t.Parallel()
+	server := baseDockerServer()
+	server.buildMuxer()
+	for _, expected := range []int{http.StatusOK, http.StatusConflict} {
+		recorder := httptest.NewRecorder()
+		request, _ := http.NewRequest(http.MethodPost, "/plugins/pull?remote=vieux/sshfs:latest&name=sshfs", strings.NewReader("[]"))
+		server.ServeHTTP(recorder, request)
+		if recorder.Code != expected {
+			t.Errorf("InstallPlugins: wrong status. Want %d. Got %d.", expected, recorder.Code)
+		}
+	}
+	recorder := httptest.NewRecorder()
+	request, _ := http.NewRequest(http.MethodPost, "/plugins/pull?remote=vieux/sshfs", strings.NewReader("not json"))
+	server.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusBadRequest {
+		t.Errorf("InstallPlugins: wrong status for invalid privileges. Want %d. Got %d.", http.StatusBadRequest, recorder.Code)
+	}
+}
+
+func TestExportImages(t *testing.T) {
+	t.Parallel()
+	server, err := NewServer("127.0.0.1:0", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Stop()
+	images := addImages(server, 2, true)
+	names := []string{"docker/python-" + images[0].ID, "docker/python-" + images[1].ID}
+	for _, version := range []string{"1.24", "1.25"} {
+		client, err := docker.NewVersionedClient(server.URL(), version)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var buf bytes.Buffer
+		err = client.ExportImages(docker.ExportImagesOptions{Names: names, OutputStream: &buf})
+		if err != nil {
+			t.Fatal(err)
+		}
+		files := make(map[string][]byte)
+		tr := tar.NewReader(&buf)
+		for {
+			header, err := tr.Next()
+			if err != nil {
+				break
+			}
+			files[header.Name], _ = ioutil.ReadAll(tr)
+		}
+		var manifest []struct {
+			Config   string
+			RepoTags []string
+		}
+		if err := json.Unmarshal(files["manifest.json"], &manifest); err != nil {
+			t.Fatalf("ExportImages (API %s): invalid manifest.json: %v", version, err)
+		}
+		var repoTags []string
+		for _, m := range manifest {
+			if _, ok := files[m.Config]; !ok {
+				t.Errorf("ExportImages (API %s): missing image config %q", version, m.Config)
+			}
+			repoTags = append(repoTags, m.RepoTags...)
+		}
+		sort.Strings(repoTags)
+		sort.Strings(names)
+		if !reflect.DeepEqual(repoTags, names) {
+			t.Errorf("ExportImages (API %s): wrong repositories in manifest. Want %#v. Got %#v.", version, names, repoTags)
+		}
+	}
+}
+
+func TestLoadImage(t *testing.T) {
+	t.Parallel()
+	server, err := NewServer("127.0.0.1:0", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Stop()
+	images := addImages(server, 2, true)
+	names := []string{"docker/python-" + images[0].ID, "docker/python-" + images[1].ID}
+	client, err := docker.NewClient(server.URL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tarball bytes.Buffer
+	if err := client.ExportImages(docker.ExportImagesOptions{Names: names, OutputStream: &tarball}); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range names {
+		if err := client.RemoveImage(name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var progress bytes.Buffer
+	loaded, err := client.ImageLoadWithProgress(docker.LoadImageOptions{InputStream: &tarball, OutputStream: &progress})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(loaded)
+	sort.Strings(names)
+	if !reflect.DeepEqual(loaded, names) {
+		t.Errorf("LoadImage: wrong loaded images. Want %#v. Got %#v.", names, loaded)
+	}
+	if expected := "Loaded image: " + names[0] + "\n"; !strings.Contains(progress.String(), expected) {
+		t.Errorf("LoadImage: missing %q in the progress output: %q", expected, progress.String())
+	}
+	for _, original := range images {
+		name := "docker/python-" + original.ID
+		image, err := client.InspectImage(name)
+		if err != nil {
+			t.Fatalf("LoadImage: image %q wasn't loaded: %v", name, err)
+		}
+		if image.ID != original.ID {
+			t.Errorf("LoadImage: wrong ID for %q. Want %q. Got %q.", name, original.ID, image.ID)
+		}
+	}
+}
+
+func TestLoadImageInvalidTarball(t *testing.T) {
+	t.Parallel()
+	server := baseDockerServer()
+	server.buildMuxer()
+	recorder := httptest.NewRecorder()
+	request, _ := http.NewRequest(http.MethodPost, "/images/load", strings.NewReader("not a tarball"))
+	server.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusBadRequest {
+		t.Errorf("LoadImage: wrong status. Want %d. Got %d.", http.StatusBadRequest, recorder.Code)
+	}
+}
+
+func TestExportImagesNotFound(t *testing.T) {
+	t.Parallel()
+	server := baseDockerServer()
+	server.buildMuxer()
+	recorder := httptest.NewRecorder()
+	request, _ := http.NewRequest(http.MethodGet, "/images/get?names=unknown", nil)
+	server.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusNotFound {
+		t.Errorf("ExportImages: wrong status. Want %d. Got %d.", http.StatusNotFound, recorder.Code)
+	}
+}
+
+func TestListImagesFilters(t *testing.T) {
+	t.Parallel()
+	server := baseDockerServer()
+	server.buildMuxer()
+	now := time.Now()
+	images := []struct {
+		id     string
+		tags   []string
+		labels map[string]string
+		age    time.Duration
+	}{
+		{id: "img1", tags: []string{"nginx:1.19", "nginx:latest"}, age: 3 * time.Hour},
+		{id: "img2", tags: []string{"nginx:1.18"}, labels: map[string]string{"env": "prod"}, age: 2 * time.Hour},
+		{id: "img3", tags: []string{"redis:6"}, labels: map[string]string{"env": "dev"}, age: time.Hour},
+		{id: "img4", age: 0},
+	}
+	for _, img := range images {
+		server.images[img.id] = docker.Image{
+			ID:          img.id,
+			Created:     now.Add(-img.age),
+			Config:      &docker.Config{Labels: img.labels},
+			RepoDigests: []string{"repo@sha256:" + img.id},
+		}
+		for _, tag := range img.tags {
+			server.imgIDs[tag] = img.id
+		}
+	}
+	tests := []struct {
+		filters  map[string][]string
+		expected []string
+	}{
+		{nil, []string{"img4", "img3", "img2", "img1"}},
+		{map[string][]string{"reference": {"nginx:1.*"}}, []string{"img2 nginx:1.18", "img1 nginx:1.19"}},
+		{map[string][]string{"reference": {"nginx"}}, []string{"img2 nginx:1.18", "img1 nginx:1.19 nginx:latest"}},
+		{map[string][]string{"since": {"nginx:1.18"}}, []string{"img4", "img3"}},
+		{map[string][]string{"before": {"img3"}}, []string{"img2", "img1"}},
+		{map[string][]string{"since": {"img1"}, "before": {"redis:6"}}, []string{"img2"}},
+		{map[string][]string{"dangling": {"true"}}, []string{"img4"}},
+		{map[string][]string{"label": {"env=prod"}}, []string{"img2"}},
+		{map[string][]string{"label": {"env"}}, []string{"img3", "img2"}},
+	}
+	for _, tt := range tests {
+		filters, _ := json.Marshal(tt.filters)
+		recorder := httptest.NewRecorder()
+		request, _ := http.NewRequest(http.MethodGet, "/images/json?filters="+url.QueryEscape(string(filters)), nil)
+		server.ServeHTTP(recorder, request)
+		if recorder.Code != http.StatusOK {
+			t.Errorf("ListImages %s: wrong status. Want %d. Got %d.", filters, http.StatusOK, recorder.Code)
+			continue
+		}
+		var got []docker.APIImages
+		if err := json.NewDecoder(recorder.Body).Decode(&got); err != nil {
+			t.Fatal(err)
+		}
+		var ids []string
+		for _, img := range got {
+			if len(tt.filters["reference"]) > 0 {
+				ids = append(ids, img.ID+" "+strings.Join(img.RepoTags, " "))
+			} else {
+				ids = append(ids, img.ID)
+			}
+			if img.RepoDigests != nil {
+				t.Errorf("ListImages %s: unexpected digests without digests=1", filters)
+			}
+		}
+		if !reflect.DeepEqual(ids, tt.expected) {
+			t.Errorf("ListImages %s: wrong images. Want %#v. Got %#v.", filters, tt.expected, ids)
+		}
+	}
+}
+
+func TestListImagesDigests(t *testing.T) {
+	t.Parallel()
+	server, err := NewServer("127.0.0.1:0", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Stop()
+	server.iMut.Lock()
+	server.images["img1"] = docker.Image{ID: "img1", RepoDigests: []string{"nginx@sha256:abc"}}
+	server.iMut.Unlock()
+	client, err := docker.NewClient(server.URL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := client.ListImages(docker.ListImagesOptions{Digests: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || !reflect.DeepEqual(got[0].RepoDigests, []string{"nginx@sha256:abc"}) {
+		t.Errorf("ListImages: expected digests to be listed. Got %#v.", got)
+	}
+}
+
+func TestPrepareExecResult(t *testing.T) {
+	t.Parallel()
+	server, err := NewServer("127.0.0.1:0", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Stop()
+	containers := addContainers(server, 1)
+	server.PrepareExecResult(PrepareExecOptions{
+		Cmd:      []string{"pg_isready", "-q"},
+		Stdout:   []byte("accepting connections\n"),
+		Stderr:   []byte("warning\n"),
+		ExitCode: 2,
+	})
+	server.PrepareExecResult(PrepareExecOptions{Stdout: []byte("first\n"), ExitCode: 1})
+	server.PrepareExecResult(PrepareExecOptions{Stdout: []byte("second\n")})
+	client, err := docker.NewClient(server.URL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		cmd            []string
+		tty            bool
+		expectedStdout string
+		expectedStderr string
+		expectedCode   int
+	}{
+		{[]string{"pg_isready", "-q"}, false, "accepting connections\n", "warning\n", 2},
+		{[]string{"pg_isready", "-q"}, true, "accepting connections\nwarning\n", "", 2},
+		{[]string{"ls"}, false, "first\n", "", 1},
+		{[]string{"ls"}, false, "second\n", "", 0},
+		{[]string{"ls"}, false, "", "", 0},
+	}
+	for _, tt := range tests {
+		exec, err := client.CreateExec(docker.CreateExecOptions{
+			Container:    containers[0].ID,
+			Cmd:          tt.cmd,
+			Tty:          tt.tty,
+			AttachStdout: true,
+			AttachStderr: true,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var stdout, stderr bytes.Buffer
+		err = client.StartExec(exec.ID, docker.StartExecOptions{
+			OutputStream: &stdout,
+			ErrorStream:  &stderr,
+			Tty:          tt.tty,
+			RawTerminal:  tt.tty,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stdout.String() != tt.expectedStdout {
+			t.Errorf("StartExec %v (tty=%v): wrong stdout. Want %q. Got %q.", tt.cmd, tt.tty, tt.expectedStdout, stdout.String())
+		}
+		if stderr.String() != tt.expectedStderr {
+			t.Errorf("StartExec %v (tty=%v): wrong stderr. Want %q. Got %q.", tt.cmd, tt.tty, tt.expectedStderr, stderr.String())
+		}
+		inspect, err := client.InspectExec(exec.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if inspect.Running || inspect.ExitCode != tt.expectedCode {
+			t.Errorf("InspectExec %v: want a finished exec with exit code %d. Got running=%v, exit code %d.", tt.cmd, tt.expectedCode, inspect.Running, inspect.ExitCode)
+		}
+	}
+}
+
+func receiveEvent(t *testing.T, listener <-chan *docker.APIEvents) *docker.APIEvents {
+	t.Helper()
+	select {
+	case event := <-listener:
+		return event
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for event")
+		return nil
+	}
+}
+
+func TestContainerEvents(t *testing.T) {
+	t.Parallel()
+	server, err := NewServer("127.0.0.1:0", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Stop()
+	client, err := docker.NewClient(server.URL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := client.PullImage(docker.PullImageOptions{Repository: "busybox"}, docker.AuthConfiguration{}); err != nil {
+		t.Fatal(err)
+	}
+	listener := make(chan *docker.APIEvents, 10)
+	since := strconv.FormatInt(time.Now().Unix(), 10)
+	if err := client.AddEventListenerWithOptions(docker.EventsOptions{Since: since}, listener); err != nil {
+		t.Fatal(err)
+	}
+	defer client.RemoveEventListener(listener)
+	container, err := client.CreateContainer(docker.CreateContainerOptions{
+		Name:   "events",
+		Config: &docker.Config{Image: "busybox"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := client.StartContainer(container.ID, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.KillContainer(docker.KillContainerOptions{ID: container.ID}); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.RemoveContainer(docker.RemoveContainerOptions{ID: container.ID}); err != nil {
+		t.Fatal(err)
+	}
+	for _, action := range []string{"create", "start", "kill", "destroy"} {
+		event := receiveEvent(t, listener)
+		if event.Action != action || event.Type != "container" || event.Actor.ID != container.ID {
+			t.Errorf("ContainerEvents: wrong event. Want %s of container %s. Got %#v.", action, container.ID, event)
+		}
+		if name := event.Actor.Attributes["name"]; name != "events" {
+			t.Errorf("ContainerEvents: wrong name attribute. Want %q. Got %q.", "events", name)
+		}
+	}
+}
+
+func TestInjectEvent(t *testing.T) {
+	t.Parallel()
+	server, err := NewServer("127.0.0.1:0", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Stop()
+	client, err := docker.NewClient(server.URL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	listener := make(chan *docker.APIEvents, 10)
+	since := strconv.FormatInt(time.Now().Unix(), 10)
+	if err := client.AddEventListenerWithOptions(docker.EventsOptions{Since: since}, listener); err != nil {
+		t.Fatal(err)
+	}
+	defer client.RemoveEventListener(listener)
+	now := time.Now().Unix()
+	server.InjectEvent(docker.APIEvents{
+		Action: "connect",
+		Type:   "network",
+		Actor:  docker.APIActor{ID: "net1", Attributes: map[string]string{"container": "abc"}},
+		Time:   now,
+	})
+	event := receiveEvent(t, listener)
+	expected := &docker.APIEvents{
+		Action: "connect",
+		Type:   "network",
+		Actor:  docker.APIActor{ID: "net1", Attributes: map[string]string{"container": "abc"}},
+		Status: "network:connect",
+		ID:     "net1",
+		Time:   now,
+	}
+	if !reflect.DeepEqual(event, expected) {
+		t.Errorf("InjectEvent: wrong event. Want %#v. Got %#v.", expected, event)
+	}
+}
+
+func TestEventFilters(t *testing.T) {
+	t.Parallel()
+	server, err := NewServer("127.0.0.1:0", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Stop()
+	client, err := docker.NewClient(server.URL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	listener := make(chan *docker.APIEvents, 10)
+	opts := docker.EventsOptions{
+		Since:   strconv.FormatInt(time.Now().Unix(), 10),
+		Filters: map[string][]string{"type": {"container"}, "container": {"web"}, "label": {"env=prod"}},
+	}
+	if err := client.AddEventListenerWithOptions(opts, listener); err != nil {
+		t.Fatal(err)
+	}
+	defer client.RemoveEventListener(listener)
+	server.InjectEvent(docker.APIEvents{Action: "connect", Type: "network", Actor: docker.APIActor{ID: "net1", Attributes: map[string]string{"container": "web"}}})
+	server.InjectEvent(docker.APIEvents{Action: "start", Type: "container", Actor: docker.APIActor{ID: "abc", Attributes: map[string]string{"name": "db", "env": "prod"}}})
+	server.InjectEvent(docker.APIEvents{Action: "start", Type: "container", Actor: docker.APIActor{ID: "def", Attributes: map[string]string{"name": "web", "env": "dev"}}})
+	server.InjectEvent(docker.APIEvents{Action: "start", Type: "container", Actor: docker.APIActor{ID: "ghi", Attributes: map[string]string{"name": "web", "env": "prod"}}})
+	server.InjectEvent(docker.APIEvents{Action: "stop", Type: "container", Actor: docker.APIActor{ID: "ghi", Attributes: map[string]string{"name": "web", "env": "prod"}}})
+	for _, action := range []string{"start", "stop"} {
+		event := receiveEvent(t, listener)
+		if event.Action != action || event.Actor.ID != "ghi" {
+			t.Errorf("EventFilters: wrong event. Want %s of container ghi. Got %#v.", action, event)
+		}
+	}
+}
+
+func TestEventsUntil(t *testing.T) {
+	t.Parallel()
+	server, err := NewServer("127.0.0.1:0", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Stop()
+	start := time.Now().Add(-time.Minute).Truncate(time.Second)
+	for i, action := range []string{"create", "start", "die"} {
+		server.InjectEvent(docker.APIEvents{Action: action, Type: "container", TimeNano: start.Add(time.Duration(i) * time.Second).UnixNano()})
+	}
+	query := url.Values{
+		"since":   {strconv.FormatInt(start.Unix(), 10)},
+		"until":   {strconv.FormatInt(start.Add(time.Second).Unix(), 10)},
+		"filters": {`{"type":["container"]}`},
+	}
+	resp, err := http.Get(server.URL() + "events?" + query.Encode())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var actions []string
+	decoder := json.NewDecoder(resp.Body)
+	for {
+		var event docker.APIEvents
+		if err := decoder.Decode(&event); err != nil {
+			break
+		}
+		actions = append(actions, event.Action)
+	}
+	expected := []string{"create", "start"}
+	if !reflect.DeepEqual(actions, expected) {
+		t.Errorf("Events: wrong events with until. Want %v. Got %v.", expected, actions)
+	}
+}
+
+func TestSetEventDelay(t *testing.T) {
+	t.Parallel()
+	server, err := NewServer("127.0.0.1:0", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Stop()
+	client, err := docker.NewClient(server.URL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	listener := make(chan *docker.APIEvents, 10)
+	since := strconv.FormatInt(time.Now().Unix(), 10)
+	if err := client.AddEventListenerWithOptions(docker.EventsOptions{Since: since}, listener); err != nil {
+		t.Fatal(err)
+	}
+	defer client.RemoveEventListener(listener)
+	delay := 300 * time.Millisecond
+	server.SetEventDelay(delay)
+	start := time.Now()
+	server.InjectEvent(docker.APIEvents{Action: "first", Type: "container"})
+	server.InjectEvent(docker.APIEvents{Action: "second", Type: "container"})
+	for _, action := range []string{"first", "second"} {
+		event := receiveEvent(t, listener)
+		if event.Action != action {
+			t.Errorf("SetEventDelay: wrong event. Want %q. Got %q.", action, event.Action)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < delay {
+		t.Errorf("SetEventDelay: events delivered after %s, expected at least %s", elapsed, delay)
 	}
 }
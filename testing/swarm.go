@@ -14,6 +14,9 @@ import (
 	"math/rand"
 	"net"
 	"net/http"
+	"reflect"
+	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
@@ -78,6 +81,10 @@ func (s *DockerServer) swarmInit(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	if err := validateAddrPools(req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 	node, err := s.initSwarmNode(req.ListenAddr, req.AdvertiseAddr)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -92,12 +99,35 @@ func (s *DockerServer) swarmInit(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	now := time.Now()
 	s.swarm = &swarm.Swarm{
+		ClusterInfo: swarm.ClusterInfo{
+			ID: s.generateID(),
+			Meta: swarm.Meta{
+				Version:   swarm.Version{Index: 1},
+				CreatedAt: now,
+				UpdatedAt: now,
+			},
+			Spec:            req.Spec,
+			DefaultAddrPool: req.DefaultAddrPool,
+			SubnetSize:      req.SubnetSize,
+			DataPathPort:    req.DataPathPort,
+		},
 		JoinTokens: swarm.JoinTokens{
 			Manager: s.generateID(),
 			Worker:  s.generateID(),
 		},
 	}
+	if len(s.swarm.DefaultAddrPool) == 0 {
+		s.swarm.DefaultAddrPool = []string{"10.0.0.0/8"}
+	}
+	if s.swarm.SubnetSize == 0 {
+		s.swarm.SubnetSize = 24
+	}
+	if s.swarm.DataPathPort == 0 {
+		s.swarm.DataPathPort = 4789
+	}
+	s.updateSwarmUnlockKey(false)
 	w.Header().Set("Content-Type", "application/json")
 	err = json.NewEncoder(w).Encode(s.nodeID)
 	if err != nil {
@@ -105,6 +135,27 @@ func (s *DockerServer) swarmInit(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// validateAddrPools mimics the validation done by the daemon on the data path
+// port and the default address pools of a Swarm init request.
+func validateAddrPools(req swarm.InitRequest) error {
+	if req.DataPathPort != 0 && (req.DataPathPort < 1024 || req.DataPathPort > 49151) {
+		return fmt.Errorf("invalid data path port %d: it must be within the range 1024-49151", req.DataPathPort)
+	}
+	if req.SubnetSize > 32 {
+		return fmt.Errorf("invalid subnet size %d", req.SubnetSize)
+	}
+	for _, pool := range req.DefaultAddrPool {
+		_, subnet, err := net.ParseCIDR(pool)
+		if err != nil {
+			return fmt.Errorf("invalid default address pool %q: %w", pool, err)
+		}
+		if ones, _ := subnet.Mask.Size(); req.SubnetSize != 0 && int(req.SubnetSize) < ones {
+			return fmt.Errorf("invalid subnet size %d: it must not be smaller than the mask of the address pool %s", req.SubnetSize, pool)
+		}
+	}
+	return nil
+}
+
 func (s *DockerServer) swarmInspect(w http.ResponseWriter, r *http.Request) {
 	s.swarmMut.Lock()
 	defer s.swarmMut.Unlock()
@@ -117,6 +168,108 @@ func (s *DockerServer) swarmInspect(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+func (s *DockerServer) swarmUpdate(w http.ResponseWriter, r *http.Request) {
+	s.swarmMut.Lock()
+	defer s.swarmMut.Unlock()
+	if s.swarm == nil {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	if version := r.URL.Query().Get("version"); version != strconv.FormatUint(s.swarm.Version.Index, 10) {
+		http.Error(w, "update out of sequence", http.StatusBadRequest)
+		return
+	}
+	var spec swarm.Spec
+	if err := json.NewDecoder(r.Body).Decode(&spec); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s.swarm.Spec = spec
+	if r.URL.Query().Get("rotateWorkerToken") == "true" {
+		s.swarm.JoinTokens.Worker = s.generateID()
+	}
+	if r.URL.Query().Get("rotateManagerToken") == "true" {
+		s.swarm.JoinTokens.Manager = s.generateID()
+	}
+	s.updateSwarmUnlockKey(r.URL.Query().Get("rotateManagerUnlockKey") == "true")
+	s.swarm.Version.Index++
+	s.swarm.UpdatedAt = time.Now()
+	w.WriteHeader(http.StatusOK)
+}
+
+// updateSwarmUnlockKey generates or removes the unlock key of the Swarm
+// according to its autolock setting. It must be called with swarmMut held.
+func (s *DockerServer) updateSwarmUnlockKey(rotate bool) {
+	if !s.swarm.Spec.EncryptionConfig.AutoLockManagers {
+		s.swarmUnlockKey = ""
+		return
+	}
+	if s.swarmUnlockKey == "" || rotate {
+		s.swarmUnlockKey = "SWMKEY-1-" + s.generateID()
+	}
+}
+
+// LockSwarm simulates a restart of the manager of a Swarm with autolock
+// enabled: until the Swarm is unlocked with its key, via the /swarm/unlock
+// endpoint, Swarm operations fail with a "swarm is locked" error.
+func (s *DockerServer) LockSwarm() error {
+	s.swarmMut.Lock()
+	defer s.swarmMut.Unlock()
+	if s.swarm == nil {
+		return errors.New("node is not part of a swarm")
+	}
+	if s.swarmUnlockKey == "" {
+		return errors.New("autolock is not enabled")
+	}
+	s.swarmLocked = true
+	return nil
+}
+
+func (s *DockerServer) swarmIsLocked() bool {
+	s.swarmMut.RLock()
+	defer s.swarmMut.RUnlock()
+	return s.swarmLocked
+}
+
+// lockedSwarmPath matches the endpoints that are unavailable while the Swarm
+// is locked.
+var lockedSwarmPath = regexp.MustCompile(`^(/v[0-9]+\.[0-9]+)?(/swarm|/swarm/update|/swarm/unlockkey|/(services|nodes|tasks|secrets|configs)(/.*)?)$`)
+
+func (s *DockerServer) swarmUnlock(w http.ResponseWriter, r *http.Request) {
+	s.swarmMut.Lock()
+	defer s.swarmMut.Unlock()
+	if s.swarm == nil {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	if !s.swarmLocked {
+		http.Error(w, "swarm is not locked", http.StatusBadRequest)
+		return
+	}
+	var req swarm.UnlockRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.UnlockKey != s.swarmUnlockKey {
+		http.Error(w, "invalid unlock key", http.StatusBadRequest)
+		return
+	}
+	s.swarmLocked = false
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *DockerServer) swarmUnlockKeyHandler(w http.ResponseWriter, r *http.Request) {
+	s.swarmMut.RLock()
+	defer s.swarmMut.RUnlock()
+	if s.swarm == nil {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"UnlockKey": s.swarmUnlockKey})
+}
+
 func (s *DockerServer) swarmJoin(w http.ResponseWriter, r *http.Request) {
 	s.swarmMut.Lock()
 	defer s.swarmMut.Unlock()
@@ -167,6 +320,8 @@ func (s *DockerServer) swarmLeave(w http.ResponseWriter, r *http.Request) {
 	} else {
 		s.swarmServer.listener.Close()
 		s.swarm = nil
+		s.swarmUnlockKey = ""
+		s.swarmLocked = false
 		s.nodes = nil
 		s.swarmServer = nil
 		s.nodeID = ""
@@ -222,6 +377,10 @@ func (s *DockerServer) serviceCreate(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 	}
+	if err := s.validateServiceSpec(config); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 	service := swarm.Service{
 		ID:   s.generateID(),
 		Spec: config,
@@ -238,6 +397,31 @@ func (s *DockerServer) serviceCreate(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(service)
 }
 
+// validateServiceSpec checks that every secret and config referenced by the
+// given spec is known by the server. It must be called with swarmMut held.
+func (s *DockerServer) validateServiceSpec(spec swarm.ServiceSpec) error {
+	if spec.TaskTemplate.ContainerSpec == nil {
+		return nil
+	}
+	for _, ref := range spec.TaskTemplate.ContainerSpec.Secrets {
+		if ref == nil {
+			continue
+		}
+		if s.findSecret(ref.SecretID) == nil {
+			return fmt.Errorf("secret not found: %s", ref.SecretID)
+		}
+	}
+	for _, ref := range spec.TaskTemplate.ContainerSpec.Configs {
+		if ref == nil {
+			continue
+		}
+		if s.findConfig(ref.ConfigID) == nil {
+			return fmt.Errorf("config not found: %s", ref.ConfigID)
+		}
+	}
+	return nil
+}
+
 func (s *DockerServer) setServiceEndpoint(service *swarm.Service) {
 	if service.Spec.EndpointSpec == nil {
 		return
@@ -271,25 +455,72 @@ func (s *DockerServer) addTasks(service *swarm.Service, update bool) {
 		if update {
 			name = fmt.Sprintf("%s-%d-updated", service.Spec.Name, i)
 		}
-		container := s.containerForService(service, name)
-		chosenNode := s.nodes[s.nodeRR]
-		s.nodeRR = (s.nodeRR + 1) % len(s.nodes)
-		task := swarm.Task{
-			ID:        s.generateID(),
-			ServiceID: service.ID,
-			NodeID:    chosenNode.ID,
-			Status: swarm.TaskStatus{
-				State: swarm.TaskStateReady,
-				ContainerStatus: &swarm.ContainerStatus{
-					ContainerID: container.ID,
-				},
+		s.addTask(service, name)
+	}
+}
+
+// addTask schedules a new task of the service, with a container of the given
+// name, on the next node.
+func (s *DockerServer) addTask(service *swarm.Service, name string) {
+	container := s.containerForService(service, name)
+	chosenNode := s.nodes[s.nodeRR]
+	s.nodeRR = (s.nodeRR + 1) % len(s.nodes)
+	task := swarm.Task{
+		ID:        s.generateID(),
+		ServiceID: service.ID,
+		NodeID:    chosenNode.ID,
+		Status: swarm.TaskStatus{
+			State: swarm.TaskStateReady,
+			ContainerStatus: &swarm.ContainerStatus{
+				ContainerID: container.ID,
 			},
-			DesiredState: swarm.TaskStateReady,
-			Spec:         service.Spec.TaskTemplate,
+		},
+		DesiredState: swarm.TaskStateReady,
+		Spec:         service.Spec.TaskTemplate,
+	}
+	s.tasks = append(s.tasks, &task)
+	s.addContainer(container)
+	s.notify(container)
+}
+
+// removeServiceTasks removes the tasks of the service, along with their
+// containers, keeping the first keep ones.
+func (s *DockerServer) removeServiceTasks(service *swarm.Service, keep int) {
+	for i := 0; i < len(s.tasks); i++ {
+		if s.tasks[i].ServiceID != service.ID {
+			continue
 		}
-		s.tasks = append(s.tasks, &task)
-		s.addContainer(container)
-		s.notify(container)
+		if keep > 0 {
+			keep--
+			continue
+		}
+		cont, _ := s.findContainerWithLock(s.tasks[i].Status.ContainerStatus.ContainerID, false)
+		if cont != nil {
+			delete(s.containers, cont.ID)
+			delete(s.contNameToID, cont.Name)
+		}
+		s.tasks = append(s.tasks[:i], s.tasks[i+1:]...)
+		i--
+	}
+}
+
+// scaleTasks adds or removes tasks of the replicated service to match its
+// number of replicas, keeping the existing tasks like the daemon does when
+// only the number of replicas changes.
+func (s *DockerServer) scaleTasks(service *swarm.Service) {
+	replicas := 1
+	if repl := service.Spec.Mode.Replicated; repl != nil && repl.Replicas != nil {
+		replicas = int(*repl.Replicas)
+	}
+	var current int
+	for _, task := range s.tasks {
+		if task.ServiceID == service.ID {
+			current++
+		}
+	}
+	s.removeServiceTasks(service, replicas)
+	for i := current; i < replicas; i++ {
+		s.addTask(service, fmt.Sprintf("%s-%d", service.Spec.Name, i))
 	}
 }
 
@@ -337,7 +568,8 @@ func (s *DockerServer) serviceList(w http.ResponseWriter, r *http.Request) {
 	filtersRaw := r.FormValue("filters")
 	var filters map[string][]string
 	json.Unmarshal([]byte(filtersRaw), &filters)
-	if filters == nil {
+	status, _ := strconv.ParseBool(r.URL.Query().Get("status"))
+	if filters == nil && !status {
 		json.NewEncoder(w).Encode(s.services)
 		return
 	}
@@ -348,9 +580,47 @@ func (s *DockerServer) serviceList(w http.ResponseWriter, r *http.Request) {
 			ret = append(ret, s.services[i])
 		}
 	}
+	if status {
+		for i, srv := range ret {
+			withStatus := *srv
+			withStatus.ServiceStatus = s.serviceStatus(srv)
+			ret[i] = &withStatus
+		}
+	}
 	json.NewEncoder(w).Encode(ret)
 }
 
+// serviceStatus computes the task counts reported when listing services with
+// status=true. A task is running when its container is running, and all the
+// tasks of a global service are desired. It must be called with swarmMut
+// held.
+func (s *DockerServer) serviceStatus(service *swarm.Service) *swarm.ServiceStatus {
+	var status swarm.ServiceStatus
+	s.cMut.RLock()
+	defer s.cMut.RUnlock()
+	for _, task := range s.tasks {
+		if task.ServiceID != service.ID {
+			continue
+		}
+		if service.Spec.Mode.Global != nil {
+			status.DesiredTasks++
+		}
+		if task.Status.ContainerStatus == nil {
+			continue
+		}
+		if container, ok := s.containers[task.Status.ContainerStatus.ContainerID]; ok && container.State.Running {
+			status.RunningTasks++
+		}
+	}
+	if repl := service.Spec.Mode.Replicated; repl != nil {
+		status.DesiredTasks = 1
+		if repl.Replicas != nil {
+			status.DesiredTasks = *repl.Replicas
+		}
+	}
+	return &status
+}
+
 func (s *DockerServer) taskList(w http.ResponseWriter, r *http.Request) {
 	s.swarmMut.Lock()
 	defer s.swarmMut.Unlock()
@@ -418,6 +688,91 @@ func inFilter(list []string, wanted string) bool {
 	return false
 }
 
+func (s *DockerServer) serviceLogs(w http.ResponseWriter, r *http.Request) {
+	s.swarmMut.Lock()
+	if s.swarm == nil {
+		s.swarmMut.Unlock()
+		w.WriteHeader(http.StatusNotAcceptable)
+		return
+	}
+	id := mux.Vars(r)["id"]
+	var service *swarm.Service
+	for _, srv := range s.services {
+		if srv.ID == id || srv.Spec.Name == id {
+			service = srv
+			break
+		}
+	}
+	if service == nil {
+		s.swarmMut.Unlock()
+		http.Error(w, "service not found", http.StatusNotFound)
+		return
+	}
+	var tasks []swarm.Task
+	for _, task := range s.tasks {
+		if task.ServiceID == service.ID {
+			tasks = append(tasks, *task)
+		}
+	}
+	s.swarmMut.Unlock()
+	query := r.URL.Query()
+	details := query.Get("details") == "1"
+	w.Header().Set("Content-Type", "application/vnd.docker.raw-stream")
+	w.WriteHeader(http.StatusOK)
+	s.cMut.RLock()
+	defer s.cMut.RUnlock()
+	for _, task := range tasks {
+		s.writeTaskLogs(w, task, query.Get("stdout") == "1", query.Get("stderr") == "1", details)
+	}
+}
+
+// writeTaskLogs writes the logs stored for the container of the given task.
+// It must be called with cMut held.
+func (s *DockerServer) writeTaskLogs(w io.Writer, task swarm.Task, stdout, stderr, details bool) {
+	if task.Status.ContainerStatus == nil {
+		return
+	}
+	logs, ok := s.logs[task.Status.ContainerStatus.ContainerID]
+	if !ok {
+		return
+	}
+	var prefix string
+	if details {
+		prefix = fmt.Sprintf("com.docker.swarm.node.id=%s,com.docker.swarm.service.id=%s,com.docker.swarm.task.id=%s ", task.NodeID, task.ServiceID, task.ID)
+	}
+	writeContainerLogs(w, logs, stdout, stderr, prefix)
+}
+
+func (s *DockerServer) taskLogs(w http.ResponseWriter, r *http.Request) {
+	s.swarmMut.Lock()
+	if s.swarm == nil {
+		s.swarmMut.Unlock()
+		w.WriteHeader(http.StatusNotAcceptable)
+		return
+	}
+	id := mux.Vars(r)["id"]
+	var task *swarm.Task
+	for _, t := range s.tasks {
+		if t.ID == id {
+			task = t
+			break
+		}
+	}
+	if task == nil {
+		s.swarmMut.Unlock()
+		http.Error(w, "task not found", http.StatusNotFound)
+		return
+	}
+	taskCopy := *task
+	s.swarmMut.Unlock()
+	query := r.URL.Query()
+	w.Header().Set("Content-Type", "application/vnd.docker.raw-stream")
+	w.WriteHeader(http.StatusOK)
+	s.cMut.RLock()
+	defer s.cMut.RUnlock()
+	s.writeTaskLogs(w, taskCopy, query.Get("stdout") == "1", query.Get("stderr") == "1", query.Get("details") == "1")
+}
+
 func (s *DockerServer) serviceDelete(w http.ResponseWriter, r *http.Request) {
 	s.swarmMut.Lock()
 	defer s.swarmMut.Unlock()
@@ -482,13 +837,31 @@ func (s *DockerServer) serviceUpdate(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "service not found", http.StatusNotFound)
 		return
 	}
+	if version := r.URL.Query().Get("version"); version != "" && version != strconv.FormatUint(toUpdate.Version.Index, 10) {
+		http.Error(w, "update out of sequence", http.StatusBadRequest)
+		return
+	}
 	var newSpec swarm.ServiceSpec
 	err := json.NewDecoder(r.Body).Decode(&newSpec)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	if r.URL.Query().Get("rollback") == "previous" {
+		if toUpdate.PreviousSpec == nil {
+			http.Error(w, "service has no previous spec to rollback to", http.StatusBadRequest)
+			return
+		}
+		newSpec = *toUpdate.PreviousSpec
+	}
+	if err := s.validateServiceSpec(newSpec); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	previousSpec := toUpdate.Spec
+	toUpdate.PreviousSpec = &previousSpec
 	toUpdate.Spec = newSpec
+	toUpdate.Version.Index++
 	end := time.Now()
 	toUpdate.UpdateStatus = &swarm.UpdateStatus{
 		State:       swarm.UpdateStateCompleted,
@@ -496,19 +869,20 @@ func (s *DockerServer) serviceUpdate(w http.ResponseWriter, r *http.Request) {
 		StartedAt:   &start,
 	}
 	s.setServiceEndpoint(toUpdate)
-	for i := 0; i < len(s.tasks); i++ {
-		if s.tasks[i].ServiceID != toUpdate.ID {
-			continue
-		}
-		cont, _ := s.findContainerWithLock(s.tasks[i].Status.ContainerStatus.ContainerID, false)
-		if cont != nil {
-			delete(s.containers, cont.ID)
-			delete(s.contNameToID, cont.Name)
-		}
-		s.tasks = append(s.tasks[:i], s.tasks[i+1:]...)
-		i--
+	// like the daemon, only redeploy the tasks when the task template (which
+	// includes the ForceUpdate counter) or the mode of the service changes,
+	// and just add or remove tasks when only the number of replicas changes.
+	switch {
+	case !reflect.DeepEqual(previousSpec.TaskTemplate, newSpec.TaskTemplate):
+		s.removeServiceTasks(toUpdate, 0)
+		s.addTasks(toUpdate, true)
+	case reflect.DeepEqual(previousSpec.Mode, newSpec.Mode):
+	case previousSpec.Mode.Replicated != nil && newSpec.Mode.Replicated != nil:
+		s.scaleTasks(toUpdate)
+	default:
+		s.removeServiceTasks(toUpdate, 0)
+		s.addTasks(toUpdate, true)
 	}
-	s.addTasks(toUpdate, true)
 	err = s.runNodeOperation(s.swarmServer.URL(), nodeOperation{})
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -535,6 +909,10 @@ func (s *DockerServer) nodeUpdate(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusNotFound)
 		return
 	}
+	if version := r.URL.Query().Get("version"); version != "" && version != strconv.FormatUint(n.Version.Index, 10) {
+		http.Error(w, "update out of sequence", http.StatusBadRequest)
+		return
+	}
 	var spec swarm.NodeSpec
 	err := json.NewDecoder(r.Body).Decode(&spec)
 	if err != nil {
@@ -542,6 +920,8 @@ func (s *DockerServer) nodeUpdate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	n.Spec = spec
+	n.Version.Index++
+	n.UpdatedAt = time.Now()
 	err = s.runNodeOperation(s.swarmServer.URL(), nodeOperation{
 		Op:   "update",
 		Node: *n,
@@ -599,12 +979,49 @@ func (s *DockerServer) nodeList(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusNotAcceptable)
 		return
 	}
-	err := json.NewEncoder(w).Encode(s.nodes)
+	filtersRaw := r.FormValue("filters")
+	var filters map[string][]string
+	json.Unmarshal([]byte(filtersRaw), &filters)
+	nodes := s.nodes
+	if filters != nil {
+		nodes = []swarm.Node{}
+		for _, node := range s.nodes {
+			if inFilter(filters["id"], node.ID) &&
+				inFilter(filters["name"], node.Spec.Name) &&
+				inFilter(filters["role"], string(nodeRole(node))) &&
+				// nodes in the fake swarm are always accepted
+				inFilter(filters["membership"], "accepted") &&
+				inFilter(filters["availability"], string(nodeAvailability(node))) &&
+				inLabelFilter(filters["node.label"], node.Spec.Labels) {
+				nodes = append(nodes, node)
+			}
+		}
+	}
+	err := json.NewEncoder(w).Encode(nodes)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 	}
 }
 
+// nodeRole returns the role of the node, falling back to the presence of a
+// manager status for nodes whose spec doesn't define one.
+func nodeRole(node swarm.Node) swarm.NodeRole {
+	if node.Spec.Role != "" {
+		return node.Spec.Role
+	}
+	if node.ManagerStatus != nil {
+		return swarm.NodeRoleManager
+	}
+	return swarm.NodeRoleWorker
+}
+
+func nodeAvailability(node swarm.Node) swarm.NodeAvailability {
+	if node.Spec.Availability != "" {
+		return node.Spec.Availability
+	}
+	return swarm.NodeAvailabilityActive
+}
+
 type nodeOperation struct {
 	Op        string
 	Node      swarm.Node
@@ -698,3 +1115,283 @@ func (s *DockerServer) internalUpdateNodes(w http.ResponseWriter, r *http.Reques
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 	}
 }
+
+func (s *DockerServer) findSecret(idOrName string) *swarm.Secret {
+	for _, secret := range s.secrets {
+		if secret.ID == idOrName || secret.Spec.Name == idOrName {
+			return secret
+		}
+	}
+	return nil
+}
+
+// publicSecret returns a copy of the secret without its payload, as the
+// daemon never returns secret data.
+func publicSecret(secret *swarm.Secret) swarm.Secret {
+	ret := *secret
+	ret.Spec.Data = nil
+	return ret
+}
+
+func (s *DockerServer) secretCreate(w http.ResponseWriter, r *http.Request) {
+	var spec swarm.SecretSpec
+	defer r.Body.Close()
+	if err := json.NewDecoder(r.Body).Decode(&spec); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s.swarmMut.Lock()
+	defer s.swarmMut.Unlock()
+	if s.swarm == nil {
+		w.WriteHeader(http.StatusNotAcceptable)
+		return
+	}
+	if spec.Name == "" {
+		http.Error(w, "name is required", http.StatusBadRequest)
+		return
+	}
+	for _, secret := range s.secrets {
+		if secret.Spec.Name == spec.Name {
+			http.Error(w, "secret "+spec.Name+" already exists", http.StatusConflict)
+			return
+		}
+	}
+	now := time.Now()
+	secret := swarm.Secret{
+		ID: s.generateID(),
+		Meta: swarm.Meta{
+			Version:   swarm.Version{Index: 1},
+			CreatedAt: now,
+			UpdatedAt: now,
+		},
+		Spec: spec,
+	}
+	s.secrets = append(s.secrets, &secret)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]string{"ID": secret.ID})
+}
+
+func (s *DockerServer) secretInspect(w http.ResponseWriter, r *http.Request) {
+	s.swarmMut.Lock()
+	defer s.swarmMut.Unlock()
+	if s.swarm == nil {
+		w.WriteHeader(http.StatusNotAcceptable)
+		return
+	}
+	secret := s.findSecret(mux.Vars(r)["id"])
+	if secret == nil {
+		http.Error(w, "secret not found", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(publicSecret(secret))
+}
+
+func (s *DockerServer) secretList(w http.ResponseWriter, r *http.Request) {
+	s.swarmMut.Lock()
+	defer s.swarmMut.Unlock()
+	if s.swarm == nil {
+		w.WriteHeader(http.StatusNotAcceptable)
+		return
+	}
+	filtersRaw := r.FormValue("filters")
+	var filters map[string][]string
+	json.Unmarshal([]byte(filtersRaw), &filters)
+	ret := []swarm.Secret{}
+	for _, secret := range s.secrets {
+		if inFilter(filters["id"], secret.ID) &&
+			inFilter(filters["name"], secret.Spec.Name) &&
+			inLabelFilter(filters["label"], secret.Spec.Labels) {
+			ret = append(ret, publicSecret(secret))
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ret)
+}
+
+func (s *DockerServer) secretDelete(w http.ResponseWriter, r *http.Request) {
+	s.swarmMut.Lock()
+	defer s.swarmMut.Unlock()
+	if s.swarm == nil {
+		w.WriteHeader(http.StatusNotAcceptable)
+		return
+	}
+	id := mux.Vars(r)["id"]
+	for i, secret := range s.secrets {
+		if secret.ID == id || secret.Spec.Name == id {
+			s.secrets = append(s.secrets[:i], s.secrets[i+1:]...)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+	}
+	http.Error(w, "secret not found", http.StatusNotFound)
+}
+
+func (s *DockerServer) secretUpdate(w http.ResponseWriter, r *http.Request) {
+	s.swarmMut.Lock()
+	defer s.swarmMut.Unlock()
+	if s.swarm == nil {
+		w.WriteHeader(http.StatusNotAcceptable)
+		return
+	}
+	secret := s.findSecret(mux.Vars(r)["id"])
+	if secret == nil {
+		http.Error(w, "secret not found", http.StatusNotFound)
+		return
+	}
+	var spec swarm.SecretSpec
+	defer r.Body.Close()
+	if err := json.NewDecoder(r.Body).Decode(&spec); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if version := r.URL.Query().Get("version"); version != fmt.Sprintf("%d", secret.Version.Index) {
+		http.Error(w, "update out of sequence", http.StatusBadRequest)
+		return
+	}
+	if spec.Data != nil && !bytes.Equal(spec.Data, secret.Spec.Data) {
+		http.Error(w, "only updates to Labels are allowed", http.StatusBadRequest)
+		return
+	}
+	secret.Spec.Labels = spec.Labels
+	secret.Version.Index++
+	secret.UpdatedAt = time.Now()
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *DockerServer) findConfig(idOrName string) *swarm.Config {
+	for _, config := range s.configs {
+		if config.ID == idOrName || config.Spec.Name == idOrName {
+			return config
+		}
+	}
+	return nil
+}
+
+func (s *DockerServer) configCreate(w http.ResponseWriter, r *http.Request) {
+	var spec swarm.ConfigSpec
+	defer r.Body.Close()
+	if err := json.NewDecoder(r.Body).Decode(&spec); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s.swarmMut.Lock()
+	defer s.swarmMut.Unlock()
+	if s.swarm == nil {
+		w.WriteHeader(http.StatusNotAcceptable)
+		return
+	}
+	if spec.Name == "" {
+		http.Error(w, "name is required", http.StatusBadRequest)
+		return
+	}
+	for _, config := range s.configs {
+		if config.Spec.Name == spec.Name {
+			http.Error(w, "config "+spec.Name+" already exists", http.StatusConflict)
+			return
+		}
+	}
+	now := time.Now()
+	config := swarm.Config{
+		ID: s.generateID(),
+		Meta: swarm.Meta{
+			Version:   swarm.Version{Index: 1},
+			CreatedAt: now,
+			UpdatedAt: now,
+		},
+		Spec: spec,
+	}
+	s.configs = append(s.configs, &config)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]string{"ID": config.ID})
+}
+
+func (s *DockerServer) configInspect(w http.ResponseWriter, r *http.Request) {
+	s.swarmMut.Lock()
+	defer s.swarmMut.Unlock()
+	if s.swarm == nil {
+		w.WriteHeader(http.StatusNotAcceptable)
+		return
+	}
+	config := s.findConfig(mux.Vars(r)["id"])
+	if config == nil {
+		http.Error(w, "config not found", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(config)
+}
+
+func (s *DockerServer) configList(w http.ResponseWriter, r *http.Request) {
+	s.swarmMut.Lock()
+	defer s.swarmMut.Unlock()
+	if s.swarm == nil {
+		w.WriteHeader(http.StatusNotAcceptable)
+		return
+	}
+	filtersRaw := r.FormValue("filters")
+	var filters map[string][]string
+	json.Unmarshal([]byte(filtersRaw), &filters)
+	ret := []*swarm.Config{}
+	for _, config := range s.configs {
+		if inFilter(filters["id"], config.ID) &&
+			inFilter(filters["name"], config.Spec.Name) &&
+			inLabelFilter(filters["label"], config.Spec.Labels) {
+			ret = append(ret, config)
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ret)
+}
+
+func (s *DockerServer) configDelete(w http.ResponseWriter, r *http.Request) {
+	s.swarmMut.Lock()
+	defer s.swarmMut.Unlock()
+	if s.swarm == nil {
+		w.WriteHeader(http.StatusNotAcceptable)
+		return
+	}
+	id := mux.Vars(r)["id"]
+	for i, config := range s.configs {
+		if config.ID == id || config.Spec.Name == id {
+			s.configs = append(s.configs[:i], s.configs[i+1:]...)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+	}
+	http.Error(w, "config not found", http.StatusNotFound)
+}
+
+func (s *DockerServer) configUpdate(w http.ResponseWriter, r *http.Request) {
+	s.swarmMut.Lock()
+	defer s.swarmMut.Unlock()
+	if s.swarm == nil {
+		w.WriteHeader(http.StatusNotAcceptable)
+		return
+	}
+	config := s.findConfig(mux.Vars(r)["id"])
+	if config == nil {
+		http.Error(w, "config not found", http.StatusNotFound)
+		return
+	}
+	var spec swarm.ConfigSpec
+	defer r.Body.Close()
+	if err := json.NewDecoder(r.Body).Decode(&spec); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if version := r.URL.Query().Get("version"); version != fmt.Sprintf("%d", config.Version.Index) {
+		http.Error(w, "update out of sequence", http.StatusBadRequest)
+		return
+	}
+	if spec.Data != nil && !bytes.Equal(spec.Data, config.Spec.Data) {
+		http.Error(w, "only updates to Labels are allowed", http.StatusBadRequest)
+		return
+	}
+	config.Spec.Labels = spec.Labels
+	config.Version.Index++
+	config.UpdatedAt = time.Now()
+	w.WriteHeader(http.StatusOK)
+}
@@ -6,10 +6,12 @@ package testing
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"net"
 	"net/http"
 	"net/http/httptest"
@@ -58,6 +60,107 @@ func TestSwarmInit(t *testing.T) {
 	}
 }
 
+func TestSwarmInitAddrPools(t *testing.T) {
+	t.Parallel()
+	server, err := NewServer("127.0.0.1:0", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Stop()
+	client, err := docker.NewClient(server.URL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = client.InitSwarm(docker.InitSwarmOptions{
+		InitRequest: swarm.InitRequest{
+			DefaultAddrPool: []string{"172.80.0.0/16"},
+			SubnetSize:      26,
+			DataPathPort:    7789,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := client.InspectSwarm(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"172.80.0.0/16"}; !reflect.DeepEqual(info.DefaultAddrPool, expected) {
+		t.Errorf("InspectSwarm: wrong DefaultAddrPool. Want %#v. Got %#v.", expected, info.DefaultAddrPool)
+	}
+	if info.SubnetSize != 26 || info.DataPathPort != 7789 {
+		t.Errorf("InspectSwarm: wrong SubnetSize or DataPathPort. Want 26 and 7789. Got %d and %d.", info.SubnetSize, info.DataPathPort)
+	}
+	expectedIPAM := [][]docker.IPAMConfig{
+		{{Subnet: "172.80.0.0/26", Gateway: "172.80.0.1"}},
+		{{Subnet: "172.80.0.64/26", Gateway: "172.80.0.65"}},
+	}
+	for i, name := range []string{"first", "second"} {
+		network, err := client.CreateNetwork(docker.CreateNetworkOptions{Name: name, Driver: "overlay", Scope: "swarm"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		inspected, err := client.NetworkInfo(network.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(inspected.IPAM.Config, expectedIPAM[i]) {
+			t.Errorf("CreateNetwork(%q): wrong IPAM config. Want %#v. Got %#v.", name, expectedIPAM[i], inspected.IPAM.Config)
+		}
+	}
+}
+
+func TestSwarmInitAddrPoolsDefaults(t *testing.T) {
+	t.Parallel()
+	server, err := NewServer("127.0.0.1:0", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Stop()
+	client, err := docker.NewClient(server.URL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.InitSwarm(docker.InitSwarmOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	info, err := client.InspectSwarm(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(info.DefaultAddrPool, []string{"10.0.0.0/8"}) || info.SubnetSize != 24 || info.DataPathPort != 4789 {
+		t.Errorf("InspectSwarm: wrong defaults: pools %#v, subnet size %d, data path port %d", info.DefaultAddrPool, info.SubnetSize, info.DataPathPort)
+	}
+}
+
+func TestSwarmInitInvalidAddrPools(t *testing.T) {
+	t.Parallel()
+	tests := []swarm.InitRequest{
+		{DataPathPort: 80},
+		{DataPathPort: 50000},
+		{DefaultAddrPool: []string{"not-a-cidr"}},
+		{DefaultAddrPool: []string{"172.80.0.0/16"}, SubnetSize: 8},
+		{SubnetSize: 33},
+	}
+	for _, req := range tests {
+		server, err := NewServer("127.0.0.1:0", nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := json.Marshal(req)
+		recorder := httptest.NewRecorder()
+		request, _ := http.NewRequest(http.MethodPost, "/swarm/init", bytes.NewReader(data))
+		server.ServeHTTP(recorder, request)
+		if recorder.Code != http.StatusBadRequest {
+			t.Errorf("SwarmInit(%#v): wrong status. Want %d. Got %d.", req, http.StatusBadRequest, recorder.Code)
+		}
+		if server.swarm != nil {
+			t.Errorf("SwarmInit(%#v): unexpected swarm", req)
+		}
+		server.Stop()
+	}
+}
+
 func TestSwarmInitDynamicAdvertiseAddrPort(t *testing.T) {
 	t.Parallel()
 	server, err := NewServer("127.0.0.1:0", nil, nil)
@@ -1053,6 +1156,7 @@ func TestServiceUpdate(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
+	previousSpec := srv.Spec
 	recorder := httptest.NewRecorder()
 	updateOpts := swarm.ServiceSpec{
 		Annotations: swarm.Annotations{
@@ -1110,8 +1214,10 @@ func TestServiceUpdate(t *testing.T) {
 	}
 	srv = server.services[0]
 	expectedService := &swarm.Service{
-		ID:   srv.ID,
-		Spec: updateOpts,
+		ID:           srv.ID,
+		Meta:         swarm.Meta{Version: swarm.Version{Index: 1}},
+		Spec:         updateOpts,
+		PreviousSpec: &previousSpec,
 		Endpoint: swarm.Endpoint{
 			Spec:  *updateOpts.EndpointSpec,
 			Ports: []swarm.PortConfig{{Protocol: "tcp", TargetPort: 80, PublishedPort: 80}},
@@ -1195,6 +1301,164 @@ func TestServiceUpdateMoreReplicas(t *testing.T) {
 	}
 }
 
+func TestServiceScale(t *testing.T) {
+	t.Parallel()
+	server, unused := setUpSwarm(t)
+	defer server.Stop()
+	defer unused.Stop()
+	client, err := docker.NewClient(server.URL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	replicas := uint64(2)
+	created, err := client.CreateService(docker.CreateServiceOptions{
+		ServiceSpec: swarm.ServiceSpec{
+			Annotations: swarm.Annotations{Name: "web"},
+			TaskTemplate: swarm.TaskSpec{
+				ContainerSpec: &swarm.ContainerSpec{Image: "test/test"},
+			},
+			Mode: swarm.ServiceMode{Replicated: &swarm.ReplicatedService{Replicas: &replicas}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	listTasks := func() []swarm.Task {
+		tasks, err := client.ListTasks(docker.ListTasksOptions{Filters: map[string][]string{"service": {created.ID}}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tasks
+	}
+	original := listTasks()
+	service, err := client.ScaleService(created.ID, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := *service.Spec.Mode.Replicated.Replicas; got != 4 {
+		t.Errorf("ScaleService: wrong replicas. Want 4. Got %d.", got)
+	}
+	tasks := listTasks()
+	if len(tasks) != 4 {
+		t.Fatalf("ScaleService: wrong number of tasks. Want 4. Got %d.", len(tasks))
+	}
+	ids := make(map[string]bool)
+	for _, task := range tasks {
+		ids[task.ID] = true
+	}
+	for _, task := range original {
+		if !ids[task.ID] {
+			t.Errorf("ScaleService: task %s was replaced when scaling up", task.ID)
+		}
+	}
+	if _, err = client.ScaleService(created.ID, 1); err != nil {
+		t.Fatal(err)
+	}
+	if tasks = listTasks(); len(tasks) != 1 {
+		t.Errorf("ScaleService: wrong number of tasks. Want 1. Got %d.", len(tasks))
+	}
+	if len(server.containers) != 1 {
+		t.Errorf("ScaleService: wrong number of containers. Want 1. Got %d.", len(server.containers))
+	}
+}
+
+func TestServiceListStatus(t *testing.T) {
+	t.Parallel()
+	server, unused := setUpSwarm(t)
+	defer server.Stop()
+	defer unused.Stop()
+	client, err := docker.NewClient(server.URL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	replicas := uint64(3)
+	web, err := client.CreateService(docker.CreateServiceOptions{
+		ServiceSpec: swarm.ServiceSpec{
+			Annotations:  swarm.Annotations{Name: "web"},
+			TaskTemplate: swarm.TaskSpec{ContainerSpec: &swarm.ContainerSpec{Image: "test/test"}},
+			Mode:         swarm.ServiceMode{Replicated: &swarm.ReplicatedService{Replicas: &replicas}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	agent, err := client.CreateService(docker.CreateServiceOptions{
+		ServiceSpec: swarm.ServiceSpec{
+			Annotations:  swarm.Annotations{Name: "agent"},
+			TaskTemplate: swarm.TaskSpec{ContainerSpec: &swarm.ContainerSpec{Image: "test/agent"}},
+			Mode:         swarm.ServiceMode{Global: &swarm.GlobalService{}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tasks, err := client.ListTasks(docker.ListTasksOptions{Filters: map[string][]string{"service": {web.ID}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = server.MutateContainer(tasks[0].Status.ContainerStatus.ContainerID, docker.State{Running: false, ExitCode: 1}); err != nil {
+		t.Fatal(err)
+	}
+	services, err := client.ListServices(docker.ListServicesOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, service := range services {
+		if service.ServiceStatus != nil {
+			t.Errorf("ListServices: unexpected status without Status: %#v", service.ServiceStatus)
+		}
+	}
+	services, err = client.ListServices(docker.ListServicesOptions{Status: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]swarm.ServiceStatus{
+		web.ID:   {RunningTasks: 2, DesiredTasks: 3},
+		agent.ID: {RunningTasks: 2, DesiredTasks: 2},
+	}
+	if len(services) != len(expected) {
+		t.Fatalf("ListServices: wrong number of services. Want %d. Got %d.", len(expected), len(services))
+	}
+	for _, service := range services {
+		if service.ServiceStatus == nil || *service.ServiceStatus != expected[service.ID] {
+			t.Errorf("ListServices: wrong status for %s. Want %#v. Got %#v.", service.Spec.Name, expected[service.ID], service.ServiceStatus)
+		}
+	}
+	services, err = client.ListServices(docker.ListServicesOptions{Status: true, Filters: map[string][]string{"name": {"web"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(services) != 1 || services[0].ServiceStatus == nil || services[0].ServiceStatus.RunningTasks != 2 {
+		t.Errorf("ListServices: wrong filtered services: %#v", services)
+	}
+}
+
+func TestServiceScaleGlobal(t *testing.T) {
+	t.Parallel()
+	server, unused := setUpSwarm(t)
+	defer server.Stop()
+	defer unused.Stop()
+	client, err := docker.NewClient(server.URL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	created, err := client.CreateService(docker.CreateServiceOptions{
+		ServiceSpec: swarm.ServiceSpec{
+			Annotations: swarm.Annotations{Name: "agent"},
+			TaskTemplate: swarm.TaskSpec{
+				ContainerSpec: &swarm.ContainerSpec{Image: "test/test"},
+			},
+			Mode: swarm.ServiceMode{Global: &swarm.GlobalService{}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = client.ScaleService(created.ID, 3); !errors.Is(err, docker.ErrServiceNotReplicated) {
+		t.Errorf("ScaleService: wrong error. Want %v. Got %v.", docker.ErrServiceNotReplicated, err)
+	}
+}
+
 func TestServiceUpdateNotFound(t *testing.T) {
 	t.Parallel()
 	server, unused := setUpSwarm(t)
@@ -1314,6 +1578,54 @@ func TestNodeUpdate(t *testing.T) {
 	}
 }
 
+func TestNodePromoteDemote(t *testing.T) {
+	t.Parallel()
+	srv1, srv2 := setUpSwarm(t)
+	defer srv1.Stop()
+	defer srv2.Stop()
+	client, err := docker.NewClient(srv1.URL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	original, err := client.InspectNode(srv2.nodeID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = client.DemoteNode(srv2.nodeID); err != nil {
+		t.Fatal(err)
+	}
+	node, err := client.InspectNode(srv2.nodeID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if node.Spec.Role != swarm.NodeRoleWorker {
+		t.Errorf("DemoteNode: wrong role. Want %q. Got %q.", swarm.NodeRoleWorker, node.Spec.Role)
+	}
+	if node.Version.Index != original.Version.Index+1 {
+		t.Errorf("DemoteNode: wrong version. Want %d. Got %d.", original.Version.Index+1, node.Version.Index)
+	}
+	if err = client.DemoteNode(srv2.nodeID); !errors.Is(err, docker.ErrNodeAlreadyInRole) {
+		t.Errorf("DemoteNode: wrong error for a worker. Want %v. Got %v.", docker.ErrNodeAlreadyInRole, err)
+	}
+	err = client.UpdateNode(srv2.nodeID, docker.UpdateNodeOptions{NodeSpec: original.Spec, Version: original.Version.Index})
+	if err == nil {
+		t.Error("UpdateNode: expected error for an outdated version, got <nil>")
+	}
+	if err = client.PromoteNode(srv2.nodeID); err != nil {
+		t.Fatal(err)
+	}
+	node, err = client.InspectNode(srv2.nodeID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if node.Spec.Role != swarm.NodeRoleManager {
+		t.Errorf("PromoteNode: wrong role. Want %q. Got %q.", swarm.NodeRoleManager, node.Spec.Role)
+	}
+	if err = client.PromoteNode(srv2.nodeID); !errors.Is(err, docker.ErrNodeAlreadyInRole) {
+		t.Errorf("PromoteNode: wrong error for a manager. Want %v. Got %v.", docker.ErrNodeAlreadyInRole, err)
+	}
+}
+
 func TestNodeDelete(t *testing.T) {
 	t.Parallel()
 	srv1, srv2 := setUpSwarm(t)
@@ -1439,3 +1751,785 @@ func TestMutateTaskNotFound(t *testing.T) {
 		t.Errorf("wrong error message. Want %q. Got %q.", "task not found", err)
 	}
 }
+
+func TestSecretLifecycle(t *testing.T) {
+	t.Parallel()
+	server, unused := setUpSwarm(t)
+	defer server.Stop()
+	defer unused.Stop()
+	client, err := docker.NewClient(server.URL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	spec := swarm.SecretSpec{
+		Annotations: swarm.Annotations{Name: "db-password", Labels: map[string]string{"env": "test"}},
+		Data:        []byte("s3cr3t"),
+	}
+	secret, err := client.CreateSecret(docker.CreateSecretOptions{SecretSpec: spec})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(server.secrets) != 1 || !bytes.Equal(server.secrets[0].Spec.Data, spec.Data) {
+		t.Fatalf("CreateSecret: secret not stored correctly. Got %#v.", server.secrets)
+	}
+	_, err = client.CreateSecret(docker.CreateSecretOptions{SecretSpec: spec})
+	if !errors.Is(err, docker.ErrSecretAlreadyExists) {
+		t.Errorf("CreateSecret: wrong error on duplicate. Want %#v. Got %#v.", docker.ErrSecretAlreadyExists, err)
+	}
+	inspected, err := client.InspectSecret(secret.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if inspected.Spec.Name != "db-password" || inspected.Spec.Data != nil {
+		t.Errorf("InspectSecret: wrong secret returned. Got %#v.", inspected)
+	}
+	secrets, err := client.ListSecrets(docker.ListSecretsOptions{Filters: map[string][]string{"name": {"db-password"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(secrets) != 1 || secrets[0].ID != secret.ID {
+		t.Errorf("ListSecrets: wrong secrets returned. Got %#v.", secrets)
+	}
+	secrets, err = client.ListSecrets(docker.ListSecretsOptions{Filters: map[string][]string{"name": {"other"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(secrets) != 0 {
+		t.Errorf("ListSecrets: expected no secrets. Got %#v.", secrets)
+	}
+	update := docker.UpdateSecretOptions{
+		SecretSpec: swarm.SecretSpec{Annotations: swarm.Annotations{Name: "db-password", Labels: map[string]string{"env": "prod"}}},
+		Version:    inspected.Version.Index,
+	}
+	if err := client.UpdateSecret(secret.ID, update); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.UpdateSecret(secret.ID, update); err == nil {
+		t.Error("UpdateSecret: expected error when updating with a stale version")
+	}
+	inspected, err = client.InspectSecret(secret.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if inspected.Spec.Labels["env"] != "prod" {
+		t.Errorf("UpdateSecret: labels not updated. Got %#v.", inspected.Spec.Labels)
+	}
+	if err := client.RemoveSecret(docker.RemoveSecretOptions{ID: secret.ID}); err != nil {
+		t.Fatal(err)
+	}
+	_, err = client.InspectSecret(secret.ID)
+	var noSuchSecret *docker.NoSuchSecret
+	if !errors.As(err, &noSuchSecret) {
+		t.Errorf("InspectSecret: wrong error after removal. Want NoSuchSecret. Got %#v.", err)
+	}
+}
+
+func TestSecretNoSwarm(t *testing.T) {
+	t.Parallel()
+	server := baseDockerServer()
+	server.buildMuxer()
+	recorder := httptest.NewRecorder()
+	request, _ := http.NewRequest(http.MethodGet, "/secrets", nil)
+	server.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusNotAcceptable {
+		t.Errorf("SecretList: wrong status code. Want %d. Got %d.", http.StatusNotAcceptable, recorder.Code)
+	}
+}
+
+func TestServiceCreateWithSecrets(t *testing.T) {
+	t.Parallel()
+	server, unused := setUpSwarm(t)
+	defer server.Stop()
+	defer unused.Stop()
+	client, err := docker.NewClient(server.URL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret, err := client.CreateSecret(docker.CreateSecretOptions{SecretSpec: swarm.SecretSpec{
+		Annotations: swarm.Annotations{Name: "db-password"},
+		Data:        []byte("s3cr3t"),
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	spec := func(secretID string) swarm.ServiceSpec {
+		return swarm.ServiceSpec{
+			Annotations: swarm.Annotations{Name: "svc-" + secretID},
+			TaskTemplate: swarm.TaskSpec{
+				ContainerSpec: &swarm.ContainerSpec{
+					Image: "test/test",
+					Secrets: []*swarm.SecretReference{{
+						SecretID:   secretID,
+						SecretName: "db-password",
+						File:       &swarm.SecretReferenceFileTarget{Name: "db-password"},
+					}},
+				},
+			},
+		}
+	}
+	_, err = client.CreateService(docker.CreateServiceOptions{ServiceSpec: spec("unknown")})
+	var e *docker.Error
+	if !errors.As(err, &e) || e.Status != http.StatusBadRequest {
+		t.Errorf("CreateService: wrong error for unknown secret. Want status %d. Got %#v.", http.StatusBadRequest, err)
+	}
+	if _, err := client.CreateService(docker.CreateServiceOptions{ServiceSpec: spec(secret.ID)}); err != nil {
+		t.Fatal(err)
+	}
+	if len(server.services) != 1 {
+		t.Errorf("CreateService: wrong number of services. Want 1. Got %d.", len(server.services))
+	}
+}
+
+func TestConfigLifecycle(t *testing.T) {
+	t.Parallel()
+	server, unused := setUpSwarm(t)
+	defer server.Stop()
+	defer unused.Stop()
+	client, err := docker.NewClient(server.URL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	spec := swarm.ConfigSpec{
+		Annotations: swarm.Annotations{Name: "nginx.conf"},
+		Data:        []byte("server {}"),
+	}
+	config, err := client.CreateConfig(docker.CreateConfigOptions{ConfigSpec: spec})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = client.CreateConfig(docker.CreateConfigOptions{ConfigSpec: spec})
+	if !errors.Is(err, docker.ErrConfigAlreadyExists) {
+		t.Errorf("CreateConfig: wrong error on duplicate. Want %#v. Got %#v.", docker.ErrConfigAlreadyExists, err)
+	}
+	inspected, err := client.InspectConfig(config.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if inspected.Spec.Name != "nginx.conf" || string(inspected.Spec.Data) != "server {}" {
+		t.Errorf("InspectConfig: wrong config returned. Got %#v.", inspected)
+	}
+	configs, err := client.ListConfigs(docker.ListConfigsOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(configs) != 1 || configs[0].ID != config.ID {
+		t.Errorf("ListConfigs: wrong configs returned. Got %#v.", configs)
+	}
+	update := docker.UpdateConfigOptions{
+		ConfigSpec: swarm.ConfigSpec{Annotations: swarm.Annotations{Name: "nginx.conf", Labels: map[string]string{"tier": "web"}}},
+		Version:    inspected.Version.Index,
+	}
+	if err := client.UpdateConfig(config.ID, update); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.UpdateConfig(config.ID, update); err == nil {
+		t.Error("UpdateConfig: expected error when updating with a stale version")
+	}
+	update.Version++
+	update.Data = []byte("server { listen 80; }")
+	if err := client.UpdateConfig(config.ID, update); err == nil {
+		t.Error("UpdateConfig: expected error when changing the config data")
+	}
+	if err := client.RemoveConfig(docker.RemoveConfigOptions{ID: config.ID}); err != nil {
+		t.Fatal(err)
+	}
+	_, err = client.InspectConfig(config.ID)
+	var noSuchConfig *docker.NoSuchConfig
+	if !errors.As(err, &noSuchConfig) {
+		t.Errorf("InspectConfig: wrong error after removal. Want NoSuchConfig. Got %#v.", err)
+	}
+}
+
+func TestServiceCreateWithConfigs(t *testing.T) {
+	t.Parallel()
+	server, unused := setUpSwarm(t)
+	defer server.Stop()
+	defer unused.Stop()
+	client, err := docker.NewClient(server.URL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	config, err := client.CreateConfig(docker.CreateConfigOptions{ConfigSpec: swarm.ConfigSpec{
+		Annotations: swarm.Annotations{Name: "nginx.conf"},
+		Data:        []byte("server {}"),
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	spec := func(configID string) swarm.ServiceSpec {
+		return swarm.ServiceSpec{
+			Annotations: swarm.Annotations{Name: "svc-" + configID},
+			TaskTemplate: swarm.TaskSpec{
+				ContainerSpec: &swarm.ContainerSpec{
+					Image: "nginx",
+					Configs: []*swarm.ConfigReference{{
+						ConfigID:   configID,
+						ConfigName: "nginx.conf",
+						File:       &swarm.ConfigReferenceFileTarget{Name: "/etc/nginx/nginx.conf"},
+					}},
+				},
+			},
+		}
+	}
+	_, err = client.CreateService(docker.CreateServiceOptions{ServiceSpec: spec("unknown")})
+	var e *docker.Error
+	if !errors.As(err, &e) || e.Status != http.StatusBadRequest {
+		t.Errorf("CreateService: wrong error for unknown config. Want status %d. Got %#v.", http.StatusBadRequest, err)
+	}
+	if _, err := client.CreateService(docker.CreateServiceOptions{ServiceSpec: spec(config.ID)}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestServiceLogs(t *testing.T) {
+	t.Parallel()
+	server, unused := setUpSwarm(t)
+	defer server.Stop()
+	defer unused.Stop()
+	srv, err := addTestService(server)
+	if err != nil {
+		t.Fatal(err)
+	}
+	task := server.tasks[0]
+	server.SetContainerLogs(task.Status.ContainerStatus.ContainerID, "line 1\nline 2\n", "oops\n")
+	client, err := docker.NewClient(server.URL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var stdout, stderr bytes.Buffer
+	err = client.GetServiceLogs(docker.LogsServiceOptions{
+		Service:      srv.ID,
+		OutputStream: &stdout,
+		ErrorStream:  &stderr,
+		Stdout:       true,
+		Stderr:       true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "line 1\nline 2\n"; stdout.String() != expected {
+		t.Errorf("ServiceLogs: wrong stdout. Want %q. Got %q.", expected, stdout.String())
+	}
+	if expected := "oops\n"; stderr.String() != expected {
+		t.Errorf("ServiceLogs: wrong stderr. Want %q. Got %q.", expected, stderr.String())
+	}
+	stdout.Reset()
+	err = client.GetServiceLogs(docker.LogsServiceOptions{
+		Service:      srv.Spec.Name,
+		OutputStream: &stdout,
+		ErrorStream:  ioutil.Discard,
+		Stdout:       true,
+		Details:      true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	prefix := fmt.Sprintf("com.docker.swarm.node.id=%s,com.docker.swarm.service.id=%s,com.docker.swarm.task.id=%s ", task.NodeID, srv.ID, task.ID)
+	if expected := prefix + "line 1\n" + prefix + "line 2\n"; stdout.String() != expected {
+		t.Errorf("ServiceLogs: wrong stdout with details. Want %q. Got %q.", expected, stdout.String())
+	}
+}
+
+func TestServiceLogsNotFound(t *testing.T) {
+	t.Parallel()
+	server, unused := setUpSwarm(t)
+	defer server.Stop()
+	defer unused.Stop()
+	recorder := httptest.NewRecorder()
+	request, _ := http.NewRequest(http.MethodGet, "/services/unknown/logs?stdout=1", nil)
+	server.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusNotFound {
+		t.Errorf("ServiceLogs: wrong status code. Want %d. Got %d.", http.StatusNotFound, recorder.Code)
+	}
+}
+
+func TestTaskLogs(t *testing.T) {
+	t.Parallel()
+	server, unused := setUpSwarm(t)
+	defer server.Stop()
+	defer unused.Stop()
+	srv, err := addTestService(server)
+	if err != nil {
+		t.Fatal(err)
+	}
+	task := server.tasks[0]
+	server.SetContainerLogs(task.Status.ContainerStatus.ContainerID, "line 1\nline 2\n", "oops\n")
+	client, err := docker.NewClient(server.URL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var stdout, stderr bytes.Buffer
+	err = client.GetTaskLogs(docker.LogsTaskOptions{
+		Task:         task.ID,
+		OutputStream: &stdout,
+		ErrorStream:  &stderr,
+		Stdout:       true,
+		Stderr:       true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "line 1\nline 2\n"; stdout.String() != expected {
+		t.Errorf("TaskLogs: wrong stdout. Want %q. Got %q.", expected, stdout.String())
+	}
+	if expected := "oops\n"; stderr.String() != expected {
+		t.Errorf("TaskLogs: wrong stderr. Want %q. Got %q.", expected, stderr.String())
+	}
+	stdout.Reset()
+	err = client.GetTaskLogs(docker.LogsTaskOptions{
+		Task:         task.ID,
+		OutputStream: &stdout,
+		ErrorStream:  ioutil.Discard,
+		Stdout:       true,
+		Details:      true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	prefix := fmt.Sprintf("com.docker.swarm.node.id=%s,com.docker.swarm.service.id=%s,com.docker.swarm.task.id=%s ", task.NodeID, srv.ID, task.ID)
+	if expected := prefix + "line 1\n" + prefix + "line 2\n"; stdout.String() != expected {
+		t.Errorf("TaskLogs: wrong stdout with details. Want %q. Got %q.", expected, stdout.String())
+	}
+	err = client.GetTaskLogs(docker.LogsTaskOptions{Task: "unknown", Stdout: true})
+	var e *docker.NoSuchTask
+	if !errors.As(err, &e) {
+		t.Errorf("TaskLogs: wrong error for unknown task. Want NoSuchTask. Got %#v.", err)
+	}
+}
+
+func TestNodeListFilters(t *testing.T) {
+	t.Parallel()
+	srv1, srv2 := setUpSwarm(t)
+	defer srv1.Stop()
+	defer srv2.Stop()
+	srv1.swarmMut.Lock()
+	srv1.nodes = append(srv1.nodes, swarm.Node{
+		ID: "worker-1",
+		Spec: swarm.NodeSpec{
+			Role:         swarm.NodeRoleWorker,
+			Availability: swarm.NodeAvailabilityDrain,
+		},
+	})
+	srv1.swarmMut.Unlock()
+	var tests = []struct {
+		filters  map[string][]string
+		expected int
+	}{
+		{map[string][]string{"role": {"manager"}}, 2},
+		{map[string][]string{"role": {"worker"}}, 1},
+		{map[string][]string{"availability": {"drain"}}, 1},
+		{map[string][]string{"availability": {"active"}, "role": {"manager"}}, 2},
+		{map[string][]string{"membership": {"pending"}}, 0},
+		{map[string][]string{"id": {"worker-1"}}, 1},
+	}
+	for _, tt := range tests {
+		data, _ := json.Marshal(tt.filters)
+		recorder := httptest.NewRecorder()
+		request, _ := http.NewRequest(http.MethodGet, "/nodes?filters="+url.QueryEscape(string(data)), nil)
+		srv1.ServeHTTP(recorder, request)
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("NodeList: invalid status code: %d", recorder.Code)
+		}
+		var nodes []swarm.Node
+		if err := json.NewDecoder(recorder.Body).Decode(&nodes); err != nil {
+			t.Fatal(err)
+		}
+		if len(nodes) != tt.expected {
+			t.Errorf("NodeList(%v): wrong number of nodes. Want %d. Got %d.", tt.filters, tt.expected, len(nodes))
+		}
+	}
+}
+
+func TestServiceUpdateRollback(t *testing.T) {
+	t.Parallel()
+	server, unused := setUpSwarm(t)
+	defer server.Stop()
+	defer unused.Stop()
+	client, err := docker.NewClient(server.URL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv, err := addTestService(server)
+	if err != nil {
+		t.Fatal(err)
+	}
+	originalImage := srv.Spec.TaskTemplate.ContainerSpec.Image
+	err = client.UpdateService(srv.ID, docker.UpdateServiceOptions{Version: 1, Rollback: "previous"})
+	var e *docker.Error
+	if !errors.As(err, &e) || e.Status != http.StatusBadRequest {
+		t.Errorf("UpdateService: expected rollback without previous spec to fail with %d. Got %#v.", http.StatusBadRequest, err)
+	}
+	current, err := client.InspectService(srv.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newSpec := current.Spec
+	newSpec.TaskTemplate.ContainerSpec = &swarm.ContainerSpec{Image: "test/test2"}
+	err = client.UpdateService(srv.ID, docker.UpdateServiceOptions{ServiceSpec: newSpec, Version: current.Version.Index + 1})
+	if !errors.As(err, &e) || e.Status != http.StatusBadRequest {
+		t.Errorf("UpdateService: expected stale version to fail with %d. Got %#v.", http.StatusBadRequest, err)
+	}
+	server.swarmMut.Lock()
+	server.services[0].Version.Index = 5
+	server.swarmMut.Unlock()
+	err = client.UpdateService(srv.ID, docker.UpdateServiceOptions{ServiceSpec: newSpec, Version: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	updated, err := client.InspectService(srv.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Version.Index != 6 {
+		t.Errorf("UpdateService: wrong version. Want 6. Got %d.", updated.Version.Index)
+	}
+	if updated.PreviousSpec == nil || updated.PreviousSpec.TaskTemplate.ContainerSpec.Image != originalImage {
+		t.Fatalf("UpdateService: wrong previous spec. Got %#v.", updated.PreviousSpec)
+	}
+	err = client.UpdateService(srv.ID, docker.UpdateServiceOptions{Version: updated.Version.Index, Rollback: "previous"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rolledBack, err := client.InspectService(srv.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if image := rolledBack.Spec.TaskTemplate.ContainerSpec.Image; image != originalImage {
+		t.Errorf("UpdateService: rollback did not restore the previous spec. Want image %q. Got %q.", originalImage, image)
+	}
+}
+
+func TestServiceUpdateForceUpdate(t *testing.T) {
+	t.Parallel()
+	server, unused := setUpSwarm(t)
+	defer server.Stop()
+	defer unused.Stop()
+	client, err := docker.NewClient(server.URL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv, err := addTestService(server)
+	if err != nil {
+		t.Fatal(err)
+	}
+	taskIDs := func() []string {
+		tasks, err := client.ListTasks(docker.ListTasksOptions{Filters: map[string][]string{"service": {srv.ID}}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var ids []string
+		for _, task := range tasks {
+			ids = append(ids, task.ID)
+		}
+		return ids
+	}
+	originalTasks := taskIDs()
+	if len(originalTasks) == 0 {
+		t.Fatal("UpdateService: the service has no tasks")
+	}
+	current, err := client.InspectService(srv.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = client.UpdateService(srv.ID, docker.UpdateServiceOptions{ServiceSpec: current.Spec, Version: current.Version.Index})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tasks := taskIDs(); !reflect.DeepEqual(tasks, originalTasks) {
+		t.Errorf("UpdateService: tasks were redeployed without changes. Want %v. Got %v.", originalTasks, tasks)
+	}
+	current, err = client.InspectService(srv.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = client.UpdateService(srv.ID, docker.UpdateServiceOptions{ServiceSpec: current.Spec, Version: current.Version.Index, ForceUpdate: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	updated, err := client.InspectService(srv.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Spec.TaskTemplate.ForceUpdate != current.Spec.TaskTemplate.ForceUpdate+1 {
+		t.Errorf("UpdateService: wrong ForceUpdate. Want %d. Got %d.", current.Spec.TaskTemplate.ForceUpdate+1, updated.Spec.TaskTemplate.ForceUpdate)
+	}
+	tasks := taskIDs()
+	if len(tasks) != len(originalTasks) {
+		t.Fatalf("UpdateService: wrong number of tasks. Want %d. Got %d.", len(originalTasks), len(tasks))
+	}
+	for _, id := range tasks {
+		for _, original := range originalTasks {
+			if id == original {
+				t.Errorf("UpdateService: task %q was not redeployed", id)
+			}
+		}
+	}
+}
+
+func TestClusterVolumeUpdate(t *testing.T) {
+	t.Parallel()
+	server, _ := setUpSwarm(t)
+	defer server.Stop()
+	data, _ := json.Marshal(docker.CreateVolumeOptions{
+		Name:              "shared",
+		Driver:            "csi-driver",
+		ClusterVolumeSpec: &docker.ClusterVolumeSpec{Group: "storage"},
+	})
+	recorder := httptest.NewRecorder()
+	request, _ := http.NewRequest(http.MethodPost, "/volumes/create", bytes.NewReader(data))
+	server.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusCreated {
+		t.Fatalf("CreateVolume: wrong status. Want %d. Got %d.", http.StatusCreated, recorder.Code)
+	}
+	var volume docker.Volume
+	json.NewDecoder(recorder.Body).Decode(&volume)
+	if volume.ClusterVolume == nil || volume.ClusterVolume.Spec.Availability != docker.VolumeAvailabilityActive {
+		t.Fatalf("CreateVolume: expected an active cluster volume. Got %#v.", volume.ClusterVolume)
+	}
+	spec := volume.ClusterVolume.Spec
+	spec.Availability = docker.VolumeAvailabilityDrain
+	data, _ = json.Marshal(map[string]docker.ClusterVolumeSpec{"Spec": spec})
+	for _, tt := range []struct {
+		version  uint64
+		expected int
+	}{
+		{version: 2, expected: http.StatusBadRequest},
+		{version: 1, expected: http.StatusOK},
+		{version: 1, expected: http.StatusBadRequest},
+	} {
+		recorder = httptest.NewRecorder()
+		request, _ = http.NewRequest(http.MethodPut, fmt.Sprintf("/volumes/shared?version=%d", tt.version), bytes.NewReader(data))
+		server.ServeHTTP(recorder, request)
+		if recorder.Code != tt.expected {
+			t.Errorf("UpdateVolume: wrong status for version %d. Want %d. Got %d.", tt.version, tt.expected, recorder.Code)
+		}
+	}
+	recorder = httptest.NewRecorder()
+	request, _ = http.NewRequest(http.MethodGet, "/volumes/shared", nil)
+	server.ServeHTTP(recorder, request)
+	volume = docker.Volume{}
+	json.NewDecoder(recorder.Body).Decode(&volume)
+	if volume.ClusterVolume.Spec.Availability != docker.VolumeAvailabilityDrain {
+		t.Errorf("UpdateVolume: availability not updated. Got %q.", volume.ClusterVolume.Spec.Availability)
+	}
+	if volume.ClusterVolume.Version.Index != 2 {
+		t.Errorf("UpdateVolume: wrong version. Want 2. Got %d.", volume.ClusterVolume.Version.Index)
+	}
+}
+
+func TestClusterVolumeUpdateNoSwarm(t *testing.T) {
+	t.Parallel()
+	server := baseDockerServer()
+	server.buildMuxer()
+	server.volStore = map[string]*volumeCounter{"local": {volume: docker.Volume{Name: "local"}}}
+	recorder := httptest.NewRecorder()
+	request, _ := http.NewRequest(http.MethodPut, "/volumes/local?version=1", strings.NewReader(`{"Spec":{}}`))
+	server.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusServiceUnavailable {
+		t.Errorf("UpdateVolume: wrong status. Want %d. Got %d.", http.StatusServiceUnavailable, recorder.Code)
+	}
+}
+
+func TestSwarmUpdate(t *testing.T) {
+	t.Parallel()
+	server, err := NewServer("127.0.0.1:0", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Stop()
+	client, err := docker.NewClient(server.URL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := client.UpdateSwarm(docker.UpdateSwarmOptions{}); !errors.Is(err, docker.ErrNodeNotInSwarm) {
+		t.Fatalf("SwarmUpdate: wrong error before init. Want %v. Got %v.", docker.ErrNodeNotInSwarm, err)
+	}
+	if _, err := client.InitSwarm(docker.InitSwarmOptions{InitRequest: swarm.InitRequest{ListenAddr: "127.0.0.1:0"}}); err != nil {
+		t.Fatal(err)
+	}
+	before, err := client.InspectSwarm(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	spec := before.Spec
+	spec.Raft.SnapshotInterval = 5000
+	spec.EncryptionConfig.AutoLockManagers = true
+	err = client.UpdateSwarm(docker.UpdateSwarmOptions{
+		Version:                int(before.Version.Index),
+		RotateWorkerToken:      true,
+		RotateManagerUnlockKey: true,
+		Swarm:                  spec,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	after, err := client.InspectSwarm(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if after.Version.Index != before.Version.Index+1 {
+		t.Errorf("SwarmUpdate: wrong version. Want %d. Got %d.", before.Version.Index+1, after.Version.Index)
+	}
+	if !reflect.DeepEqual(after.Spec, spec) {
+		t.Errorf("SwarmUpdate: wrong spec. Want %#v. Got %#v.", spec, after.Spec)
+	}
+	if after.JoinTokens.Worker == before.JoinTokens.Worker {
+		t.Error("SwarmUpdate: worker token wasn't rotated")
+	}
+	if after.JoinTokens.Manager != before.JoinTokens.Manager {
+		t.Error("SwarmUpdate: manager token was rotated")
+	}
+	err = client.UpdateSwarm(docker.UpdateSwarmOptions{Version: int(before.Version.Index), Swarm: spec})
+	var e *docker.Error
+	if !errors.As(err, &e) || e.Status != http.StatusBadRequest {
+		t.Errorf("SwarmUpdate: wrong error with outdated version. Want 400. Got %v.", err)
+	}
+}
+
+func TestSwarmLockUnlock(t *testing.T) {
+	t.Parallel()
+	server, err := NewServer("127.0.0.1:0", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Stop()
+	client, err := docker.NewClient(server.URL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var spec swarm.Spec
+	spec.EncryptionConfig.AutoLockManagers = true
+	if _, err := client.InitSwarm(docker.InitSwarmOptions{InitRequest: swarm.InitRequest{ListenAddr: "127.0.0.1:0", Spec: spec}}); err != nil {
+		t.Fatal(err)
+	}
+	key, err := client.GetSwarmUnlockKey(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(key, "SWMKEY-1-") {
+		t.Fatalf("GetSwarmUnlockKey: wrong key %q", key)
+	}
+	if err := server.LockSwarm(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.InspectSwarm(context.Background()); !errors.Is(err, docker.ErrSwarmLocked) {
+		t.Errorf("InspectSwarm: wrong error on locked swarm. Want %v. Got %v.", docker.ErrSwarmLocked, err)
+	}
+	if _, err := client.ListServices(docker.ListServicesOptions{}); err == nil || !strings.Contains(err.Error(), "swarm is locked") {
+		t.Errorf("ListServices: wrong error on locked swarm: %v", err)
+	}
+	if _, err := client.GetSwarmUnlockKey(context.Background()); !errors.Is(err, docker.ErrSwarmLocked) {
+		t.Errorf("GetSwarmUnlockKey: wrong error on locked swarm. Want %v. Got %v.", docker.ErrSwarmLocked, err)
+	}
+	var e *docker.Error
+	if err := client.UnlockSwarm(docker.UnlockSwarmOptions{UnlockKey: "SWMKEY-1-wrong"}); !errors.As(err, &e) || e.Status != http.StatusBadRequest {
+		t.Errorf("UnlockSwarm: wrong error with invalid key: %v", err)
+	}
+	if err := client.UnlockSwarm(docker.UnlockSwarmOptions{UnlockKey: key}); err != nil {
+		t.Fatal(err)
+	}
+	info, err := client.InspectSwarm(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := client.UnlockSwarm(docker.UnlockSwarmOptions{UnlockKey: key}); err == nil {
+		t.Error("UnlockSwarm: expected error on unlocked swarm")
+	}
+	err = client.UpdateSwarm(docker.UpdateSwarmOptions{Version: int(info.Version.Index), RotateManagerUnlockKey: true, Swarm: info.Spec})
+	if err != nil {
+		t.Fatal(err)
+	}
+	newKey, err := client.GetSwarmUnlockKey(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if newKey == key || newKey == "" {
+		t.Errorf("UpdateSwarm: unlock key wasn't rotated. Old %q. New %q.", key, newKey)
+	}
+}
+
+func TestLockSwarmWithoutAutolock(t *testing.T) {
+	t.Parallel()
+	server, _ := setUpSwarm(t)
+	defer server.Stop()
+	if err := server.LockSwarm(); err == nil {
+		t.Error("LockSwarm: expected error when autolock is disabled")
+	}
+}
+
+func TestInspectNetworkVerbose(t *testing.T) {
+	t.Parallel()
+	server, unused := setUpSwarm(t)
+	defer server.Stop()
+	defer unused.Stop()
+	client, err := docker.NewClient(server.URL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	network, err := client.CreateNetwork(docker.CreateNetworkOptions{
+		Name:   "overlay",
+		Driver: "overlay",
+		Scope:  "swarm",
+		IPAM:   &docker.IPAMOptions{Config: []docker.IPAMConfig{{Subnet: "10.0.0.0/24"}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	replicas := uint64(2)
+	_, err = client.CreateService(docker.CreateServiceOptions{
+		ServiceSpec: swarm.ServiceSpec{
+			Annotations: swarm.Annotations{Name: "web"},
+			Mode:        swarm.ServiceMode{Replicated: &swarm.ReplicatedService{Replicas: &replicas}},
+			TaskTemplate: swarm.TaskSpec{
+				ContainerSpec: &swarm.ContainerSpec{Image: "nginx"},
+				Networks:      []swarm.NetworkAttachmentConfig{{Target: "overlay"}},
+			},
+			EndpointSpec: &swarm.EndpointSpec{
+				Ports: []swarm.PortConfig{{TargetPort: 80, PublishedPort: 8080}},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := client.InspectNetworkWithOptions(docker.InspectNetworkOptions{ID: network.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Services != nil {
+		t.Errorf("InspectNetwork: unexpected services without verbose: %#v", info.Services)
+	}
+	info, err = client.InspectNetworkWithOptions(docker.InspectNetworkOptions{ID: network.ID, Verbose: true, Scope: "swarm"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	service, ok := info.Services["web"]
+	if !ok {
+		t.Fatalf("InspectNetwork: service not reported: %#v", info.Services)
+	}
+	if service.VIP != "10.0.0.2" {
+		t.Errorf("InspectNetwork: wrong VIP. Want %q. Got %q.", "10.0.0.2", service.VIP)
+	}
+	if expected := []string{"Target: 80, Publish: 8080"}; !reflect.DeepEqual(service.Ports, expected) {
+		t.Errorf("InspectNetwork: wrong ports. Want %#v. Got %#v.", expected, service.Ports)
+	}
+	if len(service.Tasks) != 2 {
+		t.Fatalf("InspectNetwork: wrong number of tasks. Want 2. Got %d.", len(service.Tasks))
+	}
+	for i, task := range service.Tasks {
+		if expected := fmt.Sprintf("10.0.0.%d", i+3); task.EndpointIP != expected {
+			t.Errorf("InspectNetwork: wrong IP for task %d. Want %q. Got %q.", i, expected, task.EndpointIP)
+		}
+		if !strings.HasPrefix(task.Name, fmt.Sprintf("web.%d.", i+1)) {
+			t.Errorf("InspectNetwork: wrong name for task %d: %q", i, task.Name)
+		}
+		if task.Info["Host IP"] != "127.0.0.1" {
+			t.Errorf("InspectNetwork: wrong host IP for task %d: %q", i, task.Info["Host IP"])
+		}
+	}
+	_, err = client.InspectNetworkWithOptions(docker.InspectNetworkOptions{ID: network.ID, Scope: "local"})
+	var noSuchNetwork *docker.NoSuchNetwork
+	if !errors.As(err, &noSuchNetwork) {
+		t.Errorf("InspectNetwork: wrong error with mismatched scope. Want NoSuchNetwork. Got %#v.", err)
+	}
+}
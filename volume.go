@@ -8,8 +8,12 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
+	"regexp"
 	"time"
+
+	"github.com/docker/docker/api/types/swarm"
 )
 
 var (
@@ -18,8 +22,23 @@ var (
 
 	// ErrVolumeInUse is the error returned when the volume requested to be removed is still in use.
 	ErrVolumeInUse = errors.New("volume in use and cannot be removed")
+
+	// ErrClusterVolumesUnavailable is the error returned by UpdateVolume
+	// when the daemon is not a swarm manager, and thus can't manage cluster
+	// volumes.
+	ErrClusterVolumesUnavailable = errors.New("cluster volumes require a swarm manager")
+
+	// ErrInvalidVolumeName is the error returned by CreateVolume when the
+	// name of a local volume contains characters not allowed by the daemon.
+	ErrInvalidVolumeName = errors.New("invalid volume name, only [a-zA-Z0-9][a-zA-Z0-9_.-] are allowed")
+
+	// ErrInvalidVolumeDriverOpt is the error returned by CreateVolume when
+	// one of the driver options has an empty key.
+	ErrInvalidVolumeDriverOpt = errors.New("invalid volume driver option, keys must not be empty")
 )
 
+var volumeNameRegexp = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]+$`)
+
 // Volume represents a volume.
 //
 // See https://goo.gl/3wgTsd for more details.
@@ -30,6 +49,109 @@ type Volume struct {
 	Labels     map[string]string `json:"Labels,omitempty" yaml:"Labels,omitempty" toml:"Labels,omitempty"`
 	Options    map[string]string `json:"Options,omitempty" yaml:"Options,omitempty" toml:"Options,omitempty"`
 	CreatedAt  time.Time         `json:"CreatedAt,omitempty" yaml:"CreatedAt,omitempty" toml:"CreatedAt,omitempty"`
+	UsageData  *VolumeUsageData  `json:"UsageData,omitempty" yaml:"UsageData,omitempty" toml:"UsageData,omitempty"`
+
+	// ClusterVolume is set only for swarm cluster volumes, backed by a CSI
+	// plugin.
+	ClusterVolume *ClusterVolume `json:"ClusterVolume,omitempty" yaml:"ClusterVolume,omitempty" toml:"ClusterVolume,omitempty"`
+}
+
+// ClusterVolume contains the swarm specific information of a cluster volume.
+//
+// Cluster volumes require Docker API 1.42 or greater.
+type ClusterVolume struct {
+	ID string `json:"ID" yaml:"ID" toml:"ID"`
+	swarm.Meta
+	Spec          ClusterVolumeSpec      `json:"Spec" yaml:"Spec" toml:"Spec"`
+	PublishStatus []*VolumePublishStatus `json:"PublishStatus,omitempty" yaml:"PublishStatus,omitempty" toml:"PublishStatus,omitempty"`
+	Info          *ClusterVolumeInfo     `json:"Info,omitempty" yaml:"Info,omitempty" toml:"Info,omitempty"`
+}
+
+// VolumeAvailability is the availability of a cluster volume, which
+// determines whether new tasks may be scheduled with it.
+type VolumeAvailability string
+
+const (
+	// VolumeAvailabilityActive allows the volume to be used by new tasks.
+	VolumeAvailabilityActive VolumeAvailability = "active"
+
+	// VolumeAvailabilityPause prevents new tasks from using the volume,
+	// keeping the existing ones running.
+	VolumeAvailabilityPause VolumeAvailability = "pause"
+
+	// VolumeAvailabilityDrain prevents new tasks from using the volume and
+	// removes the tasks currently using it.
+	VolumeAvailabilityDrain VolumeAvailability = "drain"
+)
+
+// ClusterVolumeSpec is the specification of a cluster volume.
+type ClusterVolumeSpec struct {
+	// Group of volumes, that can be referenced in a service mount using
+	// the "group:" prefix.
+	Group                     string                     `json:"Group,omitempty" yaml:"Group,omitempty" toml:"Group,omitempty"`
+	AccessMode                *VolumeAccessMode          `json:"AccessMode,omitempty" yaml:"AccessMode,omitempty" toml:"AccessMode,omitempty"`
+	AccessibilityRequirements *VolumeTopologyRequirement `json:"AccessibilityRequirements,omitempty" yaml:"AccessibilityRequirements,omitempty" toml:"AccessibilityRequirements,omitempty"`
+	CapacityRange             *VolumeCapacityRange       `json:"CapacityRange,omitempty" yaml:"CapacityRange,omitempty" toml:"CapacityRange,omitempty"`
+	Secrets                   []VolumeSecret             `json:"Secrets,omitempty" yaml:"Secrets,omitempty" toml:"Secrets,omitempty"`
+	Availability              VolumeAvailability         `json:"Availability,omitempty" yaml:"Availability,omitempty" toml:"Availability,omitempty"`
+}
+
+// VolumeAccessMode defines how a cluster volume may be accessed.
+type VolumeAccessMode struct {
+	// Scope is either "single" (one node at a time) or "multi".
+	Scope string `json:"Scope,omitempty" yaml:"Scope,omitempty" toml:"Scope,omitempty"`
+	// Sharing is one of "none", "readonly", "onewriter" or "all".
+	Sharing     string             `json:"Sharing,omitempty" yaml:"Sharing,omitempty" toml:"Sharing,omitempty"`
+	MountVolume *VolumeMountVolume `json:"MountVolume,omitempty" yaml:"MountVolume,omitempty" toml:"MountVolume,omitempty"`
+	BlockVolume *struct{}          `json:"BlockVolume,omitempty" yaml:"BlockVolume,omitempty" toml:"BlockVolume,omitempty"`
+}
+
+// VolumeMountVolume holds the options of a cluster volume used as a
+// filesystem mount.
+type VolumeMountVolume struct {
+	FsType     string   `json:"FsType,omitempty" yaml:"FsType,omitempty" toml:"FsType,omitempty"`
+	MountFlags []string `json:"MountFlags,omitempty" yaml:"MountFlags,omitempty" toml:"MountFlags,omitempty"`
+}
+
+// VolumeTopologyRequirement expresses where a cluster volume must, or
+// should preferably, be accessible from.
+type VolumeTopologyRequirement struct {
+	Requisite []VolumeTopology `json:"Requisite,omitempty" yaml:"Requisite,omitempty" toml:"Requisite,omitempty"`
+	Preferred []VolumeTopology `json:"Preferred,omitempty" yaml:"Preferred,omitempty" toml:"Preferred,omitempty"`
+}
+
+// VolumeTopology is a set of segments, like region or zone, defined by the
+// CSI plugin.
+type VolumeTopology struct {
+	Segments map[string]string `json:"Segments,omitempty" yaml:"Segments,omitempty" toml:"Segments,omitempty"`
+}
+
+// VolumeCapacityRange is the range of sizes acceptable for a cluster volume.
+type VolumeCapacityRange struct {
+	RequiredBytes int64 `json:"RequiredBytes,omitempty" yaml:"RequiredBytes,omitempty" toml:"RequiredBytes,omitempty"`
+	LimitBytes    int64 `json:"LimitBytes,omitempty" yaml:"LimitBytes,omitempty" toml:"LimitBytes,omitempty"`
+}
+
+// VolumeSecret references a swarm secret passed to the CSI plugin.
+type VolumeSecret struct {
+	Key    string `json:"Key,omitempty" yaml:"Key,omitempty" toml:"Key,omitempty"`
+	Secret string `json:"Secret,omitempty" yaml:"Secret,omitempty" toml:"Secret,omitempty"`
+}
+
+// VolumePublishStatus represents the status of a cluster volume on a node.
+type VolumePublishStatus struct {
+	NodeID         string            `json:"NodeID,omitempty" yaml:"NodeID,omitempty" toml:"NodeID,omitempty"`
+	State          string            `json:"State,omitempty" yaml:"State,omitempty" toml:"State,omitempty"`
+	PublishContext map[string]string `json:"PublishContext,omitempty" yaml:"PublishContext,omitempty" toml:"PublishContext,omitempty"`
+}
+
+// ClusterVolumeInfo holds the information about a cluster volume reported by
+// its CSI plugin.
+type ClusterVolumeInfo struct {
+	CapacityBytes      int64             `json:"CapacityBytes,omitempty" yaml:"CapacityBytes,omitempty" toml:"CapacityBytes,omitempty"`
+	VolumeContext      map[string]string `json:"VolumeContext,omitempty" yaml:"VolumeContext,omitempty" toml:"VolumeContext,omitempty"`
+	VolumeID           string            `json:"VolumeID,omitempty" yaml:"VolumeID,omitempty" toml:"VolumeID,omitempty"`
+	AccessibleTopology []VolumeTopology  `json:"AccessibleTopology,omitempty" yaml:"AccessibleTopology,omitempty" toml:"AccessibleTopology,omitempty"`
 }
 
 // ListVolumesOptions specify parameters to the ListVolumes function.
@@ -79,12 +201,30 @@ type CreateVolumeOptions struct {
 	DriverOpts map[string]string
 	Context    context.Context `json:"-"`
 	Labels     map[string]string
+
+	// ClusterVolumeSpec creates a swarm cluster volume when set. It requires
+	// Docker API 1.42 or greater and a CSI volume plugin.
+	ClusterVolumeSpec *ClusterVolumeSpec `json:",omitempty"`
 }
 
 // CreateVolume creates a volume on the server.
 //
+// The name of local volumes, when not empty, and the driver options are
+// checked before sending the request, returning ErrInvalidVolumeName or
+// ErrInvalidVolumeDriverOpt instead of the daemon's error. The names of
+// volumes using other drivers are left for the driver to validate.
+//
 // See https://goo.gl/qEhmEC for more details.
 func (c *Client) CreateVolume(opts CreateVolumeOptions) (*Volume, error) {
+	isLocal := opts.Driver == "" || opts.Driver == "local"
+	if isLocal && opts.Name != "" && !volumeNameRegexp.MatchString(opts.Name) {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidVolumeName, opts.Name)
+	}
+	for key := range opts.DriverOpts {
+		if key == "" {
+			return nil, ErrInvalidVolumeDriverOpt
+		}
+	}
 	resp, err := c.do(http.MethodPost, "/volumes/create", doOptions{
 		data:    opts,
 		context: opts.Context,
@@ -160,6 +300,56 @@ func (c *Client) RemoveVolumeWithOptions(opts RemoveVolumeOptions) error {
 	return nil
 }
 
+// UpdateVolumeOptions specify parameters to the UpdateVolume function.
+//
+// See https://docs.docker.com/engine/api/v1.42/#operation/VolumeUpdate for
+// more details.
+type UpdateVolumeOptions struct {
+	// Version is the current version of the cluster volume, as returned by
+	// InspectVolume, used to detect conflicting concurrent updates.
+	Version uint64 `qs:"version"`
+
+	// Spec replaces the whole specification of the cluster volume, so it
+	// should be based on the one returned by InspectVolume.
+	Spec    ClusterVolumeSpec `qs:"-"`
+	Context context.Context
+}
+
+// UpdateVolume updates the specification of a swarm cluster volume, for
+// instance to change its availability before draining a storage node.
+//
+// Cluster volumes require Docker API 1.42 or greater.
+//
+// See https://docs.docker.com/engine/api/v1.42/#operation/VolumeUpdate for
+// more details.
+func (c *Client) UpdateVolume(name string, opts UpdateVolumeOptions) error {
+	if c.serverAPIVersion == nil {
+		c.checkAPIVersion()
+	}
+	if c.serverAPIVersion != nil && c.serverAPIVersion.LessThan(apiVersion142) {
+		return errors.New("cluster volumes are only supported in API#1.42 and above")
+	}
+	path := "/volumes/" + name + "?" + queryString(opts)
+	resp, err := c.do(http.MethodPut, path, doOptions{
+		data:    map[string]ClusterVolumeSpec{"Spec": opts.Spec},
+		context: opts.Context,
+	})
+	if err != nil {
+		var e *Error
+		if errors.As(err, &e) {
+			if e.Status == http.StatusNotFound {
+				return ErrNoSuchVolume
+			}
+			if e.Status == http.StatusServiceUnavailable {
+				return ErrClusterVolumesUnavailable
+			}
+		}
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
 // PruneVolumesOptions specify parameters to the PruneVolumes function.
 //
 // See https://goo.gl/f9XDem for more details.
@@ -83,6 +83,52 @@ func TestCreateVolume(t *testing.T) {
 	}
 }
 
+func TestCreateVolumeInvalidOptions(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name string
+		opts CreateVolumeOptions
+		err  error
+	}{
+		{"invalid name", CreateVolumeOptions{Name: "my/volume"}, ErrInvalidVolumeName},
+		{"leading dash", CreateVolumeOptions{Name: "-volume"}, ErrInvalidVolumeName},
+		{"single character", CreateVolumeOptions{Name: "v"}, ErrInvalidVolumeName},
+		{"invalid local name", CreateVolumeOptions{Name: "my/volume", Driver: "local"}, ErrInvalidVolumeName},
+		{"empty driver opt", CreateVolumeOptions{Name: "tardis", DriverOpts: map[string]string{"": "bar"}}, ErrInvalidVolumeDriverOpt},
+	}
+	for _, tt := range tests {
+		test := tt
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			fakeRT := &FakeRoundTripper{message: "{}", status: http.StatusOK}
+			client := newTestClient(fakeRT)
+			_, err := client.CreateVolume(test.opts)
+			if !errors.Is(err, test.err) {
+				t.Errorf("CreateVolume: wrong error. Want %v. Got %v.", test.err, err)
+			}
+			if len(fakeRT.requests) != 0 {
+				t.Errorf("CreateVolume: unexpected request for invalid options %#v", test.opts)
+			}
+		})
+	}
+}
+
+func TestCreateVolumePluginName(t *testing.T) {
+	t.Parallel()
+	fakeRT := &FakeRoundTripper{message: `{"Name": "bucket/volume", "Driver": "s3fs"}`, status: http.StatusOK}
+	client := newTestClient(fakeRT)
+	volume, err := client.CreateVolume(CreateVolumeOptions{Name: "bucket/volume", Driver: "s3fs"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if volume.Name != "bucket/volume" {
+		t.Errorf("CreateVolume: wrong name. Want %q. Got %q.", "bucket/volume", volume.Name)
+	}
+	if len(fakeRT.requests) != 1 {
+		t.Errorf("CreateVolume: wrong number of requests. Want 1. Got %d.", len(fakeRT.requests))
+	}
+}
+
 func TestInspectVolume(t *testing.T) {
 	t.Parallel()
 	body := `{
@@ -206,3 +252,104 @@ func TestPruneVolumes(t *testing.T) {
 		t.Errorf("PruneContainers: Expected %#v. Got %#v.", expected, got)
 	}
 }
+
+func TestInspectClusterVolume(t *testing.T) {
+	t.Parallel()
+	body := `{
+		"Name": "shared",
+		"Driver": "csi-driver",
+		"ClusterVolume": {
+			"ID": "vol123",
+			"Version": {"Index": 12},
+			"Spec": {
+				"Group": "storage",
+				"AccessMode": {"Scope": "multi", "Sharing": "all", "MountVolume": {}},
+				"Availability": "active"
+			},
+			"PublishStatus": [{"NodeID": "node1", "State": "published"}]
+		}
+	}`
+	client := newTestClient(&FakeRoundTripper{message: body, status: http.StatusOK})
+	volume, err := client.InspectVolume("shared")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := &ClusterVolume{
+		ID: "vol123",
+		Spec: ClusterVolumeSpec{
+			Group:        "storage",
+			AccessMode:   &VolumeAccessMode{Scope: "multi", Sharing: "all", MountVolume: &VolumeMountVolume{}},
+			Availability: VolumeAvailabilityActive,
+		},
+		PublishStatus: []*VolumePublishStatus{{NodeID: "node1", State: "published"}},
+	}
+	expected.Version.Index = 12
+	if !reflect.DeepEqual(volume.ClusterVolume, expected) {
+		t.Errorf("InspectVolume: Wrong cluster volume. Want %#v. Got %#v.", expected, volume.ClusterVolume)
+	}
+}
+
+func TestUpdateVolume(t *testing.T) {
+	t.Parallel()
+	fakeRT := &FakeRoundTripper{message: "", status: http.StatusOK}
+	client := newTestClient(fakeRT)
+	client.serverAPIVersion = apiVersion142
+	opts := UpdateVolumeOptions{
+		Version: 12,
+		Spec:    ClusterVolumeSpec{Group: "storage", Availability: VolumeAvailabilityDrain},
+	}
+	if err := client.UpdateVolume("shared", opts); err != nil {
+		t.Fatal(err)
+	}
+	req := fakeRT.requests[0]
+	if req.Method != http.MethodPut {
+		t.Errorf("UpdateVolume: Wrong HTTP method. Want %s. Got %s.", http.MethodPut, req.Method)
+	}
+	u, _ := url.Parse(client.getURL("/volumes/shared"))
+	if req.URL.Path != u.Path {
+		t.Errorf("UpdateVolume: Wrong request path. Want %q. Got %q.", u.Path, req.URL.Path)
+	}
+	expectedQuery := map[string][]string{"version": {"12"}}
+	if query := map[string][]string(req.URL.Query()); !reflect.DeepEqual(query, expectedQuery) {
+		t.Errorf("UpdateVolume: Wrong query string. Want %#v. Got %#v.", expectedQuery, query)
+	}
+	var body struct{ Spec ClusterVolumeSpec }
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(body.Spec, opts.Spec) {
+		t.Errorf("UpdateVolume: Wrong request body. Want %#v. Got %#v.", opts.Spec, body.Spec)
+	}
+}
+
+func TestUpdateVolumeErrors(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		status   int
+		expected error
+	}{
+		{http.StatusNotFound, ErrNoSuchVolume},
+		{http.StatusServiceUnavailable, ErrClusterVolumesUnavailable},
+	}
+	for _, tt := range tests {
+		client := newTestClient(&FakeRoundTripper{message: "error", status: tt.status})
+		client.serverAPIVersion = apiVersion142
+		err := client.UpdateVolume("shared", UpdateVolumeOptions{Version: 1})
+		if !errors.Is(err, tt.expected) {
+			t.Errorf("UpdateVolume: Wrong error for status %d. Want %#v. Got %#v.", tt.status, tt.expected, err)
+		}
+	}
+}
+
+func TestUpdateVolumeOldAPI(t *testing.T) {
+	t.Parallel()
+	fakeRT := &FakeRoundTripper{message: "", status: http.StatusOK}
+	client := newTestClient(fakeRT)
+	err := client.UpdateVolume("shared", UpdateVolumeOptions{Version: 1})
+	if err == nil || err.Error() != "cluster volumes are only supported in API#1.42 and above" {
+		t.Errorf("UpdateVolume: unexpected error: %v", err)
+	}
+	if len(fakeRT.requests) > 0 {
+		t.Errorf("UpdateVolume: expected no requests, got %d", len(fakeRT.requests))
+	}
+}